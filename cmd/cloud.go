@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cloud"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+func CloudCommands() *coral.Command {
+	cloudCmd := baseCommand("cloud")
+	cloudCmd.Short = "Create the servers of a cluster at a cloud provider"
+	cloudCmd.Long = "Create the servers of a cluster at a cloud provider"
+	cloudCmd.AddCommand(cloudCreateCommand())
+	return cloudCmd
+}
+
+func cloudCreateCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "create",
+		Short: "create servers, write the configuration file and deploy the cluster",
+		Long: `Create the servers, data disks and firewall described by a cloud file with the hcloud, aws
+or gcloud command line tool, wait for SSH, write the hosts into a configuration file and deploy it.
+
+Example cloud file:
+
+  name: prod
+  provider: hetzner
+  region: fsn1
+  ssh_key: ops
+  admin_cidrs: [203.0.113.0/24]
+  machines:
+    - roles: [master, filer]
+      count: 3
+      type: cx32
+      image: ubuntu-24.04
+    - roles: [volume]
+      count: 3
+      type: cx42
+      image: ubuntu-24.04
+      disks: 2
+      disk_size_gb: 500
+  cluster:
+    replication: "001"
+    s3: true`,
+		SilenceUsage: true,
+	}
+	m := manager.NewManager()
+	m.IdentityFile = path.Join(utils.UserHome(), ".ssh", "id_rsa")
+
+	var fileName, output string
+	var force, skipDeploy, estimateCost bool
+	var pricingFile string
+	var egressGB float64
+	var sshTimeout time.Duration
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "cloud file describing the servers to create")
+	cmd.Flags().StringVarP(&output, "output", "o", "cluster.yaml", "configuration file to write with the created hosts")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite the configuration file if it exists")
+	cmd.Flags().BoolVar(&skipDeploy, "skip-deploy", false, "only create the servers and write the configuration file")
+	cmd.Flags().BoolVar(&estimateCost, "estimate-cost", false, "print the monthly cost of the servers instead of creating them")
+	cmd.Flags().StringVar(&pricingFile, "pricing", "", "yaml file with the prices of the machine types, disks and egress, built-in list prices if empty")
+	cmd.Flags().Float64Var(&egressGB, "egress-gb", 0, "outgoing traffic in GB a month to include in the cost estimate")
+	cmd.Flags().DurationVar(&sshTimeout, "ssh-timeout", 5*time.Minute, "how long to wait for SSH on the new servers")
+	cmd.Flags().StringVarP(&m.IdentityFile, "identity_file", "i", m.IdentityFile, "The path of the SSH identity file of the ssh_key.")
+	cmd.Flags().StringVarP(&m.Version, "version", "v", "", "The SeaweedFS version")
+	cmd.RegisterFlagCompletionFunc("version", completeVersions)
+	cmd.Flags().StringVarP(&m.StateDir, "state-dir", "", m.StateDir, "local directory to keep the progress and locks of operations")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		cloudSpec, err := loadCloudSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		if estimateCost {
+			return printCostEstimate(cloudSpec, pricingFile, egressGB)
+		}
+		if _, err := os.Stat(output); err == nil && !force {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s already exists, use --force to overwrite it", output))
+		}
+		provider, err := cloud.NewProvider(cloudSpec)
+		if err != nil {
+			return exitcode.WithCode(exitcode.Invalid, err)
+		}
+
+		ctx := context.Background()
+		servers, err := cloud.Create(ctx, provider, cloudSpec)
+		if err != nil {
+			if len(servers) > 0 {
+				var names []string
+				for _, server := range servers {
+					names = append(names, server.Name)
+				}
+				logging.Warn(fmt.Sprintf("created before the failure: %s, delete them at %s before trying again", strings.Join(names, ", "), provider.Name()))
+			}
+			return err
+		}
+		if err := writeClusterFile(output, cloudClusterData(cloudSpec, servers)); err != nil {
+			return err
+		}
+		info(fmt.Sprintf("wrote %s", output))
+
+		for _, server := range servers {
+			address := server.Address(cloudSpec.PrivateAddresses)
+			info(fmt.Sprintf("Waiting for SSH on %s %s", server.Name, address))
+			if err := cloud.WaitForSsh(ctx, address, sshTimeout); err != nil {
+				return exitcode.WithCode(exitcode.Unreachable, err)
+			}
+		}
+		if skipDeploy {
+			info(fmt.Sprintf("deploy it with: seaweed-up deploy -f %s -u %s", output, cloudSpec.SshUser))
+			return nil
+		}
+
+		specification, err := loadValidSpecification(output)
+		if err != nil {
+			return err
+		}
+		m.User = cloudSpec.SshUser
+		if m.Version == "" {
+			if err := resolveLatestVersion(m, specification); err != nil {
+				return err
+			}
+		}
+		return m.DeployCluster(specification)
+	}
+
+	return cmd
+}
+
+// printCostEstimate prints the monthly cost of the servers of the cloud spec by tier.
+func printCostEstimate(cloudSpec *cloud.Spec, pricingFile string, egressGB float64) error {
+	var pricing *cloud.Pricing
+	if pricingFile != "" {
+		var err error
+		if pricing, err = cloud.LoadPricing(pricingFile); err != nil {
+			return exitcode.WithCode(exitcode.Invalid, err)
+		}
+	}
+	estimate, err := cloud.EstimateCost(cloudSpec, pricing, egressGB)
+	if err != nil {
+		return exitcode.WithCode(exitcode.Invalid, err)
+	}
+	return output.Print(estimate, func(w io.Writer) {
+		t := output.NewTable(w)
+		fmt.Fprintf(t, "TIER\tCOUNT\tTYPE\tINSTANCES\tDISKS\tMONTHLY %s\n", estimate.Currency)
+		for _, item := range estimate.Items {
+			count := ""
+			if item.Count > 0 {
+				count = fmt.Sprint(item.Count)
+			}
+			fmt.Fprintf(t, "%s\t%s\t%s\t%.2f\t%.2f\t%.2f\n", item.Tier, count, item.Type, item.Instances, item.Disks, item.Total)
+		}
+		fmt.Fprintf(t, "total\t\t\t\t\t%.2f\n", estimate.Total)
+		t.Flush()
+		fmt.Fprintf(w, "\nprices: %s\nboot disks, addresses and taxes are not included\n", estimate.Source)
+	})
+}
+
+func loadCloudSpecification(fileName string) (*cloud.Spec, error) {
+	data, err := readSpecificationFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	cloudSpec := &cloud.Spec{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cloudSpec); err != nil {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("unmarshal %s: %v", fileName, err))
+	}
+	if err := cloudSpec.Validate(); err != nil {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s: %w", fileName, err))
+	}
+	cloudSpec.SshUser = utils.Nvl(cloudSpec.SshUser, "root")
+	return cloudSpec, nil
+}
+
+// cloudClusterData fills the init template with the created servers.
+func cloudClusterData(cloudSpec *cloud.Spec, servers []*cloud.Server) map[string]interface{} {
+	hosts := make(map[string][]clusterFileHost)
+	// the deploy mounts new disks on /data1, /data2, ..., all volume servers get
+	// the folders of the one with the fewest disks
+	dataDisks := -1
+	for _, server := range servers {
+		for _, role := range server.Roles {
+			hosts[role] = append(hosts[role], clusterFileHost{Ip: server.Address(cloudSpec.PrivateAddresses)})
+			if role == "volume" && (dataDisks < 0 || server.Disks < dataDisks) {
+				dataDisks = server.Disks
+			}
+		}
+	}
+	folders := []string{"data"}
+	if dataDisks > 0 {
+		folders = nil
+		for i := 1; i <= dataDisks; i++ {
+			folders = append(folders, fmt.Sprintf("/data%d", i))
+		}
+	}
+	return map[string]interface{}{
+		"Generator":         "seaweed-up cloud create",
+		"Masters":           hosts["master"],
+		"Volumes":           hosts["volume"],
+		"Filers":            hosts["filer"],
+		"Envoys":            hosts["envoy"],
+		"Folders":           folders,
+		"DiskType":          utils.Nvl(cloudSpec.Cluster.DiskType, "hdd"),
+		"MountDisks":        dataDisks > 0,
+		"FsType":            utils.Nvl(cloudSpec.Cluster.FsType, "ext4"),
+		"Replication":       utils.Nvl(cloudSpec.Cluster.Replication, "000"),
+		"VolumeSizeLimitMB": 5000,
+		"S3":                cloudSpec.Cluster.S3,
+		"ConfigDir":         "/etc/seaweed",
+		"DataDir":           "/opt/seaweed",
+	}
+}
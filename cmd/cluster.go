@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+func ClusterCommand() *coral.Command {
+	clusterCmd := baseCommand("cluster")
+	clusterCmd.Short = "Operate a cluster described by a configuration file"
+	clusterCmd.Long = "Operate a cluster described by a configuration file"
+	clusterCmd.AddCommand(firewallCommands())
+	clusterCmd.AddCommand(preflightCommand())
+	clusterCmd.AddCommand(disksCommands())
+	clusterCmd.AddCommand(systemdCommands())
+	clusterCmd.AddCommand(tuningCommands())
+	clusterCmd.AddCommand(balanceCommand())
+	clusterCmd.AddCommand(statusCommand())
+	clusterCmd.AddCommand(historyCommand())
+	clusterCmd.AddCommand(uptimeCommand())
+	clusterCmd.AddCommand(doctorCommand())
+	clusterCmd.AddCommand(logsCommand())
+	clusterCmd.AddCommand(execCommand())
+	clusterCmd.AddCommand(osUpdateCommand())
+	clusterCmd.AddCommand(unlockCommand())
+	return clusterCmd
+}
+
+// newClusterManager creates a manager with the SSH flags shared by all cluster commands.
+func newClusterManager(cmd *coral.Command) *manager.Manager {
+	m := manager.NewManager()
+	m.IdentityFile = path.Join(utils.UserHome(), ".ssh", "id_rsa")
+
+	cmd.Flags().StringVarP(&m.User, "user", "u", utils.CurrentUser(), "The user name to login via SSH. The user must has root (or sudo) privilege.")
+	cmd.Flags().IntVarP(&m.SshPort, "port", "p", 22, "The port to SSH.")
+	cmd.Flags().StringVarP(&m.IdentityFile, "identity_file", "i", m.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
+	cmd.Flags().StringVarP(&m.StateDir, "state-dir", "", m.StateDir, "local directory to keep the progress and locks of operations")
+	return m
+}
+
+// strictSpecification makes every command refuse a configuration file with problems, see --strict.
+var strictSpecification bool
+
+// loadSpecification loads the configuration file of a command only reading the cluster,
+// warning about unknown fields unless --strict is given.
+func loadSpecification(fileName string) (*spec.Specification, error) {
+	return parseSpecification(fileName, func(*spec.Specification) bool { return false })
+}
+
+// loadValidSpecification loads the configuration file of a command changing the hosts,
+// refusing unknown fields and the problems reported by config validate.
+func loadValidSpecification(fileName string) (*spec.Specification, error) {
+	return parseSpecification(fileName, func(*spec.Specification) bool { return true })
+}
+
+// loadSpecificationChanging loads the configuration file of a command changing the hosts
+// depending on the configuration, like loadValidSpecification when changing returns true.
+func loadSpecificationChanging(fileName string, changing func(*spec.Specification) bool) (*spec.Specification, error) {
+	return parseSpecification(fileName, changing)
+}
+
+func parseSpecification(fileName string, changing func(*spec.Specification) bool) (*spec.Specification, error) {
+	if fileName == "" && activeContext != nil {
+		fileName = activeContext.File
+	}
+	specification := &spec.Specification{}
+	data, readErr := readSpecificationFile(fileName)
+	if readErr != nil {
+		return nil, readErr
+	}
+	if unmarshalErr := yaml.Unmarshal(data, specification); unmarshalErr != nil {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("unmarshal %s: %v", fileName, unmarshalErr))
+	}
+	problems, _ := spec.UnknownFields(data)
+	strict := strictSpecification || changing(specification)
+	if strict {
+		problems = append(problems, specification.Validate()...)
+	}
+	for _, problem := range problems {
+		logging.Warn(fmt.Sprintf("%s: %v", fileName, problem))
+	}
+	if strict && len(problems) > 0 {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s: %d problems found, fix them or see seaweed-up config validate", fileName, len(problems)))
+	}
+	for _, warning := range specification.Warnings() {
+		logging.Warn(fmt.Sprintf("%s: %v", fileName, warning))
+	}
+	return specification, nil
+}
+
+// readSpecificationFile reads a specification file with templates and environment variables expanded.
+func readSpecificationFile(fileName string) ([]byte, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("read %s: %w", fileName, err))
+	}
+	data, err = spec.Render(data)
+	if err != nil {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s: %w", fileName, err))
+	}
+	return data, nil
+}
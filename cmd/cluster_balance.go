@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/muesli/coral"
+)
+
+func balanceCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "balance",
+		Short: "fix replication and balance volumes across volume servers",
+		Long: `Run volume.fix.replication and volume.balance through the master.
+
+Nothing is done while a volume server of the configuration is not registered on the master.
+With --dry-run the replication fixes and the balancing plan are only printed.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName, collection string
+	var dryRun bool
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringVarP(&collection, "collection", "", "", "only fix and balance this collection")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "print the plan without moving volumes")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadValidSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.Balance(specification, collection, dryRun)
+	}
+
+	return cmd
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+)
+
+func disksCommands() *coral.Command {
+	disksCmd := baseCommand("disks")
+	disksCmd.Short = "Inspect the disks of volume servers"
+	disksCmd.Long = "Inspect the disks of volume servers"
+	disksCmd.AddCommand(disksStatusCommand())
+	return disksCmd
+}
+
+func disksStatusCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "status",
+		Short: "show SMART and mount health of volume server disks",
+		Long: `Show SMART health, read-only mounts and writability of the volume folders for every disk
+on the volume servers. smartctl is used when it is installed on the host.
+
+With --action readonly, the volumes stored on failing disks are marked read-only on the master.
+With --action evacuate, all volumes of a volume server with a failing disk are moved to other servers.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	var action string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringVar(&action, "action", "", "[readonly|evacuate] what to do with the volumes of failing disks")
+	cmd.Flags().StringVarP(&m.DiskDiscovery, "disk-discovery", "", "auto", "[auto|lsblk-json|lsblk|sysfs] how to discover disks")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		diskAction := manager.DiskAction(action)
+		switch diskAction {
+		case manager.DiskActionNone, manager.DiskActionMarkReadonly, manager.DiskActionEvacuate:
+		default:
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("unknown action %q, supported: readonly, evacuate", action))
+		}
+		load := loadSpecification
+		if diskAction != manager.DiskActionNone {
+			load = loadValidSpecification
+		}
+		specification, err := load(fileName)
+		if err != nil {
+			return err
+		}
+		return m.DiskStatus(specification, diskAction)
+	}
+
+	return cmd
+}
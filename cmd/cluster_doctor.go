@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/muesli/coral"
+)
+
+func doctorCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "doctor",
+		Short: "diagnose cluster problems and suggest fixes",
+		Long: `Check master quorum and leader, volume server registration on the master, filer to master
+connectivity, S3 endpoint reachability, clock skew and disk usage, then print the problems found,
+most fundamental first, with suggested remediation commands.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().DurationVarP(&m.MaxClockSkew, "max-clock-skew", "", m.MaxClockSkew, "fail when the clocks of the hosts are further apart")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.Doctor(specification, fileName)
+	}
+
+	return cmd
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+)
+
+func execCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "exec -- <command>",
+		Short: "run a command on all hosts of the cluster",
+		Long: `Run a shell command, or a local script with --script, on all hosts of the cluster in parallel,
+e.g.
+
+  seaweed-up cluster exec -f cluster.yaml --role volume -- 'df -h /data'
+
+The output is grouped by host and followed by a summary of the exit codes.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	var options manager.ExecOptions
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringVarP(&options.Role, "role", "", "", "[master|volume|filer|envoy] only run on hosts of one component")
+	cmd.Flags().StringVarP(&options.Script, "script", "", "", "local script to upload and run")
+	cmd.Flags().BoolVarP(&options.Sudo, "sudo", "", false, "run as root")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		options.Command = strings.Join(args, " ")
+		if (options.Command == "") == (options.Script == "") {
+			return fmt.Errorf("give either a command after -- or a --script")
+		}
+		specification, err := loadValidSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.Exec(specification, options)
+	}
+
+	return cmd
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/muesli/coral"
+)
+
+func firewallCommands() *coral.Command {
+	firewallCmd := baseCommand("firewall")
+	firewallCmd.Short = "Manage the OS firewall for cluster ports"
+	firewallCmd.Long = "Manage the OS firewall for cluster ports"
+	firewallCmd.AddCommand(firewallApplyCommand())
+	return firewallCmd
+}
+
+func firewallApplyCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "apply",
+		Short: "allow cluster ports only from cluster nodes and admin networks",
+		Long: `Configure firewalld, ufw or nftables on each host so that the master, volume, filer,
+S3 and WebDAV ports are reachable only from the other cluster nodes and the admin CIDRs.
+
+firewalld and ufw rely on their default deny policy for other sources, an inactive ufw is an
+error. nftables rules are kept in the "inet seaweed" table, which drops other sources explicitly.
+The table is saved to /etc/nftables.d/seaweed.nft, included by the nftables service, which is
+enabled to load it at boot.
+
+The rules added before for ports or sources no longer in the cluster are removed, unless only
+one component is applied. ufw rules are found by their "seaweed" comment, firewalld rules by
+the firewalld.rules file in dir.conf.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	var adminCidrs []string
+	var backend string
+	var dryRun bool
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringSliceVar(&adminCidrs, "admin-cidr", []string{}, "networks allowed to reach the cluster ports besides the cluster nodes, e.g. 10.0.0.0/8")
+	cmd.Flags().StringVar(&backend, "backend", "auto", "[auto|firewalld|ufw|nftables] firewall to configure")
+	cmd.Flags().StringVarP(&m.ComponentToDeploy, "component", "c", "", "[master|volume|filer|s3|webdav|broker|envoy] only open ports of one component")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the commands of each host without changing the firewall")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadValidSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.ApplyFirewall(specification, adminCidrs, backend, dryRun)
+	}
+
+	return cmd
+}
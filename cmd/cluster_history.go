@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/contexts"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+)
+
+func historyCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "history [context]",
+		Short: "show the configurations applied to the cluster",
+		Long: `Show the revisions of the configuration deploy applied to the cluster, who applied them and when.
+
+Each revision is kept in the state dir, named by the hash of its content. --diff shows the changes of
+each revision from the one applied before, --revision the changes of one revision. The cluster is the
+one of the context given as argument, or else of -f or of the current context.
+
+Set global.history.git_repo to also commit each applied revision to a git repository:
+
+  global:
+    history:
+      git_repo: /srv/ops/clusters
+      git_file: prod-eu/cluster.yaml`,
+		Example:           "  seaweed-up cluster history prod-eu --diff",
+		Args:              coral.MaximumNArgs(1),
+		ValidArgsFunction: completeContexts,
+		SilenceUsage:      true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	var options manager.HistoryOptions
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().BoolVarP(&options.Diff, "diff", "", false, "show the changes of each revision")
+	cmd.Flags().StringVarP(&options.Revision, "revision", "r", "", "only show the changes of this revision")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		if len(args) > 0 {
+			config, err := contexts.Load()
+			if err != nil {
+				return err
+			}
+			context := config.Find(args[0])
+			if context == nil {
+				return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("there is no context %s", args[0]))
+			}
+			fileName = context.File
+			if !command.Flags().Changed("state-dir") {
+				m.StateDir = contexts.StateDir(context.Name)
+			}
+		}
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.History(specification, options)
+	}
+
+	return cmd
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+)
+
+func logsCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "logs",
+		Short: "show the logs of all components, prefixed by host",
+		Long: `Show the logs of the masters, volume servers, filers and envoy proxies of the cluster over SSH,
+one line at a time prefixed with the host and instance, e.g.
+
+  seaweed-up cluster logs -f cluster.yaml -c filer --since 1h --follow
+
+The weed log files are read by default, --journal reads the systemd journal of the services.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName, format string
+	var options manager.LogOptions
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringVarP(&m.ComponentToDeploy, "component", "c", "", "[master|volume|filer|s3|webdav|mount|broker|envoy] only show the logs of one component")
+	cmd.Flags().DurationVarP(&options.Since, "since", "", 0, "only show lines newer than this, e.g. 30m or 2h")
+	cmd.Flags().IntVarP(&options.Lines, "lines", "n", 100, "number of recent lines of each instance, unless --since is given")
+	cmd.Flags().BoolVarP(&options.Follow, "follow", "", false, "keep printing new lines")
+	cmd.Flags().BoolVarP(&options.Journal, "journal", "", false, "read the systemd journal instead of the log files")
+	cmd.Flags().StringVarP(&format, "output", "o", "text", "[text|json] json prints one object per line, also implied by --format json or yaml")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		switch format {
+		case "text":
+			options.JSON = output.Structured()
+		case "json":
+			options.JSON = true
+		default:
+			return fmt.Errorf("unknown output %q, use text or json", format)
+		}
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.Logs(specification, options)
+	}
+
+	return cmd
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+)
+
+func osUpdateCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "os-update",
+		Short: "apply OS package updates to the hosts one after the other",
+		Long: `Apply the apt, dnf or yum package updates to the hosts of the cluster, one host at a time.
+
+Each host is put in maintenance while it is updated. With --reboot-if-needed, hosts whose updates need
+a reboot, like a new kernel, are rebooted. The next host is only updated once the services of the host
+are active, answer on their http port and its volume servers are registered on the master again.
+If a host fails, it stays in maintenance and the remaining hosts are not updated.
+Hosts already in maintenance are skipped.`,
+		Example:      "  seaweed-up cluster os-update -f cluster.yaml --reboot-if-needed",
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	var options manager.OsUpdateOptions
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().BoolVarP(&options.RebootIfNeeded, "reboot-if-needed", "", false, "reboot hosts whose updates need it")
+	cmd.Flags().BoolVarP(&options.Drain, "drain", "", false, "move the volumes off a host before updating it")
+	cmd.Flags().DurationVarP(&options.Timeout, "timeout", "", 10*time.Minute, "how long to wait for a host to come back healthy")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadValidSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.OsUpdate(specification, options)
+	}
+
+	return cmd
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/muesli/coral"
+)
+
+func preflightCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "preflight",
+		Short: "check that all hosts are ready for deployment",
+		Long: `Check SSH and sudo access, OS and kernel, required utilities, port conflicts,
+disk space, memory, ulimits, time sync and SELinux/AppArmor on every host.
+
+The same checks run before each deploy unless --skip-preflight is given.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().DurationVarP(&m.MaxClockSkew, "max-clock-skew", "", m.MaxClockSkew, "fail when the clocks of the hosts are further apart")
+	cmd.Flags().StringVarP(&m.ComponentToDeploy, "component", "c", "", "[master|volume|filer|s3|webdav|broker|envoy] only check ports of one component")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.Preflight(specification)
+	}
+
+	return cmd
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/contexts"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+func statusCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "status",
+		Short: "show the state of all component instances",
+		Long: `Show whether the service of each component instance is active and answers on its http port.
+
+The hosts are queried in parallel. The status is kept in the state dir for --cache-ttl and shown again
+by the runs within it, so several operators watching the cluster do not query the hosts each.
+--watch refreshes the status every --interval until interrupted. A status a minute at most is also
+recorded for cluster uptime.
+
+With global.remediation set, the status also looks for drift: services stopped, or systemd units
+differing from the configuration. notify runs the on_drift hooks once per drift, auto restarts
+the service, writing its unit again for config drift, and then runs the on_drift hooks.`,
+		Example:      "  seaweed-up cluster status -f cluster.yaml --watch --interval 5s",
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	var options manager.StatusOptions
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().IntVarP(&options.Parallel, "parallel", "", 10, "how many hosts to query at the same time")
+	cmd.Flags().DurationVarP(&options.CacheTTL, "cache-ttl", "", 5*time.Second, "reuse a status collected within this time, 0 to always query the hosts")
+	cmd.Flags().BoolVarP(&options.Watch, "watch", "w", false, "refresh the status until interrupted")
+	cmd.Flags().DurationVarP(&options.Interval, "interval", "", 5*time.Second, "time between two refreshes of --watch")
+	var allContexts bool
+	cmd.Flags().BoolVarP(&allContexts, "all-contexts", "", false, "show the status of the clusters of all contexts")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		if allContexts {
+			if options.Watch {
+				return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("--watch shows one cluster, give it with --context"))
+			}
+			return allContextsStatus(m, options)
+		}
+		specification, err := loadSpecificationChanging(fileName, autoRemediation)
+		if err != nil {
+			return err
+		}
+		return m.Status(specification, options)
+	}
+
+	return cmd
+}
+
+// autoRemediation tells whether cluster status restores the drift it finds on the hosts.
+func autoRemediation(specification *spec.Specification) bool {
+	remediation := specification.GlobalOptions.Remediation
+	return remediation != nil && (remediation.Service == "auto" || remediation.Config == "auto")
+}
+
+// allContextsStatus prints the status of the cluster of each context, logged
+// in with the settings of the context or else the ones of m.
+func allContextsStatus(m *manager.Manager, options manager.StatusOptions) error {
+	config, err := contexts.Load()
+	if err != nil {
+		return err
+	}
+	if len(config.Contexts) == 0 {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("no context yet, add one with: seaweed-up context add <name> -f <file>"))
+	}
+	statuses := make(map[string]*manager.ClusterStatus)
+	var failed []string
+	for _, context := range config.Contexts {
+		specification, err := loadSpecification(context.File)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("context %s: %v", context.Name, err))
+			failed = append(failed, context.Name)
+			continue
+		}
+		cm := manager.NewManager()
+		cm.User = utils.Nvl(context.User, m.User)
+		cm.IdentityFile = utils.Nvl(context.IdentityFile, m.IdentityFile)
+		cm.SshPort = m.SshPort
+		cm.StateDir = contexts.StateDir(context.Name)
+		operator.KnownHosts = filepath.Join(cm.StateDir, "known_hosts")
+		statuses[context.Name] = cm.CollectStatus(specification, options)
+	}
+	err = output.Print(statuses, func(w io.Writer) {
+		for _, context := range config.Contexts {
+			if status, found := statuses[context.Name]; found {
+				fmt.Fprintf(w, "===== %s =====\n", context.Name)
+				manager.PrintStatus(w, status)
+				fmt.Fprintln(w)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return exitcode.WithCode(exitcode.Partial, fmt.Errorf("no status of context %s", strings.Join(failed, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/muesli/coral"
+)
+
+func systemdCommands() *coral.Command {
+	systemdCmd := baseCommand("systemd")
+	systemdCmd.Short = "Inspect the systemd units of the components"
+	systemdCmd.Long = "Inspect the systemd units of the components"
+	systemdCmd.AddCommand(systemdDiffCommand())
+	return systemdCmd
+}
+
+func systemdDiffCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "diff",
+		Short: "show how the installed systemd units differ from the configuration",
+		Long: `Render the systemd unit of every master, volume and filer from the systemd: options of the
+configuration file and show a diff against the unit installed on the host.
+
+Deploy installs the new units and restarts the components whose unit changed.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.SystemdDiff(specification)
+	}
+
+	return cmd
+}
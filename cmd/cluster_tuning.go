@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/muesli/coral"
+)
+
+func tuningCommands() *coral.Command {
+	tuningCmd := baseCommand("tuning")
+	tuningCmd.Short = "Inspect the kernel tuning of the hosts"
+	tuningCmd.Long = "Inspect the kernel tuning of the hosts"
+	tuningCmd.AddCommand(tuningDiffCommand())
+	return tuningCmd
+}
+
+func tuningDiffCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "diff",
+		Short: "show the tuning settings of the hosts that differ from the configuration",
+		Long: `Compare the sysctl and transparent hugepages settings of every host with the global.tuning
+section of the configuration file, its profile included.
+
+Deploy applies them again with seaweed-up deploy -c tuning. The open files limit of the components
+is part of their systemd unit, see seaweed-up cluster systemd diff.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.TuningDiff(specification)
+	}
+
+	return cmd
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/muesli/coral"
+)
+
+func unlockCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "unlock",
+		Short: "show or remove the lock held by a running or crashed operation",
+		Long: `Deploy, clean, balance, firewall apply and disk actions lock the cluster, on this machine and on the
+first master, so two operators can not change the cluster at the same time. unlock shows who holds
+the lock, and removes it with --force after an operation crashed or was killed.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	var force bool
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().BoolVarP(&force, "force", "", false, "remove the locks")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.Unlock(specification, force)
+	}
+
+	return cmd
+}
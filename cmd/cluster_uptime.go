@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/contexts"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+func uptimeCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "uptime [context]",
+		Short: "report the availability of the components",
+		Long: `Report the availability of each component over a window and by calendar month, from the statuses
+cluster status collected, at most one a minute, kept in the state dir for 400 days. Run cluster status
+regularly, with --watch or from cron, to record them.
+
+The availability is the percent of the statuses of the instances that were healthy, instances in
+maintenance left out. An incident is an instance becoming unhealthy, the MTTR the mean time until it
+was healthy again. The cluster is the one of the context given as argument, or else of -f or of the
+current context. -o writes the report to a file, as csv for .csv files, yaml for .yaml or .yml files
+and json otherwise.`,
+		Example:           "  seaweed-up cluster uptime prod-eu --window 30d -o sla.csv",
+		Args:              coral.MaximumNArgs(1),
+		ValidArgsFunction: completeContexts,
+		SilenceUsage:      true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName, window, outputFile string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringVarP(&window, "window", "", "30d", "period to report, ending now, like 30d or 12h")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "file to write the report to, instead of printing it")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		duration, err := utils.ParseDuration(window)
+		if err != nil || duration <= 0 {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("--window must be a positive duration like 30d, got %q", window))
+		}
+		if len(args) > 0 {
+			config, err := contexts.Load()
+			if err != nil {
+				return err
+			}
+			context := config.Find(args[0])
+			if context == nil {
+				return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("there is no context %s", args[0]))
+			}
+			fileName = context.File
+			if !command.Flags().Changed("state-dir") {
+				m.StateDir = contexts.StateDir(context.Name)
+			}
+		}
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		report, err := m.Uptime(specification, duration)
+		if err != nil {
+			return err
+		}
+		if report.Samples == 0 {
+			logging.Warn(fmt.Sprintf("no status recorded in the last %s, run cluster status to record them", window))
+		}
+
+		switch {
+		case outputFile == "":
+			return output.Print(report, func(w io.Writer) { manager.PrintUptime(w, report) })
+		case strings.EqualFold(filepath.Ext(outputFile), ".csv"):
+			f, err := os.Create(outputFile)
+			if err != nil {
+				return err
+			}
+			if err := manager.WriteUptimeCSV(f, report); err != nil {
+				f.Close()
+				return fmt.Errorf("write %s: %w", outputFile, err)
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		default:
+			if err := writeReport(outputFile, report); err != nil {
+				return err
+			}
+		}
+		info(fmt.Sprintf("wrote the uptime report to %s", outputFile))
+		return nil
+	}
+
+	return cmd
+}
@@ -2,23 +2,111 @@ package cmd
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/mitchellh/go-homedir"
 	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/httpclient"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
 )
 
 type Installer func() *coral.Command
 
 func Execute() error {
 
+	if err := loadSettings(); err != nil {
+		return err
+	}
 	rootCmd := baseCommand("seaweed-up")
+	var format string
+	rootCmd.PersistentFlags().StringVar(&format, "format", "table", "[table|json|yaml] format of the command results")
+	rootCmd.PersistentFlags().BoolVar(&utils.NonInteractive, "non-interactive", false, "never prompt, fail if input like a sudo password is required")
+	rootCmd.PersistentFlags().BoolVar(&utils.NonInteractive, "yes", false, "alias of --non-interactive")
+	rootCmd.PersistentFlags().BoolVar(&strictSpecification, "strict", false, "refuse configuration files with unknown fields or invalid values in all commands, not only in those changing the hosts")
+	rootCmd.PersistentFlags().BoolVar(&operator.StrictHostKeys, "strict-host-keys", false, "refuse SSH hosts whose key is not trusted yet, instead of trusting it on first use")
+	rootCmd.PersistentFlags().StringVar(&operator.Certificates.CAKey, "ssh-ca-key", "", "login with short-lived SSH certificates signed with this user CA key")
+	rootCmd.PersistentFlags().StringVar(&operator.Certificates.VaultSign, "ssh-vault-sign", "", "login with short-lived SSH certificates signed by this vault endpoint, like ssh-client-signer/sign/deployer")
+	rootCmd.PersistentFlags().DurationVar(&operator.Certificates.Validity, "ssh-cert-validity", 5*time.Minute, "how long the signed SSH certificates are valid")
+	rootCmd.PersistentFlags().StringVar(&httpclient.CABundle, "ca-bundle", httpclient.CABundle, "PEM file of CAs to trust besides the system ones, for the GitHub API, downloads, vault and health checks")
+	var contextName string
+	rootCmd.PersistentFlags().BoolVar(&redact.ShowSecrets, "show-secrets", false, "do not redact passwords and keys in messages, results, plans and support bundles")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "run on the cluster of this context instead of the current one, see seaweed-up context")
+	rootCmd.RegisterFlagCompletionFunc("context", func(cmd *coral.Command, args []string, toComplete string) ([]string, coral.ShellCompDirective) {
+		return contextNames(), coral.ShellCompDirectiveNoFileComp
+	})
+	var verbosity int
+	var logFormat, logFile string
+	rootCmd.PersistentFlags().CountVar(&verbosity, "verbose", "show remote commands with their duration and exit status, twice to also show their output")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "[text|json] format of the log messages")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "append all log messages, including every remote command, to this file")
+	rootCmd.PersistentPreRunE = func(cmd *coral.Command, args []string) error {
+		// flags given win over the environment, the context, then the config file
+		if err := applyDefaults(cmd, fileSettings); err != nil {
+			return err
+		}
+		if err := useContext(cmd, utils.Nvl(contextName, envSettings.Context)); err != nil {
+			return err
+		}
+		if err := applyDefaults(cmd, envSettings); err != nil {
+			return err
+		}
+		if err := output.SetFormat(format); err != nil {
+			return exitcode.WithCode(exitcode.Invalid, err)
+		}
+		if err := logging.SetFormat(logFormat); err != nil {
+			return exitcode.WithCode(exitcode.Invalid, err)
+		}
+		logging.SetVerbosity(verbosity)
+		if operator.Certificates.CAKey != "" && operator.Certificates.VaultSign != "" {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("--ssh-ca-key and --ssh-vault-sign can not be used together"))
+		}
+		if logFile != "" {
+			if err := logging.SetFile(logFile); err != nil {
+				return err
+			}
+			logging.Debug("run", "command", cmd.CommandPath())
+		}
+		return nil
+	}
+	defer logging.Close()
+	rootCmd.SetFlagErrorFunc(func(cmd *coral.Command, err error) error {
+		return exitcode.WithCode(exitcode.Invalid, err)
+	})
 	rootCmd.AddCommand(TlsCommands())
 	rootCmd.AddCommand(VersionCommand())
 	rootCmd.AddCommand(GetCommand())
 	rootCmd.AddCommand(ScaffoldCommand())
+	rootCmd.AddCommand(InitCommand())
+	rootCmd.AddCommand(TemplateCommands())
 	rootCmd.AddCommand(DeployCommand())
+	rootCmd.AddCommand(ExportCommand())
 	rootCmd.AddCommand(CleanCommand())
+	rootCmd.AddCommand(ClusterCommand())
+	rootCmd.AddCommand(ShellCommand())
+	rootCmd.AddCommand(SupportBundleCommand())
+	rootCmd.AddCommand(ConfigCommands())
+	rootCmd.AddCommand(CloudCommands())
+	rootCmd.AddCommand(InventoryCommands())
+	rootCmd.AddCommand(PluginCommands())
+	rootCmd.AddCommand(NodeCommands())
+	rootCmd.AddCommand(SshCommands())
+	rootCmd.AddCommand(ContextCommands())
+	rootCmd.AddCommand(SecurityCommands())
+	rootCmd.AddCommand(ReportCommands())
+	rootCmd.AddCommand(MonitoringCommands())
+	rootCmd.AddCommand(FilerCommands())
+	rootCmd.AddCommand(MigrateCommand())
 
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if err != nil {
+		logging.Debug("failed", "error", err)
+	}
+	return err
 }
 
 func baseCommand(name string) *coral.Command {
@@ -37,5 +125,5 @@ func expandPath(path string) string {
 }
 
 func info(message string) {
-	fmt.Println("[INFO] " + message)
+	logging.Info(message)
 }
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/config"
+	"github.com/seaweedfs/seaweed-up/pkg/contexts"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// completionTimeout bounds the lookups of the shell completion, like the
+// releases on GitHub, so the shell never hangs on a tab.
+const completionTimeout = 5 * time.Second
+
+// completeContexts completes the first argument with the names of the contexts.
+func completeContexts(cmd *coral.Command, args []string, toComplete string) ([]string, coral.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, coral.ShellCompDirectiveNoFileComp
+	}
+	return contextNames(), coral.ShellCompDirectiveNoFileComp
+}
+
+// completeHosts completes the first argument with the hosts of the
+// configuration of -f or of the context.
+func completeHosts(cmd *coral.Command, args []string, toComplete string) ([]string, coral.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, coral.ShellCompDirectiveNoFileComp
+	}
+	return specificationHosts(cmd), coral.ShellCompDirectiveNoFileComp
+}
+
+// completeVersions completes with the SeaweedFS releases, the latest first.
+func completeVersions(cmd *coral.Command, args []string, toComplete string) ([]string, coral.ShellCompDirective) {
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	releases, err := config.GitHubReleases(ctx, "seaweedfs", "seaweedfs")
+	if err != nil {
+		coral.CompDebugln(fmt.Sprintf("list releases: %v", err), false)
+		return nil, coral.ShellCompDirectiveNoFileComp
+	}
+	var versions []string
+	for _, release := range releases {
+		if !release.Draft {
+			versions = append(versions, release.TagName)
+		}
+	}
+	return versions, coral.ShellCompDirectiveNoFileComp
+}
+
+// completeKnownHosts completes the first argument with the hosts whose keys are trusted.
+func completeKnownHosts(cmd *coral.Command, args []string, toComplete string) ([]string, coral.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, coral.ShellCompDirectiveNoFileComp
+	}
+	return knownHosts(), coral.ShellCompDirectiveNoFileComp
+}
+
+func contextNames() []string {
+	config, err := contexts.Load()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, context := range config.Contexts {
+		names = append(names, context.Name)
+	}
+	return names
+}
+
+func knownHosts() []string {
+	hosts, err := operator.ListKnownHosts()
+	if err != nil {
+		return nil
+	}
+	var addresses []string
+	for _, h := range hosts {
+		addresses = append(addresses, h.Host)
+	}
+	return addresses
+}
+
+// specificationHosts returns the hosts of the configuration file of -f, or
+// else of the context of --context or the current one. The completion runs
+// without the context selected by the root command, so it is looked up here.
+func specificationHosts(cmd *coral.Command) []string {
+	fileName := ""
+	if flag := cmd.Flags().Lookup("file"); flag != nil {
+		fileName = flag.Value.String()
+	}
+	if fileName == "" && activeContext != nil {
+		fileName = activeContext.File
+	}
+	if fileName == "" {
+		config, err := contexts.Load()
+		if err != nil {
+			return nil
+		}
+		name := utils.Nvl(envSettings.Context, config.Current, fileSettings.Context)
+		if flag := cmd.Flag("context"); flag != nil && flag.Value.String() != "" {
+			name = flag.Value.String()
+		}
+		context := config.Find(name)
+		if context == nil {
+			return nil
+		}
+		fileName = context.File
+	}
+	data, err := readSpecificationFile(fileName)
+	if err != nil {
+		return nil
+	}
+	specification := &spec.Specification{}
+	if err := yaml.Unmarshal(data, specification); err != nil {
+		return nil
+	}
+	return manager.NewManager().Hosts(specification)
+}
+
+// pickArg returns the first argument, or else asks to pick one of the options.
+func pickArg(args []string, prompt string, options []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	choice, err := utils.Pick(prompt, options)
+	if err != nil {
+		return "", exitcode.WithCode(exitcode.Invalid, err)
+	}
+	return choice, nil
+}
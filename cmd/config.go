@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"gopkg.in/yaml.v3"
+)
+
+func ConfigCommands() *coral.Command {
+	configCmd := baseCommand("config")
+	configCmd.Short = "Work with cluster configuration files"
+	configCmd.Long = "Work with cluster configuration files"
+	configCmd.AddCommand(configValidateCommand())
+	configCmd.AddCommand(configSchemaCommand())
+	configCmd.AddCommand(configDefaultsCommand())
+	return configCmd
+}
+
+func configValidateCommand() *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "validate",
+		Short:        "check a configuration file for unknown fields and invalid values",
+		Long:         "check a configuration file for unknown fields and invalid values",
+		SilenceUsage: true,
+	}
+
+	var fileName string
+	command.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		data, err := readSpecificationFile(fileName)
+		if err != nil {
+			return err
+		}
+		specification := &spec.Specification{}
+		if err := yaml.Unmarshal(data, specification); err != nil {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s: %w", fileName, err))
+		}
+		problems, err := spec.UnknownFields(data)
+		if err != nil {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s: %w", fileName, err))
+		}
+		problems = append(problems, specification.Validate()...)
+		warnings := specification.Warnings()
+		result := struct {
+			File     string            `json:"file" yaml:"file"`
+			Valid    bool              `json:"valid" yaml:"valid"`
+			Problems []spec.FieldError `json:"problems" yaml:"problems"`
+			Warnings []spec.FieldError `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+		}{fileName, len(problems) == 0, append([]spec.FieldError{}, problems...), warnings}
+		err = output.Print(result, func(w io.Writer) {
+			for _, problem := range problems {
+				fmt.Fprintf(w, "%s: %v\n", fileName, problem)
+			}
+			for _, warning := range warnings {
+				fmt.Fprintf(w, "%s: warning: %v\n", fileName, warning)
+			}
+			if len(problems) == 0 {
+				fmt.Fprintf(w, "%s is valid\n", fileName)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if len(problems) > 0 {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s: %d problems found", fileName, len(problems)))
+		}
+		return nil
+	}
+
+	return command
+}
+
+func configSchemaCommand() *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "schema",
+		Short:        "print the JSON Schema of configuration files",
+		Long:         "print the JSON Schema of configuration files, e.g. for editor completion and validation",
+		SilenceUsage: true,
+	}
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(spec.JSONSchema())
+	}
+
+	return command
+}
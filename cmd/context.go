@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/contexts"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// activeContext is the context the command runs in, nil if none.
+var activeContext *contexts.Context
+
+func ContextCommands() *coral.Command {
+	contextCmd := baseCommand("context")
+	contextCmd.Short = "Switch between named clusters"
+	contextCmd.Long = `Switch between named clusters
+
+A context names a cluster by its configuration file, with the SSH user and identity file to operate it.
+Commands given no -f run on the context selected with "context use", or on the one given with --context.
+Each context keeps its locks, progress and trusted host keys apart, in ~/.seaweed-up/contexts/<name>.`
+	contextCmd.AddCommand(contextAddCommand())
+	contextCmd.AddCommand(contextUseCommand())
+	contextCmd.AddCommand(contextListCommand())
+	contextCmd.AddCommand(contextRemoveCommand())
+	return contextCmd
+}
+
+func contextAddCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:          "add <name>",
+		Short:        "add a context, or change an existing one",
+		Long:         "add a context for the cluster of a configuration file, or change an existing one",
+		Example:      "  seaweed-up context add prod-eu -f prod-eu.yaml -u ops -i ~/.ssh/prod",
+		Args:         coral.ExactArgs(1),
+		SilenceUsage: true,
+	}
+
+	context := &contexts.Context{}
+	cmd.Flags().StringVarP(&context.File, "file", "f", "", "configuration file of the cluster")
+	cmd.Flags().StringVarP(&context.User, "user", "u", "", "the user name to login via SSH, the one of the command if empty")
+	cmd.Flags().StringVarP(&context.IdentityFile, "identity_file", "i", "", "the path of the SSH identity file, the one of the command if empty")
+	cmd.MarkFlagRequired("file")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		config, err := contexts.Load()
+		if err != nil {
+			return err
+		}
+		context.Name = args[0]
+		if context.IdentityFile != "" {
+			context.IdentityFile, _ = filepath.Abs(expandPath(context.IdentityFile))
+		}
+		if err := config.Set(context); err != nil {
+			return exitcode.WithCode(exitcode.Invalid, err)
+		}
+		if config.Current == "" {
+			config.Current = context.Name
+		}
+		if err := config.Save(); err != nil {
+			return err
+		}
+		info(fmt.Sprintf("added context %s for %s", context.Name, context.File))
+		return nil
+	}
+
+	return cmd
+}
+
+func contextUseCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:               "use [name]",
+		Short:             "run commands given no -f on the cluster of a context",
+		Long:              "run commands given no -f on the cluster of a context, picked from a list if not given",
+		Args:              coral.MaximumNArgs(1),
+		ValidArgsFunction: completeContexts,
+		SilenceUsage:      true,
+	}
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		config, err := contexts.Load()
+		if err != nil {
+			return err
+		}
+		name, err := pickArg(args, "context", contextNames())
+		if err != nil {
+			return err
+		}
+		if config.Find(name) == nil {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("there is no context %s", name))
+		}
+		config.Current = name
+		if err := config.Save(); err != nil {
+			return err
+		}
+		info(fmt.Sprintf("using context %s", name))
+		return nil
+	}
+
+	return cmd
+}
+
+func contextListCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:          "list",
+		Short:        "list the contexts",
+		Long:         "list the contexts, the one in use marked with *",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		config, err := contexts.Load()
+		if err != nil {
+			return err
+		}
+		return output.Print(config, func(w io.Writer) {
+			if len(config.Contexts) == 0 {
+				fmt.Fprintln(w, "no context yet, add one with: seaweed-up context add <name> -f <file>")
+				return
+			}
+			t := output.NewTable(w)
+			fmt.Fprintln(t, "CURRENT\tNAME\tFILE\tUSER")
+			for _, context := range config.Contexts {
+				current := ""
+				if context.Name == config.Current {
+					current = "*"
+				}
+				fmt.Fprintf(t, "%s\t%s\t%s\t%s\n", current, context.Name, context.File, context.User)
+			}
+			t.Flush()
+		})
+	}
+
+	return cmd
+}
+
+func contextRemoveCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:               "remove [name]",
+		Short:             "remove a context and its state",
+		Long:              "remove a context with its locks, progress and trusted host keys, the configuration file is kept",
+		Args:              coral.MaximumNArgs(1),
+		ValidArgsFunction: completeContexts,
+		SilenceUsage:      true,
+	}
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		config, err := contexts.Load()
+		if err != nil {
+			return err
+		}
+		name, err := pickArg(args, "context", contextNames())
+		if err != nil {
+			return err
+		}
+		found, err := config.Remove(name)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("there is no context %s", name))
+		}
+		if err := config.Save(); err != nil {
+			return err
+		}
+		info(fmt.Sprintf("removed context %s", name))
+		return nil
+	}
+
+	return cmd
+}
+
+// useContext selects the context the command runs in: the one of --context,
+// or else the current one if the command reads a configuration file and is
+// given none. The flags of the command not given take the settings of the
+// context, and its state is kept apart from the other clusters.
+func useContext(cmd *coral.Command, name string) error {
+	if cmd.Parent() != nil && cmd.Parent().Name() == "context" {
+		return nil
+	}
+	config, err := contexts.Load()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		if file := cmd.Flags().Lookup("file"); file == nil || file.Changed {
+			return nil
+		}
+		if name = utils.Nvl(config.Current, fileSettings.Context); name == "" {
+			return nil
+		}
+	}
+	context := config.Find(name)
+	if context == nil {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("there is no context %s, add it with: seaweed-up context add %s -f <file>", name, name))
+	}
+	for flag, value := range map[string]string{
+		"user":          context.User,
+		"identity_file": context.IdentityFile,
+		"state-dir":     contexts.StateDir(context.Name),
+	} {
+		if f := cmd.Flags().Lookup(flag); f != nil && !f.Changed && value != "" {
+			if err := f.Value.Set(value); err != nil {
+				return err
+			}
+		}
+	}
+	operator.KnownHosts = filepath.Join(contexts.StateDir(context.Name), "known_hosts")
+	activeContext = context
+	return nil
+}
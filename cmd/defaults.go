@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/contexts"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/httpclient"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/settings"
+	"github.com/seaweedfs/seaweed-up/pkg/statecrypt"
+)
+
+// fileSettings and envSettings are the defaults of the user, of the config
+// file and of the environment variables.
+var fileSettings, envSettings = &settings.Settings{}, &settings.Settings{}
+
+// loadSettings reads the defaults of the user, and moves the data dir before
+// the commands take it as the default of their flags.
+func loadSettings() error {
+	s, err := settings.Load()
+	if err != nil {
+		return exitcode.WithCode(exitcode.Invalid, err)
+	}
+	fileSettings, envSettings = s, settings.FromEnv()
+	merged := envSettings.Merge(fileSettings)
+	if merged.DataDir != "" {
+		useDataDir(expandPath(merged.DataDir))
+	}
+	if merged.CABundle != "" {
+		httpclient.CABundle = expandPath(merged.CABundle)
+	}
+	return nil
+}
+
+func useDataDir(dir string) {
+	contexts.Dir = dir
+	statecrypt.Dir = dir
+	operator.KnownHosts = filepath.Join(dir, "known_hosts")
+	manager.DefaultStateDir = dir
+	defaultStateDir = dir
+}
+
+// applyDefaults sets the flags of the command not given to the settings.
+func applyDefaults(cmd *coral.Command, s *settings.Settings) error {
+	for flag, value := range s.Flags() {
+		if f := cmd.Flags().Lookup(flag); f != nil && !f.Changed && value != "" {
+			if err := f.Value.Set(value); err != nil {
+				return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("default of --%s: %w", flag, err))
+			}
+		}
+	}
+	return nil
+}
+
+func configDefaultsCommand() *coral.Command {
+
+	var command = &coral.Command{
+		Use:   "defaults",
+		Short: "show the defaults of the user and where they are set",
+		Long: `Show the defaults of the user and where they are set
+
+The defaults are read from ` + settings.File() + `, or the file of ` + settings.FileEnv + `:
+
+  data_dir: /srv/seaweed-up      # locks, progress, history, contexts and host keys
+  identity_file: ~/.ssh/ops      # -i
+  proxy: http://proxy:3128       # -x
+  format: json                   # --format
+  context: prod-eu               # the context used when none is selected with "context use"
+  repo_url: https://mirror.local/seaweedfs/{version}/{asset}   # --repo-url
+  ca_bundle: /etc/pki/corp-ca.pem                             # --ca-bundle
+
+Each can be set by an environment variable too, like SEAWEED_UP_DATA_DIR or SEAWEED_UP_REPO_URL.
+The flags given win, then the environment variables, then the settings of the context, then the file.`,
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		type setting struct {
+			Name   string `json:"name" yaml:"name"`
+			Value  string `json:"value" yaml:"value"`
+			Source string `json:"source" yaml:"source"`
+		}
+		var result []setting
+		add := func(name, fromEnv, fromFile string) {
+			switch {
+			case fromEnv != "":
+				result = append(result, setting{name, fromEnv, "environment"})
+			case fromFile != "":
+				result = append(result, setting{name, fromFile, settings.File()})
+			}
+		}
+		add("data_dir", envSettings.DataDir, fileSettings.DataDir)
+		add("identity_file", envSettings.IdentityFile, fileSettings.IdentityFile)
+		add("proxy", envSettings.Proxy, fileSettings.Proxy)
+		add("format", envSettings.Format, fileSettings.Format)
+		add("context", envSettings.Context, fileSettings.Context)
+		add("repo_url", envSettings.RepoUrl, fileSettings.RepoUrl)
+		add("ca_bundle", envSettings.CABundle, fileSettings.CABundle)
+		return output.Print(result, func(w io.Writer) {
+			if len(result) == 0 {
+				fmt.Fprintf(w, "no default set, see seaweed-up config defaults --help\n")
+				return
+			}
+			t := output.NewTable(w)
+			fmt.Fprintln(t, "SETTING\tVALUE\tSOURCE")
+			for _, s := range result {
+				fmt.Fprintf(t, "%s\t%s\t%s\n", s.Name, s.Value, s.Source)
+			}
+			t.Flush()
+		})
+	}
+
+	return command
+}
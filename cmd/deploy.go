@@ -10,8 +10,6 @@ import (
 	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
 	"github.com/seaweedfs/seaweed-up/pkg/config"
 	"github.com/seaweedfs/seaweed-up/pkg/utils"
-	"gopkg.in/yaml.v3"
-	"os"
 	"path"
 )
 
@@ -32,33 +30,52 @@ func DeployCommand() *coral.Command {
 	cmd.Flags().IntVarP(&m.SshPort, "port", "p", 22, "The port to SSH.")
 	cmd.Flags().StringVarP(&m.IdentityFile, "identity_file", "i", m.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().StringVarP(&m.Version, "version", "v", "", "The SeaweedFS version")
-	cmd.Flags().StringVarP(&m.ComponentToDeploy, "component", "c", "", "[master|volume|filer|envoy] only install one component")
+	cmd.RegisterFlagCompletionFunc("version", completeVersions)
+	cmd.Flags().StringVarP(&m.ComponentToDeploy, "component", "c", "", "[master|volume|filer|s3|webdav|mount|broker|envoy|keepalived|chrony|tuning] only install one component")
 	cmd.Flags().BoolVarP(&m.PrepareVolumeDisks, "mountDisks", "", true, "auto mount disks on volume server if unmounted")
+	cmd.Flags().StringVarP(&m.DiskDiscovery, "disk-discovery", "", "auto", "[auto|lsblk-json|lsblk|sysfs] how to discover disks on volume servers")
 	cmd.Flags().BoolVarP(&m.ForceRestart, "restart", "", false, "force to restart the service")
+	cmd.Flags().BoolVarP(&m.SkipPreflight, "skip-preflight", "", false, "deploy even if the preflight checks fail")
+	cmd.Flags().BoolVarP(&m.SkipSnapshot, "skip-snapshot", "", false, "do not snapshot the configuration and metadata of the cluster before and after the deployment")
+	cmd.Flags().DurationVarP(&m.MaxClockSkew, "max-clock-skew", "", m.MaxClockSkew, "fail when the clocks of the hosts are further apart")
 	cmd.Flags().StringVarP(&m.ProxyUrl, "proxy", "x", "", "proxy for curl in format PROTO://PROXY (example: http://someproxy.com:8080/)")
+	cmd.Flags().StringVarP(&m.RepoUrl, "repo-url", "", "", "download weed archives from this url instead of GitHub, {version} and {asset} are replaced (example: https://mirror.local/seaweedfs/{version}/{asset})")
+	cmd.Flags().StringArrayVarP(&m.RepoHeaders, "repo-header", "", nil, "http header for downloads from --repo-url, as \"Name: value\", can be repeated")
+	cmd.Flags().BoolVarP(&m.Resume, "resume", "", false, "continue an interrupted deployment, skipping the servers already deployed")
+	cmd.Flags().StringVarP(&m.StateDir, "state-dir", "", m.StateDir, "local directory to keep the progress and locks of operations")
+	cmd.Flags().BoolVarP(&m.SkipCompatibilityCheck, "skip-compatibility-check", "", false, "upgrade even if it is a downgrade, skips a major version or breaks a compatibility rule")
+	cmd.Flags().StringVarP(&m.CompatibilityFile, "compatibility-file", "", "", "file or URL with more upgrade compatibility rules")
+	cmd.Flags().BoolVarP(&m.DryRun, "dry-run", "", false, "print the files, commands and restarts of each server without changing anything")
 
 	cmd.RunE = func(command *coral.Command, args []string) error {
 
-		if m.Version == "" {
-			latest, err := config.GitHubLatestRelease(context.Background(), "0", "seaweedfs", "seaweedfs")
-			if err != nil {
-				return errors.Wrapf(err, "unable to get latest version number, define a version manually with the --version flag")
-			}
-			m.Version = latest.Version
+		specification, err := loadValidSpecification(fileName)
+		if err != nil {
+			return err
 		}
 
-		fmt.Println(fileName)
-		spec := &spec.Specification{}
-		data, readErr := os.ReadFile(fileName)
-		if readErr != nil {
-			return fmt.Errorf("read %s: %v", fileName, readErr)
-		}
-		if unmarshalErr := yaml.Unmarshal(data, spec); unmarshalErr != nil {
-			return fmt.Errorf("unmarshal %s: %v", fileName, unmarshalErr)
+		// resuming deploys the version of the interrupted deployment, dry runs do not read it
+		if m.Version == "" && (!m.Resume || m.DryRun) {
+			if err := resolveLatestVersion(m, specification); err != nil {
+				return err
+			}
 		}
 
-		return m.DeployCluster(spec)
+		return m.DeployCluster(specification)
 	}
 
 	return cmd
 }
+
+// resolveLatestVersion sets the latest SeaweedFS release as the version to deploy.
+func resolveLatestVersion(m *manager.Manager, specification *spec.Specification) error {
+	if m.UsesCustomRepository(specification) {
+		return fmt.Errorf("define the version to download from the custom repository with the --version flag")
+	}
+	latest, err := config.GitHubLatestRelease(context.Background(), "0", "seaweedfs", "seaweedfs")
+	if err != nil {
+		return errors.Wrapf(err, "unable to get latest version number, define a version manually with the --version flag")
+	}
+	m.Version = latest.Version
+	return nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/exporters"
+	"github.com/seaweedfs/seaweed-up/pkg/plugins"
+)
+
+func ExportCommand() *coral.Command {
+
+	var command = &coral.Command{
+		Use:   "export <format>",
+		Short: "export a cluster configuration to other deployment tools",
+		Long: "export a cluster configuration to other deployment tools, supported formats: " + strings.Join(exporters.Names(), ", ") +
+			", and the formats of installed plugins",
+		Args:         coral.ExactArgs(1),
+		SilenceUsage: true,
+	}
+
+	var fileName, output, stateDir string
+	var options exporters.Options
+	command.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	command.Flags().StringVarP(&output, "output", "o", "", "directory to write the generated files to, print to stdout if empty")
+	command.Flags().StringVarP(&options.Version, "version", "v", "", "The SeaweedFS version, latest if empty")
+	command.RegisterFlagCompletionFunc("version", completeVersions)
+	command.Flags().BoolVar(&options.Profiles, "profiles", false, "group the services by component (compose)")
+	command.Flags().StringVar(&options.CPUs, "cpus", "", "cpu limit of each service, e.g. 2")
+	command.Flags().StringVar(&options.Memory, "memory", "", "memory limit of each service, e.g. 4G")
+	command.Flags().StringVar(&stateDir, "state-dir", defaultStateDir, "local directory keeping the installed plugins")
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		registry, err := plugins.Open(stateDir)
+		if err != nil {
+			return err
+		}
+		registry.RegisterExporters()
+		exporter, err := exporters.Get(args[0])
+		if err != nil {
+			return err
+		}
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		files, err := exporter.Export(specification, options)
+		if err != nil {
+			return err
+		}
+
+		var names []string
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if output == "" {
+				if len(names) > 1 {
+					fmt.Printf("# %s\n", name)
+				}
+				os.Stdout.Write(files[name])
+				continue
+			}
+			target := filepath.Join(output, name)
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(target, files[name], 0644); err != nil {
+				return err
+			}
+			info("wrote " + target)
+		}
+		return nil
+	}
+
+	return command
+}
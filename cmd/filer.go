@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+)
+
+func FilerCommands() *coral.Command {
+	filerCmd := baseCommand("filer")
+	filerCmd.Short = "Manage the filers of the cluster"
+	metaCmd := baseCommand("meta")
+	metaCmd.Short = "Export and import the filer metadata"
+	metaCmd.Long = `Export and import the filer metadata
+
+The metadata of the files and directories, not their content, is saved with weed shell fs.meta.save
+and loaded with fs.meta.load. Exported from a cluster and imported into another one, or into the same
+one after changing its filer store, it moves the filer to another store backend.`
+	metaCmd.AddCommand(metaExportCommand())
+	metaCmd.AddCommand(metaImportCommand())
+	filerCmd.AddCommand(metaCmd)
+	return filerCmd
+}
+
+func metaExportCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "export",
+		Short: "download the filer metadata to a local file",
+		Long: `Save the filer metadata with fs.meta.save on the host of the filer, and download it gzipped.
+
+The download is verified with its sha256 and written to <output>.part until complete. An interrupted
+export, run again, resumes the download while the saved metadata is still on the host.`,
+		Example:      "  seaweed-up filer meta export -f cluster.yaml -o meta.gz",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	var options manager.FilerMetaOptions
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringVarP(&options.File, "output", "o", "filer-meta.gz", "local file to write the gzipped metadata to")
+	cmd.Flags().StringVarP(&options.Path, "path", "", "/", "filer directory to export")
+	cmd.Flags().IntVarP(&options.Filer, "filer", "", 0, "index of the filer server to export from")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.ExportFilerMeta(specification, options)
+	}
+
+	return cmd
+}
+
+func metaImportCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "import <file>",
+		Short: "load exported filer metadata into the filer",
+		Long: `Upload gzipped metadata exported before to the host of the filer, and load it with fs.meta.load.
+Entries already in the filer are overwritten, the others are kept.
+
+The upload is sent in chunks and verified with its sha256. An interrupted import, run again, resumes
+from the chunks uploaded.`,
+		Example:      "  seaweed-up filer meta import -f new-cluster.yaml meta.gz",
+		Args:         coral.ExactArgs(1),
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	var options manager.FilerMetaOptions
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().IntVarP(&options.Filer, "filer", "", 0, "index of the filer server to import into")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadValidSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		options.File = args[0]
+		return m.ImportFilerMeta(specification, options)
+	}
+
+	return cmd
+}
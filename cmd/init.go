@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+//go:embed init.yaml.tpl
+var initYamlTemplate string
+
+func InitCommand() *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "init",
+		Short:        "create a configuration file by answering a few questions",
+		Long:         "create a configuration file by answering a few questions about hosts, disks, replication, S3, TLS and authentication",
+		SilenceUsage: true,
+	}
+
+	var output string
+	var force bool
+	command.Flags().StringVarP(&output, "output", "o", "cluster.yaml", "configuration file to create")
+	command.Flags().BoolVar(&force, "force", false, "overwrite the configuration file if it exists")
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		if _, err := os.Stat(output); err == nil && !force {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", output)
+		}
+
+		w := &wizard{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+		data := map[string]interface{}{}
+
+		masters := w.askRequiredList("Master server IPs, 1 or 3 for high availability", "192.168.2.7")
+		data["Masters"] = clusterFileHosts(masters)
+		volumes := w.askRequiredList("Volume server IPs", strings.Join(masters, ","))
+		data["Volumes"] = clusterFileHosts(volumes)
+		data["Folders"] = w.askRequiredList("Data folders on each volume server", "/data1")
+		data["DiskType"] = w.ask("Disk type of the folders [hdd|ssd]", "hdd")
+		mountDisks := w.askBool("Format and mount unused disks of volume servers during deploy", true)
+		data["MountDisks"] = mountDisks
+		if mountDisks {
+			data["FsType"] = w.ask("File system for new disks [ext4|xfs]", "ext4")
+		}
+		defaultReplication := "000"
+		if len(volumes) > 1 {
+			defaultReplication = "001"
+		}
+		data["Replication"] = w.ask("Default replication", defaultReplication)
+		data["VolumeSizeLimitMB"] = w.askInt("Volume size limit in MB", 5000)
+		data["Filers"] = clusterFileHosts(w.askRequiredList("Filer IPs", masters[0]))
+		data["S3"] = w.askBool("Enable the S3 gateway on filers", true)
+		if w.askBool("Sign the writes to volume servers with a generated JWT key", false) {
+			key := make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return fmt.Errorf("generate the JWT signing key: %w", err)
+			}
+			data["JwtSigningKey"] = base64.StdEncoding.EncodeToString(key)
+		}
+		if w.askBool("Use mutual TLS between the servers, with a CA and a certificate of your own", false) {
+			data["CaFile"] = w.askRequired("Local file of the CA", "")
+			data["CertFile"] = w.askRequired("Local file of the certificate of all servers, signed by the CA", "")
+			data["KeyFile"] = w.askRequired("Local file of the private key of the certificate", "")
+		}
+		data["Envoys"] = clusterFileHosts(w.askList("Envoy proxy IPs in front of the filers, empty for none", ""))
+		data["ConfigDir"] = w.ask("Configuration directory on the hosts", "/etc/seaweed")
+		data["DataDir"] = w.ask("Data directory on the hosts", "/opt/seaweed")
+		if w.err != nil {
+			return w.err
+		}
+
+		data["Generator"] = "seaweed-up init"
+		if err := writeClusterFile(output, data); err != nil {
+			return err
+		}
+		fmt.Printf("\nwrote %s, deploy it with: seaweed-up deploy -f %s\n", output, output)
+		return nil
+	}
+
+	return command
+}
+
+// clusterFileHost is a host of the init template, with optional settings of its own.
+type clusterFileHost struct {
+	Ip      string
+	PortSsh int
+	User    string   // ssh user, only written as a comment
+	Folders []string // replaces the Folders of the template for a volume server
+}
+
+func clusterFileHosts(ips []string) (hosts []clusterFileHost) {
+	for _, ip := range ips {
+		hosts = append(hosts, clusterFileHost{Ip: ip})
+	}
+	return
+}
+
+// writeClusterFile writes a configuration file from the answers of the init template.
+func writeClusterFile(fileName string, data map[string]interface{}) error {
+	t, err := template.New("cluster.yaml").Parse(initYamlTemplate)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, buf.Bytes(), 0644)
+}
+
+// wizard asks questions on the terminal, remembering the first read error.
+// In non-interactive mode all questions take their default answer.
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+	err error
+}
+
+func (w *wizard) ask(question, defaultValue string) string {
+	if w.err != nil || utils.NonInteractive {
+		return defaultValue
+	}
+	if defaultValue != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	line, err := w.in.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		w.err = fmt.Errorf("read answer: %w", err)
+		return defaultValue
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer
+	}
+	return defaultValue
+}
+
+// askRequired asks until the answer is not empty.
+func (w *wizard) askRequired(question, defaultValue string) string {
+	for {
+		answer := w.ask(question, defaultValue)
+		if answer != "" || w.err != nil {
+			return answer
+		}
+		fmt.Fprintln(w.out, "an answer is required")
+	}
+}
+
+// askRequiredList asks until at least one item is given.
+func (w *wizard) askRequiredList(question, defaultValue string) []string {
+	for {
+		items := w.askList(question, defaultValue)
+		if len(items) > 0 {
+			return items
+		}
+		if w.err != nil {
+			return []string{defaultValue}
+		}
+		fmt.Fprintln(w.out, "at least one is required")
+	}
+}
+
+func (w *wizard) askList(question, defaultValue string) (items []string) {
+	for _, item := range strings.Split(w.ask(question+" (comma separated)", defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+func (w *wizard) askInt(question string, defaultValue int) int {
+	for {
+		answer := w.ask(question, strconv.Itoa(defaultValue))
+		value, err := strconv.Atoi(answer)
+		if err == nil || w.err != nil {
+			return value
+		}
+		fmt.Fprintf(w.out, "%q is not a number\n", answer)
+	}
+}
+
+func (w *wizard) askBool(question string, defaultValue bool) bool {
+	defaultAnswer := "n"
+	if defaultValue {
+		defaultAnswer = "y"
+	}
+	for {
+		switch strings.ToLower(w.ask(question+" (y/n)", defaultAnswer)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		if w.err != nil {
+			return defaultValue
+		}
+	}
+}
@@ -0,0 +1,91 @@
+{{- define "ssh"}}
+{{- if .PortSsh}}
+    port.ssh: {{.PortSsh}}
+{{- end}}
+{{- if .User}}
+    # ssh user: {{.User}}
+{{- end}}
+{{- end -}}
+# Generated by {{.Generator}}, adjust the content accordingly.
+# Global variables are applied to all deployments and used as the default values
+global:
+  # Storage directory for cluster deployment files, startup scripts, and configuration files.
+  dir.conf: "{{.ConfigDir}}"
+  # Data directory for cluster metadata files, embedded filer store files, and log files.
+  dir.data: "{{.DataDir}}"
+  # volume size limit in MB
+  volumeSizeLimitMB: {{.VolumeSizeLimitMB}}
+  # default replication, see https://github.com/seaweedfs/seaweedfs/wiki/Replication
+  replication: "{{.Replication}}"
+{{- if .MountDisks}}
+  # How unmounted disks of volume servers are formatted and mounted by deploy.
+  disks:
+    fs_type: {{.FsType}}
+    mount_options: noatime,nodiratime
+    mount_by_uuid: true
+{{- else}}
+  # Unmounted disks of volume servers are left alone by deploy.
+  disks:
+    disabled: true
+{{- end}}
+{{- if or .JwtSigningKey .CaFile}}
+  # The security.toml of the servers, see https://github.com/seaweedfs/seaweedfs/wiki/Security-Configuration
+  security:
+{{- if .JwtSigningKey}}
+    # volume servers only accept the writes signed with this key
+    jwt.signing.key: "{{.JwtSigningKey}}"
+{{- end}}
+{{- if .CaFile}}
+    # mutual TLS on the gRPC ports, the certificate is used by all servers and clients
+    ca.file: "{{.CaFile}}"
+    cert.file: "{{.CertFile}}"
+    key.file: "{{.KeyFile}}"
+{{- end}}
+{{- end}}
+
+# Server configs are used to specify the configuration of master servers.
+master_servers:
+{{- range .Masters}}
+  - ip: {{.Ip}}
+{{- template "ssh" .}}
+    port: 9333
+{{- end}}
+
+# Server configs are used to specify the configuration of volume servers.
+volume_servers:
+{{- range .Volumes}}
+  - ip: {{.Ip}}
+{{- template "ssh" .}}
+    port: 8080
+    folders:
+{{- range (or .Folders $.Folders)}}
+      - folder: {{.}}
+        disk: "{{$.DiskType}}"
+{{- end}}
+{{- end}}
+
+# Server configs are used to specify the configuration of filers.
+filer_servers:
+{{- range .Filers}}
+  - ip: {{.Ip}}
+{{- template "ssh" .}}
+    port: 8888
+{{- if $.S3}}
+    # embedded S3 gateway
+    s3: true
+    s3.port: 8333
+{{- end}}
+{{- end}}
+{{- if .Envoys}}
+
+# Server configs are used to specify the configuration of envoy proxies.
+envoy_servers:
+{{- range .Envoys}}
+  - ip: {{.Ip}}
+{{- template "ssh" .}}
+    filer.port: 8000
+{{- if $.S3}}
+    s3.port: 8001
+{{- end}}
+{{- end}}
+{{- end}}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cloud"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/inventory"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+func InventoryCommands() *coral.Command {
+	inventoryCmd := baseCommand("inventory")
+	inventoryCmd.Short = "Build a configuration file from existing hosts"
+	inventoryCmd.Long = "Build a configuration file from existing hosts"
+	inventoryCmd.AddCommand(inventoryImportCommand())
+	return inventoryCmd
+}
+
+func inventoryImportCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "import",
+		Short: "write a configuration file with the hosts of EC2 or an Ansible inventory",
+		Long: `Discover the hosts of a cluster and write a configuration file skeleton with their addresses,
+SSH users and ports, and the data folders of volume servers.
+
+With --aws, the running EC2 instances having all --tag are imported. The comma separated values of
+their --role-tag, like "master,filer", give their roles.
+
+With --ansible-inventory, the hosts of an INI inventory are imported. Groups named after a component,
+like [masters], [volume_servers] or [seaweed_filers], give their hosts that role. ansible_host,
+ansible_user and ansible_port are used to reach them.
+
+Hosts without any role get a default layout: all are volume servers, the first three are masters and
+the first is a filer. Volume servers are reached over SSH to list their /dataN mount points and
+unused disks, skip it with --discover-disks=false.`,
+		Example: `  seaweed-up inventory import --aws --region eu-west-1 --tag cluster=prod -u ubuntu
+  seaweed-up inventory import --ansible-inventory hosts.ini --group seaweed`,
+		SilenceUsage: true,
+	}
+	m := manager.NewManager()
+	m.IdentityFile = path.Join(utils.UserHome(), ".ssh", "id_rsa")
+
+	var fromAws, privateAddresses, discoverDisks, force bool
+	var region, roleTag, ansibleInventory, group, output string
+	var tags []string
+	cmd.Flags().BoolVar(&fromAws, "aws", false, "import running EC2 instances with the aws command line tool")
+	cmd.Flags().StringVar(&region, "region", "", "AWS region, the default of the aws command line tool if empty")
+	cmd.Flags().StringArrayVar(&tags, "tag", nil, "only import EC2 instances with this key=value tag, can be repeated")
+	cmd.Flags().StringVar(&roleTag, "role-tag", "seaweed-role", "EC2 tag with the comma separated roles of an instance")
+	cmd.Flags().BoolVar(&privateAddresses, "private-addresses", false, "use the private addresses of EC2 instances")
+	cmd.Flags().StringVar(&ansibleInventory, "ansible-inventory", "", "import the hosts of this Ansible inventory in INI format")
+	cmd.Flags().StringVar(&group, "group", "", "only import the hosts of this Ansible group")
+	cmd.Flags().BoolVar(&discoverDisks, "discover-disks", true, "reach volume servers over SSH to find their data folders")
+	cmd.Flags().StringVarP(&output, "output", "o", "cluster.yaml", "configuration file to write")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite the configuration file if it exists")
+	cmd.Flags().StringVarP(&m.User, "user", "u", utils.CurrentUser(), "The user name to login via SSH, if the inventory has none")
+	cmd.Flags().StringVarP(&m.IdentityFile, "identity_file", "i", m.IdentityFile, "The path of the SSH identity file.")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		if fromAws == (ansibleInventory != "") {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("use either --aws or --ansible-inventory"))
+		}
+		if _, err := os.Stat(output); err == nil && !force {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s already exists, use --force to overwrite it", output))
+		}
+
+		var hosts []*inventory.Host
+		var source string
+		if fromAws {
+			servers, err := cloud.ListAwsInstances(context.Background(), region, tags, roleTag)
+			if err != nil {
+				return err
+			}
+			for _, server := range servers {
+				h := &inventory.Host{Name: server.Name, Address: server.Address(privateAddresses)}
+				for _, role := range server.Roles {
+					if r := inventory.Role(role); r != "" {
+						h.Roles = append(h.Roles, r)
+					}
+				}
+				if h.Address == "" {
+					logging.Warn(fmt.Sprintf("skip %s without a public address, use --private-addresses", h.Name))
+					continue
+				}
+				hosts = append(hosts, h)
+			}
+			source = "EC2"
+		} else {
+			f, err := os.Open(ansibleInventory)
+			if err != nil {
+				return exitcode.WithCode(exitcode.Invalid, err)
+			}
+			defer f.Close()
+			if hosts, err = inventory.ParseAnsible(f, group); err != nil {
+				return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s: %w", ansibleInventory, err))
+			}
+			source = ansibleInventory
+		}
+		if len(hosts) == 0 {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("no hosts found in %s", source))
+		}
+		inventory.AssignRoles(hosts)
+
+		data, err := inventoryClusterData(m, hosts, discoverDisks)
+		if err != nil {
+			return err
+		}
+		if err := writeClusterFile(output, data); err != nil {
+			return err
+		}
+		info(fmt.Sprintf("wrote %s with %d hosts from %s", output, len(hosts), source))
+		return nil
+	}
+
+	return cmd
+}
+
+// inventoryClusterData fills the init template with the hosts, finding the
+// data folders of volume servers over SSH if discoverDisks.
+func inventoryClusterData(m *manager.Manager, hosts []*inventory.Host, discoverDisks bool) (map[string]interface{}, error) {
+	byRole := make(map[string][]clusterFileHost)
+	users := make(map[string]bool)
+	for _, h := range hosts {
+		host := clusterFileHost{Ip: h.Address, PortSsh: h.Port, User: h.User}
+		users[utils.Nvl(h.User, m.User)] = true
+		for _, role := range h.Roles {
+			host := host
+			if role == "volume" && discoverDisks {
+				info(fmt.Sprintf("Discovering disks of %s", h.Address))
+				folders, err := m.DataFolders(utils.JoinHostPort(h.Address, utils.NvlInt(h.Port, 22)), utils.Nvl(h.User, m.User))
+				if err != nil {
+					return nil, exitcode.WithCode(exitcode.Unreachable, fmt.Errorf("discover disks of %s: %w", h.Address, err))
+				}
+				if len(folders) == 0 {
+					logging.Warn(fmt.Sprintf("%s has no /dataN mount point or unused disk, it gets the default folder", h.Address))
+				}
+				host.Folders = folders
+			}
+			byRole[role] = append(byRole[role], host)
+		}
+	}
+	if len(byRole["master"]) == 0 || len(byRole["volume"]) == 0 {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("found %d masters and %d volume servers, give hosts roles to have at least one of each",
+			len(byRole["master"]), len(byRole["volume"])))
+	}
+	if len(users) > 1 {
+		var names []string
+		for user := range users {
+			names = append(names, user)
+		}
+		sort.Strings(names)
+		logging.Warn(fmt.Sprintf("the hosts use different SSH users (%s), deploy logs in with a single -u user", strings.Join(names, ", ")))
+	}
+	return map[string]interface{}{
+		"Generator":         "seaweed-up inventory import",
+		"Masters":           byRole["master"],
+		"Volumes":           byRole["volume"],
+		"Filers":            byRole["filer"],
+		"Envoys":            byRole["envoy"],
+		"Folders":           []string{"/data1"},
+		"DiskType":          "hdd",
+		"MountDisks":        true,
+		"FsType":            "ext4",
+		"Replication":       "000",
+		"VolumeSizeLimitMB": 5000,
+		"S3":                len(byRole["filer"]) > 0,
+		"ConfigDir":         "/etc/seaweed",
+		"DataDir":           "/opt/seaweed",
+	}, nil
+}
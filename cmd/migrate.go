@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+)
+
+// migrationFlags are the flags naming a migration, common to its commands.
+type migrationFlags struct {
+	source, target string
+	options        manager.MigrationOptions
+}
+
+func addMigrationFlags(cmd *coral.Command) *migrationFlags {
+	flags := &migrationFlags{}
+	cmd.Flags().StringVarP(&flags.source, "source", "", "", "configuration file of the cluster to copy from")
+	cmd.Flags().StringVarP(&flags.target, "target", "", "", "configuration file of the cluster to copy to")
+	cmd.Flags().StringVarP(&flags.options.Prefix, "prefix", "", "/", "filer directory to migrate, like /buckets/bucket1")
+	cmd.MarkFlagRequired("source")
+	cmd.MarkFlagRequired("target")
+	return flags
+}
+
+// load loads both configuration files with loadSpecification, or loadValidSpecification for the
+// commands changing the hosts.
+func (flags *migrationFlags) load(loadSpecification func(string) (*spec.Specification, error)) (*spec.Specification, *spec.Specification, error) {
+	source, err := loadSpecification(flags.source)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := loadSpecification(flags.target)
+	if err != nil {
+		return nil, nil, err
+	}
+	return source, target, nil
+}
+
+func MigrateCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "migrate",
+		Short: "copy the files of a cluster to another one and cut over to it",
+		Long: `Copy the files of a cluster to another one and cut over to it
+
+weed filer.sync runs one way from the first source filer to the first target filer, as a systemd service
+on the source filer host. It replays the metadata log of the source filer, copying the files under the
+prefix, then follows their changes until the cutover. The source filer host must reach the target filer
+and volume servers.
+
+When both clusters have the same ha.virtual_ip, keepalived is stopped on the target servers until the
+cutover moves the virtual IP to them. Otherwise the clients are pointed to the target by hand.
+
+  seaweed-up migrate --source old.yaml --target new.yaml --prefix /buckets/bucket1
+  seaweed-up migrate status --source old.yaml --target new.yaml --prefix /buckets/bucket1
+  seaweed-up migrate verify --source old.yaml --target new.yaml --prefix /buckets/bucket1
+  seaweed-up migrate cutover --source old.yaml --target new.yaml --prefix /buckets/bucket1`,
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+	flags := addMigrationFlags(cmd)
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		source, target, err := flags.load(loadValidSpecification)
+		if err != nil {
+			return err
+		}
+		return m.StartMigration(source, target, flags.options)
+	}
+
+	cmd.AddCommand(migrateStatusCommand())
+	cmd.AddCommand(migrateVerifyCommand())
+	cmd.AddCommand(migrateCutoverCommand())
+	return cmd
+}
+
+func migrateStatusCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "status",
+		Short: "show how much of the files are copied",
+		Long: `Show the state of filer.sync, the usage of the prefix on both clusters as fs.du reports it, what
+remains to copy, and the time of the last change copied. On a source without changes, the time since
+the last change copied grows with nothing left to copy.`,
+		Example:      "  seaweed-up migrate status --source old.yaml --target new.yaml --format json",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+	flags := addMigrationFlags(cmd)
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		source, target, err := flags.load(loadSpecification)
+		if err != nil {
+			return err
+		}
+		p, err := m.MigrationStatus(source, target, flags.options)
+		if err != nil {
+			return err
+		}
+		return output.Print(p, func(w io.Writer) { manager.PrintMigration(w, p) })
+	}
+
+	return cmd
+}
+
+func migrateVerifyCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "verify",
+		Short: "compare the checksums of sampled files on both clusters",
+		Long: `Sample files under the prefix on the source filer and compare their sha256 on both clusters, each
+file downloaded from each filer on its host. It exits with status 3 if a sampled file is missing on the
+target or differs, like a file changed since the last change copied.`,
+		Example:      "  seaweed-up migrate verify --source old.yaml --target new.yaml --samples 200",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+	flags := addMigrationFlags(cmd)
+
+	var samples int
+	cmd.Flags().IntVarP(&samples, "samples", "", 50, "number of files to compare")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		source, target, err := flags.load(loadSpecification)
+		if err != nil {
+			return err
+		}
+		v, err := m.VerifyMigration(source, target, flags.options, samples)
+		if err != nil {
+			return err
+		}
+		if err := output.Print(v, func(w io.Writer) { manager.PrintVerification(w, v) }); err != nil {
+			return err
+		}
+		if len(v.Mismatches) > 0 {
+			return exitcode.WithCode(exitcode.Partial, fmt.Errorf("%d of %d sampled files differ on the target", len(v.Mismatches), v.Checked))
+		}
+		return nil
+	}
+
+	return cmd
+}
+
+func migrateCutoverCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "cutover",
+		Short: "move the clients to the target and stop copying",
+		Long: `Once nothing remains to copy, move the virtual IP shared by both clusters from the source servers to
+the target servers, let filer.sync copy the changes written meanwhile for --drain, then remove its
+service. If the target servers can not start keepalived, the source servers take the virtual IP back.
+
+Without a shared virtual IP, only filer.sync is stopped, after pointing the clients to the target.`,
+		Example:      "  seaweed-up migrate cutover --source old.yaml --target new.yaml --drain 2m",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+	flags := addMigrationFlags(cmd)
+
+	cmd.Flags().BoolVarP(&flags.options.Force, "force", "", false, "cut over even if the target is behind or filer.sync is not running")
+	cmd.Flags().DurationVarP(&flags.options.Drain, "drain", "", time.Minute, "how long filer.sync still copies the changes after moving the virtual IP")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		source, target, err := flags.load(loadValidSpecification)
+		if err != nil {
+			return err
+		}
+		return m.Cutover(source, target, flags.options)
+	}
+
+	return cmd
+}
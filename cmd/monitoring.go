@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/dashboards"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+)
+
+func MonitoringCommands() *coral.Command {
+	monitoringCmd := baseCommand("monitoring")
+	monitoringCmd.Short = "Monitor the deployed clusters"
+	monitoringCmd.Long = `Monitor the deployed clusters
+
+The components expose Prometheus metrics on their metrics_port. These commands generate what the
+monitoring tools scraping them need.`
+	dashboardCmd := baseCommand("dashboard")
+	dashboardCmd.Short = "Dashboards of the cluster metrics"
+	dashboardCmd.AddCommand(dashboardExportCommand())
+	monitoringCmd.AddCommand(dashboardCmd)
+	return monitoringCmd
+}
+
+func dashboardExportCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "export",
+		Short: "generate ready to import dashboards",
+		Long: `Generate dashboards of the metrics the components expose on their metrics_port: a cluster
+overview, the disks of the volume servers, and the latency of the filers and S3 gateways.
+
+The panels query the Prometheus datasource of uid --datasource, or else Grafana asks for the datasource
+on import. -o writes the dashboards to a file as one JSON array, or to a directory, one file each,
+for Grafana dashboard provisioning. Without -o, they are printed as one JSON array.`,
+		Example:      "  seaweed-up monitoring dashboard export --format grafana -o seaweedfs-dashboards.json",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+
+	var format, output string
+	var options dashboards.Options
+	cmd.Flags().StringVarP(&format, "format", "", "grafana", "["+strings.Join(dashboards.Formats(), "|")+"] format of the dashboards")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file, or directory ending with /, to write the dashboards to, print to stdout if empty")
+	cmd.Flags().StringVarP(&options.Datasource, "datasource", "", "", "uid of the Prometheus datasource the panels query, asked on import if empty")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		files, err := dashboards.Export(format, options)
+		if err != nil {
+			return exitcode.WithCode(exitcode.Invalid, err)
+		}
+		if stat, err := os.Stat(output); output == "" || !strings.HasSuffix(output, "/") && (err != nil || !stat.IsDir()) {
+			data := dashboards.Bundle(files)
+			if output == "" {
+				os.Stdout.Write(data)
+				return nil
+			}
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				return fmt.Errorf("write %s: %w", output, err)
+			}
+			info(fmt.Sprintf("wrote %d dashboards to %s", len(files), output))
+			return nil
+		}
+
+		if err := os.MkdirAll(output, 0755); err != nil {
+			return err
+		}
+		var names []string
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			target := filepath.Join(output, name)
+			if err := os.WriteFile(target, files[name], 0644); err != nil {
+				return fmt.Errorf("write %s: %w", target, err)
+			}
+			info("wrote " + target)
+		}
+		return nil
+	}
+
+	return cmd
+}
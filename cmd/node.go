@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"github.com/muesli/coral"
+)
+
+func NodeCommands() *coral.Command {
+	nodeCmd := baseCommand("node")
+	nodeCmd.Short = "Manage single hosts of a cluster"
+	nodeCmd.Long = "Manage single hosts of a cluster"
+	nodeCmd.AddCommand(maintenanceCommands())
+	return nodeCmd
+}
+
+func maintenanceCommands() *coral.Command {
+	maintenanceCmd := baseCommand("maintenance")
+	maintenanceCmd.Short = "Take hosts out of operations, e.g. for OS patching"
+	maintenanceCmd.Long = `Take hosts out of operations, e.g. for OS patching
+
+A host in maintenance is skipped by deploy and cluster exec, and not checked by cluster doctor.
+cluster balance refuses to run while a volume server is in maintenance, as it would move volumes back to it.
+The hosts in maintenance are kept in the state dir.`
+	maintenanceCmd.AddCommand(maintenanceEnableCommand())
+	maintenanceCmd.AddCommand(maintenanceDisableCommand())
+	maintenanceCmd.AddCommand(maintenanceListCommand())
+	return maintenanceCmd
+}
+
+func maintenanceEnableCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "enable [host]",
+		Short: "put a host in maintenance",
+		Long: `Put a host, given by its ip or ip:ssh port, in maintenance.
+
+The volumes of its volume servers are first moved to the other volume servers with volumeServer.evacuate,
+skip it with --drain=false. The host is picked from the hosts of the configuration if not given.`,
+		Example:           "  seaweed-up node maintenance enable 192.168.1.12 -f cluster.yaml --reason \"kernel update\"",
+		Args:              coral.MaximumNArgs(1),
+		ValidArgsFunction: completeHosts,
+		SilenceUsage:      true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName, reason string
+	var drain bool
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringVarP(&reason, "reason", "", "", "why the host is in maintenance, shown by list")
+	cmd.Flags().BoolVarP(&drain, "drain", "", true, "move the volumes of its volume servers to the other volume servers")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadValidSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		host, err := pickArg(args, "host", m.Hosts(specification))
+		if err != nil {
+			return err
+		}
+		return m.EnableMaintenance(specification, host, reason, drain)
+	}
+
+	return cmd
+}
+
+func maintenanceDisableCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:               "disable [host]",
+		Short:             "take a host back into operations",
+		Long:              "take a host, given by its ip or ip:ssh port or picked from a list, back into operations",
+		Args:              coral.MaximumNArgs(1),
+		ValidArgsFunction: completeHosts,
+		SilenceUsage:      true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadValidSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		host, err := pickArg(args, "host", m.Hosts(specification))
+		if err != nil {
+			return err
+		}
+		return m.DisableMaintenance(specification, host)
+	}
+
+	return cmd
+}
+
+func maintenanceListCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:          "list",
+		Short:        "list the hosts in maintenance",
+		Long:         "list the hosts in maintenance",
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		return m.ListMaintenance(specification)
+	}
+
+	return cmd
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/plugins"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// defaultStateDir is the --state-dir of commands without a manager.
+var defaultStateDir = path.Join(utils.UserHome(), ".seaweed-up")
+
+func PluginCommands() *coral.Command {
+	pluginCmd := baseCommand("plugin")
+	pluginCmd.Short = "Install and run plugins"
+	pluginCmd.Long = `Install and run plugins
+
+A plugin is an executable answering the subcommands "describe", "export <format>" and "run".
+Plugins add export formats to seaweed-up export, and commands run with seaweed-up plugin run.`
+	var stateDir string
+	pluginCmd.PersistentFlags().StringVar(&stateDir, "state-dir", defaultStateDir, "local directory keeping the installed plugins")
+	pluginCmd.AddCommand(pluginInstallCommand(&stateDir))
+	pluginCmd.AddCommand(pluginListCommand(&stateDir))
+	pluginCmd.AddCommand(pluginRemoveCommand(&stateDir))
+	pluginCmd.AddCommand(pluginRunCommand(&stateDir))
+	return pluginCmd
+}
+
+func pluginInstallCommand(stateDir *string) *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "install <path|url>",
+		Short:        "install a plugin executable from a file or an http(s) URL",
+		Long:         "install a plugin executable from a file or an http(s) URL, replacing an installed plugin of the same name",
+		Args:         coral.ExactArgs(1),
+		SilenceUsage: true,
+	}
+
+	var sha256sum string
+	command.Flags().StringVar(&sha256sum, "sha256", "", "expected sha256 checksum of the executable")
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		registry, err := plugins.Open(*stateDir)
+		if err != nil {
+			return err
+		}
+		p, err := registry.Install(context.Background(), args[0], sha256sum)
+		if err != nil {
+			return exitcode.WithCode(exitcode.Invalid, err)
+		}
+		info(fmt.Sprintf("installed plugin %s %s", p.Name, p.Version))
+		return nil
+	}
+
+	return command
+}
+
+func pluginListCommand(stateDir *string) *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "list",
+		Short:        "list the installed plugins",
+		Long:         "list the installed plugins",
+		SilenceUsage: true,
+	}
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		registry, err := plugins.Open(*stateDir)
+		if err != nil {
+			return err
+		}
+		return output.Print(append([]*plugins.Plugin{}, registry.Plugins...), func(w io.Writer) {
+			t := output.NewTable(w)
+			fmt.Fprintln(t, "NAME\tVERSION\tEXPORTERS\tCOMMANDS\tDESCRIPTION")
+			for _, p := range registry.Plugins {
+				fmt.Fprintf(t, "%s\t%s\t%s\t%v\t%s\n", p.Name, p.Version, strings.Join(p.Exporters, ","), p.Commands, p.Description)
+			}
+			t.Flush()
+		})
+	}
+
+	return command
+}
+
+func pluginRemoveCommand(stateDir *string) *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "remove <name>",
+		Short:        "remove an installed plugin",
+		Long:         "remove an installed plugin",
+		Args:         coral.ExactArgs(1),
+		SilenceUsage: true,
+	}
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		registry, err := plugins.Open(*stateDir)
+		if err != nil {
+			return err
+		}
+		if err := registry.Remove(args[0]); err != nil {
+			return exitcode.WithCode(exitcode.Invalid, err)
+		}
+		info("removed plugin " + args[0])
+		return nil
+	}
+
+	return command
+}
+
+func pluginRunCommand(stateDir *string) *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "run <name> [-- args...]",
+		Short:        "run a command of a plugin",
+		Long:         "run a command of a plugin, passing it the arguments after the name, put them after -- if they have flags",
+		Args:         coral.MinimumNArgs(1),
+		SilenceUsage: true,
+	}
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		registry, err := plugins.Open(*stateDir)
+		if err != nil {
+			return err
+		}
+		p, err := registry.Get(args[0])
+		if err != nil {
+			return exitcode.WithCode(exitcode.Invalid, err)
+		}
+		return registry.Run(p, *stateDir, args[1:])
+	}
+
+	return command
+}
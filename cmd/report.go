@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
+	"github.com/seaweedfs/seaweed-up/pkg/contexts"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+func ReportCommands() *coral.Command {
+	reportCmd := baseCommand("report")
+	reportCmd.Short = "Report on the deployed clusters"
+	reportCmd.Long = `Report on the deployed clusters
+
+The reports are collected from the hosts and can be kept offline, for license and compliance audits.`
+	reportCmd.AddCommand(reportVersionsCommand())
+	return reportCmd
+}
+
+func reportVersionsCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "versions",
+		Short: "inventory the versions of the deployed binaries",
+		Long: `Inventory the version and build hash of the weed or envoy binary of every component instance,
+with the OS, kernel and uptime of its host, and the uptime of its service.
+
+--all-contexts reports the clusters of all contexts, keyed by context name. -o writes the report
+to a file, as yaml for .yaml or .yml files and as json otherwise, for offline audits.`,
+		Example:      "  seaweed-up report versions --all-contexts -o report.json",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName, outputFile string
+	var parallel int
+	var allContexts bool
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "file to write the report to, instead of printing it")
+	cmd.Flags().IntVarP(&parallel, "parallel", "", 10, "how many hosts to query at the same time")
+	cmd.Flags().BoolVarP(&allContexts, "all-contexts", "", false, "report the clusters of all contexts")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		var report interface{}
+		var failed []string
+		var print func(w io.Writer)
+		if allContexts {
+			reports, names, err := allContextsVersions(m, parallel)
+			if err != nil {
+				return err
+			}
+			report = reports
+			print = func(w io.Writer) {
+				for _, name := range names {
+					fmt.Fprintf(w, "===== %s =====\n", name)
+					manager.PrintVersions(w, reports[name])
+					fmt.Fprintln(w)
+				}
+			}
+			for _, name := range names {
+				if reports[name] == nil {
+					failed = append(failed, "context "+name)
+				} else if len(reports[name].Unreachable) > 0 {
+					failed = append(failed, reports[name].Unreachable...)
+				}
+			}
+		} else {
+			specification, err := loadSpecification(fileName)
+			if err != nil {
+				return err
+			}
+			versions := m.CollectVersions(specification, parallel)
+			report = versions
+			print = func(w io.Writer) { manager.PrintVersions(w, versions) }
+			failed = versions.Unreachable
+		}
+
+		if outputFile != "" {
+			if err := writeReport(outputFile, report); err != nil {
+				return err
+			}
+			info(fmt.Sprintf("wrote the version report to %s", outputFile))
+		} else if err := output.Print(report, print); err != nil {
+			return err
+		}
+		if len(failed) > 0 {
+			return exitcode.WithCode(exitcode.Partial, fmt.Errorf("no versions of %s", strings.Join(failed, ", ")))
+		}
+		return nil
+	}
+
+	return cmd
+}
+
+// allContextsVersions collects the versions of the cluster of each context,
+// logged in with the settings of the context or else the ones of m. The
+// report of a context whose configuration can not be loaded is nil.
+func allContextsVersions(m *manager.Manager, parallel int) (map[string]*manager.VersionReport, []string, error) {
+	config, err := contexts.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(config.Contexts) == 0 {
+		return nil, nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("no context yet, add one with: seaweed-up context add <name> -f <file>"))
+	}
+	reports := make(map[string]*manager.VersionReport)
+	var names []string
+	for _, context := range config.Contexts {
+		names = append(names, context.Name)
+		specification, err := loadSpecification(context.File)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("context %s: %v", context.Name, err))
+			reports[context.Name] = nil
+			continue
+		}
+		cm := manager.NewManager()
+		cm.User = utils.Nvl(context.User, m.User)
+		cm.IdentityFile = utils.Nvl(context.IdentityFile, m.IdentityFile)
+		cm.SshPort = m.SshPort
+		cm.StateDir = contexts.StateDir(context.Name)
+		operator.KnownHosts = filepath.Join(cm.StateDir, "known_hosts")
+		reports[context.Name] = cm.CollectVersions(specification, parallel)
+	}
+	return reports, names, nil
+}
+
+// writeReport writes v to fileName, as yaml for .yaml or .yml files and as json otherwise.
+func writeReport(fileName string, v interface{}) error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(v)
+	default:
+		data, err = json.MarshalIndent(v, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", fileName, err)
+	}
+	return nil
+}
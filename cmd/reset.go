@@ -5,10 +5,7 @@ import (
 	"fmt"
 	"github.com/muesli/coral"
 	"github.com/seaweedfs/seaweed-up/pkg/cluster/manager"
-	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
 	"github.com/seaweedfs/seaweed-up/pkg/utils"
-	"gopkg.in/yaml.v3"
-	"os"
 	"path"
 )
 
@@ -29,21 +26,18 @@ func CleanCommand() *coral.Command {
 	cmd.Flags().IntVarP(&m.SshPort, "port", "p", 22, "The port to SSH.")
 	cmd.Flags().StringVarP(&m.IdentityFile, "identity_file", "i", m.IdentityFile, "The path of the SSH identity file. If specified, public key authentication will be used.")
 	cmd.Flags().StringVarP(&m.Version, "version", "v", "", "The SeaweedFS version")
+	cmd.RegisterFlagCompletionFunc("version", completeVersions)
 	cmd.Flags().StringVarP(&m.ComponentToDeploy, "component", "c", "", "[master|volume|filer] only clean one component")
 
 	cmd.RunE = func(command *coral.Command, args []string) error {
 
 		fmt.Println(fileName)
-		spec := &spec.Specification{}
-		data, readErr := os.ReadFile(fileName)
-		if readErr != nil {
-			return fmt.Errorf("read %s: %v", fileName, readErr)
-		}
-		if unmarshalErr := yaml.Unmarshal(data, spec); unmarshalErr != nil {
-			return fmt.Errorf("unmarshal %s: %v", fileName, unmarshalErr)
+		specification, err := loadValidSpecification(fileName)
+		if err != nil {
+			return err
 		}
 
-		return m.CleanCluster(spec)
+		return m.CleanCluster(specification)
 	}
 
 	return cmd
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/statecrypt"
+)
+
+func SecurityCommands() *coral.Command {
+	securityCmd := baseCommand("security")
+	securityCmd.Short = "Protect the local state of seaweed-up"
+	securityCmd.Long = `Protect the local state of seaweed-up
+
+The configurations applied to the clusters, kept in ~/.seaweed-up for "cluster history", and the
+snapshots deploy takes may hold secrets. encrypt-state encrypts them with AES-GCM, and the ones
+written later, with a key derived from a passphrase. The passphrase is read from the file of ` + statecrypt.KeyFileEnv + `, or from
+` + statecrypt.PassphraseEnv + `, or else asked. Keep ~/.seaweed-up/encryption, the state can
+not be decrypted without it.`
+	securityCmd.AddCommand(encryptStateCommand())
+	securityCmd.AddCommand(decryptStateCommand())
+	return securityCmd
+}
+
+func encryptStateCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:          "encrypt-state",
+		Short:        "encrypt the local state holding secrets",
+		Long:         "encrypt the local state holding secrets, and write it encrypted from now on",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+
+	var stateDirs []string
+	cmd.Flags().StringSliceVarP(&stateDirs, "state-dir", "", nil, "state dir given to other commands with --state-dir, besides "+statecrypt.Dir)
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		if !statecrypt.Enabled() {
+			passphrase, err := statecrypt.Passphrase("New passphrase of the seaweed-up state: ")
+			if err != nil {
+				return err
+			}
+			if passphrase == "" {
+				return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("the passphrase can not be empty"))
+			}
+			_, fromEnv := os.LookupEnv(statecrypt.PassphraseEnv)
+			if !fromEnv && os.Getenv(statecrypt.KeyFileEnv) == "" {
+				again, err := statecrypt.Passphrase("Repeat the passphrase: ")
+				if err != nil {
+					return err
+				}
+				if again != passphrase {
+					return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("the passphrases differ"))
+				}
+			}
+			if err := statecrypt.Enable(passphrase); err != nil {
+				return err
+			}
+		}
+		changed, err := rewriteState(stateDirs, true)
+		if err != nil {
+			return err
+		}
+		info(fmt.Sprintf("encrypted %d files, the state is written encrypted from now on", changed))
+		return nil
+	}
+
+	return cmd
+}
+
+func decryptStateCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:          "decrypt-state",
+		Short:        "decrypt the local state, and write it in plain text from now on",
+		Long:         "decrypt the local state, and write it in plain text from now on",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+
+	var stateDirs []string
+	cmd.Flags().StringSliceVarP(&stateDirs, "state-dir", "", nil, "state dir given to other commands with --state-dir, besides "+statecrypt.Dir)
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		if !statecrypt.Enabled() {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("the state is not encrypted"))
+		}
+		changed, err := rewriteState(stateDirs, false)
+		if err != nil {
+			return err
+		}
+		if err := statecrypt.Disable(); err != nil {
+			return err
+		}
+		info(fmt.Sprintf("decrypted %d files", changed))
+		return nil
+	}
+
+	return cmd
+}
+
+// rewriteState encrypts or decrypts the sensitive files of the data dir and of the state dirs.
+func rewriteState(stateDirs []string, encrypt bool) (int, error) {
+	changed := 0
+	for _, dir := range append([]string{statecrypt.Dir}, stateDirs...) {
+		files, err := statecrypt.SensitiveFiles(expandPath(dir))
+		if err != nil {
+			return changed, err
+		}
+		n, err := statecrypt.Rewrite(files, encrypt)
+		changed += n
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/muesli/coral"
+)
+
+func ShellCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "shell",
+		Short: "open weed shell on the cluster or run maintenance commands",
+		Long: `Open an interactive weed shell on a master host of the cluster, found in the configuration file.
+
+With -c or --script the commands run as one job, holding the cluster and shell locks, e.g.
+
+  seaweed-up shell -f cluster.yaml -c 'volume.balance -force'
+  seaweed-up shell -f cluster.yaml --script weekly.txt --log maintenance.log
+
+Script files have one command per line; empty lines and lines starting with # are skipped.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName, scriptFile, logFile string
+	var commands []string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringArrayVarP(&commands, "command", "c", nil, "weed shell command to run, can be repeated")
+	cmd.Flags().StringVarP(&scriptFile, "script", "", "", "file with weed shell commands to run")
+	cmd.Flags().StringVarP(&logFile, "log", "", "", "append the commands and their output to this file")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		if scriptFile != "" {
+			script, err := readShellScript(scriptFile)
+			if err != nil {
+				return err
+			}
+			commands = append(commands, script...)
+		}
+		if len(commands) == 0 && logFile != "" {
+			return fmt.Errorf("--log needs commands from -c or --script")
+		}
+		load := loadSpecification
+		if len(commands) > 0 {
+			load = loadValidSpecification
+		}
+		specification, err := load(fileName)
+		if err != nil {
+			return err
+		}
+		return m.Shell(specification, commands, logFile)
+	}
+
+	return cmd
+}
+
+func readShellScript(fileName string) (commands []string, err error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		commands = append(commands, line)
+	}
+	return commands, scanner.Err()
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+)
+
+func SshCommands() *coral.Command {
+	sshCmd := baseCommand("ssh")
+	sshCmd.Short = "Manage the SSH host keys seaweed-up trusts"
+	sshCmd.Long = `Manage the SSH host keys seaweed-up trusts
+
+The keys are kept in ~/.seaweed-up/known_hosts. The key of an unknown host is trusted and added on first use,
+unless --strict-host-keys is given. A host answering with another key than the one added is always refused.`
+	sshCmd.AddCommand(sshTrustCommand())
+	sshCmd.AddCommand(sshForgetCommand())
+	sshCmd.AddCommand(sshListCommand())
+	return sshCmd
+}
+
+func sshTrustCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "trust [host]",
+		Short: "trust the current key of a host",
+		Long: `Trust the key a host, given by its ip or ip:ssh port, answers with, replacing the keys known for it.
+
+Compare the fingerprint with the one of the host, shown by ssh-keygen -lf /etc/ssh/ssh_host_ed25519_key.pub,
+or give it with --fingerprint to have it checked. The host is picked from the hosts of the current context
+if not given.`,
+		Example:           "  seaweed-up ssh trust 192.168.1.12 --fingerprint SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8",
+		Args:              coral.MaximumNArgs(1),
+		ValidArgsFunction: completeHosts,
+		SilenceUsage:      true,
+	}
+
+	var fingerprint string
+	cmd.Flags().StringVarP(&fingerprint, "fingerprint", "", "", "the SHA256 fingerprint the key must have")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		address, err := pickArg(args, "host", specificationHosts(command))
+		if err != nil {
+			return err
+		}
+		host, err := operator.TrustHost(address, fingerprint)
+		if err != nil {
+			return err
+		}
+		return output.Print(host, func(w io.Writer) {
+			fmt.Fprintf(w, "trusted %s key %s of %s\n", host.Type, host.Fingerprint, host.Host)
+		})
+	}
+
+	return cmd
+}
+
+func sshForgetCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:               "forget [host]",
+		Short:             "remove the keys of a host",
+		Long:              "remove the keys of a host, given by its ip or ip:ssh port or picked from a list, its next key is trusted on first use",
+		Args:              coral.MaximumNArgs(1),
+		ValidArgsFunction: completeKnownHosts,
+		SilenceUsage:      true,
+	}
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		host, err := pickArg(args, "host", knownHosts())
+		if err != nil {
+			return err
+		}
+		found, err := operator.ForgetHost(host)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s is not a known host", host))
+		}
+		info(fmt.Sprintf("removed the keys of %s", host))
+		return nil
+	}
+
+	return cmd
+}
+
+func sshListCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:          "list",
+		Short:        "list the trusted host keys",
+		Long:         "list the trusted host keys",
+		Args:         coral.NoArgs,
+		SilenceUsage: true,
+	}
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		hosts, err := operator.ListKnownHosts()
+		if err != nil {
+			return err
+		}
+		return output.Print(hosts, func(w io.Writer) {
+			if len(hosts) == 0 {
+				fmt.Fprintln(w, "no host key is trusted yet")
+				return
+			}
+			t := output.NewTable(w)
+			fmt.Fprintln(t, "HOST\tTYPE\tFINGERPRINT")
+			for _, h := range hosts {
+				fmt.Fprintf(t, "%s\t%s\t%s\n", h.Host, h.Type, h.Fingerprint)
+			}
+			t.Flush()
+		})
+	}
+
+	return cmd
+}
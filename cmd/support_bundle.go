@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/muesli/coral"
+)
+
+func SupportBundleCommand() *coral.Command {
+
+	var cmd = &coral.Command{
+		Use:   "support-bundle",
+		Short: "collect cluster diagnostics into an archive for issue reports",
+		Long: `Collect the configuration with secrets redacted, component versions, systemd units, recent logs,
+status and metrics snapshots of every host, and a log of the collection into a tar.gz archive
+that can be attached to GitHub issues. The log ends with the end of --log-file, or else with the
+debug messages of the run.`,
+		SilenceUsage: true,
+	}
+	m := newClusterManager(cmd)
+
+	var fileName, output string
+	cmd.Flags().StringVarP(&fileName, "file", "f", "", "configuration file")
+	cmd.Flags().StringVarP(&output, "output", "o", "seaweed-up-bundle.tar.gz", "archive to write")
+
+	cmd.RunE = func(command *coral.Command, args []string) error {
+		data, err := readSpecificationFile(fileName)
+		if err != nil {
+			return err
+		}
+		specification, err := loadSpecification(fileName)
+		if err != nil {
+			return err
+		}
+		about := fmt.Sprintf("version: %s\ngit commit: %s\ngo: %s %s/%s\ncommand: %s\n",
+			Version, GitCommit, runtime.Version(), runtime.GOOS, runtime.GOARCH, strings.Join(os.Args, " "))
+		return m.SupportBundle(specification, data, about, output)
+	}
+
+	return cmd
+}
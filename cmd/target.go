@@ -8,6 +8,7 @@ import (
 	"github.com/mitchellh/go-homedir"
 	"github.com/muesli/coral"
 	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
 )
 
 const SshTargetPassword = "SSH_TARGET_PASSWORD"
@@ -39,6 +40,7 @@ func (t *Target) execute(callback operator.Callback) error {
 		if err != nil {
 			return err
 		}
+		redact.Secret(pwd)
 		return operator.ExecuteRemote(t.Addr, t.User, t.Key, pwd, callback)
 	}
 }
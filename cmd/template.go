@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/templates"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+)
+
+func TemplateCommands() *coral.Command {
+	templateCmd := baseCommand("template")
+	templateCmd.Short = "Generate configuration files from built-in cluster templates"
+	templateCmd.Long = "Generate configuration files from built-in cluster templates"
+	templateCmd.AddCommand(templateListCommand())
+	templateCmd.AddCommand(templateShowCommand())
+	templateCmd.AddCommand(templateGenerateCommand())
+	return templateCmd
+}
+
+func templateListCommand() *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "list",
+		Short:        "list the built-in cluster templates",
+		Long:         "list the built-in cluster templates",
+		SilenceUsage: true,
+	}
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		list := templates.List()
+		return output.Print(list, func(out io.Writer) {
+			w := output.NewTable(out)
+			fmt.Fprintln(w, "NAME\tDESCRIPTION")
+			for _, t := range list {
+				fmt.Fprintf(w, "%s\t%s\n", t.Name, t.Description)
+			}
+			w.Flush()
+		})
+	}
+
+	return command
+}
+
+func templateShowCommand() *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "show <name>",
+		Short:        "show the parameters and source of a template",
+		Long:         "show the parameters and source of a template",
+		Args:         coral.ExactArgs(1),
+		SilenceUsage: true,
+	}
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		t, err := templates.Get(args[0])
+		if err != nil {
+			return err
+		}
+		source, err := t.Source()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n\nParameters:\n", t.Name, t.Description)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, p := range t.Parameters {
+			fmt.Fprintf(w, "  %s\t%s\t(default %q)\n", p.Name, p.Description, p.Default)
+		}
+		w.Flush()
+		fmt.Printf("\n%s", source)
+		return nil
+	}
+
+	return command
+}
+
+func templateGenerateCommand() *coral.Command {
+
+	var command = &coral.Command{
+		Use:          "generate <name>",
+		Short:        "generate a configuration file from a template",
+		Long:         "generate a configuration file from a template, e.g. seaweed-up template generate ha --set masters=10.0.0.1,10.0.0.2,10.0.0.3",
+		Args:         coral.ExactArgs(1),
+		SilenceUsage: true,
+	}
+
+	var output string
+	var force bool
+	var values []string
+	command.Flags().StringVarP(&output, "output", "o", "", "configuration file to create, print to stdout if empty")
+	command.Flags().BoolVar(&force, "force", false, "overwrite the configuration file if it exists")
+	command.Flags().StringArrayVar(&values, "set", nil, "template parameter as name=value, can be repeated")
+
+	command.RunE = func(command *coral.Command, args []string) error {
+		t, err := templates.Get(args[0])
+		if err != nil {
+			return err
+		}
+		parameters := make(map[string]string)
+		for _, value := range values {
+			parts := strings.SplitN(value, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid parameter %q, expected name=value", value)
+			}
+			parameters[parts[0]] = parts[1]
+		}
+		data, err := t.Render(parameters)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if _, err := os.Stat(output); err == nil && !force {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", output)
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("wrote %s, deploy it with: seaweed-up deploy -f %s\n", output, output)
+		return nil
+	}
+
+	return command
+}
@@ -55,7 +55,8 @@ func UninstallCommand(product string) *coral.Command {
 			if err != nil {
 				return fmt.Errorf("error received during installation: %s", err)
 			}
-			err = op.Execute(fmt.Sprintf("cat %s/run.sh | SERVICE=%s SUDO_PASS=\"%s\" sh -\n", dir, product, sudoPass))
+			quotedPass := "'" + strings.ReplaceAll(sudoPass, "'", `'"'"'`) + "'"
+			err = op.Execute(fmt.Sprintf("cat %s/run.sh | SERVICE=%s SUDO_PASS=%s sh -\n", dir, product, quotedPass))
 			if err != nil {
 				return fmt.Errorf("error received during uninstallation: %s", err)
 			}
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/muesli/coral"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
 )
 
 var (
@@ -19,13 +21,19 @@ func VersionCommand() *coral.Command {
 		SilenceUsage: true,
 	}
 
-	command.Run = func(cmd *coral.Command, args []string) {
-		if len(Version) == 0 {
-			fmt.Println("Version: dev")
-		} else {
-			fmt.Println("Version:", Version)
+	command.RunE = func(cmd *coral.Command, args []string) error {
+		version := Version
+		if len(version) == 0 {
+			version = "dev"
 		}
-		fmt.Println("Git Commit:", GitCommit)
+		result := struct {
+			Version   string `json:"version" yaml:"version"`
+			GitCommit string `json:"gitCommit" yaml:"gitCommit"`
+		}{version, GitCommit}
+		return output.Print(result, func(w io.Writer) {
+			fmt.Fprintln(w, "Version:", result.Version)
+			fmt.Fprintln(w, "Git Commit:", result.GitCommit)
+		})
 	}
 	return command
 }
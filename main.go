@@ -1,26 +1,32 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/seaweedfs/seaweed-up/cmd"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
 	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
 
-		switch err.(type) {
-		case *operator.TargetConnectError:
-			fmt.Printf(targetConnectErrorMessage, err)
-		case *operator.SshAgentError:
-			fmt.Printf(sshAgentErrorMessage, err)
+		var connectError *operator.TargetConnectError
+		var agentError *operator.SshAgentError
+		message := redact.String(err.Error())
+		switch {
+		case errors.As(err, &connectError):
+			fmt.Fprintf(os.Stderr, targetConnectErrorMessage, message)
+		case errors.As(err, &agentError):
+			fmt.Fprintf(os.Stderr, sshAgentErrorMessage, message)
 		default:
-			fmt.Println(err)
+			fmt.Fprintln(os.Stderr, message)
 		}
 
-		os.Exit(1)
+		os.Exit(exitcode.Of(err))
 	}
 }
 
@@ -0,0 +1,177 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// aws creates EC2 instances with the aws command line tool, in the default VPC.
+type aws struct {
+	spec          *Spec
+	securityGroup string
+}
+
+func (a *aws) Name() string { return "aws" }
+
+func (a *aws) ec2(ctx context.Context, args ...string) ([]byte, error) {
+	return run(ctx, "aws", append(append([]string{"ec2"}, args...), "--region", a.spec.Region, "--output", "json")...)
+}
+
+// Prepare creates a security group open between its members and to the admin networks.
+func (a *aws) Prepare(ctx context.Context) error {
+	out, err := a.ec2(ctx, "create-security-group", "--group-name", "seaweed-up-"+a.spec.Name,
+		"--description", "seaweed-up cluster "+a.spec.Name)
+	if err != nil {
+		return err
+	}
+	var group struct {
+		GroupId string
+	}
+	if err := json.Unmarshal(out, &group); err != nil {
+		return fmt.Errorf("parse aws output: %w", err)
+	}
+	a.securityGroup = group.GroupId
+
+	type ipRange struct {
+		CidrIp string `json:"CidrIp"`
+	}
+	type ipv6Range struct {
+		CidrIpv6 string `json:"CidrIpv6"`
+	}
+	type permission struct {
+		IpProtocol       string              `json:"IpProtocol"`
+		FromPort         int                 `json:"FromPort,omitempty"`
+		ToPort           int                 `json:"ToPort,omitempty"`
+		UserIdGroupPairs []map[string]string `json:"UserIdGroupPairs,omitempty"`
+		IpRanges         []ipRange           `json:"IpRanges,omitempty"`
+		Ipv6Ranges       []ipv6Range         `json:"Ipv6Ranges,omitempty"`
+	}
+	ranges := func(cidrs []string, from, to int) permission {
+		p := permission{IpProtocol: "tcp", FromPort: from, ToPort: to}
+		for _, cidr := range cidrs {
+			if strings.Contains(cidr, ":") {
+				p.Ipv6Ranges = append(p.Ipv6Ranges, ipv6Range{cidr})
+			} else {
+				p.IpRanges = append(p.IpRanges, ipRange{cidr})
+			}
+		}
+		return p
+	}
+	permissions := []permission{
+		{IpProtocol: "-1", UserIdGroupPairs: []map[string]string{{"GroupId": a.securityGroup}}},
+		ranges(a.spec.sshSources(), 22, 22),
+	}
+	if len(a.spec.AdminCidrs) > 0 {
+		permissions = append(permissions, ranges(a.spec.AdminCidrs, 1, 65535))
+	}
+	data, err := json.Marshal(permissions)
+	if err != nil {
+		return err
+	}
+	_, err = a.ec2(ctx, "authorize-security-group-ingress", "--group-id", a.securityGroup, "--ip-permissions", string(data))
+	return err
+}
+
+func (a *aws) CreateServer(ctx context.Context, name string, machine *MachineSpec) (*Server, error) {
+	args := []string{"run-instances", "--image-id", machine.Image, "--instance-type", machine.Type,
+		"--key-name", a.spec.SshKey, "--security-group-ids", a.securityGroup, "--count", "1",
+		"--tag-specifications", fmt.Sprintf("ResourceType=instance,Tags=[{Key=Name,Value=%s},{Key=seaweed-up,Value=%s}]", name, a.spec.Name)}
+	if machine.Disks > 0 {
+		var mappings []string
+		for i := 0; i < machine.Disks; i++ {
+			// /dev/sdf to /dev/sdp are the names recommended for EBS volumes
+			mappings = append(mappings, fmt.Sprintf(`{"DeviceName":"/dev/sd%c","Ebs":{"VolumeSize":%d,"DeleteOnTermination":true}}`, 'f'+i, machine.DiskSizeGB))
+		}
+		args = append(args, "--block-device-mappings", "["+strings.Join(mappings, ",")+"]")
+	}
+	out, err := a.ec2(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var reservation struct {
+		Instances []struct {
+			InstanceId string
+		}
+	}
+	if err := json.Unmarshal(out, &reservation); err != nil || len(reservation.Instances) == 0 {
+		return nil, fmt.Errorf("parse aws output: %v", err)
+	}
+	id := reservation.Instances[0].InstanceId
+
+	if _, err := a.ec2(ctx, "wait", "instance-running", "--instance-ids", id); err != nil {
+		return nil, err
+	}
+	out, err = a.ec2(ctx, "describe-instances", "--instance-ids", id, "--query", "Reservations[0].Instances[0]")
+	if err != nil {
+		return nil, err
+	}
+	var instance struct {
+		PublicIpAddress  string
+		PrivateIpAddress string
+	}
+	if err := json.Unmarshal(out, &instance); err != nil {
+		return nil, fmt.Errorf("parse aws output: %w", err)
+	}
+	return &Server{PublicIp: instance.PublicIpAddress, PrivateIp: instance.PrivateIpAddress}, nil
+}
+
+func (a *aws) Finish(ctx context.Context, servers []*Server) error { return nil }
+
+// ListAwsInstances lists the running EC2 instances having all tags, given as
+// key=value. The comma separated values of the roleTag of an instance are its roles.
+func ListAwsInstances(ctx context.Context, region string, tags []string, roleTag string) ([]*Server, error) {
+	args := []string{"ec2", "describe-instances", "--output", "json"}
+	if region != "" {
+		args = append(args, "--region", region)
+	}
+	args = append(args, "--filters", "Name=instance-state-name,Values=running")
+	for _, tag := range tags {
+		key, value, found := strings.Cut(tag, "=")
+		if !found {
+			return nil, fmt.Errorf("tag %q must be key=value", tag)
+		}
+		args = append(args, fmt.Sprintf("Name=tag:%s,Values=%s", key, value))
+	}
+	out, err := run(ctx, "aws", args...)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Reservations []struct {
+			Instances []struct {
+				InstanceId       string
+				PublicIpAddress  string
+				PrivateIpAddress string
+				Tags             []struct {
+					Key   string
+					Value string
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("parse aws output: %w", err)
+	}
+	var servers []*Server
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			server := &Server{Name: instance.InstanceId, PublicIp: instance.PublicIpAddress, PrivateIp: instance.PrivateIpAddress}
+			for _, tag := range instance.Tags {
+				switch tag.Key {
+				case "Name":
+					server.Name = tag.Value
+				case roleTag:
+					for _, role := range strings.Split(tag.Value, ",") {
+						if role = strings.TrimSpace(role); role != "" {
+							server.Roles = append(server.Roles, role)
+						}
+					}
+				}
+			}
+			servers = append(servers, server)
+		}
+	}
+	return servers, nil
+}
@@ -0,0 +1,219 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+)
+
+// Spec describes the machines of a cluster to create at a cloud provider.
+type Spec struct {
+	Name     string `yaml:"name"`     // prefix of the server names, also labels the created resources
+	Provider string `yaml:"provider"` // hetzner, aws or gcp
+	Region   string `yaml:"region"`   // location at hetzner, region at aws, zone at gcp
+	SshUser  string `yaml:"ssh_user,omitempty" default:"root"`
+	// name of the ssh key at hetzner and aws, path of the public key file for gcp
+	SshKey string `yaml:"ssh_key"`
+	// networks allowed to reach the servers besides the servers themselves, SSH is open to all without them
+	AdminCidrs []string `yaml:"admin_cidrs,omitempty"`
+	// write the private addresses to the cluster file, when deploying from inside the network
+	PrivateAddresses bool           `yaml:"private_addresses,omitempty"`
+	Machines         []*MachineSpec `yaml:"machines"`
+	Cluster          ClusterSpec    `yaml:"cluster,omitempty"`
+}
+
+// MachineSpec is a group of identical servers running the same components.
+type MachineSpec struct {
+	Roles      []string `yaml:"roles"` // master, volume, filer or envoy
+	Count      int      `yaml:"count,omitempty" default:"1"`
+	Type       string   `yaml:"type"`
+	Image      string   `yaml:"image"`                  // for gcp as image-project/image-family
+	Disks      int      `yaml:"disks,omitempty"`        // data disks to attach, formatted and mounted by deploy
+	DiskSizeGB int      `yaml:"disk_size_gb,omitempty"` // size of each data disk
+}
+
+// ClusterSpec holds the options of the generated cluster file.
+type ClusterSpec struct {
+	Replication string `yaml:"replication,omitempty" default:"000"`
+	DiskType    string `yaml:"disk_type,omitempty" default:"hdd"`
+	FsType      string `yaml:"fs_type,omitempty" default:"ext4"`
+	S3          bool   `yaml:"s3,omitempty"`
+}
+
+// Server is a created machine.
+type Server struct {
+	Name      string
+	Roles     []string
+	PublicIp  string
+	PrivateIp string
+	Disks     int
+}
+
+// Address is the address the cluster file uses for the server.
+func (s *Server) Address(private bool) string {
+	if private || s.PublicIp == "" {
+		return s.PrivateIp
+	}
+	return s.PublicIp
+}
+
+// Provider creates servers with the command line tool of a cloud.
+type Provider interface {
+	Name() string
+	// Prepare creates the resources the servers are created in, like security groups.
+	Prepare(ctx context.Context) error
+	CreateServer(ctx context.Context, name string, machine *MachineSpec) (*Server, error)
+	// Finish restricts the access to the servers to each other and the admin networks.
+	Finish(ctx context.Context, servers []*Server) error
+}
+
+// NewProvider returns the provider of the spec, if its command line tool is installed.
+func NewProvider(s *Spec) (Provider, error) {
+	var p Provider
+	var tool string
+	switch s.Provider {
+	case "hetzner":
+		p, tool = &hetzner{spec: s}, "hcloud"
+	case "aws":
+		p, tool = &aws{spec: s}, "aws"
+	case "gcp":
+		p, tool = &gcp{spec: s}, "gcloud"
+	default:
+		return nil, fmt.Errorf("unknown provider %q, supported: hetzner, aws, gcp", s.Provider)
+	}
+	if _, err := exec.LookPath(tool); err != nil {
+		return nil, fmt.Errorf("%s needs the %s command line tool, install and configure it first", s.Provider, tool)
+	}
+	return p, nil
+}
+
+// Validate checks the spec before anything is created.
+func (s *Spec) Validate() error {
+	if s.Name == "" || s.Region == "" || s.SshKey == "" {
+		return fmt.Errorf("name, region and ssh_key are required")
+	}
+	roles := make(map[string]int)
+	for i, machine := range s.Machines {
+		if machine.Type == "" || machine.Image == "" {
+			return fmt.Errorf("machines[%d]: type and image are required", i)
+		}
+		if machine.Disks > 0 && machine.DiskSizeGB <= 0 {
+			return fmt.Errorf("machines[%d]: disk_size_gb is required with disks", i)
+		}
+		for _, role := range machine.Roles {
+			switch role {
+			case "master", "volume", "filer", "envoy":
+				roles[role] += machine.count()
+			default:
+				return fmt.Errorf("machines[%d]: unknown role %q, use master, volume, filer or envoy", i, role)
+			}
+		}
+	}
+	if roles["master"] == 0 || roles["volume"] == 0 {
+		return fmt.Errorf("at least one master and one volume server are required")
+	}
+	return nil
+}
+
+func (m *MachineSpec) count() int {
+	if m.Count <= 0 {
+		return 1
+	}
+	return m.Count
+}
+
+// Create creates the servers of all machines, named <name>-<role>-<n>.
+func Create(ctx context.Context, p Provider, s *Spec) ([]*Server, error) {
+	if err := p.Prepare(ctx); err != nil {
+		return nil, err
+	}
+	var servers []*Server
+	for _, machine := range s.Machines {
+		for i := 1; i <= machine.count(); i++ {
+			name := fmt.Sprintf("%s-%s-%d", s.Name, strings.Join(machine.Roles, "-"), i)
+			logging.Info(fmt.Sprintf("Creating %s %s at %s", machine.Type, name, p.Name()))
+			server, err := p.CreateServer(ctx, name, machine)
+			if err != nil {
+				return servers, fmt.Errorf("create %s: %w", name, err)
+			}
+			server.Name, server.Roles, server.Disks = name, machine.Roles, machine.Disks
+			logging.Info(fmt.Sprintf("%s is %s (private %s)", name, server.PublicIp, server.PrivateIp))
+			servers = append(servers, server)
+		}
+	}
+	if err := p.Finish(ctx, servers); err != nil {
+		return servers, err
+	}
+	return servers, nil
+}
+
+// WaitForSsh waits until the SSH port of the address accepts connections.
+func WaitForSsh(ctx context.Context, address string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(address, "22"), 5*time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("ssh on %s not reachable after %s: %w", address, timeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// run runs a command line tool and returns its standard output.
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	logging.Debug("cloud command", "command", name+" "+strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w: %s", name, strings.Join(args[:minInt(2, len(args))], " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// hostCidrs turns addresses into single host networks.
+func hostCidrs(servers []*Server) (cidrs []string) {
+	for _, server := range servers {
+		for _, ip := range []string{server.PublicIp, server.PrivateIp} {
+			if ip == "" {
+				continue
+			}
+			if strings.Contains(ip, ":") {
+				cidrs = append(cidrs, ip+"/128")
+			} else {
+				cidrs = append(cidrs, ip+"/32")
+			}
+		}
+	}
+	return
+}
+
+// sshSources are the networks allowed to connect with SSH.
+func (s *Spec) sshSources() []string {
+	if len(s.AdminCidrs) > 0 {
+		return s.AdminCidrs
+	}
+	return []string{"0.0.0.0/0", "::/0"}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,142 @@
+package cloud
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Pricing holds the prices the cost of a spec is estimated with.
+type Pricing struct {
+	Currency  string             `yaml:"currency"`
+	Source    string             `yaml:"source,omitempty"` // where and when the prices were taken from
+	Instances map[string]float64 `yaml:"instances"`        // monthly price by machine type
+	DiskGB    float64            `yaml:"disk_gb"`          // monthly price of a GB of data disk
+	EgressGB  float64            `yaml:"egress_gb"`        // price of a GB of outgoing traffic
+	// outgoing traffic in GB a month included with each server, free of charge
+	IncludedEgressGB float64 `yaml:"included_egress_gb,omitempty"`
+}
+
+// DefaultPricing are on-demand list prices of common machine types by
+// provider, to compare architectures. They get outdated and differ by region
+// and contract, load a pricing file with LoadPricing for actual prices.
+var DefaultPricing = map[string]*Pricing{
+	"hetzner": {
+		Currency: "EUR",
+		Source:   "hetzner cloud list prices of 2024, without VAT",
+		Instances: map[string]float64{
+			"cx22": 3.79, "cx32": 6.80, "cx42": 16.40, "cx52": 32.40,
+		},
+		DiskGB:           0.044,
+		EgressGB:         0.001,
+		IncludedEgressGB: 20480,
+	},
+	"aws": {
+		Currency: "USD",
+		Source:   "aws on-demand list prices of us-east-1 of 2024, 730 hours a month, gp2 volumes",
+		Instances: map[string]float64{
+			"t3.large": 60.74, "m5.large": 70.08, "m5.xlarge": 140.16, "m5.2xlarge": 280.32,
+			"r5.large": 91.98, "r5.xlarge": 183.96, "i3.large": 113.88, "i3.xlarge": 227.76,
+		},
+		DiskGB:   0.10,
+		EgressGB: 0.09,
+	},
+	"gcp": {
+		Currency: "USD",
+		Source:   "gcp on-demand list prices of us-central1 of 2024, 730 hours a month, pd-standard disks",
+		Instances: map[string]float64{
+			"e2-standard-2": 48.91, "e2-standard-4": 97.83, "e2-standard-8": 195.65,
+			"n2-standard-2": 70.90, "n2-standard-4": 141.79, "n2-standard-8": 283.58,
+		},
+		DiskGB:   0.04,
+		EgressGB: 0.12,
+	},
+}
+
+// LoadPricing reads a pricing file, in the yaml format of Pricing.
+func LoadPricing(fileName string) (*Pricing, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", fileName, err)
+	}
+	pricing := &Pricing{}
+	if err := yaml.Unmarshal(data, pricing); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", fileName, err)
+	}
+	pricing.Source = utils.Nvl(pricing.Source, fileName)
+	return pricing, nil
+}
+
+// CostItem is the monthly cost of a group of machines, or of the traffic.
+type CostItem struct {
+	Tier      string  `json:"tier" yaml:"tier"` // the roles of the machines, or egress
+	Count     int     `json:"count" yaml:"count"`
+	Type      string  `json:"type" yaml:"type"`
+	Instances float64 `json:"instances" yaml:"instances"`
+	Disks     float64 `json:"disks" yaml:"disks"`
+	Total     float64 `json:"total" yaml:"total"`
+}
+
+// CostEstimate is the monthly cost of the machines of a spec.
+type CostEstimate struct {
+	Provider string      `json:"provider" yaml:"provider"`
+	Currency string      `json:"currency" yaml:"currency"`
+	Source   string      `json:"source" yaml:"source"`
+	Items    []*CostItem `json:"items" yaml:"items"`
+	Total    float64     `json:"total" yaml:"total"`
+}
+
+// EstimateCost returns the monthly cost of the machines and data disks of the
+// spec, plus egressGB of outgoing traffic beyond the included one, with the
+// pricing of the provider if pricing is nil. Boot disks, addresses and taxes
+// are left out.
+func EstimateCost(s *Spec, pricing *Pricing, egressGB float64) (*CostEstimate, error) {
+	hint := ""
+	if pricing == nil {
+		pricing = DefaultPricing[s.Provider]
+		if pricing == nil {
+			return nil, fmt.Errorf("no prices known for provider %q, give a pricing file", s.Provider)
+		}
+		hint = ", give a pricing file"
+	}
+	estimate := &CostEstimate{Provider: s.Provider, Currency: pricing.Currency, Source: pricing.Source}
+	var unknown []string
+	servers := 0
+	for _, machine := range s.Machines {
+		price, found := pricing.Instances[machine.Type]
+		if !found {
+			unknown = append(unknown, machine.Type)
+			continue
+		}
+		count := machine.count()
+		servers += count
+		item := &CostItem{
+			Tier:      strings.Join(machine.Roles, "+"),
+			Count:     count,
+			Type:      machine.Type,
+			Instances: price * float64(count),
+			Disks:     pricing.DiskGB * float64(count*machine.Disks*machine.DiskSizeGB),
+		}
+		item.Total = item.Instances + item.Disks
+		estimate.Items = append(estimate.Items, item)
+		estimate.Total += item.Total
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("no price for machine type %s in %s%s", strings.Join(unknown, ", "), pricing.Source, hint)
+	}
+	if egressGB > 0 {
+		charged := egressGB - pricing.IncludedEgressGB*float64(servers)
+		if charged < 0 {
+			charged = 0
+		}
+		item := &CostItem{Tier: "egress", Type: fmt.Sprintf("%g GB", egressGB), Total: pricing.EgressGB * charged}
+		estimate.Items = append(estimate.Items, item)
+		estimate.Total += item.Total
+	}
+	return estimate, nil
+}
@@ -0,0 +1,85 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// gcp creates compute instances with the gcloud command line tool.
+type gcp struct {
+	spec *Spec
+}
+
+func (g *gcp) Name() string { return "gcp" }
+
+func (g *gcp) tag() string { return "seaweed-up-" + g.spec.Name }
+
+func (g *gcp) Prepare(ctx context.Context) error { return nil }
+
+func (g *gcp) CreateServer(ctx context.Context, name string, machine *MachineSpec) (*Server, error) {
+	project, family, found := strings.Cut(machine.Image, "/")
+	if !found {
+		return nil, fmt.Errorf("image %q must be image-project/image-family, e.g. ubuntu-os-cloud/ubuntu-2204-lts", machine.Image)
+	}
+	publicKey, err := os.ReadFile(g.spec.SshKey)
+	if err != nil {
+		return nil, fmt.Errorf("read ssh_key: %w", err)
+	}
+	args := []string{"compute", "instances", "create", name, "--zone", g.spec.Region, "--machine-type", machine.Type,
+		"--image-project", project, "--image-family", family, "--tags", g.tag(),
+		"--labels", "seaweed-up=" + g.spec.Name,
+		"--metadata", fmt.Sprintf("ssh-keys=%s:%s", utils.Nvl(g.spec.SshUser, "root"), strings.TrimSpace(string(publicKey))),
+		"--format", "json"}
+	for i := 0; i < machine.Disks; i++ {
+		args = append(args, "--create-disk", fmt.Sprintf("size=%dGB,auto-delete=yes", machine.DiskSizeGB))
+	}
+	out, err := run(ctx, "gcloud", args...)
+	if err != nil {
+		return nil, err
+	}
+	var instances []struct {
+		NetworkInterfaces []struct {
+			NetworkIP     string `json:"networkIP"`
+			AccessConfigs []struct {
+				NatIP string `json:"natIP"`
+			} `json:"accessConfigs"`
+		} `json:"networkInterfaces"`
+	}
+	if err := json.Unmarshal(out, &instances); err != nil || len(instances) == 0 || len(instances[0].NetworkInterfaces) == 0 {
+		return nil, fmt.Errorf("parse gcloud output: %v", err)
+	}
+	nic := instances[0].NetworkInterfaces[0]
+	server := &Server{PrivateIp: nic.NetworkIP}
+	if len(nic.AccessConfigs) > 0 {
+		server.PublicIp = nic.AccessConfigs[0].NatIP
+	}
+	return server, nil
+}
+
+// Finish opens all traffic between the tagged instances, and SSH and the
+// cluster ports to the admin networks.
+func (g *gcp) Finish(ctx context.Context, servers []*Server) error {
+	if _, err := run(ctx, "gcloud", "compute", "firewall-rules", "create", g.tag()+"-internal",
+		"--allow", "tcp,udp,icmp", "--source-tags", g.tag(), "--target-tags", g.tag()); err != nil {
+		return err
+	}
+	allow := "tcp"
+	if len(g.spec.AdminCidrs) == 0 {
+		allow = "tcp:22"
+	}
+	var sources []string
+	for _, cidr := range g.spec.sshSources() {
+		// gcp firewall rules are either for IPv4 or for IPv6
+		if !strings.Contains(cidr, ":") {
+			sources = append(sources, cidr)
+		}
+	}
+	_, err := run(ctx, "gcloud", "compute", "firewall-rules", "create", g.tag()+"-admin",
+		"--allow", allow, "--source-ranges", strings.Join(sources, ","), "--target-tags", g.tag())
+	return err
+}
@@ -0,0 +1,92 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// hetzner creates servers with the hcloud command line tool.
+type hetzner struct {
+	spec *Spec
+}
+
+func (h *hetzner) Name() string { return "hetzner" }
+
+func (h *hetzner) label() string { return "seaweed-up=" + h.spec.Name }
+
+func (h *hetzner) Prepare(ctx context.Context) error { return nil }
+
+func (h *hetzner) CreateServer(ctx context.Context, name string, machine *MachineSpec) (*Server, error) {
+	out, err := run(ctx, "hcloud", "server", "create", "--name", name, "--type", machine.Type, "--image", machine.Image,
+		"--location", h.spec.Region, "--ssh-key", h.spec.SshKey, "--label", h.label(), "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var created struct {
+		Server struct {
+			PublicNet struct {
+				Ipv4 struct {
+					Ip string `json:"ip"`
+				} `json:"ipv4"`
+			} `json:"public_net"`
+			PrivateNet []struct {
+				Ip string `json:"ip"`
+			} `json:"private_net"`
+		} `json:"server"`
+	}
+	if err := json.Unmarshal(out, &created); err != nil {
+		return nil, fmt.Errorf("parse hcloud output: %w", err)
+	}
+	server := &Server{PublicIp: created.Server.PublicNet.Ipv4.Ip, PrivateIp: created.Server.PublicNet.Ipv4.Ip}
+	if len(created.Server.PrivateNet) > 0 {
+		server.PrivateIp = created.Server.PrivateNet[0].Ip
+	}
+
+	// volumes are attached unformatted, deploy formats and mounts them
+	for i := 1; i <= machine.Disks; i++ {
+		if _, err := run(ctx, "hcloud", "volume", "create", "--name", fmt.Sprintf("%s-data%d", name, i),
+			"--size", fmt.Sprint(machine.DiskSizeGB), "--server", name, "--label", h.label(), "-o", "json"); err != nil {
+			return nil, err
+		}
+	}
+	return server, nil
+}
+
+func (h *hetzner) Finish(ctx context.Context, servers []*Server) error {
+	type rule struct {
+		Direction string   `json:"direction"`
+		Protocol  string   `json:"protocol"`
+		Port      string   `json:"port,omitempty"`
+		SourceIps []string `json:"source_ips"`
+	}
+	nodes := hostCidrs(servers)
+	rules := []rule{
+		{"in", "tcp", "22", h.spec.sshSources()},
+		{"in", "tcp", "1-65535", append(nodes, h.spec.AdminCidrs...)},
+		{"in", "udp", "1-65535", nodes},
+		{"in", "icmp", "", nodes},
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp("", "seaweed-up-firewall-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	file.Close()
+
+	firewall := "seaweed-up-" + h.spec.Name
+	if _, err := run(ctx, "hcloud", "firewall", "create", "--name", firewall, "--rules-file", file.Name(), "--label", h.label()); err != nil {
+		return err
+	}
+	_, err = run(ctx, "hcloud", "firewall", "apply-to-resource", firewall, "--type", "label_selector", "--label-selector", h.label())
+	return err
+}
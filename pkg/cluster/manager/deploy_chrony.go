@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/scripts"
+	"github.com/thanhpk/randstr"
+)
+
+func (m *Manager) DeployChrony(timeSync *spec.TimeSyncSpec, h *clusterHost, index int) error {
+	return m.executeOnHost(h, func(op operator.CommandOperator) error {
+		return m.deployChrony(op, timeSync, index)
+	})
+}
+
+// deployChrony installs and enables chrony, syncing with the servers of
+// timeSync if there are any.
+func (m *Manager) deployChrony(op operator.CommandOperator, timeSync *spec.TimeSyncSpec, index int) error {
+	var confFile, conf string
+	if len(timeSync.Servers) > 0 {
+		// debian keeps the configuration in a directory, red hat does not
+		out, err := op.Output("if [ -d /etc/chrony ]; then echo /etc/chrony/chrony.conf; else echo /etc/chrony.conf; fi")
+		if err != nil {
+			return fmt.Errorf("find the chrony configuration: %w", err)
+		}
+		confFile = strings.TrimSpace(string(out))
+		var b strings.Builder
+		b.WriteString("# Generated by seaweed-up\n")
+		for _, server := range timeSync.Servers {
+			fmt.Fprintf(&b, "server %s iburst\n", server)
+		}
+		b.WriteString("driftfile /var/lib/chrony/drift\nmakestep 1.0 3\nrtcsync\n")
+		conf = b.String()
+	}
+
+	componentInstance := fmt.Sprintf("chrony%d", index)
+	if p, planning := op.(*planOperator); planning {
+		var changed bool
+		if confFile != "" {
+			change, err := p.file(confFile, conf)
+			if err != nil {
+				return err
+			}
+			changed = change.Status != "unchanged"
+		}
+		out, err := op.Output("command -v chronyd || true")
+		if err != nil {
+			return fmt.Errorf("find chrony: %w", err)
+		}
+		if strings.TrimSpace(string(out)) == "" {
+			p.command("install the chrony package")
+			changed = true
+		}
+		if !m.skipStart && (changed || m.ForceRestart) {
+			p.node.Restart = append(p.node.Restart, "chrony")
+		}
+		return nil
+	}
+
+	info("Deploying " + componentInstance + "...")
+
+	dir := "/tmp/seaweed-up." + randstr.String(6)
+
+	defer op.Execute("rm -rf " + dir)
+
+	if err := op.Execute("mkdir -p " + dir); err != nil {
+		return fmt.Errorf("error received during installation: %w", err)
+	}
+
+	installScript, err := scripts.RenderScript("install_chrony.sh", map[string]interface{}{
+		"TmpDir":       dir,
+		"ConfFile":     confFile,
+		"SkipStart":    m.skipStart,
+		"ForceRestart": m.ForceRestart,
+	})
+	if err != nil {
+		return err
+	}
+	if err := op.Upload(installScript, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance), "0755"); err != nil {
+		return fmt.Errorf("error received during upload install script: %w", err)
+	}
+	if confFile != "" {
+		if err := op.Upload(strings.NewReader(conf), dir+"/chrony.conf", "0644"); err != nil {
+			return fmt.Errorf("error received during upload chrony.conf: %w", err)
+		}
+	}
+
+	info("Installing " + componentInstance + "...")
+	if err := op.Execute(m.scriptCommand(op, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance))); err != nil {
+		return fmt.Errorf("error received during installation: %w", err)
+	}
+
+	info("Done.")
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
 	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
 	"github.com/seaweedfs/seaweed-up/scripts"
 	"github.com/thanhpk/randstr"
 	"strings"
@@ -15,52 +16,77 @@ import (
 //go:embed envoy.yaml.tpl
 var envoyYamlTemplate string
 
-func (m *Manager) DeployEnvoyServer(filerSpecs []*spec.FilerServerSpec, envoySpec *spec.EnvoyServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", envoySpec.Ip, envoySpec.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
-
-		var s3EndPoints, webdavEndPoints []*spec.FilerServerSpec
-		for _, filerSpec := range filerSpecs {
-			if filerSpec.PortGrpc == 0 {
-				filerSpec.PortGrpc = filerSpec.Port + 10000
-			}
-			if filerSpec.S3 || filerSpec.S3Port != 0 {
-				s3EndPoints = append(s3EndPoints, filerSpec)
-			}
-			if filerSpec.Webdav || filerSpec.WebdavPort != 0 {
-				webdavEndPoints = append(webdavEndPoints, filerSpec)
-			}
-		}
+// envoyEndPoint is a server envoy balances the requests of a listener over.
+type envoyEndPoint struct {
+	Ip   string
+	Port int
+}
+
+func (m *Manager) DeployEnvoyServer(filerSpecs []*spec.FilerServerSpec, s3Specs []*spec.S3ServerSpec, envoySpec *spec.EnvoyServerSpec, index int) error {
+	return m.executeRemote(utils.JoinHostPort(envoySpec.Ip, envoySpec.PortSsh), func(op operator.CommandOperator) error {
+		return m.deployEnvoyServer(op, filerSpecs, s3Specs, envoySpec, index)
+	})
+}
 
-		funcs := template.FuncMap{"join": strings.Join}
-		envoyTmpl, err := template.New("envoy.yaml").Funcs(funcs).Parse(envoyYamlTemplate)
-		if err != nil {
-			return fmt.Errorf("parsing template: %v", err)
+// deployEnvoyServer proxies the filers, and the S3 servers of the filers and
+// the s3 gateways.
+func (m *Manager) deployEnvoyServer(op operator.CommandOperator, filerSpecs []*spec.FilerServerSpec, s3Specs []*spec.S3ServerSpec, envoySpec *spec.EnvoyServerSpec, index int) error {
+	var s3EndPoints []envoyEndPoint
+	var webdavEndPoints []*spec.FilerServerSpec
+	for _, filerSpec := range filerSpecs {
+		if filerSpec.PortGrpc == 0 {
+			filerSpec.PortGrpc = filerSpec.Port + 10000
 		}
-		data := map[string]interface{}{
-			"ConfigDir":            m.confDir,
-			"DataDir":              m.dataDir,
-			"HasFilerEndPoint":     len(filerSpecs) > 0 && envoySpec.FilerPort != 0,
-			"HasFilerGrpcEndPoint": len(filerSpecs) > 0 && envoySpec.FilerGrpcPort != 0,
-			"FilerEndPoints":       filerSpecs,
-			"HasS3EndPoint":        len(s3EndPoints) > 0 && envoySpec.S3Port != 0,
-			"S3EndPoints":          s3EndPoints,
-			"HasWebdavEndPoint":    len(webdavEndPoints) > 0 && envoySpec.WebdavPort != 0,
-			"WebdavEndPoints":      s3EndPoints,
-			"Envoy":                envoySpec,
+		if filerSpec.S3 || filerSpec.S3Port != 0 {
+			s3EndPoints = append(s3EndPoints, envoyEndPoint{filerSpec.Ip, defaultPort(filerSpec.S3Port, 8333)})
 		}
-		var buf bytes.Buffer
-		if err := envoyTmpl.Execute(&buf, data); err != nil {
-			return fmt.Errorf("generating template: %v", err)
+		if filerSpec.Webdav || filerSpec.WebdavPort != 0 {
+			webdavEndPoints = append(webdavEndPoints, filerSpec)
 		}
+	}
+	for _, s3Spec := range s3Specs {
+		s3EndPoints = append(s3EndPoints, envoyEndPoint{s3Spec.Ip, s3Spec.ListenPorts()[0]})
+	}
 
-		component := "envoy"
-		componentInstance := fmt.Sprintf("%s%d", component, index)
-		return m.deployEnvoyInstance(op, component, componentInstance, envoySpec, &buf)
+	funcs := template.FuncMap{"join": strings.Join}
+	envoyTmpl, err := template.New("envoy.yaml").Funcs(funcs).Parse(envoyYamlTemplate)
+	if err != nil {
+		return fmt.Errorf("parsing template: %w", err)
+	}
+	data := map[string]interface{}{
+		"ConfigDir":            m.confDir,
+		"DataDir":              m.dataDir,
+		"HasFilerEndPoint":     len(filerSpecs) > 0 && envoySpec.FilerPort != 0,
+		"HasFilerGrpcEndPoint": len(filerSpecs) > 0 && envoySpec.FilerGrpcPort != 0,
+		"FilerEndPoints":       filerSpecs,
+		"HasS3EndPoint":        len(s3EndPoints) > 0 && envoySpec.S3Port != 0,
+		"S3EndPoints":          s3EndPoints,
+		"HasWebdavEndPoint":    len(webdavEndPoints) > 0 && envoySpec.WebdavPort != 0,
+		"WebdavEndPoints":      webdavEndPoints,
+		"Envoy":                envoySpec,
+		"DualStack":            m.dualStack,
+	}
+	var buf bytes.Buffer
+	if err := envoyTmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("generating template: %w", err)
+	}
 
-	})
+	component := "envoy"
+	componentInstance := fmt.Sprintf("%s%d", component, index)
+	return m.deployEnvoyInstance(op, component, componentInstance, envoySpec, &buf)
 }
 
 func (m *Manager) deployEnvoyInstance(op operator.CommandOperator, component string, componentInstance string, envoySpec *spec.EnvoyServerSpec, buf *bytes.Buffer) error {
+	if strings.HasPrefix(envoySpec.Version, "v") {
+		envoySpec.Version = envoySpec.Version[1:]
+	}
+
+	if p, planning := op.(*planOperator); planning {
+		return m.planInstance(p, componentInstance, "envoy", envoySpec.Version,
+			`if [ -x /usr/local/bin/envoy ]; then /usr/local/bin/envoy --version | grep "\S" | cut -d'/' -f6; fi`,
+			plannedFile{fmt.Sprintf("%s/%s.d/%s.yaml", m.confDir, componentInstance, component), buf.String()})
+	}
+
 	info("Deploying " + componentInstance + "...")
 
 	dir := "/tmp/seaweed-up." + randstr.String(6)
@@ -69,11 +95,7 @@ func (m *Manager) deployEnvoyInstance(op operator.CommandOperator, component str
 
 	err := op.Execute("mkdir -p " + dir + "/config")
 	if err != nil {
-		return fmt.Errorf("error received during installation: %s", err)
-	}
-
-	if strings.HasPrefix(envoySpec.Version, "v") {
-		envoySpec.Version = envoySpec.Version[1:]
+		return fmt.Errorf("error received during installation: %w", err)
 	}
 
 	data := map[string]interface{}{
@@ -101,18 +123,18 @@ func (m *Manager) deployEnvoyInstance(op operator.CommandOperator, component str
 
 	err = op.Upload(installScript, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance), "0755")
 	if err != nil {
-		return fmt.Errorf("error received during upload install script: %s", err)
+		return fmt.Errorf("error received during upload install script: %w", err)
 	}
 
 	err = op.Upload(buf, fmt.Sprintf("%s/config/%s.yaml", dir, component), "0644")
 	if err != nil {
-		return fmt.Errorf("error received during upload %s.yaml: %s", component, err)
+		return fmt.Errorf("error received during upload %s.yaml: %w", component, err)
 	}
 
 	info("Installing " + componentInstance + "...")
-	err = op.Execute(fmt.Sprintf("cat %s/install_%s.sh | SUDO_PASS=\"%s\" sh -\n", dir, componentInstance, m.sudoPass))
+	err = op.Execute(m.scriptCommand(op, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance)))
 	if err != nil {
-		return fmt.Errorf("error received during installation: %s", err)
+		return fmt.Errorf("error received during installation: %w", err)
 	}
 
 	info("Done.")
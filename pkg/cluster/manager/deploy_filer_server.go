@@ -5,23 +5,24 @@ import (
 	"fmt"
 	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
 	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
 )
 
 func (m *Manager) DeployFilerServer(masters []string, f *spec.FilerServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", f.Ip, f.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
-
-		component := "filer"
-		componentInstance := fmt.Sprintf("%s%d", component, index)
-		var buf bytes.Buffer
-		f.WriteToBuffer(masters, &buf)
+	return m.executeRemote(utils.JoinHostPort(f.Ip, f.PortSsh), func(op operator.CommandOperator) error {
+		return m.deployFilerServer(op, masters, f, index)
+	})
+}
 
-		return m.deployComponentInstance(op, component, componentInstance, &buf)
+func (m *Manager) deployFilerServer(op operator.CommandOperator, masters []string, f *spec.FilerServerSpec, index int) error {
+	var buf bytes.Buffer
+	f.WriteToBuffer(masters, &buf)
 
-	})
+	return m.deployComponentInstance(op, m.newSystemdUnit("filer", index, f.Systemd, nil), f.Arch, &buf)
 }
 
 func (m *Manager) ResetFilerServer(f *spec.FilerServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", f.Ip, f.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(f.Ip, f.PortSsh), func(op operator.CommandOperator) error {
 		component := "filer"
 		componentInstance := fmt.Sprintf("%s%d", component, index)
 		return m.sudo(op, fmt.Sprintf("rm -Rf %s/%s/*", m.dataDir, componentInstance))
@@ -29,7 +30,7 @@ func (m *Manager) ResetFilerServer(f *spec.FilerServerSpec, index int) error {
 }
 
 func (m *Manager) StartFilerServer(f *spec.FilerServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", f.Ip, f.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(f.Ip, f.PortSsh), func(op operator.CommandOperator) error {
 		component := "filer"
 		componentInstance := fmt.Sprintf("%s%d", component, index)
 		return m.sudo(op, fmt.Sprintf("systemctl start seaweed_%s.service", componentInstance))
@@ -37,7 +38,7 @@ func (m *Manager) StartFilerServer(f *spec.FilerServerSpec, index int) error {
 }
 
 func (m *Manager) StopFilerServer(f *spec.FilerServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", f.Ip, f.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(f.Ip, f.PortSsh), func(op operator.CommandOperator) error {
 		component := "filer"
 		componentInstance := fmt.Sprintf("%s%d", component, index)
 		return m.sudo(op, fmt.Sprintf("systemctl stop seaweed_%s.service", componentInstance))
@@ -0,0 +1,178 @@
+package manager
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"github.com/seaweedfs/seaweed-up/scripts"
+	"github.com/thanhpk/randstr"
+)
+
+//go:embed keepalived.conf.tpl
+var keepalivedConfTemplate string
+
+const keepalivedCheckScript = "/etc/keepalived/check_seaweed.sh"
+
+// haServer is a server sharing the virtual IP, and the port that must answer
+// for it to hold the address.
+type haServer struct {
+	ip        string
+	portSsh   int
+	checkPort int
+}
+
+// haServers lists the servers sharing the virtual IP, the first one has the
+// highest priority.
+func haServers(specification *spec.Specification) ([]*haServer, error) {
+	ha := specification.HighAvailability
+	var servers []*haServer
+	component := specification.HighAvailabilityComponent()
+	switch component {
+	case "filer":
+		for _, filerSpec := range specification.FilerServers {
+			checkPort := defaultPort(filerSpec.Port, 8888)
+			if filerSpec.S3 || filerSpec.S3Port != 0 {
+				checkPort = defaultPort(filerSpec.S3Port, 8333)
+			}
+			servers = append(servers, &haServer{filerSpec.Ip, filerSpec.PortSsh, utils.NvlInt(ha.CheckPort, checkPort)})
+		}
+	case "envoy":
+		for _, envoySpec := range specification.EnvoyServers {
+			checkPort := utils.NvlInt(ha.CheckPort, envoySpec.S3Port, envoySpec.FilerPort)
+			if checkPort == 0 {
+				return nil, fmt.Errorf("envoy server %s has no s3.port or filer.port, set ha.check_port", envoySpec.Ip)
+			}
+			servers = append(servers, &haServer{envoySpec.Ip, envoySpec.PortSsh, checkPort})
+		}
+	default:
+		return nil, fmt.Errorf("ha.component %q must be filer or envoy", component)
+	}
+	if len(servers) < 2 {
+		return nil, fmt.Errorf("a virtual IP needs at least 2 %s servers", component)
+	}
+	return servers, nil
+}
+
+func (m *Manager) DeployKeepalived(specification *spec.Specification, servers []*haServer, index int) error {
+	server := servers[index]
+	return m.executeRemote(utils.JoinHostPort(server.ip, server.portSsh), func(op operator.CommandOperator) error {
+		return m.deployKeepalived(op, specification, servers, index)
+	})
+}
+
+// deployKeepalived installs keepalived, holding the virtual IP while the check
+// port of the server answers.
+func (m *Manager) deployKeepalived(op operator.CommandOperator, specification *spec.Specification, servers []*haServer, index int) error {
+	ha := specification.HighAvailability
+	server := servers[index]
+
+	networkInterface := ha.Interface
+	if networkInterface == "" {
+		out, err := op.Output(fmt.Sprintf("ip -o route get %s", ha.Address()))
+		if err != nil {
+			return fmt.Errorf("find the network interface of %s: %w", ha.Address(), err)
+		}
+		fields := strings.Fields(string(out))
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[i] == "dev" {
+				networkInterface = fields[i+1]
+				break
+			}
+		}
+		if networkInterface == "" {
+			return fmt.Errorf("no network interface routes to %s, set ha.interface", ha.Address())
+		}
+	}
+
+	var peers []string
+	for i, peer := range servers {
+		if i != index {
+			peers = append(peers, peer.ip)
+		}
+	}
+	confTmpl, err := template.New("keepalived.conf").Parse(keepalivedConfTemplate)
+	if err != nil {
+		return fmt.Errorf("parsing template: %w", err)
+	}
+	data := map[string]interface{}{
+		"CheckScript":     keepalivedCheckScript,
+		"Interface":       networkInterface,
+		"VirtualRouterId": utils.NvlInt(ha.VirtualRouterId, 51),
+		"Priority":        150 - index,
+		"AuthPass":        ha.AuthPass,
+		"Ip":              server.ip,
+		"Peers":           peers,
+		"VirtualIp":       ha.VirtualIp,
+	}
+	var conf bytes.Buffer
+	if err := confTmpl.Execute(&conf, data); err != nil {
+		return fmt.Errorf("generating template: %w", err)
+	}
+	check := fmt.Sprintf("#!/bin/sh\n# Generated by seaweed-up\nexec curl -s -o /dev/null --max-time 2 http://%s/\n", utils.JoinHostPort(server.ip, server.checkPort))
+
+	componentInstance := fmt.Sprintf("keepalived%d", index)
+	if p, planning := op.(*planOperator); planning {
+		var changed bool
+		for _, f := range []plannedFile{{"/etc/keepalived/keepalived.conf", conf.String()}, {keepalivedCheckScript, check}} {
+			change, err := p.file(f.path, f.content)
+			if err != nil {
+				return err
+			}
+			changed = changed || change.Status != "unchanged"
+		}
+		out, err := p.Output("command -v keepalived || true")
+		if err != nil {
+			return fmt.Errorf("find keepalived: %w", err)
+		}
+		if strings.TrimSpace(string(out)) == "" {
+			p.command("install the keepalived package")
+			changed = true
+		}
+		if !m.skipStart && (changed || m.ForceRestart) {
+			p.node.Restart = append(p.node.Restart, "keepalived")
+		}
+		return nil
+	}
+
+	info("Deploying " + componentInstance + "...")
+
+	dir := "/tmp/seaweed-up." + randstr.String(6)
+
+	defer op.Execute("rm -rf " + dir)
+
+	if err := op.Execute("mkdir -p " + dir); err != nil {
+		return fmt.Errorf("error received during installation: %w", err)
+	}
+
+	installScript, err := scripts.RenderScript("install_keepalived.sh", map[string]interface{}{
+		"TmpDir":       dir,
+		"SkipStart":    m.skipStart,
+		"ForceRestart": m.ForceRestart,
+	})
+	if err != nil {
+		return err
+	}
+	if err := op.Upload(installScript, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance), "0755"); err != nil {
+		return fmt.Errorf("error received during upload install script: %w", err)
+	}
+	if err := op.Upload(&conf, dir+"/keepalived.conf", "0644"); err != nil {
+		return fmt.Errorf("error received during upload keepalived.conf: %w", err)
+	}
+	if err := op.Upload(strings.NewReader(check), dir+"/check_seaweed.sh", "0755"); err != nil {
+		return fmt.Errorf("error received during upload check_seaweed.sh: %w", err)
+	}
+
+	info("Installing " + componentInstance + "...")
+	if err := op.Execute(m.scriptCommand(op, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance))); err != nil {
+		return fmt.Errorf("error received during installation: %w", err)
+	}
+
+	info("Done.")
+	return nil
+}
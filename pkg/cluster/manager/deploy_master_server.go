@@ -5,23 +5,24 @@ import (
 	"fmt"
 	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
 	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
 )
 
 func (m *Manager) DeployMasterServer(masters []string, masterSpec *spec.MasterServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", masterSpec.Ip, masterSpec.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
-
-		component := "master"
-		componentInstance := fmt.Sprintf("%s%d", component, index)
-		var buf bytes.Buffer
-		masterSpec.WriteToBuffer(masters, &buf)
+	return m.executeRemote(utils.JoinHostPort(masterSpec.Ip, masterSpec.PortSsh), func(op operator.CommandOperator) error {
+		return m.deployMasterServer(op, masters, masterSpec, index)
+	})
+}
 
-		return m.deployComponentInstance(op, component, componentInstance, &buf)
+func (m *Manager) deployMasterServer(op operator.CommandOperator, masters []string, masterSpec *spec.MasterServerSpec, index int) error {
+	var buf bytes.Buffer
+	masterSpec.WriteToBuffer(masters, &buf)
 
-	})
+	return m.deployComponentInstance(op, m.newSystemdUnit("master", index, masterSpec.Systemd, nil), masterSpec.Arch, &buf)
 }
 
 func (m *Manager) ResetMasterServer(masterSpec *spec.MasterServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", masterSpec.Ip, masterSpec.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(masterSpec.Ip, masterSpec.PortSsh), func(op operator.CommandOperator) error {
 
 		component := "master"
 		componentInstance := fmt.Sprintf("%s%d", component, index)
@@ -32,7 +33,7 @@ func (m *Manager) ResetMasterServer(masterSpec *spec.MasterServerSpec, index int
 }
 
 func (m *Manager) StartMasterServer(f *spec.MasterServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", f.Ip, f.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(f.Ip, f.PortSsh), func(op operator.CommandOperator) error {
 		component := "master"
 		componentInstance := fmt.Sprintf("%s%d", component, index)
 		return m.sudo(op, fmt.Sprintf("systemctl start seaweed_%s.service", componentInstance))
@@ -40,7 +41,7 @@ func (m *Manager) StartMasterServer(f *spec.MasterServerSpec, index int) error {
 }
 
 func (m *Manager) StopMasterServer(f *spec.MasterServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", f.Ip, f.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(f.Ip, f.PortSsh), func(op operator.CommandOperator) error {
 		component := "master"
 		componentInstance := fmt.Sprintf("%s%d", component, index)
 		return m.sudo(op, fmt.Sprintf("systemctl stop seaweed_%s.service", componentInstance))
@@ -0,0 +1,30 @@
+package manager
+
+import (
+	"bytes"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+func (m *Manager) DeployMount(filers []string, mount *spec.MountSpec, index int) error {
+	return m.executeRemote(utils.JoinHostPort(mount.Ip, mount.PortSsh), func(op operator.CommandOperator) error {
+		return m.deployMount(op, filers, mount, index)
+	})
+}
+
+func (m *Manager) deployMount(op operator.CommandOperator, filers []string, mount *spec.MountSpec, index int) error {
+	var buf bytes.Buffer
+	mount.WriteToBuffer(filers, &buf)
+
+	return m.deployComponentInstance(op, m.mountUnit(mount, index), mount.Arch, &buf)
+}
+
+// mountUnit returns the systemd unit of the mount, which unmounts the mount
+// point once weed stops, so a crash leaves no stale mount behind.
+func (m *Manager) mountUnit(mount *spec.MountSpec, index int) *systemdUnit {
+	unit := m.newSystemdUnit("mount", index, mount.Systemd, []string{mount.Dir, mount.CacheDir})
+	unit.unmount = mount.Dir
+	return unit
+}
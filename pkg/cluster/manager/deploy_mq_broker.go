@@ -0,0 +1,30 @@
+package manager
+
+import (
+	"bytes"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+func (m *Manager) DeployMQBroker(masters []string, b *spec.MQBrokerSpec, index int) error {
+	return m.executeRemote(utils.JoinHostPort(b.Ip, b.PortSsh), func(op operator.CommandOperator) error {
+		return m.deployMQBroker(op, masters, b, index)
+	})
+}
+
+func (m *Manager) deployMQBroker(op operator.CommandOperator, masters []string, b *spec.MQBrokerSpec, index int) error {
+	var buf bytes.Buffer
+	b.WriteToBuffer(masters, &buf)
+
+	return m.deployComponentInstance(op, m.mqBrokerUnit(b, index), b.Arch, &buf)
+}
+
+// mqBrokerUnit is the unit of the broker, the weed command of which is not
+// named like the component.
+func (m *Manager) mqBrokerUnit(b *spec.MQBrokerSpec, index int) *systemdUnit {
+	unit := m.newSystemdUnit("broker", index, b.Systemd, nil)
+	unit.command = "mq.broker"
+	return unit
+}
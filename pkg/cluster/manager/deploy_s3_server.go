@@ -0,0 +1,45 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+func (m *Manager) DeployS3Server(filers []string, s *spec.S3ServerSpec, index int) error {
+	return m.executeRemote(utils.JoinHostPort(s.Ip, s.PortSsh), func(op operator.CommandOperator) error {
+		return m.deployS3Server(op, filers, s, index)
+	})
+}
+
+func (m *Manager) deployS3Server(op operator.CommandOperator, filers []string, s *spec.S3ServerSpec, index int) error {
+	var buf bytes.Buffer
+	s.WriteToBuffer(s.FilerOf(filers, index), &buf)
+
+	componentInstance := fmt.Sprintf("s3%d", index)
+	var files []configFile
+	for _, f := range []struct {
+		flag, local, name string
+		secret            bool
+	}{
+		{"config", s.Config, "s3.json", true},
+		{"cert.file", s.CertFile, "s3.crt", false},
+		{"key.file", s.KeyFile, "s3.key", true},
+	} {
+		if f.local == "" {
+			continue
+		}
+		content, err := os.ReadFile(f.local)
+		if err != nil {
+			return fmt.Errorf("read the %s of %s: %w", f.flag, componentInstance, err)
+		}
+		files = append(files, configFile{name: f.name, content: content, secret: f.secret})
+		fmt.Fprintf(&buf, "%s=%s\n", f.flag, m.configPath(componentInstance, f.name))
+	}
+
+	return m.deployComponentInstance(op, m.newSystemdUnit("s3", index, s.Systemd, nil), s.Arch, &buf, files...)
+}
@@ -0,0 +1,200 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/scripts"
+	"github.com/thanhpk/randstr"
+)
+
+const (
+	tuningSysctlFile = "/etc/sysctl.d/90-seaweed-up.conf"
+	tuningThpUnit    = "seaweed-thp"
+	tuningThpDir     = "/sys/kernel/mm/transparent_hugepage"
+)
+
+func (m *Manager) DeployTuning(tuning *spec.TuningSpec, h *clusterHost, index int) error {
+	return m.executeOnHost(h, func(op operator.CommandOperator) error {
+		return m.deployTuning(op, tuning, index)
+	})
+}
+
+// tuningFiles renders the sysctl configuration and the unit setting
+// transparent hugepages at boot, empty if there is nothing to set.
+func tuningFiles(tuning *spec.TuningSpec) (sysctl, thpUnit string) {
+	if len(tuning.Sysctl) > 0 {
+		var b strings.Builder
+		b.WriteString("# Generated by seaweed-up\n")
+		for _, key := range tuning.SysctlKeys() {
+			fmt.Fprintf(&b, "%s = %s\n", key, tuning.Sysctl[key])
+		}
+		sysctl = b.String()
+	}
+	if thp := tuning.TransparentHugepages; thp != "" {
+		thpUnit = fmt.Sprintf(`# Generated by seaweed-up
+[Unit]
+Description=Transparent hugepages setting of seaweed-up
+DefaultDependencies=no
+After=sysinit.target local-fs.target
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/bin/sh -c 'echo %s > %s/enabled; echo %s > %s/defrag'
+
+[Install]
+WantedBy=basic.target
+`, thp, tuningThpDir, thp, tuningThpDir)
+	}
+	return
+}
+
+// deployTuning applies the resolved tuning settings, persisting them across
+// reboots.
+func (m *Manager) deployTuning(op operator.CommandOperator, tuning *spec.TuningSpec, index int) error {
+	sysctl, thpUnit := tuningFiles(tuning)
+	thpUnitFile := fmt.Sprintf("/etc/systemd/system/%s.service", tuningThpUnit)
+
+	componentInstance := fmt.Sprintf("tuning%d", index)
+	if p, planning := op.(*planOperator); planning {
+		if sysctl != "" {
+			change, err := p.file(tuningSysctlFile, sysctl)
+			if err != nil {
+				return err
+			}
+			if change.Status != "unchanged" {
+				p.command("sysctl -p " + tuningSysctlFile)
+			}
+		}
+		if thpUnit != "" {
+			change, err := p.file(thpUnitFile, thpUnit)
+			if err != nil {
+				return err
+			}
+			if change.Status != "unchanged" || m.ForceRestart {
+				p.node.Restart = append(p.node.Restart, tuningThpUnit)
+			}
+		}
+		return nil
+	}
+
+	info("Deploying " + componentInstance + "...")
+
+	dir := "/tmp/seaweed-up." + randstr.String(6)
+
+	defer op.Execute("rm -rf " + dir)
+
+	if err := op.Execute("mkdir -p " + dir); err != nil {
+		return fmt.Errorf("error received during installation: %w", err)
+	}
+
+	installScript, err := scripts.RenderScript("apply_tuning.sh", map[string]interface{}{
+		"TmpDir":     dir,
+		"SysctlFile": tuningSysctlFile,
+		"ThpUnit":    tuningThpUnit,
+	})
+	if err != nil {
+		return err
+	}
+	if err := op.Upload(installScript, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance), "0755"); err != nil {
+		return fmt.Errorf("error received during upload install script: %w", err)
+	}
+	if sysctl != "" {
+		if err := op.Upload(strings.NewReader(sysctl), dir+"/sysctl.conf", "0644"); err != nil {
+			return fmt.Errorf("error received during upload sysctl.conf: %w", err)
+		}
+	}
+	if thpUnit != "" {
+		if err := op.Upload(strings.NewReader(thpUnit), dir+"/thp.service", "0644"); err != nil {
+			return fmt.Errorf("error received during upload thp.service: %w", err)
+		}
+	}
+
+	info("Installing " + componentInstance + "...")
+	if err := op.Execute(m.scriptCommand(op, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance))); err != nil {
+		return fmt.Errorf("error received during installation: %w", err)
+	}
+
+	info("Done.")
+	return nil
+}
+
+// TuningDrift is a tuning setting of a host that differs from the configuration.
+type TuningDrift struct {
+	Host     string `json:"host" yaml:"host"`
+	Setting  string `json:"setting" yaml:"setting"`
+	Expected string `json:"expected" yaml:"expected"`
+	Actual   string `json:"actual" yaml:"actual"`
+}
+
+// TuningDiff prints the tuning settings of the hosts that differ from the
+// configuration, like a sysctl changed by hand or lost with a reboot.
+func (m *Manager) TuningDiff(specification *spec.Specification) error {
+	m.prepareSpecification(specification)
+	if specification.GlobalOptions.Tuning == nil {
+		return fmt.Errorf("the configuration has no global.tuning section")
+	}
+	tuning, err := specification.GlobalOptions.Tuning.Resolve()
+	if err != nil {
+		return err
+	}
+
+	drifts := []*TuningDrift{}
+	for _, h := range m.clusterHosts(specification) {
+		err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+			for _, key := range tuning.SysctlKeys() {
+				out, err := op.Output("sysctl -n " + key)
+				actual := strings.Join(strings.Fields(string(out)), " ")
+				if err != nil {
+					actual = "unknown"
+				}
+				if expected := strings.Join(strings.Fields(tuning.Sysctl[key]), " "); actual != expected {
+					drifts = append(drifts, &TuningDrift{Host: h.ip, Setting: key, Expected: expected, Actual: actual})
+				}
+			}
+			if expected := tuning.TransparentHugepages; expected != "" {
+				for _, file := range []string{"enabled", "defrag"} {
+					out, _ := op.Output(fmt.Sprintf("cat %s/%s", tuningThpDir, file))
+					actual := selectedOption(string(out))
+					if actual != expected {
+						drifts = append(drifts, &TuningDrift{Host: h.ip, Setting: "transparent_hugepage/" + file, Expected: expected, Actual: actual})
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("read tuning of %s: %w", h.address(), err)
+		}
+	}
+
+	return output.Print(drifts, func(w io.Writer) {
+		if len(drifts) == 0 {
+			fmt.Fprintln(w, "the tuning of all hosts matches the configuration")
+			return
+		}
+		t := output.NewTable(w)
+		fmt.Fprintln(t, "HOST\tSETTING\tEXPECTED\tACTUAL")
+		for _, d := range drifts {
+			fmt.Fprintf(t, "%s\t%s\t%s\t%s\n", d.Host, d.Setting, d.Expected, d.Actual)
+		}
+		t.Flush()
+		fmt.Fprintf(w, "\n%d settings differ, apply them with: seaweed-up deploy -c tuning\n", len(drifts))
+	})
+}
+
+// selectedOption returns the option in brackets of a sysfs setting, like
+// never in "always madvise [never]".
+func selectedOption(s string) string {
+	for _, option := range strings.Fields(s) {
+		if strings.HasPrefix(option, "[") && strings.HasSuffix(option, "]") {
+			return strings.Trim(option, "[]")
+		}
+	}
+	return "unknown"
+}
@@ -5,32 +5,35 @@ import (
 	"fmt"
 	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
 	"github.com/seaweedfs/seaweed-up/pkg/disks"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
 	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
 	"github.com/seaweedfs/seaweed-up/scripts"
+	"sort"
 	"strings"
 )
 
 func (m *Manager) DeployVolumeServer(masters []string, volumeServerSpec *spec.VolumeServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", volumeServerSpec.Ip, volumeServerSpec.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(volumeServerSpec.Ip, volumeServerSpec.PortSsh), func(op operator.CommandOperator) error {
+		return m.deployVolumeServer(op, masters, volumeServerSpec, index)
+	})
+}
 
-		component := "volume"
-		componentInstance := fmt.Sprintf("%s%d", component, index)
-		var buf bytes.Buffer
-		volumeServerSpec.WriteToBuffer(masters, &buf)
+func (m *Manager) deployVolumeServer(op operator.CommandOperator, masters []string, volumeServerSpec *spec.VolumeServerSpec, index int) error {
+	var buf bytes.Buffer
+	volumeServerSpec.WriteToBuffer(masters, &buf)
 
-		if m.PrepareVolumeDisks {
-			if err := m.prepareUnmountedDisks(op); err != nil {
-				return fmt.Errorf("prepare disks: %v", err)
-			}
+	if m.PrepareVolumeDisks && (volumeServerSpec.Disks == nil || !volumeServerSpec.Disks.Disabled) {
+		if err := m.prepareUnmountedDisks(op, volumeServerSpec.Disks); err != nil {
+			return fmt.Errorf("prepare disks: %w", err)
 		}
+	}
 
-		return m.deployComponentInstance(op, component, componentInstance, &buf)
-
-	})
+	return m.deployComponentInstance(op, m.newSystemdUnit("volume", index, volumeServerSpec.Systemd, volumeServerSpec.Dirs()), volumeServerSpec.Arch, &buf)
 }
 
 func (m *Manager) ResetVolumeServer(volumeServerSpec *spec.VolumeServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", volumeServerSpec.Ip, volumeServerSpec.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(volumeServerSpec.Ip, volumeServerSpec.PortSsh), func(op operator.CommandOperator) error {
 
 		component := "volume"
 		componentInstance := fmt.Sprintf("%s%d", component, index)
@@ -40,7 +43,7 @@ func (m *Manager) ResetVolumeServer(volumeServerSpec *spec.VolumeServerSpec, ind
 }
 
 func (m *Manager) StartVolumeServer(volumeServerSpec *spec.VolumeServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", volumeServerSpec.Ip, volumeServerSpec.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(volumeServerSpec.Ip, volumeServerSpec.PortSsh), func(op operator.CommandOperator) error {
 
 		component := "volume"
 		componentInstance := fmt.Sprintf("%s%d", component, index)
@@ -50,7 +53,7 @@ func (m *Manager) StartVolumeServer(volumeServerSpec *spec.VolumeServerSpec, ind
 }
 
 func (m *Manager) StopVolumeServer(volumeServerSpec *spec.VolumeServerSpec, index int) error {
-	return operator.ExecuteRemote(fmt.Sprintf("%s:%d", volumeServerSpec.Ip, volumeServerSpec.PortSsh), m.User, m.IdentityFile, m.sudoPass, func(op operator.CommandOperator) error {
+	return m.executeRemote(utils.JoinHostPort(volumeServerSpec.Ip, volumeServerSpec.PortSsh), func(op operator.CommandOperator) error {
 
 		component := "volume"
 		componentInstance := fmt.Sprintf("%s%d", component, index)
@@ -59,76 +62,81 @@ func (m *Manager) StopVolumeServer(volumeServerSpec *spec.VolumeServerSpec, inde
 	})
 }
 
-func (m *Manager) prepareUnmountedDisks(op operator.CommandOperator) error {
-	println("prepareUnmountedDisks...")
-	devices, mountpoints, err := disks.ListBlockDevices(op, []string{"/dev/sd", "/dev/nvme"})
+func (m *Manager) prepareUnmountedDisks(op operator.CommandOperator, provision *spec.DiskProvisionSpec) error {
+	logging.Debug("prepare unmounted disks")
+	devices, err := disks.Discover(op, m.DiskDiscovery, []string{"/dev/sd", "/dev/nvme"})
 	if err != nil {
-		return fmt.Errorf("list device: %v", err)
+		return fmt.Errorf("list device: %w", err)
 	}
-	fmt.Printf("mountpoints: %+v\n", mountpoints)
-
-	disks := make(map[string]*disks.BlockDevice)
-
-	// find all disks
-	for _, dev := range devices {
-		if dev.Type == "disk" {
-			disks[dev.Path] = dev
-		}
+	mountpoints, err := disks.MountPoints(op)
+	if err != nil {
+		return fmt.Errorf("list mount points: %w", err)
 	}
+	logging.Debug("mount points", "mountpoints", fmt.Sprint(mountpoints))
 
-	fmt.Printf("disks0: %+v\n", disks)
+	disksByPath := unusedDisks(devices)
+	logging.Debug("unused disks", "disks", diskPaths(disksByPath))
 
-	// remove disks already has partitions
-	for _, dev := range devices {
-		if dev.Type == "part" {
-			for parentPath, _ := range disks {
-				if strings.HasPrefix(dev.Path, parentPath) {
-					// the disk is already partitioned
-					delete(disks, parentPath)
-				}
-			}
-		}
+	// remove failing disks
+	var candidates []*disks.BlockDevice
+	for _, dev := range disksByPath {
+		candidates = append(candidates, dev)
 	}
-	fmt.Printf("disks1: %+v\n", disks)
-
-	// remove already has mount point
-	for k, dev := range disks {
-		if dev.MountPoint != "" {
-			delete(disks, k)
+	if err := disks.EnrichWithSmart(sudoOperator{op, m}, candidates); err != nil {
+		return fmt.Errorf("read SMART data: %w", err)
+	}
+	for k, dev := range disksByPath {
+		if dev.Health == disks.HealthFailed {
+			info(fmt.Sprintf("skip %s %s %s: SMART health check failed", dev.Path, dev.Model, dev.SerialId))
+			delete(disksByPath, k)
 		}
 	}
-	fmt.Printf("disks2: %+v\n", disks)
+	logging.Debug("disks to prepare", "disks", diskPaths(disksByPath))
 
 	// format disk if no fstype
-	for _, dev := range disks {
+	for _, dev := range disksByPath {
 		if dev.FilesystemType == "" {
-			info("mkfs " + dev.Path)
-			if err := m.sudo(op, fmt.Sprintf("mkfs.ext4 %s", dev.Path)); err != nil {
-				return fmt.Errorf("create file system on %s: %v", dev.Path, err)
+			fsType, mkfsOptions, _ := provision.ForDevice(dev.Path, dev.ById, dev.SerialId)
+			if out, _ := m.sudoOutput(op, "command -v mkfs."+fsType+" || true"); strings.TrimSpace(string(out)) == "" {
+				return fmt.Errorf("mkfs.%s not found on the volume server, install the tools of the file system first", fsType)
 			}
+			info(fmt.Sprintf("mkfs.%s %s", fsType, dev.Path))
+			if err := m.sudo(op, strings.Join([]string{"mkfs." + fsType, mkfsOptions, dev.Path}, " ")); err != nil {
+				return fmt.Errorf("create file system on %s: %w", dev.Path, err)
+			}
+			dev.FilesystemType = fsType
 		}
 	}
 
 	// mount them
-	for _, dev := range disks {
+	for _, dev := range disksByPath {
 		if dev.MountPoint == "" {
-			var targetMountPoint = ""
-			for i := 1; i < 100; i++ {
-				t := fmt.Sprintf("/data%d", i)
-				if _, found := mountpoints[t]; found {
-					continue
-				}
-				targetMountPoint = t
-				mountpoints[t] = struct{}{}
-				break
-			}
+			targetMountPoint := freeMountPoint(mountpoints)
 			if targetMountPoint == "" {
 				return fmt.Errorf("no good mount point")
 			}
 
+			_, _, mountOptions := provision.ForDevice(dev.Path, dev.ById, dev.SerialId)
+			fstabDevice := utils.Nvl(dev.ById, dev.Path)
+			if provision.MountByUUID {
+				out, err := m.sudoOutput(op, "blkid -s UUID -o value "+dev.Path)
+				uuid := strings.TrimSpace(string(out))
+				if _, planning := op.(*planOperator); planning && uuid == "" {
+					// the file system is only created by the deployment
+					uuid, err = "<uuid of "+dev.Path+">", nil
+				}
+				if err != nil || uuid == "" {
+					return fmt.Errorf("read file system UUID of %s: %w", dev.Path, err)
+				}
+				fstabDevice = "UUID=" + uuid
+			}
+
 			data := map[string]interface{}{
-				"DevicePath": dev.Path,
-				"MountPoint": targetMountPoint,
+				"DevicePath":   dev.Path,
+				"FstabDevice":  fstabDevice,
+				"FsType":       dev.FilesystemType,
+				"MountOptions": mountOptions,
+				"MountPoint":   targetMountPoint,
 			}
 			prepareScript, err := scripts.RenderScript("prepare_disk.sh", data)
 			if err != nil {
@@ -137,13 +145,13 @@ func (m *Manager) prepareUnmountedDisks(op operator.CommandOperator) error {
 			info("Installing mount_" + dev.DeviceName + ".sh")
 			err = op.Upload(prepareScript, fmt.Sprintf("/tmp/mount_%s.sh", dev.DeviceName), "0755")
 			if err != nil {
-				return fmt.Errorf("error received during upload mount script: %s", err)
+				return fmt.Errorf("error received during upload mount script: %w", err)
 			}
 
 			info("mount " + dev.DeviceName + "...")
-			err = op.Execute(fmt.Sprintf("cat /tmp/mount_%s.sh | SUDO_PASS=\"%s\" sh -\n", dev.DeviceName, m.sudoPass))
+			err = op.Execute(m.scriptCommand(op, fmt.Sprintf("/tmp/mount_%s.sh", dev.DeviceName)))
 			if err != nil {
-				return fmt.Errorf("error received during mount: %s", err)
+				return fmt.Errorf("error received during mount: %w", err)
 			}
 
 		}
@@ -151,3 +159,47 @@ func (m *Manager) prepareUnmountedDisks(op operator.CommandOperator) error {
 
 	return nil
 }
+
+// unusedDisks returns the disks without partitions, mount point, write
+// protection or removable media, by path.
+func unusedDisks(devices []*disks.BlockDevice) map[string]*disks.BlockDevice {
+	disksByPath := make(map[string]*disks.BlockDevice)
+	for _, dev := range devices {
+		if dev.Type == "disk" && dev.MountPoint == "" && !dev.ReadOnly && !dev.Removable {
+			disksByPath[dev.Path] = dev
+		}
+	}
+	for _, dev := range devices {
+		if dev.Type == "part" {
+			for parentPath := range disksByPath {
+				if strings.HasPrefix(dev.Path, parentPath) {
+					// the disk is already partitioned
+					delete(disksByPath, parentPath)
+				}
+			}
+		}
+	}
+	return disksByPath
+}
+
+// freeMountPoint takes the first of /data1 to /data99 not in mountpoints, or
+// returns "" if all are used.
+func freeMountPoint(mountpoints map[string]struct{}) string {
+	for i := 1; i < 100; i++ {
+		t := fmt.Sprintf("/data%d", i)
+		if _, found := mountpoints[t]; !found {
+			mountpoints[t] = struct{}{}
+			return t
+		}
+	}
+	return ""
+}
+
+func diskPaths(disksByPath map[string]*disks.BlockDevice) string {
+	var paths []string
+	for p := range disksByPath {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
@@ -0,0 +1,30 @@
+package manager
+
+import (
+	"bytes"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// filerAddresses returns the host:port of the filer servers.
+func filerAddresses(specification *spec.Specification) (filers []string) {
+	for _, filerSpec := range specification.FilerServers {
+		filers = append(filers, utils.JoinHostPort(filerSpec.Ip, defaultPort(filerSpec.Port, 8888)))
+	}
+	return
+}
+
+func (m *Manager) DeployWebDAVServer(filers []string, w *spec.WebDAVServerSpec, index int) error {
+	return m.executeRemote(utils.JoinHostPort(w.Ip, w.PortSsh), func(op operator.CommandOperator) error {
+		return m.deployWebDAVServer(op, filers, w, index)
+	})
+}
+
+func (m *Manager) deployWebDAVServer(op operator.CommandOperator, filers []string, w *spec.WebDAVServerSpec, index int) error {
+	var buf bytes.Buffer
+	w.WriteToBuffer(filers, &buf)
+
+	return m.deployComponentInstance(op, m.newSystemdUnit("webdav", index, w.Systemd, []string{w.CacheDir}), w.Arch, &buf)
+}
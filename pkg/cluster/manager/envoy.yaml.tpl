@@ -8,7 +8,7 @@ static_resources:
   {{- if .HasFilerEndPoint }}
   - name: listener_filer
     address:
-      socket_address: { address: 0.0.0.0, port_value: {{.Envoy.FilerPort}} }
+      socket_address: { address: {{if .DualStack}}"::", ipv4_compat: true{{else}}0.0.0.0{{end}}, port_value: {{.Envoy.FilerPort}} }
     filter_chains:
     - filters:
       - name: envoy.filters.network.http_connection_manager
@@ -32,7 +32,7 @@ static_resources:
   {{- if .HasFilerGrpcEndPoint }}
   - name: listener_filer_grpc
     address:
-      socket_address: { address: 0.0.0.0, port_value: {{.Envoy.FilerGrpcPort}} }
+      socket_address: { address: {{if .DualStack}}"::", ipv4_compat: true{{else}}0.0.0.0{{end}}, port_value: {{.Envoy.FilerGrpcPort}} }
     filter_chains:
     - filters:
       - name: envoy.filters.network.http_connection_manager
@@ -62,7 +62,7 @@ static_resources:
   {{- if .HasS3EndPoint }}
   - name: listener_s3
     address:
-      socket_address: { address: 0.0.0.0, port_value: {{.Envoy.S3Port}} }
+      socket_address: { address: {{if .DualStack}}"::", ipv4_compat: true{{else}}0.0.0.0{{end}}, port_value: {{.Envoy.S3Port}} }
     filter_chains:
     - filters:
       - name: envoy.filters.network.http_connection_manager
@@ -86,7 +86,7 @@ static_resources:
   {{- if .HasWebdavEndPoint }}
   - name: listener_webdav
     address:
-      socket_address: { address: 0.0.0.0, port_value: {{.Envoy.WebdavPort}} }
+      socket_address: { address: {{if .DualStack}}"::", ipv4_compat: true{{else}}0.0.0.0{{end}}, port_value: {{.Envoy.WebdavPort}} }
     filter_chains:
     - filters:
       - name: envoy.filters.network.http_connection_manager
@@ -152,7 +152,7 @@ static_resources:
             address:
               socket_address:
                 address: {{.Ip}}
-                port_value: {{.S3Port}}
+                port_value: {{.Port}}
         {{- end}}
   {{- end }}
   {{- if .HasWebdavEndPoint }}
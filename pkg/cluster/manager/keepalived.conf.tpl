@@ -0,0 +1,39 @@
+# Generated by seaweed-up, changes are overwritten by deploy.
+global_defs {
+  enable_script_security
+  script_user root
+}
+
+vrrp_script check_seaweed {
+  script "{{.CheckScript}}"
+  interval 2
+  timeout 3
+  fall 2
+  rise 2
+}
+
+vrrp_instance seaweed {
+  state BACKUP
+  interface {{.Interface}}
+  virtual_router_id {{.VirtualRouterId}}
+  priority {{.Priority}}
+  advert_int 1
+{{- if .AuthPass}}
+  authentication {
+    auth_type PASS
+    auth_pass {{.AuthPass}}
+  }
+{{- end}}
+  unicast_src_ip {{.Ip}}
+  unicast_peer {
+{{- range .Peers}}
+    {{.}}
+{{- end}}
+  }
+  virtual_ipaddress {
+    {{.VirtualIp}}
+  }
+  track_script {
+    check_seaweed
+  }
+}
@@ -2,7 +2,18 @@ package manager
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
 	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/progress"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
 )
 
 type Manager struct {
@@ -15,15 +26,43 @@ type Manager struct {
 	SshPort            int
 	PrepareVolumeDisks bool
 	ForceRestart       bool
+	SkipPreflight      bool
+	SkipSnapshot       bool     // deploy without the snapshots of the cluster before and after
+	DiskDiscovery      string   // disk discovery backend, empty to detect
+	RepoUrl            string   // download url template of the weed archives, see spec.DefaultRepositoryURL
+	RepoHeaders        []string // extra http headers for the downloads, as "Name: value"
+	StateDir           string   // local directory for the state of operations, like deployment checkpoints
+	Resume             bool     // continue the interrupted deployment of the cluster
+	DryRun             bool     // print the plan of the deployment without changing the hosts
+
+	SkipCompatibilityCheck bool   // upgrade even if the compatibility rules refuse it
+	CompatibilityFile      string // more compatibility rules, a path or URL
+
+	MaxClockSkew time.Duration // how far apart the clocks of the hosts may be
 
 	skipConfig bool
 	skipEnable bool
 	skipStart  bool
 	sudoPass   string
+	elevation  string // sudo, doas or none, of the hosts not in the specification
+	sshHosts   map[string]*spec.SshSpec
+	elevations map[string]*hostElevation // by host address
+	passwords  map[string]string         // sudo passwords by their source, see prepare
 	confDir    string
 	dataDir    string
+	repoUrl    string
+	curlArgs   string
+	listeners  []progress.Listener
+	retrySpec  *spec.RetrySpec
+	dualStack  bool
+	security   *spec.SecuritySpec
+	clientDirs map[string]string // config dir of a weed instance by host address, see weedClient
+	failures   hostFailures
 }
 
+// DefaultStateDir is the StateDir of new managers.
+var DefaultStateDir = path.Join(utils.UserHome(), ".seaweed-up")
+
 func NewManager() *Manager {
 	return &Manager{
 		skipConfig: false,
@@ -31,18 +70,124 @@ func NewManager() *Manager {
 		skipStart:  false,
 		Version:    "",
 		sudoPass:   "",
+		StateDir:   DefaultStateDir,
+
+		MaxClockSkew: time.Second,
 	}
 }
 
 func info(message string) {
-	fmt.Println("[INFO] " + message)
+	logging.Info(message)
 }
 
 func (m *Manager) sudo(op operator.CommandOperator, cmd string) error {
 	info("[execute] " + cmd)
-	if m.sudoPass == "" {
-		return op.Execute(cmd)
+	return op.Execute(m.sudoCommand(op, cmd))
+}
+
+func (m *Manager) sudoOutput(op operator.CommandOperator, cmd string) ([]byte, error) {
+	return op.Output(m.sudoCommand(op, cmd))
+}
+
+// hostElevation is how the privileged commands run on a host.
+type hostElevation struct {
+	method   string // sudo, doas, or none when logged in as root or without elevation
+	password string // sudo password, empty for passwordless sudo
+	spec     *spec.ElevationSpec
+}
+
+// elevationOf returns the elevation of the host the operator runs on, or the
+// global one for hosts not in the specification.
+func (m *Manager) elevationOf(op operator.CommandOperator) *hostElevation {
+	if elevation, found := m.elevations[operatorAddress(op)]; found {
+		return elevation
+	}
+	method := utils.Nvl(m.elevation, "sudo")
+	if m.User == "root" {
+		method = "none"
+	}
+	return &hostElevation{method: method, password: m.sudoPass}
+}
+
+// sudoCommand wraps the shell command to run it as root with the elevation
+// method of the host: as is when logged in as root or without elevation, with
+// doas or passwordless sudo without a sudo password, or else with sudo reading
+// the password, quoted for the shell, without echoing the prompt.
+func (m *Manager) sudoCommand(op operator.CommandOperator, cmd string) string {
+	elevation := m.elevationOf(op)
+	switch {
+	case elevation.method == "none":
+		return cmd
+	case elevation.method == "doas":
+		return "doas -n sh -c " + shellQuote(cmd)
+	case elevation.password == "":
+		return "sudo -n sh -c " + shellQuote(cmd)
+	}
+	return fmt.Sprintf("printf '%%s\\n' %s | sudo -S -p '' sh -c %s", shellQuote(elevation.password), shellQuote(cmd))
+}
+
+// scriptCommand runs the uploaded script, which elevates its privileged
+// commands itself with the method and sudo password of the host given in its
+// environment.
+func (m *Manager) scriptCommand(op operator.CommandOperator, script string) string {
+	elevation := m.elevationOf(op)
+	return fmt.Sprintf("cat %s | SUDO_PASS=%s ELEVATION=%s sh -\n", script, shellQuote(elevation.password), elevation.method)
+}
+
+// sudoPassword reads the sudo password with the password command of the
+// elevation, or from its environment variable, or else asks for it.
+func sudoPassword(elevation *spec.ElevationSpec) (string, error) {
+	if elevation == nil {
+		elevation = &spec.ElevationSpec{}
+	}
+	if elevation.PasswordCommand != "" {
+		out, err := exec.Command("sh", "-c", elevation.PasswordCommand).Output()
+		if err != nil {
+			return "", exitcode.WithCode(exitcode.Invalid, fmt.Errorf("elevation.password_command: %w", err))
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+	}
+	env := utils.Nvl(elevation.PasswordEnv, "SSH_TARGET_SUDO_PASS")
+	if password, found := os.LookupEnv(env); found {
+		return password, nil
 	}
-	defer fmt.Println()
-	return op.Execute(fmt.Sprintf("echo '%s' | sudo -S %s", m.sudoPass, cmd))
+	password, err := utils.PromptForPassword("Input sudo password (empty for passwordless sudo): ")
+	if err != nil {
+		return "", exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%w, set %s or login as root", err, env))
+	}
+	return password, nil
+}
+
+// sudoOperator runs the output commands of the wrapped operator with sudo.
+type sudoOperator struct {
+	operator.CommandOperator
+	m *Manager
+}
+
+// hostOperator is the operator of the host at address, see executeRemote.
+type hostOperator struct {
+	operator.CommandOperator
+	address string
+}
+
+// operatorAddress returns the address of the host the operator runs on, or
+// "" if it is not known.
+func operatorAddress(op operator.CommandOperator) string {
+	switch o := op.(type) {
+	case hostOperator:
+		return o.address
+	case sudoOperator:
+		return operatorAddress(o.CommandOperator)
+	case *planOperator:
+		return operatorAddress(o.CommandOperator)
+	}
+	return ""
+}
+
+func (s sudoOperator) Output(cmd string) ([]byte, error) {
+	return s.m.sudoOutput(s.CommandOperator, cmd)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
 }
@@ -0,0 +1,77 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+// Balance fixes under replicated volumes and then balances the volumes across
+// the volume servers. With dryRun only the plans are printed. It refuses to run
+// while a volume server of the specification is not registered on the master,
+// since moving volumes around a missing server makes things worse.
+func (m *Manager) Balance(specification *spec.Specification, collection string, dryRun bool) error {
+	if dryRun {
+		m.prepareSpecification(specification)
+	} else {
+		if err := m.prepare(specification); err != nil {
+			return err
+		}
+		unlock, err := m.lock(specification, "balance")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	masters := masterAddresses(specification)
+	var drained []string
+	for ip := range m.hostsInMaintenance(specification) {
+		if h := m.findHost(specification, ip); h != nil && h.hasComponent("volume") {
+			drained = append(drained, ip)
+		}
+	}
+	if len(drained) > 0 {
+		sort.Strings(drained)
+		return fmt.Errorf("volume servers on %s are in maintenance, balancing would move volumes back to them; disable maintenance first", strings.Join(drained, ", "))
+	}
+
+	return m.onMasterHost(specification, func(op operator.CommandOperator, address string) error {
+		info("[1/3] Checking volume servers")
+		t, err := masterTopology(op, masters)
+		if err != nil {
+			return err
+		}
+		if missing := unregisteredVolumeServers(specification, t); len(missing) > 0 {
+			return fmt.Errorf("volume servers not registered on the master: %s, bring them back before balancing", strings.Join(missing, ", "))
+		}
+		info(fmt.Sprintf("all %d volume servers are up", len(specification.VolumeServers)))
+
+		fix := "volume.fix.replication"
+		balance := "volume.balance"
+		if collection != "" {
+			fix += " -collectionPattern " + collection
+			balance += " -collection " + collection
+		}
+		if dryRun {
+			fix += " -n"
+		} else {
+			balance += " -force"
+		}
+
+		info("[2/3] Fixing replication")
+		if err := m.weedShell(op, weedMasterAddresses(specification), []string{fix}); err != nil {
+			return fmt.Errorf("fix replication: %w", err)
+		}
+		info("[3/3] Balancing volumes")
+		if err := m.weedShell(op, weedMasterAddresses(specification), []string{balance}); err != nil {
+			return fmt.Errorf("balance: %w", err)
+		}
+		if dryRun {
+			info("dry run, nothing was changed; run again without --dry-run to apply the plan")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// bundleLogLimit is how much of the end of the --log-file is added to a support bundle.
+const bundleLogLimit = 4 << 20
+
+// bundle is a support bundle being written, with a log of how it was collected.
+type bundle struct {
+	tw  *tar.Writer
+	log bytes.Buffer
+}
+
+// runLog returns the end of the log file, or else the captured messages of the run.
+func runLog(captured []byte) []byte {
+	logFile := logging.File()
+	if logFile == "" {
+		return captured
+	}
+	f, err := os.Open(logFile)
+	if err != nil {
+		return []byte(fmt.Sprintf("can not read the log file %s: %v\n", logFile, err))
+	}
+	defer f.Close()
+	if stat, err := f.Stat(); err == nil && stat.Size() > bundleLogLimit {
+		f.Seek(stat.Size()-bundleLogLimit, io.SeekStart)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return []byte(fmt.Sprintf("can not read the log file %s: %v\n", logFile, err))
+	}
+	return data
+}
+
+// add adds the file to the bundle, with its secrets redacted.
+func (b *bundle) add(name string, data []byte) error {
+	data = redact.Bytes(data)
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := b.tw.Write(data)
+	return err
+}
+
+// collect runs the command on the host and adds its output, or the error, to the bundle.
+func (b *bundle) collect(op operator.CommandOperator, name, command string) error {
+	started := time.Now()
+	out, err := op.Output(command)
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+		out = append(out, []byte(fmt.Sprintf("\nerror: %v\n", err))...)
+	}
+	fmt.Fprintf(&b.log, "%s %s (%s): %s: %s\n", started.Format(time.RFC3339), name, time.Since(started).Round(time.Millisecond), command, status)
+	return b.add(name, out)
+}
+
+// SupportBundle writes a tar.gz archive for issue reports with the sanitized
+// specification, and per host the component versions, systemd units, recent
+// logs, status and metrics snapshots. about describes the seaweed-up build.
+// seaweed-up.log has the collection log, followed by the --log-file or else
+// the debug messages of the run.
+func (m *Manager) SupportBundle(specification *spec.Specification, specData []byte, about string, output string) error {
+	var captured bytes.Buffer
+	stopCapture := logging.Capture(&captured, logging.LevelDebug)
+	defer stopCapture()
+
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	b := &bundle{tw: tar.NewWriter(gw)}
+
+	sanitized, err := redact.YAML(specData)
+	if err != nil {
+		return fmt.Errorf("sanitize configuration: %w", err)
+	}
+	if err := b.add("cluster.yaml", sanitized); err != nil {
+		return err
+	}
+	if err := b.add("seaweed-up.txt", []byte(about)); err != nil {
+		return err
+	}
+
+	for _, h := range m.clusterHosts(specification) {
+		info("collecting from " + h.ip)
+		dir := h.ip
+		err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+			sop := sudoOperator{op, m}
+			for name, command := range map[string]string{
+				"system.txt":  "uname -a; cat /etc/os-release; uptime; free -m; df -h",
+				"version.txt": "/usr/local/bin/weed version",
+				"systemd.txt": "systemctl status --no-pager 'seaweed_*'",
+			} {
+				if err := b.collect(sop, path.Join(dir, name), command+"; true"); err != nil {
+					return err
+				}
+			}
+			for _, instance := range h.instances {
+				instanceDir := path.Join(dir, instance.name)
+				address := h.instanceAddress(instance)
+				commands := map[string]string{
+					"unit.service": fmt.Sprintf("cat /etc/systemd/system/seaweed_%s.service", instance.name),
+					"journal.log":  fmt.Sprintf("journalctl -u seaweed_%s --no-pager -n 1000", instance.name),
+				}
+				switch instance.component {
+				case "master":
+					commands["log.txt"] = fmt.Sprintf("tail -n 2000 %s/%s/weed.INFO", m.dataDir, instance.name)
+					commands["cluster_status.json"] = fmt.Sprintf("curl -s --max-time 10 http://%s/cluster/status", address)
+					commands["dir_status.json"] = fmt.Sprintf("curl -s --max-time 10 http://%s/dir/status", address)
+				case "volume":
+					commands["log.txt"] = fmt.Sprintf("tail -n 2000 %s/%s/weed.INFO", m.dataDir, instance.name)
+					commands["status.json"] = fmt.Sprintf("curl -s --max-time 10 http://%s/status", address)
+				case "filer":
+					commands["log.txt"] = fmt.Sprintf("tail -n 2000 %s/%s/weed.INFO", m.dataDir, instance.name)
+					commands["options"] = fmt.Sprintf("cat %s/%s.d/filer.options", m.confDir, instance.name)
+				case "envoy":
+					commands["log.txt"] = fmt.Sprintf("tail -n 2000 %s/%s/envoy.log", m.dataDir, instance.name)
+				}
+				if instance.metricsPort != 0 {
+					commands["metrics.txt"] = fmt.Sprintf("curl -s --max-time 10 http://%s/metrics", utils.JoinHostPort(h.ip, instance.metricsPort))
+				}
+				for name, command := range commands {
+					if err := b.collect(sop, path.Join(instanceDir, name), command); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(&b.log, "%s %s: %v\n", time.Now().Format(time.RFC3339), h.address(), err)
+			info(fmt.Sprintf("skipping %s: %v", h.address(), err))
+		}
+	}
+
+	stopCapture()
+	fmt.Fprintf(&b.log, "\n===== log of the run =====\n")
+	b.log.Write(runLog(captured.Bytes()))
+	if err := b.add("seaweed-up.log", b.log.Bytes()); err != nil {
+		return err
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	info("wrote " + output)
+	return nil
+}
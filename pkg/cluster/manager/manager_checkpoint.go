@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// checkpoint records the servers a deployment has finished, so an interrupted
+// deployment can be resumed without deploying them again.
+type checkpoint struct {
+	mu          sync.Mutex
+	file        string
+	Fingerprint string    `json:"fingerprint"` // of the specification, version and flags deployed
+	Version     string    `json:"version"`
+	Started     time.Time `json:"started"`
+	Completed   []string  `json:"completed"` // instance names like volume0
+}
+
+// loadCheckpoint starts a new checkpoint of the deployment, or continues the
+// saved one if resume is set and the deployment is the same. When resuming
+// without a version, the version of the interrupted deployment is used.
+func (m *Manager) loadCheckpoint(specification *spec.Specification, resume bool) (*checkpoint, error) {
+	file := filepath.Join(m.StateDir, "checkpoints", clusterKey(specification)+".json")
+
+	previous := &checkpoint{}
+	if resume {
+		saved, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no interrupted deployment of this cluster to resume")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read checkpoint: %w", err)
+		}
+		if err := json.Unmarshal(saved, previous); err != nil {
+			return nil, fmt.Errorf("parse checkpoint %s: %w", file, err)
+		}
+		if m.Version == "" {
+			m.Version = previous.Version
+		}
+	}
+
+	data, err := yaml.Marshal(specification)
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{
+		file:        file,
+		Fingerprint: hash(string(data), m.Version, m.ComponentToDeploy),
+		Version:     m.Version,
+		Started:     time.Now(),
+	}
+	if !resume {
+		return c, c.save()
+	}
+	if previous.Fingerprint != c.Fingerprint {
+		return nil, fmt.Errorf("the configuration, version or component changed since the deployment of %s started, deploy without --resume",
+			previous.Started.Format(time.RFC3339))
+	}
+	c.Started, c.Completed = previous.Started, previous.Completed
+	return c, nil
+}
+
+func (c *checkpoint) isCompleted(instance string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, completed := range c.Completed {
+		if completed == instance {
+			return true
+		}
+	}
+	return false
+}
+
+// complete records the instance as deployed.
+func (c *checkpoint) complete(instance string) error {
+	c.mu.Lock()
+	c.Completed = append(c.Completed, instance)
+	c.mu.Unlock()
+	return c.save()
+}
+
+// remove deletes the checkpoint once the deployment succeeded.
+func (c *checkpoint) remove() error {
+	if err := os.Remove(c.file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *checkpoint) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0700); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	// write and rename, so an interruption never leaves a truncated checkpoint
+	temp := c.file + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return os.Rename(temp, c.file)
+}
+
+// clusterKey identifies the cluster by its masters in the local state dir.
+func clusterKey(specification *spec.Specification) string {
+	var addresses []string
+	for _, masterSpec := range specification.MasterServers {
+		addresses = append(addresses, utils.JoinHostPort(masterSpec.Ip, masterSpec.Port))
+	}
+	sort.Strings(addresses)
+	return hash(strings.Join(addresses, ","))
+}
+
+func hash(values ...string) string {
+	h := sha256.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
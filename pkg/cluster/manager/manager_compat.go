@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/compat"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+// checkCompatibility compares the weed version installed on each host with
+// the one to deploy, refusing downgrades, jumps over major versions and the
+// upgrades the compatibility rules forbid.
+func (m *Manager) checkCompatibility(specification *spec.Specification) error {
+	if m.SkipCompatibilityCheck || m.Version == "" {
+		return nil
+	}
+	rules, err := compat.Load(context.Background(), m.CompatibilityFile)
+	if err != nil {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("load compatibility rules: %w", err))
+	}
+
+	masters := weedMasterAddresses(specification)
+	options := make(map[string][]string)
+	filers := filerAddresses(specification)
+	addOptions := func(instance string, servers []string, write func(servers []string, buf *bytes.Buffer)) {
+		var buf bytes.Buffer
+		write(servers, &buf)
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if name, _, found := strings.Cut(line, "="); found {
+				options[instance] = append(options[instance], name)
+			}
+		}
+	}
+	for index, masterSpec := range specification.MasterServers {
+		addOptions(fmt.Sprintf("master%d", index), masters, masterSpec.WriteToBuffer)
+	}
+	for index, volumeSpec := range specification.VolumeServers {
+		addOptions(fmt.Sprintf("volume%d", index), masters, volumeSpec.WriteToBuffer)
+	}
+	for index, filerSpec := range specification.FilerServers {
+		addOptions(fmt.Sprintf("filer%d", index), masters, filerSpec.WriteToBuffer)
+	}
+	for index, s3Spec := range specification.S3Servers {
+		index, s3Spec := index, s3Spec
+		addOptions(fmt.Sprintf("s3%d", index), filers, func(filers []string, buf *bytes.Buffer) {
+			s3Spec.WriteToBuffer(s3Spec.FilerOf(filers, index), buf)
+		})
+	}
+	for index, webdavSpec := range specification.WebDAVServers {
+		addOptions(fmt.Sprintf("webdav%d", index), filers, webdavSpec.WriteToBuffer)
+	}
+	for index, brokerSpec := range specification.MQBrokers {
+		addOptions(fmt.Sprintf("broker%d", index), masters, brokerSpec.WriteToBuffer)
+	}
+	for index, mountSpec := range specification.Mounts {
+		addOptions(fmt.Sprintf("mount%d", index), filers, mountSpec.WriteToBuffer)
+	}
+
+	var problems []compat.Problem
+	for _, h := range m.clusterHosts(specification) {
+		var instances []*componentInstance
+		for _, instance := range h.instances {
+			if instance.component != "envoy" && m.shouldInstall(instance.component) {
+				instances = append(instances, instance)
+			}
+		}
+		if len(instances) == 0 {
+			continue
+		}
+		err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+			out, err := op.Output("if [ -x /usr/local/bin/weed ]; then /usr/local/bin/weed version | cut -d' ' -f3; fi")
+			if err != nil {
+				return err
+			}
+			installed := strings.TrimSpace(string(out))
+			if installed == "" {
+				return nil
+			}
+			for _, instance := range instances {
+				problems = append(problems, compat.Check(rules, instance.component, instance.name, installed, m.Version, options[instance.name])...)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("read the weed version on %s: %w", h.address(), err)
+		}
+	}
+
+	var errs []string
+	for _, problem := range problems {
+		if problem.Severity == compat.Error {
+			errs = append(errs, problem.Instance+": "+problem.Message)
+		} else {
+			logging.Warn(problem.Instance + ": " + problem.Message)
+		}
+	}
+	if len(errs) > 0 {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("upgrade to %s refused:\n  %s\ndeploy with --skip-compatibility-check to upgrade anyway",
+			m.Version, strings.Join(errs, "\n  ")))
+	}
+	return nil
+}
@@ -7,112 +7,680 @@ import (
 	"github.com/pkg/errors"
 	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
 	"github.com/seaweedfs/seaweed-up/pkg/config"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
 	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/progress"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
 	"github.com/seaweedfs/seaweed-up/pkg/utils"
 	"github.com/seaweedfs/seaweed-up/scripts"
 	"github.com/thanhpk/randstr"
+	"strings"
 	"sync"
 )
 
+// DeployResult is the outcome of the deployment of one component instance,
+// printed by deploy with --format json or yaml.
+type DeployResult struct {
+	Host     string `json:"host" yaml:"host"`
+	Instance string `json:"instance" yaml:"instance"`
+	Status   string `json:"status" yaml:"status"` // deployed, skipped or failed
+	Version  string `json:"version,omitempty" yaml:"version,omitempty"`
+	Reason   string `json:"reason,omitempty" yaml:"reason,omitempty"` // why the instance was skipped or failed
+}
+
 func (m *Manager) shouldInstall(c string) bool {
 	return m.ComponentToDeploy == "" || m.ComponentToDeploy == c
 }
 
 func (m *Manager) DeployCluster(specification *spec.Specification) error {
-	m.prepare(specification)
+	applied := marshalRevision(specification)
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+	if err := m.checkCompatibility(specification); err != nil {
+		return err
+	}
+	if m.DryRun {
+		return m.planDeployment(specification)
+	}
+	unlock, err := m.lock(specification, "deploy")
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	var masters []string
-	for _, masterSpec := range specification.MasterServers {
-		masters = append(masters, fmt.Sprintf("%s:%d", masterSpec.Ip, masterSpec.Port))
+	if !m.SkipPreflight {
+		if _, err := m.preflight(specification); err != nil {
+			return fmt.Errorf("%w, fix the problems or deploy with --skip-preflight", err)
+		}
 	}
 
-	if m.shouldInstall("master") {
-		for index, masterSpec := range specification.MasterServers {
-			if err := m.DeployMasterServer(masters, masterSpec, index); err != nil {
-				fmt.Printf("error is %v\n", err)
-				return fmt.Errorf("deploy to master server %s:%d :%v", masterSpec.Ip, masterSpec.PortSsh, err)
-			}
+	masters := weedMasterAddresses(specification)
+	maintenance := m.hostsInMaintenance(specification)
+
+	checkpoint, err := m.loadCheckpoint(specification, m.Resume)
+	if err != nil {
+		return err
+	}
+
+	// a resumed deployment was snapshotted when it started
+	var snapshots []string
+	takeSnapshots := !m.SkipSnapshot && (specification.GlobalOptions.Snapshots == nil || !specification.GlobalOptions.Snapshots.Disabled)
+	if takeSnapshots && !m.Resume {
+		snapshot, err := m.snapshot(specification, "pre-deploy")
+		if err != nil {
+			return fmt.Errorf("%w, fix the problem or deploy with --skip-snapshot", err)
+		}
+		if snapshot != "" {
+			info("snapshot of the cluster before the deployment: " + snapshot)
+			snapshots = append(snapshots, snapshot)
 		}
 	}
 
-	var wg sync.WaitGroup
+	hooks := specification.Hooks
+	if err := m.runHooks("pre_deploy", hooks.PreDeploy, hookTarget{}); err != nil {
+		return err
+	}
+
+	tracker := progress.New("Deploying the cluster")
+	defer tracker.Stop()
+	tracker.Subscribe(func(event progress.Event) {
+		logging.Debug("task "+string(event.Type), "task", event.Task, "step", event.Step, "error", event.Error)
+	})
+	for _, listener := range m.listeners {
+		tracker.Subscribe(listener)
+	}
+
+	// failures after some servers were deployed leave the cluster half updated
+	var mu sync.Mutex
+	var deployed int
 	var deployErrors []error
+	failed := func(err error) error {
+		if deployed > 0 {
+			return exitcode.WithCode(exitcode.Partial, err)
+		}
+		return err
+	}
 
-	if m.shouldInstall("volume") {
-		for index, volumeSpec := range specification.VolumeServers {
-			wg.Add(1)
-			go func(index int, volumeSpec *spec.VolumeServerSpec) {
-				defer wg.Done()
-				if err := m.DeployVolumeServer(masters, volumeSpec, index); err != nil {
-					deployErrors = append(deployErrors, fmt.Errorf("deploy to volume server %s:%d :%v", volumeSpec.Ip, volumeSpec.PortSsh, err))
+	// node deploys one server between its node hooks
+	type node struct {
+		task    *progress.Task
+		target  hookTarget
+		version func() string
+		deploy  func() error
+		result  *DeployResult
+	}
+	// the results of all instances, in the order they were added
+	var results []*DeployResult
+	if output.Structured() {
+		defer func() {
+			if err := output.Print(results, nil); err != nil {
+				logging.Warn(fmt.Sprintf("can not print the deployment results: %v", err))
+			}
+		}()
+	}
+	weedVersion := func() string { return m.Version }
+	var chronyNodes, tuningNodes, masterNodes, filerNodes, gatewayNodes, envoyNodes, haNodes []*node
+	// the volume servers by rack, in the order the racks first appear
+	var volumeRacks []*[]*node
+	rackNodes := make(map[string]*[]*node)
+	addNode := func(nodes *[]*node, component string, index int, ip string, portSsh int, version func() string, deploy func() error) {
+		instance := fmt.Sprintf("%s%d", component, index)
+		if _, found := maintenance[ip]; found {
+			logging.Warn(fmt.Sprintf("skipping %s, %s is in maintenance", instance, ip))
+			if m.shouldInstall(component) {
+				results = append(results, &DeployResult{Host: ip, Instance: instance, Status: "skipped", Reason: "host in maintenance"})
+			}
+			return
+		}
+		if m.shouldInstall(component) {
+			result := &DeployResult{Host: ip, Instance: instance, Status: "skipped", Reason: "not reached after a failure"}
+			results = append(results, result)
+			*nodes = append(*nodes, &node{
+				task:    tracker.Add(instance + " " + ip),
+				target:  hookTarget{instance: instance, ip: ip, portSsh: portSsh},
+				version: version,
+				result:  result,
+				deploy: func() error {
+					// connections dropped halfway through are retried with the whole deployment of the server
+					if err := m.retry("deploy", instance, deploy); err != nil {
+						return fmt.Errorf("deploy to %s server %s:%d :%w", component, ip, portSsh, err)
+					}
+					return nil
+				},
+			})
+		}
+	}
+	runNode := func(n *node) error {
+		if checkpoint.isCompleted(n.target.instance) {
+			n.task.Skip("deployed before the interruption")
+			n.result.Reason = "deployed before the interruption"
+			mu.Lock()
+			deployed++
+			mu.Unlock()
+			return nil
+		}
+		err := func() error {
+			if len(hooks.PreUpgradeNode) > 0 {
+				n.task.Step("pre_upgrade_node hooks")
+				if err := m.runHooks("pre_upgrade_node", hooks.PreUpgradeNode, n.target); err != nil {
+					return err
 				}
-			}(index, volumeSpec)
+			}
+			n.task.Step("installing")
+			if err := n.deploy(); err != nil {
+				return err
+			}
+			if len(hooks.PostUpgradeNode) > 0 {
+				n.task.Step("post_upgrade_node hooks")
+				return m.runHooks("post_upgrade_node", hooks.PostUpgradeNode, n.target)
+			}
+			return nil
+		}()
+		n.task.Done(err)
+		if err == nil {
+			if saveErr := checkpoint.complete(n.target.instance); saveErr != nil {
+				logging.Warn(fmt.Sprintf("can not save the progress of the deployment: %v", saveErr))
+			}
+		}
+		if n.version != nil {
+			n.result.Version = n.version()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			n.result.Status, n.result.Reason = "failed", err.Error()
+			deployErrors = append(deployErrors, err)
+		} else {
+			n.result.Status, n.result.Reason = "deployed", ""
+			deployed++
+		}
+		return err
+	}
+
+	if timeSync := specification.GlobalOptions.TimeSync; timeSync != nil && m.shouldInstall("chrony") {
+		for index, h := range m.clusterHosts(specification) {
+			index, h := index, h
+			addNode(&chronyNodes, "chrony", index, h.ip, h.portSsh, nil, func() error {
+				return m.DeployChrony(timeSync, h, index)
+			})
+		}
+	}
+	if tuning := specification.GlobalOptions.Tuning; tuning != nil && m.shouldInstall("tuning") {
+		resolved, err := tuning.Resolve()
+		if err != nil {
+			return err
 		}
+		for index, h := range m.clusterHosts(specification) {
+			index, h := index, h
+			addNode(&tuningNodes, "tuning", index, h.ip, h.portSsh, nil, func() error {
+				return m.DeployTuning(resolved, h, index)
+			})
+		}
+	}
+	for index, masterSpec := range specification.MasterServers {
+		index, masterSpec := index, masterSpec
+		addNode(&masterNodes, "master", index, masterSpec.Ip, masterSpec.PortSsh, weedVersion, func() error {
+			return m.DeployMasterServer(masters, masterSpec, index)
+		})
+	}
+	for index, volumeSpec := range specification.VolumeServers {
+		index, volumeSpec := index, volumeSpec
+		dataCenter, rack := volumeSpec.Location()
+		nodes, found := rackNodes[dataCenter+"/"+rack]
+		if !found {
+			nodes = &[]*node{}
+			rackNodes[dataCenter+"/"+rack] = nodes
+			volumeRacks = append(volumeRacks, nodes)
+		}
+		addNode(nodes, "volume", index, volumeSpec.Ip, volumeSpec.PortSsh, weedVersion, func() error {
+			return m.DeployVolumeServer(masters, volumeSpec, index)
+		})
+	}
+	for index, filerSpec := range specification.FilerServers {
+		index, filerSpec := index, filerSpec
+		addNode(&filerNodes, "filer", index, filerSpec.Ip, filerSpec.PortSsh, weedVersion, func() error {
+			return m.DeployFilerServer(masters, filerSpec, index)
+		})
+	}
+	filers := filerAddresses(specification)
+	for index, s3Spec := range specification.S3Servers {
+		index, s3Spec := index, s3Spec
+		addNode(&gatewayNodes, "s3", index, s3Spec.Ip, s3Spec.PortSsh, weedVersion, func() error {
+			return m.DeployS3Server(filers, s3Spec, index)
+		})
 	}
-	if m.shouldInstall("filer") {
-		for index, filerSpec := range specification.FilerServers {
+	for index, webdavSpec := range specification.WebDAVServers {
+		index, webdavSpec := index, webdavSpec
+		addNode(&gatewayNodes, "webdav", index, webdavSpec.Ip, webdavSpec.PortSsh, weedVersion, func() error {
+			return m.DeployWebDAVServer(filers, webdavSpec, index)
+		})
+	}
+	for index, mountSpec := range specification.Mounts {
+		index, mountSpec := index, mountSpec
+		addNode(&gatewayNodes, "mount", index, mountSpec.Ip, mountSpec.PortSsh, weedVersion, func() error {
+			return m.DeployMount(filers, mountSpec, index)
+		})
+	}
+	for index, brokerSpec := range specification.MQBrokers {
+		index, brokerSpec := index, brokerSpec
+		addNode(&gatewayNodes, "broker", index, brokerSpec.Ip, brokerSpec.PortSsh, weedVersion, func() error {
+			return m.DeployMQBroker(masters, brokerSpec, index)
+		})
+	}
+	for index, envoySpec := range specification.EnvoyServers {
+		index, envoySpec := index, envoySpec
+		addNode(&envoyNodes, "envoy", index, envoySpec.Ip, envoySpec.PortSsh, func() string { return envoySpec.Version }, func() error {
+			return m.DeployEnvoyServer(specification.FilerServers, specification.S3Servers, envoySpec, index)
+		})
+	}
+	if specification.HighAvailability != nil && m.shouldInstall("keepalived") {
+		servers, err := haServers(specification)
+		if err != nil {
+			return err
+		}
+		for index, server := range servers {
+			index := index
+			addNode(&haNodes, "keepalived", index, server.ip, server.portSsh, nil, func() error {
+				return m.DeployKeepalived(specification, servers, index)
+			})
+		}
+	}
+
+	runParallel := func(nodes []*node) error {
+		var wg sync.WaitGroup
+		for _, n := range nodes {
 			wg.Add(1)
-			go func(index int, filerSpec *spec.FilerServerSpec) {
+			go func(n *node) {
 				defer wg.Done()
-				if err := m.DeployFilerServer(masters, filerSpec, index); err != nil {
-					deployErrors = append(deployErrors, fmt.Errorf("deploy to filer server %s:%d :%v", filerSpec.Ip, filerSpec.PortSsh, err))
-				}
-			}(index, filerSpec)
+				runNode(n)
+			}(n)
+		}
+		wg.Wait()
+		if len(deployErrors) > 0 {
+			return failed(deployErrors[0])
 		}
+		return nil
 	}
-	wg.Wait()
-	if len(deployErrors) > 0 {
-		return deployErrors[0]
+
+	// clocks are synced and kernels tuned before the masters elect a leader
+	if err := runParallel(append(chronyNodes, tuningNodes...)); err != nil {
+		return err
+	}
+
+	for _, n := range masterNodes {
+		if err := runNode(n); err != nil {
+			return failed(err)
+		}
+	}
+
+	// one rack after the other, so the copies replicated to other racks stay
+	// available while the servers of a rack restart
+	if len(volumeRacks) == 0 {
+		volumeRacks = append(volumeRacks, &[]*node{})
+	}
+	for i, nodes := range volumeRacks {
+		if i == 0 {
+			*nodes = append(*nodes, filerNodes...)
+		}
+		if err := runParallel(*nodes); err != nil {
+			return err
+		}
 	}
 
-	if m.shouldInstall("envoy") && len(specification.EnvoyServers) > 0 {
-		latest, err := config.GitHubLatestRelease(context.Background(), "0", "envoyproxy", "envoy")
+	// s3 gateways, webdav servers, mounts and brokers connect to the filers
+	if err := runParallel(gatewayNodes); err != nil {
+		return err
+	}
+
+	if len(envoyNodes) > 0 {
+		if err := resolveEnvoyVersions(specification); err != nil {
+			return err
+		}
+		for _, n := range envoyNodes {
+			if err := runNode(n); err != nil {
+				return failed(err)
+			}
+		}
+	}
+
+	for _, n := range haNodes {
+		if err := runNode(n); err != nil {
+			return failed(err)
+		}
+	}
+
+	if err := m.runHooks("post_deploy", hooks.PostDeploy, hookTarget{}); err != nil {
+		return failed(err)
+	}
+	if takeSnapshots && (len(snapshots) > 0 || m.Resume) {
+		snapshot, err := m.snapshot(specification, "post-deploy")
 		if err != nil {
-			return errors.Wrapf(err, "unable to get latest version number, define a version manually with the --version flag")
+			logging.Warn(fmt.Sprintf("can not snapshot the cluster after the deployment: %v", err))
+		} else if snapshot != "" {
+			info("snapshot of the cluster after the deployment: " + snapshot)
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	if applied != nil {
+		operation := "deploy"
+		if m.ComponentToDeploy != "" {
+			operation += " -c " + m.ComponentToDeploy
+		}
+		m.recordRevision(specification, applied, operation, snapshots)
+	}
+	return checkpoint.remove()
+}
+
+// planDeployment prints the files, commands and restarts the deployment would
+// run on each component instance, without changing the hosts.
+func (m *Manager) planDeployment(specification *spec.Specification) error {
+	masters := weedMasterAddresses(specification)
+	maintenance := m.hostsInMaintenance(specification)
+	hooks := specification.Hooks
+	plan := &Plan{
+		Operation: "deploy",
+		Hooks:     append(hookDescriptions("pre_deploy", hooks.PreDeploy), hookDescriptions("post_deploy", hooks.PostDeploy)...),
+	}
+
+	addNode := func(component string, index int, ip string, portSsh int, deploy func(op operator.CommandOperator) error) error {
+		if _, found := maintenance[ip]; found {
+			logging.Warn(fmt.Sprintf("skipping %s%d, %s is in maintenance", component, index, ip))
+			return nil
+		}
+		if !m.shouldInstall(component) {
+			return nil
+		}
+		node := &NodePlan{
+			Host:     ip,
+			Instance: fmt.Sprintf("%s%d", component, index),
+			Hooks:    append(hookDescriptions("pre_upgrade_node", hooks.PreUpgradeNode), hookDescriptions("post_upgrade_node", hooks.PostUpgradeNode)...),
+		}
+		err := m.executeRemote(utils.JoinHostPort(ip, portSsh), func(op operator.CommandOperator) error {
+			return deploy(&planOperator{CommandOperator: op, node: node, m: m})
+		})
+		if err != nil {
+			return fmt.Errorf("plan %s server %s:%d :%w", component, ip, portSsh, err)
+		}
+		plan.Nodes = append(plan.Nodes, node)
+		return nil
+	}
+
+	if timeSync := specification.GlobalOptions.TimeSync; timeSync != nil && m.shouldInstall("chrony") {
+		for index, h := range m.clusterHosts(specification) {
+			index := index
+			if err := addNode("chrony", index, h.ip, h.portSsh, func(op operator.CommandOperator) error {
+				return m.deployChrony(op, timeSync, index)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	if tuning := specification.GlobalOptions.Tuning; tuning != nil && m.shouldInstall("tuning") {
+		resolved, err := tuning.Resolve()
+		if err != nil {
+			return err
+		}
+		for index, h := range m.clusterHosts(specification) {
+			index := index
+			if err := addNode("tuning", index, h.ip, h.portSsh, func(op operator.CommandOperator) error {
+				return m.deployTuning(op, resolved, index)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	for index, masterSpec := range specification.MasterServers {
+		index, masterSpec := index, masterSpec
+		if err := addNode("master", index, masterSpec.Ip, masterSpec.PortSsh, func(op operator.CommandOperator) error {
+			return m.deployMasterServer(op, masters, masterSpec, index)
+		}); err != nil {
+			return err
+		}
+	}
+	for index, volumeSpec := range specification.VolumeServers {
+		index, volumeSpec := index, volumeSpec
+		if err := addNode("volume", index, volumeSpec.Ip, volumeSpec.PortSsh, func(op operator.CommandOperator) error {
+			return m.deployVolumeServer(op, masters, volumeSpec, index)
+		}); err != nil {
+			return err
+		}
+	}
+	for index, filerSpec := range specification.FilerServers {
+		index, filerSpec := index, filerSpec
+		if err := addNode("filer", index, filerSpec.Ip, filerSpec.PortSsh, func(op operator.CommandOperator) error {
+			return m.deployFilerServer(op, masters, filerSpec, index)
+		}); err != nil {
+			return err
+		}
+	}
+	filers := filerAddresses(specification)
+	for index, s3Spec := range specification.S3Servers {
+		index, s3Spec := index, s3Spec
+		if err := addNode("s3", index, s3Spec.Ip, s3Spec.PortSsh, func(op operator.CommandOperator) error {
+			return m.deployS3Server(op, filers, s3Spec, index)
+		}); err != nil {
+			return err
+		}
+	}
+	for index, webdavSpec := range specification.WebDAVServers {
+		index, webdavSpec := index, webdavSpec
+		if err := addNode("webdav", index, webdavSpec.Ip, webdavSpec.PortSsh, func(op operator.CommandOperator) error {
+			return m.deployWebDAVServer(op, filers, webdavSpec, index)
+		}); err != nil {
+			return err
+		}
+	}
+	for index, mountSpec := range specification.Mounts {
+		index, mountSpec := index, mountSpec
+		if err := addNode("mount", index, mountSpec.Ip, mountSpec.PortSsh, func(op operator.CommandOperator) error {
+			return m.deployMount(op, filers, mountSpec, index)
+		}); err != nil {
+			return err
+		}
+	}
+	for index, brokerSpec := range specification.MQBrokers {
+		index, brokerSpec := index, brokerSpec
+		if err := addNode("broker", index, brokerSpec.Ip, brokerSpec.PortSsh, func(op operator.CommandOperator) error {
+			return m.deployMQBroker(op, masters, brokerSpec, index)
+		}); err != nil {
+			return err
+		}
+	}
+	if len(specification.EnvoyServers) > 0 && m.shouldInstall("envoy") {
+		if err := resolveEnvoyVersions(specification); err != nil {
+			return err
 		}
 		for index, envoySpec := range specification.EnvoyServers {
-			envoySpec.Version = utils.Nvl(envoySpec.Version, latest.Version)
-			if err := m.DeployEnvoyServer(specification.FilerServers, envoySpec, index); err != nil {
-				return fmt.Errorf("deploy to envoy server %s:%d :%v", envoySpec.Ip, envoySpec.PortSsh, err)
+			index, envoySpec := index, envoySpec
+			if err := addNode("envoy", index, envoySpec.Ip, envoySpec.PortSsh, func(op operator.CommandOperator) error {
+				return m.deployEnvoyServer(op, specification.FilerServers, specification.S3Servers, envoySpec, index)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	if specification.HighAvailability != nil && m.shouldInstall("keepalived") {
+		servers, err := haServers(specification)
+		if err != nil {
+			return err
+		}
+		for index, server := range servers {
+			index := index
+			if err := addNode("keepalived", index, server.ip, server.portSsh, func(op operator.CommandOperator) error {
+				return m.deployKeepalived(op, specification, servers, index)
+			}); err != nil {
+				return err
 			}
 		}
 	}
+
+	return printPlan(plan)
+}
+
+// resolveEnvoyVersions sets the latest envoy release on the envoy servers without a version.
+func resolveEnvoyVersions(specification *spec.Specification) error {
+	latest, err := config.GitHubLatestRelease(context.Background(), "0", "envoyproxy", "envoy")
+	if err != nil {
+		return errors.Wrapf(err, "unable to get latest version number, define a version manually with the --version flag")
+	}
+	for _, envoySpec := range specification.EnvoyServers {
+		envoySpec.Version = utils.Nvl(envoySpec.Version, latest.Version)
+	}
 	return nil
 }
 
-func (m *Manager) prepare(specification *spec.Specification) {
-	if m.User != "root" {
-		password := utils.PromptForPassword("Input sudo password: ")
-		m.sudoPass = password
+// prepare fills in the defaults of the specification and gets the sudo
+// passwords of the hosts of non root users elevating with sudo, once for each
+// password source, see sudoPassword.
+func (m *Manager) prepare(specification *spec.Specification) error {
+	m.prepareSpecification(specification)
+	if m.passwords == nil {
+		m.passwords = make(map[string]string)
+	}
+	for _, elevation := range m.elevations {
+		if elevation.method != "sudo" {
+			continue
+		}
+		source := passwordSource(elevation.spec)
+		password, found := m.passwords[source]
+		if !found {
+			var err error
+			if password, err = sudoPassword(elevation.spec); err != nil {
+				return err
+			}
+			m.passwords[source] = password
+			redact.Secret(password)
+		}
+		elevation.password = password
 	}
+	m.sudoPass = m.globalPassword(specification)
+	return nil
+}
+
+// prepareSpecification fills in the defaults of the specification, for commands not needing sudo.
+func (m *Manager) prepareSpecification(specification *spec.Specification) {
 	m.confDir = utils.Nvl(specification.GlobalOptions.ConfigDir, "/etc/seaweed")
 	m.dataDir = utils.Nvl(specification.GlobalOptions.DataDir, "/opt/seaweed")
+	m.prepareRepository(specification.GlobalOptions.Repository)
+	m.retrySpec = specification.GlobalOptions.Retry
+	m.dualStack = specification.GlobalOptions.DualStack
+	m.elevation = "sudo"
+	if elevation := specification.GlobalOptions.Elevation; elevation != nil {
+		m.elevation = utils.Nvl(elevation.Method, "sudo")
+	}
+	systemdDefaults := specification.GlobalOptions.Systemd
+	if tuning := specification.GlobalOptions.Tuning; tuning != nil {
+		if resolved, err := tuning.Resolve(); err == nil && resolved.LimitNOFILE != "" {
+			systemdDefaults = systemdDefaults.Merge(&spec.SystemdSpec{LimitNOFILE: resolved.LimitNOFILE})
+		}
+	}
+	bindAll := func(ipBind *string) {
+		if m.dualStack && *ipBind == "" {
+			*ipBind = "::"
+		}
+	}
 	for _, masterSpec := range specification.MasterServers {
+		bindAll(&masterSpec.IpBind)
 		masterSpec.VolumeSizeLimitMB = utils.NvlInt(masterSpec.VolumeSizeLimitMB, specification.GlobalOptions.VolumeSizeLimitMB, 5000)
 		masterSpec.DefaultReplication = utils.Nvl(masterSpec.DefaultReplication, specification.GlobalOptions.Replication, "")
 		masterSpec.PortSsh = utils.NvlInt(masterSpec.PortSsh, m.SshPort, 22)
+		masterSpec.Systemd = masterSpec.Systemd.Merge(systemdDefaults)
 	}
 	for _, volumeSpec := range specification.VolumeServers {
+		bindAll(&volumeSpec.IpBind)
 		volumeSpec.PortSsh = utils.NvlInt(volumeSpec.PortSsh, m.SshPort, 22)
+		volumeSpec.Disks = volumeSpec.Disks.Merge(specification.GlobalOptions.Disks)
+		volumeSpec.Systemd = volumeSpec.Systemd.Merge(systemdDefaults)
 	}
 	for _, filerSpec := range specification.FilerServers {
+		bindAll(&filerSpec.IpBind)
 		filerSpec.PortSsh = utils.NvlInt(filerSpec.PortSsh, m.SshPort, 22)
+		filerSpec.Systemd = filerSpec.Systemd.Merge(systemdDefaults)
 	}
 	for _, envoySpec := range specification.EnvoyServers {
 		envoySpec.PortSsh = utils.NvlInt(envoySpec.PortSsh, m.SshPort, 22)
 	}
+	for _, s3Spec := range specification.S3Servers {
+		bindAll(&s3Spec.IpBind)
+		s3Spec.PortSsh = utils.NvlInt(s3Spec.PortSsh, m.SshPort, 22)
+		s3Spec.Systemd = s3Spec.Systemd.Merge(systemdDefaults)
+	}
+	for _, webdavSpec := range specification.WebDAVServers {
+		webdavSpec.PortSsh = utils.NvlInt(webdavSpec.PortSsh, m.SshPort, 22)
+		webdavSpec.Systemd = webdavSpec.Systemd.Merge(systemdDefaults)
+	}
+	for _, mountSpec := range specification.Mounts {
+		mountSpec.PortSsh = utils.NvlInt(mountSpec.PortSsh, m.SshPort, 22)
+		mountSpec.Systemd = mountSpec.Systemd.Merge(systemdDefaults)
+	}
+	for _, brokerSpec := range specification.MQBrokers {
+		brokerSpec.PortSsh = utils.NvlInt(brokerSpec.PortSsh, m.SshPort, 22)
+		brokerSpec.Systemd = brokerSpec.Systemd.Merge(systemdDefaults)
+	}
+	if ha := specification.HighAvailability; ha != nil {
+		redact.Secret(ha.AuthPass)
+	}
+	m.prepareSecurity(specification)
+	m.prepareSsh(specification)
+}
+
+// configFile is a file of the config dir of a component instance besides its options.
+type configFile struct {
+	name    string
+	content []byte
+	secret  bool // masked in plans, only readable by the service
+}
+
+// configPath returns where the file of the config dir of the component instance is installed.
+func (m *Manager) configPath(componentInstance, name string) string {
+	return fmt.Sprintf("%s/%s.d/%s", m.confDir, componentInstance, name)
 }
 
-func (m *Manager) deployComponentInstance(op operator.CommandOperator, component string, componentInstance string, cliOptions *bytes.Buffer) error {
+// deployComponentInstance installs weed for the architecture arch, detected on the host if empty,
+// and starts the component instance with its options, more config files and systemd unit.
+func (m *Manager) deployComponentInstance(op operator.CommandOperator, unit *systemdUnit, arch string, cliOptions *bytes.Buffer, files ...configFile) error {
+	component, componentInstance := unit.component, unit.componentInstance
+
+	securityFiles, err := m.securityFiles(componentInstance)
+	if err != nil {
+		return err
+	}
+	files = append(files, securityFiles...)
+
+	serviceFile, err := m.renderSystemdUnit(unit)
+	if err != nil {
+		return err
+	}
+
+	if p, planning := op.(*planOperator); planning {
+		planned := []plannedFile{
+			{m.configPath(componentInstance, component+".options"), cliOptions.String()},
+			{fmt.Sprintf("/etc/systemd/system/seaweed_%s.service", componentInstance), serviceFile.String()},
+		}
+		for _, f := range files {
+			if f.secret {
+				for _, line := range strings.Split(string(f.content), "\n") {
+					redact.Secret(strings.TrimSpace(line))
+				}
+			}
+			planned = append(planned, plannedFile{m.configPath(componentInstance, f.name), string(f.content)})
+		}
+		return m.planInstance(p, componentInstance, "weed", m.Version,
+			"if [ -x /usr/local/bin/weed ]; then /usr/local/bin/weed version | cut -d' ' -f3; fi", planned...)
+	}
+
 	info("Deploying " + componentInstance + "...")
 
 	dir := "/tmp/seaweed-up." + randstr.String(6)
 
 	defer op.Execute("rm -rf " + dir)
 
-	err := op.Execute("mkdir -p " + dir + "/config")
+	err = op.Execute("mkdir -p " + dir + "/config")
 	if err != nil {
-		return fmt.Errorf("error received during installation: %s", err)
+		return fmt.Errorf("error received during installation: %w", err)
 	}
 
 	data := map[string]interface{}{
@@ -126,6 +694,16 @@ func (m *Manager) deployComponentInstance(op operator.CommandOperator, component
 		"ForceRestart":      m.ForceRestart,
 		"Version":           m.Version,
 		"ProxyConfig":       "",
+		"ServiceUser":       unit.systemd.User,
+		"ServiceGroup":      unit.systemd.Group,
+		"ServiceUid":        unit.systemd.UID,
+		"ServiceGid":        unit.systemd.GID,
+		"ServiceHome":       shellQuote(unit.systemd.Home),
+		"ServiceShell":      shellQuote(unit.systemd.Shell),
+		"WritableDirs":      strings.Join(unit.writableDirs, " "),
+		"Arch":              arch,
+		"RepoUrl":           shellQuote(m.repoUrl),
+		"CurlArgs":          m.curlArgs,
 	}
 
 	// Configure proxy if specified
@@ -140,18 +718,33 @@ func (m *Manager) deployComponentInstance(op operator.CommandOperator, component
 
 	err = op.Upload(installScript, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance), "0755")
 	if err != nil {
-		return fmt.Errorf("error received during upload install script: %s", err)
+		return fmt.Errorf("error received during upload install script: %w", err)
 	}
 
 	err = op.Upload(cliOptions, fmt.Sprintf("%s/config/%s.options", dir, component), "0644")
 	if err != nil {
-		return fmt.Errorf("error received during upload %s.options: %s", component, err)
+		return fmt.Errorf("error received during upload %s.options: %w", component, err)
+	}
+
+	for _, f := range files {
+		mode := "0644"
+		if f.secret {
+			mode = "0600"
+		}
+		if err := op.Upload(bytes.NewReader(f.content), fmt.Sprintf("%s/config/%s", dir, f.name), mode); err != nil {
+			return fmt.Errorf("error received during upload %s: %w", f.name, err)
+		}
+	}
+
+	err = op.Upload(serviceFile, fmt.Sprintf("%s/seaweed_%s.service", dir, componentInstance), "0644")
+	if err != nil {
+		return fmt.Errorf("error received during upload systemd unit: %w", err)
 	}
 
 	info("Installing " + componentInstance + "...")
-	err = op.Execute(fmt.Sprintf("cat %s/install_%s.sh | SUDO_PASS=\"%s\" sh -\n", dir, componentInstance, m.sudoPass))
+	err = op.Execute(m.scriptCommand(op, fmt.Sprintf("%s/install_%s.sh", dir, componentInstance)))
 	if err != nil {
-		return fmt.Errorf("error received during installation: %s", err)
+		return fmt.Errorf("error received during installation: %w", err)
 	}
 
 	info("Done.")
@@ -0,0 +1,266 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/disks"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// DiskAction is what to do with the volumes on a failing disk.
+type DiskAction string
+
+const (
+	DiskActionNone         DiskAction = ""
+	DiskActionMarkReadonly DiskAction = "readonly"
+	DiskActionEvacuate     DiskAction = "evacuate"
+)
+
+// DiskReport is the health of one disk of a volume server.
+type DiskReport struct {
+	Host     string   `json:"host" yaml:"host"`
+	Device   string   `json:"device" yaml:"device"`
+	ById     string   `json:"byId,omitempty" yaml:"byId,omitempty"`
+	Model    string   `json:"model" yaml:"model"`
+	Serial   string   `json:"serial" yaml:"serial"`
+	Health   string   `json:"health" yaml:"health"`
+	Mount    string   `json:"mount" yaml:"mount"`
+	Usage    string   `json:"usage" yaml:"usage"`
+	Folders  []string `json:"folders" yaml:"folders"`
+	Problems []string `json:"problems" yaml:"problems"`
+}
+
+type diskStatus struct {
+	device     *disks.BlockDevice
+	mountPoint string
+	usage      string
+	folders    []string
+	problems   []string
+}
+
+type folderStatus struct {
+	folder     string
+	device     string
+	mountPoint string
+	usage      string
+	writable   bool
+}
+
+// DiskStatus prints SMART and mount health of the disks on the volume servers
+// and applies the action to the volumes of failing disks.
+func (m *Manager) DiskStatus(specification *spec.Specification, action DiskAction) error {
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+	if action != DiskActionNone {
+		unlock, err := m.lock(specification, "disks "+string(action))
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	masters := weedMasterAddresses(specification)
+
+	var reports []DiskReport
+	var failing int
+	for index, volumeSpec := range specification.VolumeServers {
+		node := utils.JoinHostPort(volumeSpec.Ip, defaultPort(volumeSpec.Port, 8080))
+		err := m.executeRemote(utils.JoinHostPort(volumeSpec.Ip, volumeSpec.PortSsh), func(op operator.CommandOperator) error {
+			statuses, err := m.collectDiskStatus(op, volumeSpec, index)
+			if err != nil {
+				return err
+			}
+			var failingFolders []string
+			for _, s := range statuses {
+				if len(s.problems) > 0 {
+					failing++
+					failingFolders = append(failingFolders, s.folders...)
+				}
+				reports = append(reports, DiskReport{
+					Host:     volumeSpec.Ip,
+					Device:   s.device.Path,
+					ById:     s.device.ById,
+					Model:    s.device.Model,
+					Serial:   s.device.SerialId,
+					Health:   s.device.Health,
+					Mount:    s.mountPoint,
+					Usage:    s.usage,
+					Folders:  s.folders,
+					Problems: s.problems,
+				})
+			}
+			if len(failingFolders) == 0 {
+				return nil
+			}
+			switch action {
+			case DiskActionMarkReadonly:
+				return m.markFoldersReadonly(op, masters, node, failingFolders)
+			case DiskActionEvacuate:
+				return m.weedShell(op, masters, []string{fmt.Sprintf("volumeServer.evacuate -node %s -force", node)})
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("disk status of volume server %s:%d :%w", volumeSpec.Ip, volumeSpec.PortSsh, err)
+		}
+	}
+	err := output.Print(reports, func(out io.Writer) {
+		w := output.NewTable(out)
+		fmt.Fprintln(w, "HOST\tDEVICE\tMODEL\tSERIAL\tHEALTH\tMOUNT\tUSE\tFOLDERS\tSTATUS")
+		for _, r := range reports {
+			status := "OK"
+			if len(r.Problems) > 0 {
+				status = strings.Join(r.Problems, ", ")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Host, r.Device, r.Model, r.Serial,
+				dash(r.Health), dash(r.Mount), dash(r.Usage), dash(strings.Join(r.Folders, ",")), status)
+		}
+		w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	if failing > 0 {
+		return fmt.Errorf("%d failing disks found", failing)
+	}
+	return nil
+}
+
+func (m *Manager) collectDiskStatus(op operator.CommandOperator, volumeSpec *spec.VolumeServerSpec, index int) ([]*diskStatus, error) {
+	devices, err := disks.Discover(op, m.DiskDiscovery, []string{"/dev/sd", "/dev/nvme", "/dev/vd", "/dev/xvd"})
+	if err != nil {
+		return nil, fmt.Errorf("list device: %w", err)
+	}
+	var physical []*disks.BlockDevice
+	for _, dev := range devices {
+		if dev.Type == "disk" {
+			physical = append(physical, dev)
+		}
+	}
+	if err := disks.EnrichWithSmart(sudoOperator{op, m}, physical); err != nil {
+		return nil, fmt.Errorf("read SMART data: %w", err)
+	}
+	readonlyMounts, err := readonlyMountPoints(op)
+	if err != nil {
+		return nil, fmt.Errorf("read mounts: %w", err)
+	}
+
+	var folders []*folderStatus
+	for _, folder := range volumeSpec.Folders {
+		dir := folder.Folder
+		if !path.IsAbs(dir) {
+			dir = path.Join(m.dataDir, fmt.Sprintf("volume%d", index), dir)
+		}
+		folders = append(folders, m.checkFolder(op, dir))
+	}
+
+	var statuses []*diskStatus
+	for _, disk := range physical {
+		s := &diskStatus{device: disk}
+		if disk.Health == disks.HealthFailed {
+			s.problems = append(s.problems, "SMART FAILED")
+		}
+		for _, dev := range devices {
+			if dev.Path != disk.Path && !(dev.Type == "part" && strings.HasPrefix(dev.Path, disk.Path)) {
+				continue
+			}
+			if dev.MountPoint == "" {
+				continue
+			}
+			s.mountPoint = dev.MountPoint
+			if _, found := readonlyMounts[dev.MountPoint]; found {
+				s.problems = append(s.problems, "mounted read-only")
+			}
+			for _, f := range folders {
+				if f.device != dev.Path {
+					continue
+				}
+				s.folders = append(s.folders, f.folder)
+				s.usage = f.usage
+				if !f.writable {
+					s.problems = append(s.problems, "not writable")
+				}
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+func (m *Manager) checkFolder(op operator.CommandOperator, dir string) *folderStatus {
+	f := &folderStatus{folder: dir}
+	out, err := op.Output(fmt.Sprintf("df -P %s | tail -1", dir))
+	if fields := strings.Fields(string(out)); err == nil && len(fields) >= 6 {
+		f.device, f.usage, f.mountPoint = fields[0], fields[4], fields[5]
+	}
+	probe := path.Join(dir, ".seaweed-up-write-check")
+	_, err = m.sudoOutput(op, fmt.Sprintf("touch %s && rm -f %s", probe, probe))
+	f.writable = err == nil
+	return f
+}
+
+func readonlyMountPoints(op operator.CommandOperator) (map[string]struct{}, error) {
+	out, err := op.Output("cat /proc/mounts")
+	if err != nil {
+		return nil, err
+	}
+	readonly := make(map[string]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		for _, option := range strings.Split(fields[3], ",") {
+			if option == "ro" {
+				readonly[fields[1]] = struct{}{}
+			}
+		}
+	}
+	return readonly, nil
+}
+
+// markFoldersReadonly marks all volumes stored in the folders read-only on the master.
+func (m *Manager) markFoldersReadonly(op operator.CommandOperator, masters []string, node string, folders []string) error {
+	var commands []string
+	for _, folder := range folders {
+		out, err := m.sudoOutput(op, fmt.Sprintf("ls %s", folder))
+		if err != nil {
+			return fmt.Errorf("list volumes in %s: %w", folder, err)
+		}
+		for _, name := range strings.Fields(string(out)) {
+			if !strings.HasSuffix(name, ".dat") {
+				continue
+			}
+			// volume files are named <collection>_<id>.dat or <id>.dat
+			base := strings.TrimSuffix(name, ".dat")
+			id, err := strconv.Atoi(base[strings.LastIndex(base, "_")+1:])
+			if err != nil {
+				continue
+			}
+			commands = append(commands, fmt.Sprintf("volume.mark -node %s -volumeId %d -readonly", node, id))
+		}
+	}
+	if len(commands) == 0 {
+		info("no volumes found on failing disks of " + node)
+		return nil
+	}
+	return m.weedShell(op, masters, commands)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
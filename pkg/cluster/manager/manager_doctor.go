@@ -0,0 +1,360 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/httpclient"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+type severity int
+
+const (
+	severityWarning severity = iota
+	severityCritical
+)
+
+func (s severity) String() string {
+	if s == severityCritical {
+		return "CRITICAL"
+	}
+	return "WARNING"
+}
+
+func (s severity) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(s.String())), nil
+}
+
+// Finding is a problem found by the doctor with the command that likely fixes it.
+type Finding struct {
+	Severity  severity `json:"severity" yaml:"severity"`
+	Component string   `json:"component" yaml:"component"`
+	Message   string   `json:"message" yaml:"message"`
+	Remedy    string   `json:"remedy,omitempty" yaml:"remedy,omitempty"`
+	rank      int      // problems of components others depend on come first
+}
+
+var componentRank = map[string]int{"host": 0, "master": 1, "volume": 2, "filer": 3, "s3": 4, "broker": 4, "ha": 4, "disk": 5, "clock": 6}
+
+// masterStatus is the master /cluster/status response.
+type masterStatus struct {
+	IsLeader bool
+	Leader   string
+	Peers    []string
+}
+
+// Doctor checks the health of the cluster and its dependencies and prints the
+// problems found, most fundamental first, with suggested remediation commands.
+func (m *Manager) Doctor(specification *spec.Specification, fileName string) error {
+	m.prepareSpecification(specification)
+	masters := masterAddresses(specification)
+	findings := []*Finding{}
+	add := func(s severity, component, message, remedy string) {
+		findings = append(findings, &Finding{Severity: s, Component: component, Message: message, Remedy: remedy, rank: componentRank[component]})
+	}
+	restart := func(ip, instance string) string {
+		return fmt.Sprintf("ssh %s sudo systemctl restart seaweed_%s", ip, instance)
+	}
+
+	var leaders = make(map[string]bool)
+	var mastersUp int
+	var topo *topology
+	maintenance := m.hostsInMaintenance(specification)
+	for _, entry := range maintenance {
+		add(severityWarning, "host", fmt.Sprintf("%s is in maintenance since %s, it is not checked", entry.Host, entry.Since.Local().Format(time.RFC3339)),
+			fmt.Sprintf("seaweed-up node maintenance disable %s -f %s", entry.Host, fileName))
+	}
+	offsets := make(map[string]time.Duration)
+	timeSyncRemedy := "set global.time_sync, then " + fmt.Sprintf("seaweed-up deploy -f %s -c chrony", fileName)
+	if specification.GlobalOptions.TimeSync != nil {
+		timeSyncRemedy = fmt.Sprintf("seaweed-up deploy -f %s -c chrony", fileName)
+	}
+	for _, h := range m.clusterHosts(specification) {
+		if _, found := maintenance[h.ip]; found {
+			continue
+		}
+		info("checking " + h.ip)
+		err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+			skew, err := clockSkew(op)
+			if err != nil {
+				add(severityWarning, "clock", fmt.Sprintf("%s: can not read the clock: %v", h.ip, err), "")
+			} else {
+				offsets[h.ip] = skew
+			}
+			if out, _ := op.Output("timedatectl show -p NTPSynchronized --value 2>/dev/null || true"); strings.TrimSpace(string(out)) == "no" {
+				add(severityWarning, "clock", fmt.Sprintf("%s: clock is not synchronized", h.ip), timeSyncRemedy)
+			}
+
+			for _, instance := range h.instances {
+				address := h.instanceAddress(instance)
+				switch instance.component {
+				case "master":
+					out, err := op.Output(fmt.Sprintf("curl -sf --max-time 5 http://%s/cluster/status", address))
+					status := &masterStatus{}
+					if err != nil || json.Unmarshal(out, status) != nil {
+						add(severityCritical, "master", fmt.Sprintf("master %s does not answer", address), restart(h.ip, instance.name))
+						continue
+					}
+					mastersUp++
+					if status.Leader != "" {
+						leaders[status.Leader] = true
+					}
+					if topo == nil {
+						topo, _ = masterTopology(op, []string{address})
+					}
+				case "filer":
+					if _, err := op.Output(fmt.Sprintf("curl -s -o /dev/null --max-time 5 http://%s/", address)); err != nil {
+						add(severityCritical, "filer", fmt.Sprintf("filer %s does not answer", address), restart(h.ip, instance.name))
+						continue
+					}
+					var reachable bool
+					for _, master := range masters {
+						if _, err := op.Output(fmt.Sprintf("curl -sf -o /dev/null --max-time 5 http://%s/cluster/status", master)); err == nil {
+							reachable = true
+							break
+						}
+					}
+					if !reachable {
+						add(severityCritical, "filer", fmt.Sprintf("filer host %s can not reach any master", h.ip), fmt.Sprintf("seaweed-up cluster firewall apply -f %s", fileName))
+					}
+				case "broker":
+					if _, err := op.Output(tcpCheck(address, 5)); err != nil {
+						add(severityCritical, "broker", fmt.Sprintf("broker %s does not answer", address), restart(h.ip, instance.name))
+					}
+				}
+			}
+
+			for _, check := range m.diskChecks(specification, h) {
+				usage, err := diskUsage(op, check.dir)
+				if err != nil {
+					add(severityWarning, "disk", fmt.Sprintf("%s: can not read disk usage of %s: %v", h.ip, check.dir, err), "")
+					continue
+				}
+				remedy := "add disks or delete data, then " + fmt.Sprintf("seaweed-up cluster balance -f %s", fileName)
+				if usage >= 95 {
+					add(severityCritical, "disk", fmt.Sprintf("%s: %s of %s is %d%% full", h.ip, check.dir, check.instance, usage), remedy)
+				} else if usage >= 85 {
+					add(severityWarning, "disk", fmt.Sprintf("%s: %s of %s is %d%% full", h.ip, check.dir, check.instance, usage), remedy)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			add(severityCritical, "host", fmt.Sprintf("%s: %v", h.address(), err), fmt.Sprintf("seaweed-up cluster preflight -f %s", fileName))
+		}
+	}
+
+	if earliest, latest, spread := clockSpread(offsets); spread > m.MaxClockSkew {
+		add(severityCritical, "clock", fmt.Sprintf("the clocks of %s and %s are %s apart, more than %s", earliest, latest, spread.Round(time.Millisecond), m.MaxClockSkew), timeSyncRemedy)
+	}
+
+	if quorum := len(specification.MasterServers)/2 + 1; mastersUp < quorum {
+		add(severityCritical, "master", fmt.Sprintf("only %d of %d masters are up, %d are needed for a quorum", mastersUp, len(specification.MasterServers), quorum), fmt.Sprintf("seaweed-up deploy -f %s -c master", fileName))
+	} else if len(leaders) == 0 {
+		add(severityCritical, "master", "the masters have not elected a leader", "check the master logs and that the masters reach each other on their http and grpc ports")
+	} else if len(leaders) > 1 {
+		var names []string
+		for leader := range leaders {
+			names = append(names, leader)
+		}
+		sort.Strings(names)
+		add(severityCritical, "master", fmt.Sprintf("the masters disagree on the leader: %s", strings.Join(names, ", ")), "restart the masters one by one")
+	}
+
+	if topo != nil {
+		for _, node := range unregisteredVolumeServers(specification, topo) {
+			if _, found := maintenance[nodeHost(node)]; found {
+				continue
+			}
+			index := volumeIndex(specification, node)
+			add(severityCritical, "volume", fmt.Sprintf("volume server %s is not registered on the master", node), restart(nodeHost(node), fmt.Sprintf("volume%d", index)))
+		}
+	}
+
+	if ha := specification.HighAvailability; ha != nil {
+		servers, err := haServers(specification)
+		if err != nil {
+			add(severityCritical, "ha", err.Error(), "")
+		}
+		var holders []string
+		for _, server := range servers {
+			m.executeRemote(utils.JoinHostPort(server.ip, server.portSsh), func(op operator.CommandOperator) error {
+				if _, err := op.Output("systemctl is-active --quiet keepalived"); err != nil {
+					add(severityCritical, "ha", fmt.Sprintf("keepalived is not running on %s", server.ip), fmt.Sprintf("seaweed-up deploy -f %s -c keepalived", fileName))
+					return nil
+				}
+				if out, err := op.Output(fmt.Sprintf("ip -o addr show to %s", ha.Address())); err == nil && strings.TrimSpace(string(out)) != "" {
+					holders = append(holders, server.ip)
+				}
+				return nil
+			})
+		}
+		if len(holders) == 0 && len(servers) > 0 {
+			add(severityCritical, "ha", fmt.Sprintf("virtual IP %s is held by no server", ha.Address()), fmt.Sprintf("check that port %d answers on the servers, then seaweed-up deploy -f %s -c keepalived", servers[0].checkPort, fileName))
+		} else if len(holders) > 1 {
+			add(severityCritical, "ha", fmt.Sprintf("virtual IP %s is held by several servers: %s", ha.Address(), strings.Join(holders, ", ")), "allow VRRP (IP protocol 112) between the servers")
+		}
+	}
+
+	for _, endpoint := range s3Endpoints(specification) {
+		if err := httpReachable(endpoint); err != nil {
+			add(severityCritical, "s3", fmt.Sprintf("S3 endpoint %s is not reachable from here: %v", endpoint, err), fmt.Sprintf("seaweed-up cluster firewall apply -f %s --admin-cidr <your ip>/32", fileName))
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		return findings[i].rank < findings[j].rank
+	})
+	err := output.Print(findings, func(w io.Writer) {
+		fmt.Fprintln(w)
+		if len(findings) == 0 {
+			fmt.Fprintln(w, "no problems found")
+		}
+		for _, f := range findings {
+			fmt.Fprintf(w, "[%s] %s\n", f.Severity, f.Message)
+			if f.Remedy != "" {
+				fmt.Fprintf(w, "    fix: %s\n", f.Remedy)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	var critical int
+	for _, f := range findings {
+		if f.Severity == severityCritical {
+			critical++
+		}
+	}
+	if critical > 0 {
+		return fmt.Errorf("%d critical problems found", critical)
+	}
+	return nil
+}
+
+type diskCheck struct {
+	instance string
+	dir      string
+}
+
+// diskChecks lists the data directories of the instances on the host.
+func (m *Manager) diskChecks(specification *spec.Specification, h *clusterHost) (checks []diskCheck) {
+	for _, instance := range h.instances {
+		if instance.component == "envoy" {
+			continue
+		}
+		checks = append(checks, diskCheck{instance.name, path.Join(m.dataDir, instance.name)})
+		if instance.component != "volume" {
+			continue
+		}
+		index, _ := strconv.Atoi(strings.TrimPrefix(instance.name, "volume"))
+		for _, folder := range specification.VolumeServers[index].Folders {
+			if path.IsAbs(folder.Folder) {
+				checks = append(checks, diskCheck{instance.name, folder.Folder})
+			}
+		}
+	}
+	return
+}
+
+// diskUsage returns the used percentage of the file system of dir.
+func diskUsage(op operator.CommandOperator, dir string) (int, error) {
+	out, err := op.Output(fmt.Sprintf("df -P %s | tail -1", dir))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 5 {
+		return 0, fmt.Errorf("unexpected df output %q", strings.TrimSpace(string(out)))
+	}
+	return strconv.Atoi(strings.TrimSuffix(fields[4], "%"))
+}
+
+// clockSkew estimates how far the clock of the host is off from the local clock.
+func clockSkew(op operator.CommandOperator) (time.Duration, error) {
+	before := time.Now()
+	out, err := op.Output("date +%s.%N")
+	if err != nil {
+		return 0, err
+	}
+	after := time.Now()
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, err
+	}
+	remote := time.Unix(0, int64(seconds*float64(time.Second)))
+	local := before.Add(after.Sub(before) / 2)
+	return remote.Sub(local), nil
+}
+
+// clockSpread returns the hosts with the earliest and the latest clock, and
+// how far apart their clocks are.
+func clockSpread(offsets map[string]time.Duration) (earliest, latest string, spread time.Duration) {
+	var hosts []string
+	for host := range offsets {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if earliest == "" || offsets[host] < offsets[earliest] {
+			earliest = host
+		}
+		if latest == "" || offsets[host] > offsets[latest] {
+			latest = host
+		}
+	}
+	return earliest, latest, offsets[latest] - offsets[earliest]
+}
+
+func volumeIndex(specification *spec.Specification, node string) int {
+	for index, volumeSpec := range specification.VolumeServers {
+		if utils.JoinHostPort(volumeSpec.Ip, defaultPort(volumeSpec.Port, 8080)) == node {
+			return index
+		}
+	}
+	return -1
+}
+
+// s3Endpoints lists the S3 addresses of the filers, s3 gateways and envoy proxies.
+func s3Endpoints(specification *spec.Specification) (endpoints []string) {
+	for _, filerSpec := range specification.FilerServers {
+		if filerSpec.S3 || filerSpec.S3Port != 0 {
+			endpoints = append(endpoints, utils.JoinHostPort(filerSpec.Ip, defaultPort(filerSpec.S3Port, 8333)))
+		}
+	}
+	for _, s3Spec := range specification.S3Servers {
+		endpoints = append(endpoints, utils.JoinHostPort(s3Spec.Ip, s3Spec.ListenPorts()[0]))
+	}
+	for _, envoySpec := range specification.EnvoyServers {
+		if envoySpec.S3Port != 0 {
+			endpoints = append(endpoints, utils.JoinHostPort(envoySpec.Ip, envoySpec.S3Port))
+		}
+	}
+	return
+}
+
+// httpReachable tells if anything answers http on the address, whatever the status.
+func httpReachable(address string) error {
+	client, err := httpclient.New(5 * time.Second)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get("http://" + address + "/")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
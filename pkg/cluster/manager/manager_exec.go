@@ -0,0 +1,132 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/thanhpk/randstr"
+)
+
+// ExecOptions describe what Exec runs and where.
+type ExecOptions struct {
+	Command string // shell command to run
+	Script  string // local script to upload and run instead of Command
+	Role    string // only run on hosts with this component, all hosts if empty
+	Sudo    bool   // run as root
+}
+
+// ExecResult is the outcome of the command on one host.
+type ExecResult struct {
+	Host     string `json:"host" yaml:"host"`
+	ExitCode int    `json:"exitCode" yaml:"exitCode"`
+	Output   string `json:"output" yaml:"output"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+	err      error
+}
+
+// Exec runs a command or script on the hosts of the cluster in parallel and
+// prints the output grouped by host, followed by a summary of the exit codes.
+func (m *Manager) Exec(specification *spec.Specification, options ExecOptions) error {
+	if options.Sudo {
+		if err := m.prepare(specification); err != nil {
+			return err
+		}
+	} else {
+		m.prepareSpecification(specification)
+	}
+
+	var hosts []*clusterHost
+	for _, h := range m.clusterHosts(specification) {
+		if options.Role == "" || h.hasComponent(options.Role) {
+			hosts = append(hosts, h)
+		}
+	}
+	hosts = skipInMaintenance(hosts, m.hostsInMaintenance(specification))
+	if len(hosts) == 0 {
+		return fmt.Errorf("no hosts with role %s out of maintenance", options.Role)
+	}
+
+	results := make([]*ExecResult, len(hosts))
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h *clusterHost) {
+			defer wg.Done()
+			r := &ExecResult{Host: h.ip}
+			err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+				command := options.Command
+				if options.Script != "" {
+					target := fmt.Sprintf("/tmp/seaweed-up.%s.%s", randstr.String(6), path.Base(options.Script))
+					if err := op.UploadFile(options.Script, target, "0755"); err != nil {
+						return fmt.Errorf("upload %s: %w", options.Script, err)
+					}
+					defer op.Execute("rm -f " + target)
+					command = target
+				}
+				if options.Sudo {
+					command = m.sudoCommand(op, command)
+				}
+				out, err := op.Output(fmt.Sprintf("( %s ) 2>&1", command))
+				r.Output = string(out)
+				if exitErr, ok := err.(interface{ ExitStatus() int }); ok {
+					r.ExitCode = exitErr.ExitStatus()
+					return nil
+				}
+				return err
+			})
+			if err != nil {
+				r.Error, r.err = err.Error(), err
+			}
+			results[i] = r
+		}(i, h)
+	}
+	wg.Wait()
+
+	var failed []string
+	var unreachable int
+	for _, r := range results {
+		if r.Error != "" || r.ExitCode != 0 {
+			failed = append(failed, fmt.Sprintf("%s (%s)", r.Host, r.status()))
+		}
+		if exitcode.Of(r.err) == exitcode.Unreachable {
+			unreachable++
+		}
+	}
+	err := output.Print(results, func(w io.Writer) {
+		for _, r := range results {
+			fmt.Fprintf(w, "===== %s (%s) =====\n", r.Host, r.status())
+			fmt.Fprint(w, r.Output)
+			if r.Output != "" && !strings.HasSuffix(r.Output, "\n") {
+				fmt.Fprintln(w)
+			}
+		}
+		fmt.Fprintf(w, "\n%d hosts: %d succeeded, %d failed\n", len(results), len(results)-len(failed), len(failed))
+	})
+	if err != nil {
+		return err
+	}
+	if unreachable == len(results) {
+		return exitcode.WithCode(exitcode.Unreachable, fmt.Errorf("failed on %s", strings.Join(failed, ", ")))
+	}
+	if len(failed) == len(results) {
+		return fmt.Errorf("failed on %s", strings.Join(failed, ", "))
+	}
+	if len(failed) > 0 {
+		return exitcode.WithCode(exitcode.Partial, fmt.Errorf("failed on %s", strings.Join(failed, ", ")))
+	}
+	return nil
+}
+
+func (r *ExecResult) status() string {
+	if r.Error != "" {
+		return r.Error
+	}
+	return fmt.Sprintf("exit %d", r.ExitCode)
+}
@@ -0,0 +1,286 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/progress"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// FilerMetaOptions describe an export or import of the filer metadata.
+type FilerMetaOptions struct {
+	File  string // local file of the gzipped metadata
+	Path  string // filer directory exported, / if empty
+	Filer int    // index of the filer server saving or loading the metadata
+}
+
+// metaChunk is how much of a metadata file is transferred at once. An
+// interrupted transfer is resumed from the last chunk received.
+const metaChunk = 64 << 20
+
+// metaExport is kept next to the partial local file of an export, to resume it.
+type metaExport struct {
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+	Remote string `json:"remote"` // gzipped metadata saved on the host
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+func metaFiler(specification *spec.Specification, index int) (*spec.FilerServerSpec, error) {
+	if len(specification.FilerServers) == 0 {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("no filer servers in the configuration"))
+	}
+	if index < 0 || index >= len(specification.FilerServers) {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("there is no filer%d, the filers are filer0 to filer%d", index, len(specification.FilerServers)-1))
+	}
+	return specification.FilerServers[index], nil
+}
+
+// ExportFilerMeta saves the metadata of the filer entries under options.Path
+// with weed shell fs.meta.save on the filer host, and downloads it gzipped to
+// options.File. An interrupted export, run again, resumes the download.
+func (m *Manager) ExportFilerMeta(specification *spec.Specification, options FilerMetaOptions) error {
+	m.prepareSpecification(specification)
+	filerSpec, err := metaFiler(specification, options.Filer)
+	if err != nil {
+		return err
+	}
+	path := utils.Nvl(options.Path, "/")
+	address := utils.JoinHostPort(filerSpec.Ip, filerSpec.PortSsh)
+	part := options.File + ".part"
+	stateFile := part + ".json"
+	var state *metaExport
+	if data, err := os.ReadFile(stateFile); err == nil {
+		state = &metaExport{}
+		if json.Unmarshal(data, state) != nil || state.Host != address || state.Path != path {
+			state = nil
+		}
+	}
+
+	tracker := progress.New("Exporting the filer metadata")
+	defer tracker.Stop()
+	task := tracker.Add(fmt.Sprintf("filer%d %s", options.Filer, filerSpec.Ip))
+	err = m.executeRemote(address, func(op operator.CommandOperator) error {
+		if state != nil {
+			// resumed only if the saved metadata is still on the host
+			out, _ := op.Output(fmt.Sprintf("stat -c %%s %s 2>/dev/null || true", state.Remote))
+			if strings.TrimSpace(string(out)) != strconv.FormatInt(state.Size, 10) {
+				state = nil
+			}
+		}
+		if state == nil {
+			if err := os.Remove(part); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			task.Step("saving the metadata of " + path)
+			remote := fmt.Sprintf("/tmp/seaweed-up-meta-%d.meta", time.Now().UnixNano())
+			command := m.weedClient(op, filerShellCommand(weedMasterAddresses(specification), filerAddresses(specification)[options.Filer], []string{fmt.Sprintf("fs.meta.save -o %s %s", remote, path)}))
+			if out, err := op.Output(command); err != nil {
+				return fmt.Errorf("fs.meta.save: %w: %s", err, out)
+			}
+			size, sum, err := remoteChecksum(op, "gzip -f "+remote+" &&", remote+".gz")
+			if err != nil {
+				return fmt.Errorf("fs.meta.save saved no metadata: %w", err)
+			}
+			state = &metaExport{Host: address, Path: path, Remote: remote + ".gz", Size: size, Sha256: sum}
+			data, _ := json.MarshalIndent(state, "", "  ")
+			if err := os.WriteFile(stateFile, data, 0600); err != nil {
+				return err
+			}
+		}
+
+		f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		for offset := stat.Size(); offset < state.Size; {
+			task.Step(fmt.Sprintf("downloading, %s of %s", megabytes(offset), megabytes(state.Size)))
+			received := &countingWriter{w: f}
+			err := op.Stream(fmt.Sprintf("tail -c +%d %s | head -c %d", offset+1, state.Remote, metaChunk), received)
+			offset += received.n
+			if err != nil {
+				return fmt.Errorf("download %s, run the export again to resume: %w", state.Remote, err)
+			}
+			if received.n == 0 {
+				return fmt.Errorf("download %s: no data at %d of %d bytes", state.Remote, offset, state.Size)
+			}
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+
+		task.Step("verifying")
+		if sum, err := fileChecksum(part); err != nil || sum != state.Sha256 {
+			os.Remove(part)
+			return fmt.Errorf("the downloaded metadata does not match the one saved on the host, run the export again")
+		}
+		if err := os.Rename(part, options.File); err != nil {
+			return err
+		}
+		os.Remove(stateFile)
+		if err := op.Execute("rm -f " + state.Remote); err != nil {
+			logging.Warn(fmt.Sprintf("can not remove %s from %s: %v", state.Remote, address, err))
+		}
+		return nil
+	})
+	task.Done(err)
+	if err != nil {
+		return err
+	}
+	info(fmt.Sprintf("wrote the metadata of %s to %s, %s", path, options.File, megabytes(state.Size)))
+	return nil
+}
+
+// ImportFilerMeta uploads the gzipped metadata of options.File to the filer
+// host and loads it with weed shell fs.meta.load, adding and overwriting the
+// entries. An interrupted upload, run again, resumes from the chunks uploaded.
+func (m *Manager) ImportFilerMeta(specification *spec.Specification, options FilerMetaOptions) error {
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+	filerSpec, err := metaFiler(specification, options.Filer)
+	if err != nil {
+		return err
+	}
+	unlock, err := m.lock(specification, "filer meta import")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.Open(options.File)
+	if err != nil {
+		return exitcode.WithCode(exitcode.Invalid, err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	sum, err := fileChecksum(options.File)
+	if err != nil {
+		return err
+	}
+	// named by the content, for a run again to find the chunks uploaded
+	remote := fmt.Sprintf("/tmp/seaweed-up-meta-import-%s.meta", sum[:16])
+	address := utils.JoinHostPort(filerSpec.Ip, filerSpec.PortSsh)
+
+	tracker := progress.New("Importing the filer metadata")
+	defer tracker.Stop()
+	task := tracker.Add(fmt.Sprintf("filer%d %s", options.Filer, filerSpec.Ip))
+	err = m.executeRemote(address, func(op operator.CommandOperator) error {
+		out, err := op.Output(fmt.Sprintf("stat -c %%s %s.gz.part 2>/dev/null || echo 0", remote))
+		if err != nil {
+			return err
+		}
+		offset, _ := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		if offset > stat.Size() {
+			offset = 0
+			if err := op.Execute(fmt.Sprintf("rm -f %s.gz.part", remote)); err != nil {
+				return err
+			}
+		}
+		for offset < stat.Size() {
+			task.Step(fmt.Sprintf("uploading, %s of %s", megabytes(offset), megabytes(stat.Size())))
+			size := stat.Size() - offset
+			if size > metaChunk {
+				size = metaChunk
+			}
+			if err := op.Upload(io.NewSectionReader(f, offset, size), remote+".gz.chunk", "0600"); err != nil {
+				return fmt.Errorf("upload %s, run the import again to resume: %w", options.File, err)
+			}
+			if err := op.Execute(fmt.Sprintf("cat %[1]s.gz.chunk >> %[1]s.gz.part && rm -f %[1]s.gz.chunk", remote)); err != nil {
+				return err
+			}
+			offset += size
+		}
+
+		task.Step("verifying")
+		if _, uploaded, err := remoteChecksum(op, "", remote+".gz.part"); err != nil || uploaded != sum {
+			op.Execute(fmt.Sprintf("rm -f %s.gz.part", remote))
+			return fmt.Errorf("the uploaded metadata does not match %s, run the import again", options.File)
+		}
+
+		task.Step("loading the metadata")
+		defer op.Execute(fmt.Sprintf("rm -f %[1]s %[1]s.gz.part", remote))
+		if out, err := op.Output(fmt.Sprintf("gunzip -c %[1]s.gz.part > %[1]s", remote)); err != nil {
+			return fmt.Errorf("gunzip %s: %w: %s", options.File, err, out)
+		}
+		command := m.weedClient(op, filerShellCommand(weedMasterAddresses(specification), filerAddresses(specification)[options.Filer], []string{"fs.meta.load " + remote}))
+		out, err = op.Output(command)
+		if err != nil {
+			return fmt.Errorf("fs.meta.load: %w: %s", err, out)
+		}
+		logging.Debug("fs.meta.load", "output", string(out))
+		return nil
+	})
+	task.Done(err)
+	if err != nil {
+		return err
+	}
+	info(fmt.Sprintf("loaded the metadata of %s into filer%d", options.File, options.Filer))
+	return nil
+}
+
+// remoteChecksum runs the command before, then returns the size and sha256 of
+// the remote file.
+func remoteChecksum(op operator.CommandOperator, before, file string) (int64, string, error) {
+	out, err := op.Output(fmt.Sprintf("%s stat -c %%s %[2]s && sha256sum %[2]s", before, file))
+	if err != nil {
+		return 0, "", err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return 0, "", fmt.Errorf("unexpected output %q", out)
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("unexpected size %q", fields[0])
+	}
+	return size, fields[1], nil
+}
+
+func fileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func megabytes(n int64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/infra/firewall"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+// ApplyFirewall opens the ports of the component instances on each host to the
+// other cluster nodes and the admin CIDRs only. Unless a single component is
+// applied, the rules added before for ports or sources no longer in the
+// cluster are removed.
+func (m *Manager) ApplyFirewall(specification *spec.Specification, adminCidrs []string, backendName string, dryRun bool) error {
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+	if !dryRun {
+		unlock, err := m.lock(specification, "firewall apply")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	hosts := m.clusterHosts(specification)
+	var sources []string
+	for _, h := range hosts {
+		sources = append(sources, h.ip)
+	}
+	sources = append(sources, adminCidrs...)
+
+	prune := m.ComponentToDeploy == ""
+	plan := &Plan{Operation: "firewall apply"}
+	for _, h := range hosts {
+		var rules []firewall.Rule
+		for _, instance := range h.instances {
+			if !m.shouldInstall(instance.component) {
+				continue
+			}
+			for _, port := range instance.ports {
+				rules = append(rules, firewall.Rule{Port: port, Sources: sources, Comment: "seaweed " + instance.name})
+			}
+		}
+		rules = firewall.MergeRules(rules)
+		if len(rules) == 0 && !prune {
+			continue
+		}
+
+		err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+			backend, err := m.firewallBackend(op, backendName)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				plan.Nodes = append(plan.Nodes, &NodePlan{Host: h.ip, Commands: backend.Commands(rules, m.confDir, prune)})
+				return nil
+			}
+			info(fmt.Sprintf("Firewall %s on %s", backend.Name(), h.ip))
+			for _, command := range backend.Commands(rules, m.confDir, prune) {
+				if err := m.sudo(op, command); err != nil {
+					return fmt.Errorf("%s: %w", command, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("apply firewall on %s: %w", h.address(), err)
+		}
+	}
+	if dryRun {
+		return printPlan(plan)
+	}
+	return nil
+}
+
+func (m *Manager) firewallBackend(op operator.CommandOperator, backendName string) (firewall.Backend, error) {
+	if backendName == "" || backendName == "auto" {
+		return firewall.Detect(op)
+	}
+	return firewall.NewBackend(backendName)
+}
@@ -0,0 +1,222 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/statecrypt"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Revision is a specification applied to the cluster.
+type Revision struct {
+	Revision  string    `json:"revision" yaml:"revision"` // hash of the content
+	AppliedAt time.Time `json:"appliedAt" yaml:"appliedAt"`
+	User      string    `json:"user" yaml:"user"`
+	Host      string    `json:"host" yaml:"host"`
+	Operation string    `json:"operation" yaml:"operation"`
+	Diff      string    `json:"diff,omitempty" yaml:"diff,omitempty"` // changes from the revision applied before
+	// archives of the remote configuration and metadata taken before and after
+	Snapshots []string `json:"snapshots,omitempty" yaml:"snapshots,omitempty"`
+}
+
+// HistoryOptions select what History shows.
+type HistoryOptions struct {
+	Diff     bool   // show the changes of each revision
+	Revision string // only show this revision, by a prefix of its hash
+}
+
+func (m *Manager) historyDir(specification *spec.Specification) string {
+	return filepath.Join(m.StateDir, "history", clusterKey(specification))
+}
+
+// recordRevision keeps the applied specification in the state dir, named by
+// its content, and logs who applied it with the snapshots taken around it.
+// With global.history.git_repo set, it is also committed to that repository.
+// Failures are only warned about, the cluster is deployed already.
+func (m *Manager) recordRevision(specification *spec.Specification, content []byte, operation string, snapshots []string) {
+	owner := newLockOwner(operation)
+	revision := &Revision{
+		Revision:  fmt.Sprintf("%x", sha256.Sum256(content))[:12],
+		AppliedAt: owner.Started,
+		User:      owner.User,
+		Host:      owner.Host,
+		Operation: operation,
+		Snapshots: snapshots,
+	}
+	if err := m.saveRevision(specification, revision, content); err != nil {
+		logging.Warn(fmt.Sprintf("can not record the applied configuration: %v", err))
+		return
+	}
+	if history := specification.GlobalOptions.History; history != nil && history.GitRepo != "" {
+		if err := commitRevision(history, revision, content); err != nil {
+			logging.Warn(fmt.Sprintf("can not commit the applied configuration to %s: %v", history.GitRepo, err))
+		}
+	}
+}
+
+func (m *Manager) saveRevision(specification *spec.Specification, revision *Revision, content []byte) error {
+	dir := m.historyDir(specification)
+	if err := os.MkdirAll(filepath.Join(dir, "revisions"), 0700); err != nil {
+		return err
+	}
+	file := filepath.Join(dir, "revisions", revision.Revision+".yaml")
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		if err := statecrypt.WriteFile(file, content, 0600); err != nil {
+			return err
+		}
+	}
+
+	revisions, err := m.loadRevisions(specification)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(append(revisions, revision), "", "  ")
+	if err != nil {
+		return err
+	}
+	temp := filepath.Join(dir, fmt.Sprintf("history.json.%d.tmp", os.Getpid()))
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, filepath.Join(dir, "history.json"))
+}
+
+// loadRevisions returns the applied revisions, the oldest first.
+func (m *Manager) loadRevisions(specification *spec.Specification) ([]*Revision, error) {
+	data, err := os.ReadFile(filepath.Join(m.historyDir(specification), "history.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	var revisions []*Revision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return nil, fmt.Errorf("parse history: %w", err)
+	}
+	return revisions, nil
+}
+
+func (m *Manager) revisionContent(specification *spec.Specification, revision string) (string, error) {
+	data, err := statecrypt.ReadFile(filepath.Join(m.historyDir(specification), "revisions", revision+".yaml"))
+	if err != nil {
+		return "", fmt.Errorf("read revision %s: %w", revision, err)
+	}
+	return string(data), nil
+}
+
+// commitRevision writes the revision to the git repository and commits it,
+// unless it is already the committed content.
+func commitRevision(history *spec.HistorySpec, revision *Revision, content []byte) error {
+	gitFile := utils.Nvl(history.GitFile, "cluster.yaml")
+	file := filepath.Join(history.GitRepo, filepath.FromSlash(gitFile))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		return err
+	}
+	if err := git(history.GitRepo, "add", "--", gitFile); err != nil {
+		return err
+	}
+	if git(history.GitRepo, "diff", "--cached", "--quiet", "--", gitFile) == nil {
+		return nil
+	}
+	message := fmt.Sprintf("%s revision %s by %s@%s", revision.Operation, revision.Revision, revision.User, revision.Host)
+	return git(history.GitRepo, "commit", "-q", "-m", message, "--", gitFile)
+}
+
+func git(repo string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// History prints the revisions of the specification applied to the cluster,
+// the latest first, with who applied them and optionally their changes.
+func (m *Manager) History(specification *spec.Specification, options HistoryOptions) error {
+	revisions, err := m.loadRevisions(specification)
+	if err != nil {
+		return err
+	}
+	var shown []*Revision
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := *revisions[i]
+		if options.Revision != "" && !strings.HasPrefix(revision.Revision, options.Revision) {
+			continue
+		}
+		if options.Diff || options.Revision != "" {
+			from, previous := "/dev/null", ""
+			if i > 0 {
+				from = "revision " + revisions[i-1].Revision
+				if previous, err = m.revisionContent(specification, revisions[i-1].Revision); err != nil {
+					return err
+				}
+			}
+			current, err := m.revisionContent(specification, revision.Revision)
+			if err != nil {
+				return err
+			}
+			revision.Diff = unifiedDiff(from, "revision "+revision.Revision, previous, current)
+		}
+		shown = append(shown, &revision)
+	}
+	if options.Revision != "" && len(shown) == 0 {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("there is no revision %s", options.Revision))
+	}
+
+	return output.Print(shown, func(w io.Writer) {
+		if len(shown) == 0 {
+			fmt.Fprintln(w, "no configuration applied yet, the revisions are recorded by deploy")
+			return
+		}
+		t := output.NewTable(w)
+		fmt.Fprintln(t, "REVISION\tAPPLIED\tBY\tOPERATION")
+		for _, revision := range shown {
+			fmt.Fprintf(t, "%s\t%s\t%s@%s\t%s\n", revision.Revision, revision.AppliedAt.Local().Format(time.RFC3339), revision.User, revision.Host, revision.Operation)
+		}
+		t.Flush()
+		for _, revision := range shown {
+			if !options.Diff && options.Revision == "" {
+				break
+			}
+			fmt.Fprintf(w, "\nrevision %s\n", revision.Revision)
+			for _, snapshot := range revision.Snapshots {
+				fmt.Fprintf(w, "  snapshot %s\n", snapshot)
+			}
+			if revision.Diff == "" {
+				fmt.Fprintln(w, "  no change")
+				continue
+			}
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(revision.Diff, "\n"), "\n") {
+				fmt.Fprintf(w, "  %s", line)
+			}
+			fmt.Fprintln(w)
+		}
+	})
+}
+
+// marshalRevision returns the specification as applied, before defaults are filled in.
+func marshalRevision(specification *spec.Specification) []byte {
+	content, err := yaml.Marshal(specification)
+	if err != nil {
+		logging.Debug("marshal revision", "error", err)
+	}
+	return content
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/progress"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"github.com/thanhpk/randstr"
+)
+
+// Subscribe adds a listener for the events of the tasks of deployments.
+func (m *Manager) Subscribe(listener progress.Listener) {
+	m.listeners = append(m.listeners, listener)
+}
+
+// hookTarget is the server a node hook runs for, or the zero value for cluster hooks.
+type hookTarget struct {
+	instance string
+	ip       string
+	portSsh  int
+	env      map[string]string // more variables for the hooks
+}
+
+// runHooks runs the hooks of an event one after the other, stopping at the first failure.
+func (m *Manager) runHooks(event string, hooks []*spec.HookSpec, target hookTarget) error {
+	env := map[string]string{
+		"SEAWEED_UP_HOOK":     event,
+		"SEAWEED_UP_HOST":     target.ip,
+		"SEAWEED_UP_INSTANCE": target.instance,
+	}
+	for name, value := range target.env {
+		env[name] = value
+	}
+	for i, hook := range hooks {
+		name := fmt.Sprintf("hook %s[%d]", event, i)
+		if target.instance != "" {
+			name += " of " + target.instance
+		}
+		info("Run " + name)
+		var err error
+		if hook.Remote {
+			err = m.executeRemote(utils.JoinHostPort(target.ip, target.portSsh), func(op operator.CommandOperator) error {
+				return runRemoteHook(op, hook, env)
+			})
+		} else {
+			err = runLocalHook(hook, env)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func runLocalHook(hook *spec.HookSpec, env map[string]string) error {
+	command := hook.Command
+	if hook.Script != "" {
+		command = shellQuote(hook.Script)
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = os.Environ()
+	for name, value := range env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	cmd.Stdout = output.Log()
+	cmd.Stderr = output.Errors()
+	return cmd.Run()
+}
+
+func runRemoteHook(op operator.CommandOperator, hook *spec.HookSpec, env map[string]string) error {
+	command := hook.Command
+	if hook.Script != "" {
+		target := fmt.Sprintf("/tmp/seaweed-up.%s.%s", randstr.String(6), path.Base(hook.Script))
+		if err := op.UploadFile(hook.Script, target, "0755"); err != nil {
+			return fmt.Errorf("upload %s: %w", hook.Script, err)
+		}
+		defer op.Execute("rm -f " + target)
+		command = target
+	}
+	var assignments []string
+	for name, value := range env {
+		assignments = append(assignments, name+"="+shellQuote(value))
+	}
+	return op.Execute(fmt.Sprintf("%s sh -c %s", strings.Join(assignments, " "), shellQuote(command)))
+}
@@ -0,0 +1,288 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// clusterHost is one machine of the cluster with the component instances placed on it.
+type clusterHost struct {
+	ip        string
+	portSsh   int
+	instances []*componentInstance
+}
+
+type componentInstance struct {
+	component   string
+	name        string
+	ports       []int
+	metricsPort int
+	location    string // data center and rack of volume servers and filers
+	dir         string // the mount point of mounts, which have no port
+}
+
+func (h *clusterHost) address() string {
+	return utils.JoinHostPort(h.ip, h.portSsh)
+}
+
+// instanceAddress returns the ip and first port of the instance, or the ip
+// and mount point of mounts, which only show it.
+func (h *clusterHost) instanceAddress(instance *componentInstance) string {
+	if len(instance.ports) == 0 {
+		return h.ip + " " + instance.dir
+	}
+	return utils.JoinHostPort(h.ip, instance.ports[0])
+}
+
+// grpcPort returns the gRPC port of the instance next to its http one, 0 if
+// it has none.
+func (instance *componentInstance) grpcPort() int {
+	switch instance.component {
+	case "master", "volume", "filer", "s3":
+		return instance.ports[1]
+	}
+	return 0
+}
+
+func (h *clusterHost) hasComponent(component string) bool {
+	for _, instance := range h.instances {
+		if instance.component == component {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterHosts groups all component instances of the specification by host,
+// keeping the order in which the hosts first appear.
+func (m *Manager) clusterHosts(specification *spec.Specification) []*clusterHost {
+	var hosts []*clusterHost
+	byAddress := make(map[string]*clusterHost)
+	add := func(ip string, portSsh int, instance *componentInstance) {
+		key := utils.JoinHostPort(ip, portSsh)
+		h, found := byAddress[key]
+		if !found {
+			h = &clusterHost{ip: ip, portSsh: portSsh}
+			byAddress[key] = h
+			hosts = append(hosts, h)
+		}
+		h.instances = append(h.instances, instance)
+	}
+
+	for index, masterSpec := range specification.MasterServers {
+		add(masterSpec.Ip, masterSpec.PortSsh, &componentInstance{
+			component:   "master",
+			name:        fmt.Sprintf("master%d", index),
+			ports:       masterSpec.ListenPorts(),
+			metricsPort: masterSpec.MetricsPort,
+		})
+	}
+	for index, volumeSpec := range specification.VolumeServers {
+		add(volumeSpec.Ip, volumeSpec.PortSsh, &componentInstance{
+			component:   "volume",
+			name:        fmt.Sprintf("volume%d", index),
+			ports:       volumeSpec.ListenPorts(),
+			metricsPort: volumeSpec.MetricsPort,
+			location:    joinLocation(volumeSpec.Location()),
+		})
+	}
+	for index, filerSpec := range specification.FilerServers {
+		add(filerSpec.Ip, filerSpec.PortSsh, &componentInstance{
+			component:   "filer",
+			name:        fmt.Sprintf("filer%d", index),
+			ports:       filerSpec.ListenPorts(),
+			metricsPort: filerSpec.MetricsPort,
+			location:    joinLocation(filerSpec.Location()),
+		})
+	}
+	for index, envoySpec := range specification.EnvoyServers {
+		add(envoySpec.Ip, envoySpec.PortSsh, &componentInstance{
+			component: "envoy",
+			name:      fmt.Sprintf("envoy%d", index),
+			ports:     envoySpec.ListenPorts(),
+		})
+	}
+	for index, s3Spec := range specification.S3Servers {
+		add(s3Spec.Ip, s3Spec.PortSsh, &componentInstance{
+			component:   "s3",
+			name:        fmt.Sprintf("s3%d", index),
+			ports:       s3Spec.ListenPorts(),
+			metricsPort: s3Spec.MetricsPort,
+		})
+	}
+	for index, webdavSpec := range specification.WebDAVServers {
+		add(webdavSpec.Ip, webdavSpec.PortSsh, &componentInstance{
+			component: "webdav",
+			name:      fmt.Sprintf("webdav%d", index),
+			ports:     webdavSpec.ListenPorts(),
+		})
+	}
+	for index, mountSpec := range specification.Mounts {
+		add(mountSpec.Ip, mountSpec.PortSsh, &componentInstance{
+			component: "mount",
+			name:      fmt.Sprintf("mount%d", index),
+			dir:       mountSpec.Dir,
+		})
+	}
+	for index, brokerSpec := range specification.MQBrokers {
+		add(brokerSpec.Ip, brokerSpec.PortSsh, &componentInstance{
+			component: "broker",
+			name:      fmt.Sprintf("broker%d", index),
+			ports:     brokerSpec.ListenPorts(),
+			location:  joinLocation(brokerSpec.Location()),
+		})
+	}
+	return hosts
+}
+
+func joinLocation(dataCenter, rack string) string {
+	return dataCenter + "/" + rack
+}
+
+// Hosts returns the ips of the hosts of the specification, in the order they
+// first appear.
+func (m *Manager) Hosts(specification *spec.Specification) []string {
+	var ips []string
+	seen := make(map[string]bool)
+	for _, h := range m.clusterHosts(specification) {
+		if !seen[h.ip] {
+			seen[h.ip] = true
+			ips = append(ips, h.ip)
+		}
+	}
+	return ips
+}
+
+func (m *Manager) executeOnHost(h *clusterHost, callback operator.Callback) error {
+	return m.executeRemote(h.address(), callback)
+}
+
+// executeRemote runs the callback on the host at address, ip:ssh port, logged
+// in with its SSH settings. Transient connection failures are retried, and a
+// host failing too often in a row is skipped, see spec.RetrySpec.
+func (m *Manager) executeRemote(address string, callback operator.Callback) error {
+	threshold := m.circuitBreaker()
+	if err := m.failures.check(address, threshold); err != nil {
+		return err
+	}
+	err := m.retry("connect", address, func() error {
+		connected := false
+		err := operator.ExecuteRemoteWith(address, m.remoteOptions(address), func(op operator.CommandOperator) error {
+			connected = true
+			return callback(hostOperator{op, address})
+		})
+		if connected && err != nil {
+			// the callback may have changed the host already, deploy retries it as a whole
+			return &callbackError{err}
+		}
+		return err
+	})
+	var failed *callbackError
+	if errors.As(err, &failed) {
+		err = failed.err
+	}
+	m.failures.record(address, threshold, err)
+	return err
+}
+
+// callbackError keeps the errors of callbacks from being retried as connection failures.
+type callbackError struct {
+	err error
+}
+
+func (e *callbackError) Error() string {
+	return e.err.Error()
+}
+
+// remoteOptions are the login settings of the host at address: the ssh
+// section of its servers, global.ssh, then the flags of the command.
+func (m *Manager) remoteOptions(address string) operator.RemoteOptions {
+	options := operator.RemoteOptions{User: m.User, PrivateKey: m.IdentityFile, Password: m.sudoPass}
+	ssh, found := m.sshHosts[address]
+	if !found {
+		return options
+	}
+	options.User = ssh.User
+	options.ProxyJump = ssh.ProxyJump
+	options.ConnectTimeout = ssh.Timeout()
+	if ssh.IdentityFile != "" {
+		options.PrivateKey, options.Password = ssh.IdentityFile, ""
+	}
+	if ssh.PasswordEnv != "" {
+		options.Password = os.Getenv(ssh.PasswordEnv)
+		redact.Secret(options.Password)
+	}
+	return options
+}
+
+// prepareSsh resolves the SSH settings of each host. Of servers sharing a
+// host, the settings of the first one given win.
+func (m *Manager) prepareSsh(specification *spec.Specification) {
+	m.sshHosts = make(map[string]*spec.SshSpec)
+	add := func(ip string, portSsh int, ssh *spec.SshSpec) {
+		address := utils.JoinHostPort(ip, portSsh)
+		m.sshHosts[address] = m.sshHosts[address].Merge(ssh)
+	}
+	for _, masterSpec := range specification.MasterServers {
+		add(masterSpec.Ip, masterSpec.PortSsh, masterSpec.Ssh)
+	}
+	for _, volumeSpec := range specification.VolumeServers {
+		add(volumeSpec.Ip, volumeSpec.PortSsh, volumeSpec.Ssh)
+	}
+	for _, filerSpec := range specification.FilerServers {
+		add(filerSpec.Ip, filerSpec.PortSsh, filerSpec.Ssh)
+	}
+	for _, envoySpec := range specification.EnvoyServers {
+		add(envoySpec.Ip, envoySpec.PortSsh, envoySpec.Ssh)
+	}
+	for _, s3Spec := range specification.S3Servers {
+		add(s3Spec.Ip, s3Spec.PortSsh, s3Spec.Ssh)
+	}
+	for _, webdavSpec := range specification.WebDAVServers {
+		add(webdavSpec.Ip, webdavSpec.PortSsh, webdavSpec.Ssh)
+	}
+	for _, mountSpec := range specification.Mounts {
+		add(mountSpec.Ip, mountSpec.PortSsh, mountSpec.Ssh)
+	}
+	for _, brokerSpec := range specification.MQBrokers {
+		add(brokerSpec.Ip, brokerSpec.PortSsh, brokerSpec.Ssh)
+	}
+	m.elevations = make(map[string]*hostElevation)
+	for address, ssh := range m.sshHosts {
+		ssh = ssh.Merge(specification.GlobalOptions.Ssh).Merge(&spec.SshSpec{User: m.User, Elevation: specification.GlobalOptions.Elevation})
+		m.sshHosts[address] = ssh
+		// root needs no elevation
+		method := utils.Nvl(ssh.Elevation.Method, "sudo")
+		if ssh.User == "root" {
+			method = "none"
+		}
+		m.elevations[address] = &hostElevation{method: method, password: m.passwords[passwordSource(ssh.Elevation)], spec: ssh.Elevation}
+	}
+	// keep the passwords prepare obtained when switching between specifications
+	m.sudoPass = m.globalPassword(specification)
+}
+
+// globalPassword returns the password of the global elevation, also the SSH
+// password, once prepare obtained it.
+func (m *Manager) globalPassword(specification *spec.Specification) string {
+	return m.passwords[passwordSource(specification.GlobalOptions.Elevation.Merge(nil))]
+}
+
+// passwordSource tells where the sudo password of the elevation is read from,
+// hosts with the same source share the password.
+func passwordSource(elevation *spec.ElevationSpec) string {
+	return elevation.PasswordCommand + "\x00" + utils.Nvl(elevation.PasswordEnv, "SSH_TARGET_SUDO_PASS")
+}
+
+func defaultPort(port, defaultValue int) int {
+	if port == 0 {
+		return defaultValue
+	}
+	return port
+}
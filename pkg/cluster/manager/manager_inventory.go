@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/seaweedfs/seaweed-up/pkg/disks"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+var dataMountPoint = regexp.MustCompile(`^/data([0-9]+)$`)
+
+// DataFolders suggests the volume folders of a host reached at address as
+// user: the /dataN mount points in use plus the ones deploy would mount its
+// unused disks on. It needs no sudo.
+func (m *Manager) DataFolders(address, user string) (folders []string, err error) {
+	err = operator.ExecuteRemote(address, user, m.IdentityFile, "", func(op operator.CommandOperator) error {
+		devices, err := disks.Discover(op, m.DiskDiscovery, []string{"/dev/sd", "/dev/nvme"})
+		if err != nil {
+			return fmt.Errorf("list device: %w", err)
+		}
+		mountpoints, err := disks.MountPoints(op)
+		if err != nil {
+			return fmt.Errorf("list mount points: %w", err)
+		}
+		var numbers []int
+		for mountpoint := range mountpoints {
+			if match := dataMountPoint.FindStringSubmatch(mountpoint); match != nil {
+				n, _ := strconv.Atoi(match[1])
+				numbers = append(numbers, n)
+			}
+		}
+		sort.Ints(numbers)
+		for _, n := range numbers {
+			folders = append(folders, fmt.Sprintf("/data%d", n))
+		}
+		for range unusedDisks(devices) {
+			if mountpoint := freeMountPoint(mountpoints); mountpoint != "" {
+				folders = append(folders, mountpoint)
+			}
+		}
+		return nil
+	})
+	return
+}
@@ -0,0 +1,193 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// lockOwner describes who holds the lock of a cluster.
+type lockOwner struct {
+	Operation string    `json:"operation"`
+	User      string    `json:"user"`
+	Host      string    `json:"host"`
+	Pid       int       `json:"pid"`
+	Started   time.Time `json:"started"`
+}
+
+func (o *lockOwner) String() string {
+	return fmt.Sprintf("%s by %s@%s (pid %d) since %s", o.Operation, o.User, o.Host, o.Pid, o.Started.Format(time.RFC3339))
+}
+
+func newLockOwner(operation string) *lockOwner {
+	o := &lockOwner{Operation: operation, User: "unknown", Pid: os.Getpid(), Started: time.Now().UTC()}
+	if u, err := user.Current(); err == nil {
+		o.User = u.Username
+	}
+	o.Host, _ = os.Hostname()
+	return o
+}
+
+// alive tells if the owner is a running process of this machine, or may be one of another machine.
+func (o *lockOwner) alive() bool {
+	if hostname, _ := os.Hostname(); o.Host != hostname {
+		return true
+	}
+	p, err := os.FindProcess(o.Pid)
+	return err == nil && p.Signal(syscall.Signal(0)) == nil
+}
+
+// lock makes sure no other operation changes the cluster at the same time. It
+// takes a lock file in the local state dir, against operations started from
+// this machine, and a lock directory on the first master, against operations
+// started by other operators. The returned function releases both.
+func (m *Manager) lock(specification *spec.Specification, operation string) (unlock func(), err error) {
+	owner := newLockOwner(operation)
+	localFile := m.localLockFile(specification)
+	if err := acquireLocalLock(localFile, owner); err != nil {
+		return nil, err
+	}
+
+	masterAddress, remoteDir := m.remoteLock(specification)
+	data, _ := json.Marshal(owner)
+	var holder string
+	err = m.executeRemote(masterAddress, func(op operator.CommandOperator) error {
+		out, err := m.sudoOutput(op, fmt.Sprintf("mkdir -p %s && if mkdir %s 2>/dev/null; then printf '%%s' %s > %s/owner && echo locked; else cat %s/owner 2>/dev/null; fi",
+			path.Dir(remoteDir), remoteDir, shellQuote(string(data)), remoteDir, remoteDir))
+		if err != nil {
+			return err
+		}
+		if result := strings.TrimSpace(string(out)); result != "locked" {
+			holder = result
+		}
+		return nil
+	})
+	if err != nil {
+		logging.Warn(fmt.Sprintf("can not lock the cluster on master %s, only locked on this machine: %v", masterAddress, err))
+		return func() { os.Remove(localFile) }, nil
+	}
+	if holder != "" {
+		os.Remove(localFile)
+		return nil, fmt.Errorf("the cluster is locked by %s, if it is not running anymore remove the lock with: seaweed-up cluster unlock --force", describeOwner(holder))
+	}
+
+	return func() {
+		err := m.executeRemote(masterAddress, func(op operator.CommandOperator) error {
+			_, err := m.sudoOutput(op, "rm -rf "+remoteDir)
+			return err
+		})
+		if err != nil {
+			logging.Warn(fmt.Sprintf("can not remove the lock %s on master %s: %v", remoteDir, masterAddress, err))
+		}
+		os.Remove(localFile)
+	}, nil
+}
+
+// Unlock shows who holds the locks of the cluster, and removes them if force is set.
+func (m *Manager) Unlock(specification *spec.Specification, force bool) error {
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+	var held []string
+
+	localFile := m.localLockFile(specification)
+	if data, err := os.ReadFile(localFile); err == nil {
+		info(fmt.Sprintf("Locked on this machine by %s", describeOwner(string(data))))
+		held = append(held, localFile)
+	}
+
+	masterAddress, remoteDir := m.remoteLock(specification)
+	err := m.executeRemote(masterAddress, func(op operator.CommandOperator) error {
+		out, err := m.sudoOutput(op, fmt.Sprintf("if [ -d %s ]; then cat %s/owner 2>/dev/null; echo; fi", remoteDir, remoteDir))
+		if err != nil {
+			return err
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		info(fmt.Sprintf("Locked on master %s by %s", masterAddress, describeOwner(strings.TrimSpace(string(out)))))
+		held = append(held, masterAddress+":"+remoteDir)
+		if force {
+			_, err = m.sudoOutput(op, "rm -rf "+remoteDir)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("check the lock on master %s: %w", masterAddress, err)
+	}
+
+	if len(held) == 0 {
+		info("The cluster is not locked")
+		return nil
+	}
+	if !force {
+		return fmt.Errorf("the cluster is locked, make sure the operation is not running anymore and remove the locks with --force")
+	}
+	if err := os.Remove(localFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	info("Removed the locks of the cluster")
+	return nil
+}
+
+func (m *Manager) localLockFile(specification *spec.Specification) string {
+	return filepath.Join(m.StateDir, "locks", clusterKey(specification)+".json")
+}
+
+// remoteLock returns the ssh address of the first master and the lock directory on it.
+func (m *Manager) remoteLock(specification *spec.Specification) (address, dir string) {
+	masterSpec := specification.MasterServers[0]
+	return utils.JoinHostPort(masterSpec.Ip, masterSpec.PortSsh), path.Join(m.dataDir, ".seaweed-up.lock")
+}
+
+// acquireLocalLock creates the lock file, replacing it if its owner process died.
+func acquireLocalLock(file string, owner *lockOwner) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	data, _ := json.Marshal(owner)
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = f.Write(data)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("create lock file: %w", err)
+		}
+		existing, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read lock file: %w", err)
+		}
+		previous := &lockOwner{}
+		if json.Unmarshal(existing, previous) == nil && previous.alive() {
+			return fmt.Errorf("the cluster is locked by %s, if it is not running anymore remove the lock with: seaweed-up cluster unlock --force", previous)
+		}
+		logging.Warn(fmt.Sprintf("removing the stale lock %s", file))
+		os.Remove(file)
+	}
+	return fmt.Errorf("can not lock %s", file)
+}
+
+func describeOwner(data string) string {
+	owner := &lockOwner{}
+	if err := json.Unmarshal([]byte(data), owner); err != nil || owner.Operation == "" {
+		return "an unknown operation"
+	}
+	return owner.String()
+}
@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+// LogOptions select the log lines shown by Logs.
+type LogOptions struct {
+	Since   time.Duration // only lines newer than this, all lines if zero
+	Lines   int           // number of recent lines when Since is zero
+	Follow  bool          // keep printing new lines
+	Journal bool          // read the systemd journal instead of the log files
+	JSON    bool          // print one json object per line
+}
+
+// Logs prints the logs of the component instances on all hosts, each line
+// prefixed with its host and instance.
+func (m *Manager) Logs(specification *spec.Specification, options LogOptions) error {
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []string
+	for _, h := range m.clusterHosts(specification) {
+		for _, instance := range h.instances {
+			if !m.shouldInstall(instance.component) {
+				continue
+			}
+			wg.Add(1)
+			go func(h *clusterHost, instance *componentInstance) {
+				defer wg.Done()
+				w := &logWriter{mu: &mu, host: h.ip, instance: instance.name, json: options.JSON}
+				err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+					return op.Stream(m.sudoCommand(op, m.logCommand(instance, options)), w)
+				})
+				w.flush()
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Sprintf("%s %s: %v", h.ip, instance.name, err))
+					mu.Unlock()
+				}
+			}(h, instance)
+		}
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return fmt.Errorf("read logs: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (m *Manager) logCommand(instance *componentInstance, options LogOptions) string {
+	if options.Journal {
+		cmd := fmt.Sprintf("journalctl -u seaweed_%s --no-pager -o short-iso", instance.name)
+		if options.Since > 0 {
+			cmd += fmt.Sprintf(" --since=-%ds", int(options.Since.Seconds()))
+		} else {
+			cmd += fmt.Sprintf(" -n %d", options.Lines)
+		}
+		if options.Follow {
+			cmd += " -f"
+		}
+		return cmd
+	}
+
+	// weed logs with glog, lines start like I1017 12:34:56.789, envoy like [2024-10-17 12:34:56.789]
+	logFile := path.Join(m.dataDir, instance.name, "weed.INFO")
+	timeFormat, timeWidth := "%m%d %H:%M:%S", 13
+	if instance.component == "envoy" {
+		logFile = path.Join(m.dataDir, instance.name, "envoy.log")
+		timeFormat, timeWidth = "%Y-%m-%d %H:%M:%S", 19
+	}
+	follow := ""
+	if options.Follow {
+		follow = " -F"
+	}
+	if options.Since <= 0 {
+		return fmt.Sprintf("tail -n %d%s %s", options.Lines, follow, logFile)
+	}
+	// print from the first line newer than the threshold, continuation lines included
+	return fmt.Sprintf(`t=$(date -d "@$(( $(date +%%s) - %d ))" "+%s"); tail -n +1%s %s | awk -v t="$t" 'p || substr($0, 2, %d) >= t {p=1; print; fflush()}'`,
+		int(options.Since.Seconds()), timeFormat, follow, logFile, timeWidth)
+}
+
+// logWriter prints the complete lines written to it with the host and instance they come from.
+type logWriter struct {
+	mu       *sync.Mutex
+	host     string
+	instance string
+	json     bool
+	partial  []byte
+}
+
+type logLine struct {
+	Host     string `json:"host"`
+	Instance string `json:"instance"`
+	Line     string `json:"line"`
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.print(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *logWriter) flush() {
+	if len(w.partial) > 0 {
+		w.print(string(w.partial))
+		w.partial = nil
+	}
+}
+
+func (w *logWriter) print(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.json {
+		data, _ := json.Marshal(logLine{Host: w.host, Instance: w.instance, Line: line})
+		fmt.Fprintln(os.Stdout, string(data))
+		return
+	}
+	fmt.Fprintf(os.Stdout, "%s %s | %s\n", w.host, w.instance, line)
+}
@@ -0,0 +1,221 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// Maintenance is a host taken out of the operations of seaweed-up, e.g. for
+// OS patching. Deploy, exec and balance skip it and doctor does not check it.
+type Maintenance struct {
+	Host   string    `json:"host" yaml:"host"`
+	Reason string    `json:"reason,omitempty" yaml:"reason,omitempty"`
+	User   string    `json:"user" yaml:"user"`
+	Since  time.Time `json:"since" yaml:"since"`
+}
+
+func (m *Manager) maintenanceFile(specification *spec.Specification) string {
+	return filepath.Join(m.StateDir, "maintenance", clusterKey(specification)+".json")
+}
+
+// loadMaintenance returns the hosts in maintenance by ip.
+func (m *Manager) loadMaintenance(specification *spec.Specification) (map[string]*Maintenance, error) {
+	hosts := make(map[string]*Maintenance)
+	data, err := os.ReadFile(m.maintenanceFile(specification))
+	if os.IsNotExist(err) {
+		return hosts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read maintenance state: %w", err)
+	}
+	var entries []*Maintenance
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", m.maintenanceFile(specification), err)
+	}
+	for _, entry := range entries {
+		hosts[entry.Host] = entry
+	}
+	return hosts, nil
+}
+
+func (m *Manager) saveMaintenance(specification *spec.Specification, hosts map[string]*Maintenance) error {
+	entries := []*Maintenance{}
+	for _, entry := range hosts {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Host < entries[j].Host })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := m.maintenanceFile(specification)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+	temp := file + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return fmt.Errorf("write maintenance state: %w", err)
+	}
+	return os.Rename(temp, file)
+}
+
+// hostsInMaintenance is loadMaintenance for operations that go on without
+// it if the state can not be read.
+func (m *Manager) hostsInMaintenance(specification *spec.Specification) map[string]*Maintenance {
+	hosts, err := m.loadMaintenance(specification)
+	if err != nil {
+		logging.Warn(fmt.Sprintf("%v, no host is considered in maintenance", err))
+		return map[string]*Maintenance{}
+	}
+	return hosts
+}
+
+// EnableMaintenance puts the host in maintenance. Unless drain is false, the
+// volumes of its volume servers are first moved to the other volume servers.
+func (m *Manager) EnableMaintenance(specification *spec.Specification, host, reason string, drain bool) error {
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+	h := m.findHost(specification, host)
+	if h == nil {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s is not a host of the configuration", host))
+	}
+	unlock, err := m.lock(specification, "maintenance enable")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	hosts, err := m.loadMaintenance(specification)
+	if err != nil {
+		return err
+	}
+	if entry, found := hosts[h.ip]; found {
+		info(fmt.Sprintf("%s is in maintenance since %s", h.ip, entry.Since.Format(time.RFC3339)))
+		return nil
+	}
+	if err := m.enterMaintenance(specification, h, hosts, reason, drain); err != nil {
+		return err
+	}
+	info(fmt.Sprintf("%s is in maintenance, deploy, exec, balance and doctor skip it", h.ip))
+	return nil
+}
+
+// enterMaintenance drains the host if asked and adds it to the hosts in
+// maintenance, for callers holding the cluster lock.
+func (m *Manager) enterMaintenance(specification *spec.Specification, h *clusterHost, hosts map[string]*Maintenance, reason string, drain bool) error {
+	if drain {
+		masters := weedMasterAddresses(specification)
+		var commands []string
+		for index, volumeSpec := range specification.VolumeServers {
+			if volumeSpec.Ip == h.ip && volumeSpec.PortSsh == h.portSsh {
+				info(fmt.Sprintf("Draining volume%d", index))
+				commands = append(commands, fmt.Sprintf("volumeServer.evacuate -node %s -force", utils.JoinHostPort(volumeSpec.Ip, defaultPort(volumeSpec.Port, 8080))))
+			}
+		}
+		if len(commands) > 0 {
+			err := m.onMasterHost(specification, func(op operator.CommandOperator, address string) error {
+				return m.weedShell(op, masters, commands)
+			})
+			if err != nil {
+				return fmt.Errorf("drain %s: %w, enable maintenance with --drain=false to skip it", h.ip, err)
+			}
+		}
+	}
+
+	entry := &Maintenance{Host: h.ip, Reason: reason, User: "unknown", Since: time.Now().UTC()}
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+	hosts[h.ip] = entry
+	return m.saveMaintenance(specification, hosts)
+}
+
+// DisableMaintenance takes the host back into operations.
+func (m *Manager) DisableMaintenance(specification *spec.Specification, host string) error {
+	m.prepareSpecification(specification)
+	hosts, err := m.loadMaintenance(specification)
+	if err != nil {
+		return err
+	}
+	if h := m.findHost(specification, host); h != nil {
+		host = h.ip
+	}
+	if _, found := hosts[host]; !found {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s is not in maintenance", host))
+	}
+	delete(hosts, host)
+	if err := m.saveMaintenance(specification, hosts); err != nil {
+		return err
+	}
+	info(fmt.Sprintf("%s is back in operations", host))
+	if h := m.findHost(specification, host); h != nil && h.hasComponent("volume") {
+		info("move volumes back to it with: seaweed-up cluster balance")
+	}
+	return nil
+}
+
+// ListMaintenance prints the hosts in maintenance.
+func (m *Manager) ListMaintenance(specification *spec.Specification) error {
+	m.prepareSpecification(specification)
+	hosts, err := m.loadMaintenance(specification)
+	if err != nil {
+		return err
+	}
+	entries := []*Maintenance{}
+	for _, entry := range hosts {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Host < entries[j].Host })
+	return output.Print(entries, func(w io.Writer) {
+		if len(entries) == 0 {
+			fmt.Fprintln(w, "no host is in maintenance")
+			return
+		}
+		t := output.NewTable(w)
+		fmt.Fprintln(t, "HOST\tSINCE\tUSER\tREASON")
+		for _, entry := range entries {
+			fmt.Fprintf(t, "%s\t%s\t%s\t%s\n", entry.Host, entry.Since.Local().Format(time.RFC3339), entry.User, entry.Reason)
+		}
+		t.Flush()
+	})
+}
+
+// findHost returns the host with the ip, or ip:ssh port.
+func (m *Manager) findHost(specification *spec.Specification, host string) *clusterHost {
+	for _, h := range m.clusterHosts(specification) {
+		if h.ip == host || h.address() == host {
+			return h
+		}
+	}
+	return nil
+}
+
+// skipInMaintenance warns about and leaves out the hosts in maintenance.
+func skipInMaintenance(hosts []*clusterHost, maintenance map[string]*Maintenance) (kept []*clusterHost) {
+	var skipped []string
+	for _, h := range hosts {
+		if _, found := maintenance[h.ip]; found {
+			skipped = append(skipped, h.ip)
+			continue
+		}
+		kept = append(kept, h)
+	}
+	if len(skipped) > 0 {
+		logging.Warn(fmt.Sprintf("skipping hosts in maintenance: %s", strings.Join(skipped, ", ")))
+	}
+	return
+}
@@ -0,0 +1,625 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/progress"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"github.com/thanhpk/randstr"
+)
+
+// MigrationOptions describe a migration of the files under Prefix from the
+// source cluster to the target cluster.
+type MigrationOptions struct {
+	Prefix string        // filer directory migrated, / if empty
+	Force  bool          // cut over even if the target is behind or the sync is not running
+	Drain  time.Duration // how long filer.sync still runs after the cutover
+}
+
+// migration is kept in the state dir while the files are copied.
+type migration struct {
+	Source    string     `json:"source"` // filer copied from
+	Target    string     `json:"target"` // filer copied to
+	Prefix    string     `json:"prefix"`
+	Host      string     `json:"host"` // running filer.sync
+	Service   string     `json:"service"`
+	StartedAt time.Time  `json:"startedAt"`
+	CutoverAt *time.Time `json:"cutoverAt,omitempty"`
+}
+
+// DirUsage is what fs.du reports for a filer directory.
+type DirUsage struct {
+	Chunks int64 `json:"chunks"`
+	Bytes  int64 `json:"bytes"`
+}
+
+// MigrationProgress compares the usage of the migrated directory on both
+// clusters, and tells how recent the last change copied is.
+type MigrationProgress struct {
+	Source    string     `json:"source"`
+	Target    string     `json:"target"`
+	Prefix    string     `json:"prefix"`
+	Host      string     `json:"host"`
+	Service   string     `json:"service"` // active, inactive, failed or not-installed
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	CutoverAt *time.Time `json:"cutoverAt,omitempty"`
+	SyncedTo  *time.Time `json:"syncedTo,omitempty"` // time of the last change copied
+	Lag       string     `json:"lag,omitempty"`
+	Copied    DirUsage   `json:"copied"`    // on the target
+	Total     DirUsage   `json:"total"`     // on the source
+	Remaining int64      `json:"remaining"` // bytes
+	Percent   float64    `json:"percent"`
+}
+
+// FileMismatch is a sampled file that differs on the target.
+type FileMismatch struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// MigrationVerification is the result of comparing sampled files.
+type MigrationVerification struct {
+	Prefix     string          `json:"prefix"`
+	Listed     int             `json:"listed"`  // files listed on the source to sample from
+	Checked    int             `json:"checked"` // sampled files still on the source
+	Matched    int             `json:"matched"`
+	Mismatches []*FileMismatch `json:"mismatches"`
+}
+
+// verifyListLimit bounds the files listed per directory and verifyDirs the
+// directories walked, so sampling a large tree stays quick.
+const (
+	verifyListLimit = 1000
+	verifyDirs      = 100
+)
+
+var (
+	fsDuPattern       = regexp.MustCompile(`block:\s*(\d+)\s+(?:byte|logical size):\s*(\d+)`)
+	progressedPattern = regexp.MustCompile(`progressed to (\d{4}-\d\d-\d\d \d\d:\d\d:\d\d(?:\.\d+)? [+-]\d{4} \w+)`)
+)
+
+func migrationKey(source, target *spec.Specification, prefix string) string {
+	return hash(clusterKey(source), clusterKey(target), prefix)
+}
+
+func (m *Manager) migrationFile(key string) string {
+	return filepath.Join(m.StateDir, "migrations", key+".json")
+}
+
+func (m *Manager) loadMigration(key string) *migration {
+	data, err := os.ReadFile(m.migrationFile(key))
+	if err != nil {
+		return nil
+	}
+	state := &migration{}
+	if json.Unmarshal(data, state) != nil {
+		return nil
+	}
+	return state
+}
+
+func (m *Manager) saveMigration(key string, state *migration) error {
+	file := m.migrationFile(key)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(state, "", "  ")
+	return os.WriteFile(file, data, 0600)
+}
+
+// prepareMigration checks the clusters and returns the migrated prefix, the
+// key of the migration and the source filer running filer.sync. The sudo
+// passwords of both clusters are obtained once. The SSH and elevation
+// settings are the ones of the source, prepareSpecification switches to the
+// ones of the target before running commands on its hosts.
+func (m *Manager) prepareMigration(source, target *spec.Specification, options MigrationOptions) (string, string, *spec.FilerServerSpec, error) {
+	if err := m.prepare(target); err != nil {
+		return "", "", nil, err
+	}
+	if err := m.prepare(source); err != nil {
+		return "", "", nil, err
+	}
+	prefix := path.Clean(utils.Nvl(options.Prefix, "/"))
+	if !strings.HasPrefix(prefix, "/") {
+		return "", "", nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("--prefix must be an absolute filer path, got %q", options.Prefix))
+	}
+	if len(source.FilerServers) == 0 || len(target.FilerServers) == 0 {
+		return "", "", nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("both clusters need a filer server"))
+	}
+	if clusterKey(source) == clusterKey(target) {
+		return "", "", nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("the source and target are the same cluster"))
+	}
+	return prefix, migrationKey(source, target, prefix), source.FilerServers[0], nil
+}
+
+// lockMigrated locks one of the clusters of a migration, with its SSH
+// settings, also when released.
+func (m *Manager) lockMigrated(specification *spec.Specification, operation string) (unlock func(), err error) {
+	m.prepareSpecification(specification)
+	unlockCluster, err := m.lock(specification, operation)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		m.prepareSpecification(specification)
+		unlockCluster()
+	}, nil
+}
+
+// sharedVirtualIp tells if both clusters use the same virtual IP, which the
+// cutover moves from the source servers to the target servers.
+func sharedVirtualIp(source, target *spec.Specification) bool {
+	return source.HighAvailability != nil && target.HighAvailability != nil &&
+		source.HighAvailability.Address() == target.HighAvailability.Address()
+}
+
+// StartMigration runs weed filer.sync one way from the first source filer to
+// the first target filer, as a systemd service on the source filer host. It
+// copies the files under the prefix then follows their changes until the
+// cutover. When both clusters share a virtual IP, keepalived is stopped on the
+// target servers so the target does not take it before the cutover.
+func (m *Manager) StartMigration(source, target *spec.Specification, options MigrationOptions) error {
+	prefix, key, filerSpec, err := m.prepareMigration(source, target, options)
+	if err != nil {
+		return err
+	}
+	if state := m.loadMigration(key); state != nil && state.CutoverAt != nil {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s was already cut over to %s at %s", prefix, state.Target, state.CutoverAt.Local().Format(time.RFC3339)))
+	}
+	unlock, err := m.lockMigrated(target, "migrate")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	state := &migration{
+		Source:    filerAddresses(source)[0],
+		Target:    filerAddresses(target)[0],
+		Prefix:    prefix,
+		Host:      utils.JoinHostPort(filerSpec.Ip, filerSpec.PortSsh),
+		Service:   "seaweed_migrate_" + key[:8],
+		StartedAt: time.Now().UTC(),
+	}
+	if previous := m.loadMigration(key); previous != nil {
+		state.StartedAt = previous.StartedAt
+	}
+
+	tracker := progress.New("Starting the migration of " + prefix)
+	defer tracker.Stop()
+	if sharedVirtualIp(source, target) {
+		m.prepareSpecification(target)
+		servers, err := haServers(target)
+		if err != nil {
+			return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("target: %w", err))
+		}
+		for _, server := range servers {
+			task := tracker.Add("keepalived " + server.ip)
+			task.Step("stopping until the cutover")
+			err := m.executeRemote(utils.JoinHostPort(server.ip, server.portSsh), func(op operator.CommandOperator) error {
+				_, err := m.sudoOutput(op, "if systemctl is-enabled --quiet keepalived || systemctl is-active --quiet keepalived; then systemctl disable --now keepalived; fi")
+				return err
+			})
+			task.Done(err)
+			if err != nil {
+				return fmt.Errorf("stop keepalived on %s: %w", server.ip, err)
+			}
+		}
+	}
+
+	m.prepareSpecification(source)
+	task := tracker.Add(fmt.Sprintf("filer.sync %s", filerSpec.Ip))
+	err = m.executeRemote(state.Host, func(op operator.CommandOperator) error {
+		task.Step("installing " + state.Service)
+		// filer.sync reads the security.toml of the source filer host in its working directory
+		var workingDirectory string
+		if dir, found := m.clientDirs[state.Host]; found && m.security.TLS() {
+			workingDirectory = "WorkingDirectory=" + dir + "\n"
+		}
+		unit := fmt.Sprintf(`[Unit]
+Description=SeaweedFS migration of %[1]s from %[2]s to %[3]s
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+%[4]sExecStart=/usr/local/bin/weed -logtostderr=true filer.sync -a=%[2]s -b=%[3]s -a.path=%[1]s -b.path=%[1]s -isActivePassive
+Restart=always
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+`, prefix, state.Source, state.Target, workingDirectory)
+		temp := fmt.Sprintf("/tmp/seaweed-up.%s.service", randstr.String(6))
+		defer op.Execute("rm -f " + temp)
+		if err := op.Upload(strings.NewReader(unit), temp, "0644"); err != nil {
+			return fmt.Errorf("upload unit: %w", err)
+		}
+		_, err := m.sudoOutput(op, fmt.Sprintf("install -m 0644 %[1]s /etc/systemd/system/%[2]s.service && systemctl daemon-reload && systemctl enable %[2]s && systemctl restart %[2]s", temp, state.Service))
+		return err
+	})
+	task.Done(err)
+	if err != nil {
+		return err
+	}
+	if err := m.saveMigration(key, state); err != nil {
+		return err
+	}
+	info(fmt.Sprintf("copying %s from %s to %s, follow it with seaweed-up migrate status", prefix, state.Source, state.Target))
+	return nil
+}
+
+// MigrationStatus reports the state of filer.sync, the last change it copied,
+// and the usage of the prefix on both clusters.
+func (m *Manager) MigrationStatus(source, target *spec.Specification, options MigrationOptions) (*MigrationProgress, error) {
+	prefix, key, filerSpec, err := m.prepareMigration(source, target, options)
+	if err != nil {
+		return nil, err
+	}
+	p := &MigrationProgress{
+		Source:  filerAddresses(source)[0],
+		Target:  filerAddresses(target)[0],
+		Prefix:  prefix,
+		Host:    utils.JoinHostPort(filerSpec.Ip, filerSpec.PortSsh),
+		Service: "not-installed",
+	}
+	service := "seaweed_migrate_" + key[:8]
+	if state := m.loadMigration(key); state != nil {
+		p.StartedAt, p.CutoverAt = &state.StartedAt, state.CutoverAt
+	}
+
+	err = m.executeRemote(p.Host, func(op operator.CommandOperator) error {
+		p.Service = commandOutput(op, fmt.Sprintf("if [ -f /etc/systemd/system/%[1]s.service ]; then systemctl is-active %[1]s || true; else echo not-installed; fi", service))
+		if p.Service != "not-installed" {
+			out, _ := m.sudoOutput(op, fmt.Sprintf("journalctl -u %s --no-pager -o cat -n 1000 | grep 'progressed to' | tail -n 1 || true", service))
+			if match := progressedPattern.FindStringSubmatch(string(out)); match != nil {
+				if t, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", match[1]); err == nil {
+					p.SyncedTo = &t
+					p.Lag = time.Since(t).Round(time.Second).String()
+				}
+			}
+		}
+		p.Total, err = m.dirUsage(op, source, prefix)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	m.prepareSpecification(target)
+	targetFiler := target.FilerServers[0]
+	err = m.executeRemote(utils.JoinHostPort(targetFiler.Ip, targetFiler.PortSsh), func(op operator.CommandOperator) error {
+		p.Copied, err = m.dirUsage(op, target, prefix)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+	if p.Remaining = p.Total.Bytes - p.Copied.Bytes; p.Remaining < 0 {
+		p.Remaining = 0
+	}
+	p.Percent = 100
+	if p.Total.Bytes > 0 && p.Remaining > 0 {
+		p.Percent = float64(p.Total.Bytes-p.Remaining) * 100 / float64(p.Total.Bytes)
+	}
+	return p, nil
+}
+
+// dirUsage runs fs.du on the first filer of the cluster, from its host. A
+// directory not created yet is empty.
+func (m *Manager) dirUsage(op operator.CommandOperator, specification *spec.Specification, dir string) (DirUsage, error) {
+	command := m.weedClient(op, filerShellCommand(weedMasterAddresses(specification), filerAddresses(specification)[0], []string{"fs.du " + dir}))
+	out, err := op.Output(command)
+	if err != nil {
+		return DirUsage{}, fmt.Errorf("fs.du %s: %w: %s", dir, err, out)
+	}
+	matches := fsDuPattern.FindAllStringSubmatch(string(out), -1)
+	if len(matches) == 0 {
+		return DirUsage{}, nil
+	}
+	// the total of the directory comes last
+	last := matches[len(matches)-1]
+	chunks, _ := strconv.ParseInt(last[1], 10, 64)
+	bytes, _ := strconv.ParseInt(last[2], 10, 64)
+	return DirUsage{Chunks: chunks, Bytes: bytes}, nil
+}
+
+func PrintMigration(w io.Writer, p *MigrationProgress) {
+	t := output.NewTable(w)
+	fmt.Fprintf(t, "PREFIX\t%s\n", p.Prefix)
+	fmt.Fprintf(t, "FROM\t%s\n", p.Source)
+	fmt.Fprintf(t, "TO\t%s\n", p.Target)
+	fmt.Fprintf(t, "FILER.SYNC\t%s on %s\n", p.Service, p.Host)
+	if p.StartedAt != nil {
+		fmt.Fprintf(t, "STARTED\t%s\n", p.StartedAt.Local().Format(time.RFC3339))
+	}
+	if p.CutoverAt != nil {
+		fmt.Fprintf(t, "CUT OVER\t%s\n", p.CutoverAt.Local().Format(time.RFC3339))
+	}
+	fmt.Fprintf(t, "COPIED\t%s of %s, %d of %d chunks, %.1f%%\n", megabytes(p.Copied.Bytes), megabytes(p.Total.Bytes), p.Copied.Chunks, p.Total.Chunks, p.Percent)
+	fmt.Fprintf(t, "REMAINING\t%s\n", megabytes(p.Remaining))
+	if p.SyncedTo != nil {
+		fmt.Fprintf(t, "LAST CHANGE COPIED\t%s, %s ago\n", p.SyncedTo.Local().Format(time.RFC3339), p.Lag)
+	} else {
+		fmt.Fprintf(t, "LAST CHANGE COPIED\t-\n")
+	}
+	t.Flush()
+}
+
+// filerEntry is an entry of a filer directory listed as JSON.
+type filerEntry struct {
+	FullPath string      `json:"FullPath"`
+	Mode     os.FileMode `json:"Mode"`
+}
+
+// VerifyMigration samples files under the prefix on the source filer and
+// compares their sha256 on both clusters, downloaded from each filer on its
+// host. Files changed since the last change copied may differ until filer.sync
+// catches up.
+func (m *Manager) VerifyMigration(source, target *spec.Specification, options MigrationOptions, samples int) (*MigrationVerification, error) {
+	prefix, _, filerSpec, err := m.prepareMigration(source, target, options)
+	if err != nil {
+		return nil, err
+	}
+	if samples < 1 {
+		return nil, exitcode.WithCode(exitcode.Invalid, fmt.Errorf("--samples must be at least 1"))
+	}
+	result := &MigrationVerification{Prefix: prefix, Mismatches: []*FileMismatch{}}
+	sourceFiler, targetFiler := filerAddresses(source)[0], filerAddresses(target)[0]
+
+	tracker := progress.New("Verifying the migration of " + prefix)
+	defer tracker.Stop()
+	task := tracker.Add("source " + filerSpec.Ip)
+	var files []string
+	sums := map[string]string{}
+	err = m.executeRemote(utils.JoinHostPort(filerSpec.Ip, filerSpec.PortSsh), func(op operator.CommandOperator) error {
+		task.Step("listing " + prefix)
+		files, err = listFilerFiles(op, sourceFiler, prefix, samples*20)
+		if err != nil {
+			return err
+		}
+		result.Listed = len(files)
+		rand.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+		if len(files) > samples {
+			files = files[:samples]
+		}
+		for i, file := range files {
+			task.Step(fmt.Sprintf("checksum %d of %d", i+1, len(files)))
+			sum, err := filerFileChecksum(op, sourceFiler, file)
+			if err != nil {
+				return err
+			}
+			sums[file] = sum
+		}
+		return nil
+	})
+	task.Done(err)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+
+	m.prepareSpecification(target)
+	task = tracker.Add("target " + target.FilerServers[0].Ip)
+	err = m.executeRemote(utils.JoinHostPort(target.FilerServers[0].Ip, target.FilerServers[0].PortSsh), func(op operator.CommandOperator) error {
+		for i, file := range files {
+			if sums[file] == "" {
+				// removed from the source since listed
+				continue
+			}
+			task.Step(fmt.Sprintf("checksum %d of %d", i+1, len(files)))
+			sum, err := filerFileChecksum(op, targetFiler, file)
+			if err != nil {
+				return err
+			}
+			result.Checked++
+			switch sum {
+			case sums[file]:
+				result.Matched++
+			case "":
+				result.Mismatches = append(result.Mismatches, &FileMismatch{file, "missing on the target"})
+			default:
+				result.Mismatches = append(result.Mismatches, &FileMismatch{file, "content differs"})
+			}
+		}
+		return nil
+	})
+	task.Done(err)
+	if err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+	return result, nil
+}
+
+// listFilerFiles walks the directory on the filer from the host, breadth
+// first, until it found max files or walked verifyDirs directories.
+func listFilerFiles(op operator.CommandOperator, filer, dir string, max int) ([]string, error) {
+	var files []string
+	queue := []string{dir}
+	for walked := 0; len(queue) > 0 && walked < verifyDirs && len(files) < max; walked++ {
+		current := queue[0]
+		queue = queue[1:]
+		out, err := op.Output(fmt.Sprintf("curl -sf -H 'Accept: application/json' %s", shellQuote(filerUrl(filer, current)+fmt.Sprintf("/?limit=%d", verifyListLimit))))
+		if err != nil {
+			if walked == 0 {
+				return nil, fmt.Errorf("list %s on %s: %w", current, filer, err)
+			}
+			logging.Warn(fmt.Sprintf("can not list %s on %s: %v", current, filer, err))
+			continue
+		}
+		var listing struct {
+			Entries []filerEntry `json:"Entries"`
+		}
+		if err := json.Unmarshal(out, &listing); err != nil {
+			return nil, fmt.Errorf("list %s on %s: %w", current, filer, err)
+		}
+		for _, entry := range listing.Entries {
+			if entry.Mode.IsDir() {
+				queue = append(queue, entry.FullPath)
+			} else {
+				files = append(files, entry.FullPath)
+			}
+		}
+	}
+	return files, nil
+}
+
+// filerFileChecksum downloads the file from the filer on the host, and returns
+// its sha256, or "" if the filer does not have it.
+func filerFileChecksum(op operator.CommandOperator, filer, file string) (string, error) {
+	address := shellQuote(filerUrl(filer, file))
+	out, err := op.Output(fmt.Sprintf("code=$(curl -s -o /dev/null -w '%%{http_code}' -I %[1]s); [ \"$code\" = 404 ] && { echo missing; exit 0; }; [ \"$code\" = 200 ] && curl -sf %[1]s | sha256sum", address))
+	if err != nil {
+		return "", fmt.Errorf("checksum %s on %s: %w", file, filer, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 || fields[0] == "missing" {
+		return "", nil
+	}
+	return fields[0], nil
+}
+
+// filerUrl returns the http URL of the path on the filer, its names escaped.
+func filerUrl(filer, file string) string {
+	names := strings.Split(strings.Trim(file, "/"), "/")
+	for i, name := range names {
+		names[i] = url.PathEscape(name)
+	}
+	return "http://" + filer + strings.TrimSuffix("/"+strings.Join(names, "/"), "/")
+}
+
+func PrintVerification(w io.Writer, v *MigrationVerification) {
+	fmt.Fprintf(w, "%d of %d sampled files of %s match, out of %d listed\n", v.Matched, v.Checked, v.Prefix, v.Listed)
+	if len(v.Mismatches) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	t := output.NewTable(w)
+	fmt.Fprintln(t, "FILE\tMISMATCH")
+	for _, mismatch := range v.Mismatches {
+		fmt.Fprintf(t, "%s\t%s\n", mismatch.Path, mismatch.Reason)
+	}
+	t.Flush()
+}
+
+// Cutover ends a migration: once the target caught up, it moves the shared
+// virtual IP from the source servers to the target servers, lets filer.sync
+// copy the last changes for options.Drain, then removes its service.
+func (m *Manager) Cutover(source, target *spec.Specification, options MigrationOptions) error {
+	if _, _, _, err := m.prepareMigration(source, target, options); err != nil {
+		return err
+	}
+	unlockTarget, err := m.lockMigrated(target, "migrate cutover")
+	if err != nil {
+		return err
+	}
+	defer unlockTarget()
+	unlockSource, err := m.lockMigrated(source, "migrate cutover")
+	if err != nil {
+		return err
+	}
+	defer unlockSource()
+
+	p, err := m.MigrationStatus(source, target, options)
+	if err != nil {
+		return err
+	}
+	key := migrationKey(source, target, p.Prefix)
+	state := m.loadMigration(key)
+	switch {
+	case state == nil:
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s is not migrated from %s to %s, run seaweed-up migrate first", p.Prefix, p.Source, p.Target))
+	case state.CutoverAt != nil:
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("%s was already cut over at %s", p.Prefix, state.CutoverAt.Local().Format(time.RFC3339)))
+	case p.Service != "active" && !options.Force:
+		return exitcode.WithCode(exitcode.Partial, fmt.Errorf("filer.sync is %s on %s, check journalctl -u %s there, or cut over with --force", p.Service, p.Host, state.Service))
+	case p.Remaining > 0 && !options.Force:
+		return exitcode.WithCode(exitcode.Partial, fmt.Errorf("the target is %s behind, wait until seaweed-up migrate status shows nothing remaining, or cut over with --force", megabytes(p.Remaining)))
+	}
+
+	tracker := progress.New("Cutting over " + p.Prefix)
+	defer tracker.Stop()
+	if sharedVirtualIp(source, target) {
+		if err := m.moveVirtualIp(tracker, source, target); err != nil {
+			return err
+		}
+	} else if ha := target.HighAvailability; ha != nil {
+		logging.Warn(fmt.Sprintf("the clusters do not share a virtual IP, point the clients to %s", ha.Address()))
+	} else {
+		logging.Warn(fmt.Sprintf("the target has no virtual IP, point the clients to %s", p.Target))
+	}
+
+	m.prepareSpecification(source)
+	task := tracker.Add(fmt.Sprintf("filer.sync %s", p.Host))
+	if options.Drain > 0 {
+		task.Step(fmt.Sprintf("copying the last changes for %s", options.Drain))
+		time.Sleep(options.Drain)
+	}
+	task.Step("removing " + state.Service)
+	err = m.executeRemote(state.Host, func(op operator.CommandOperator) error {
+		_, err := m.sudoOutput(op, fmt.Sprintf("systemctl disable --now %[1]s; rm -f /etc/systemd/system/%[1]s.service && systemctl daemon-reload", state.Service))
+		return err
+	})
+	task.Done(err)
+	if err != nil {
+		return fmt.Errorf("remove %s on %s: %w", state.Service, state.Host, err)
+	}
+	now := time.Now().UTC()
+	state.CutoverAt = &now
+	if err := m.saveMigration(key, state); err != nil {
+		return err
+	}
+	info(fmt.Sprintf("cut %s over to %s", p.Prefix, p.Target))
+	return nil
+}
+
+// moveVirtualIp stops keepalived on the source servers then starts it on the
+// target servers. If the target can not take the virtual IP, keepalived is
+// started again on the source servers.
+func (m *Manager) moveVirtualIp(tracker *progress.Tracker, source, target *spec.Specification) error {
+	sourceServers, err := haServers(source)
+	if err != nil {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("source: %w", err))
+	}
+	targetServers, err := haServers(target)
+	if err != nil {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("target: %w", err))
+	}
+	keepalived := func(specification *spec.Specification, servers []*haServer, command string) error {
+		m.prepareSpecification(specification)
+		for _, server := range servers {
+			task := tracker.Add("keepalived " + server.ip)
+			task.Step(command)
+			err := m.executeRemote(utils.JoinHostPort(server.ip, server.portSsh), func(op operator.CommandOperator) error {
+				_, err := m.sudoOutput(op, command)
+				return err
+			})
+			task.Done(err)
+			if err != nil {
+				return fmt.Errorf("%s on %s: %w", command, server.ip, err)
+			}
+		}
+		return nil
+	}
+	if err := keepalived(source, sourceServers, "systemctl disable --now keepalived"); err != nil {
+		return err
+	}
+	if err := keepalived(target, targetServers, "systemctl enable --now keepalived"); err != nil {
+		if restoreErr := keepalived(source, sourceServers, "systemctl enable --now keepalived"); restoreErr != nil {
+			logging.Warn(fmt.Sprintf("can not give the virtual IP back to the source: %v", restoreErr))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"github.com/seaweedfs/seaweed-up/scripts"
+	"github.com/thanhpk/randstr"
+)
+
+// OsUpdateOptions describe how OsUpdate rolls through the hosts.
+type OsUpdateOptions struct {
+	RebootIfNeeded bool          // reboot hosts whose updates need it, like a new kernel
+	Drain          bool          // move the volumes off volume servers before updating their host
+	Timeout        time.Duration // how long to wait for a host to come back and its components to be healthy
+}
+
+const osUpdateCheckInterval = 5 * time.Second
+
+// OsUpdate applies the package updates of the OS on one host after the other.
+// Each host is in maintenance while it is updated, and rebooted if the updates
+// need it and options.RebootIfNeeded. The next host is only updated once all
+// components of the host are healthy again. On failure, the host stays in
+// maintenance and the remaining hosts are not updated.
+func (m *Manager) OsUpdate(specification *spec.Specification, options OsUpdateOptions) error {
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+	unlock, err := m.lock(specification, "os-update")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	maintenance, err := m.loadMaintenance(specification)
+	if err != nil {
+		return err
+	}
+	hosts := skipInMaintenance(m.clusterHosts(specification), maintenance)
+	var pendingReboot []string
+	for i, h := range hosts {
+		info(fmt.Sprintf("Updating %s (%d/%d)", h.ip, i+1, len(hosts)))
+		if err := m.enterMaintenance(specification, h, maintenance, "os-update", options.Drain); err != nil {
+			return err
+		}
+		rebootRequired, err := m.updateHost(h, options)
+		if err == nil {
+			err = m.waitHealthy(specification, h, options.Timeout)
+		}
+		if err != nil {
+			return fmt.Errorf("update %s: %w, it stays in maintenance", h.ip, err)
+		}
+		if rebootRequired {
+			pendingReboot = append(pendingReboot, h.ip)
+		}
+		delete(maintenance, h.ip)
+		if err := m.saveMaintenance(specification, maintenance); err != nil {
+			return err
+		}
+	}
+
+	if len(pendingReboot) > 0 {
+		logging.Warn(fmt.Sprintf("%s need a reboot, run again with --reboot-if-needed", strings.Join(pendingReboot, ", ")))
+	}
+	if options.Drain {
+		info("move volumes back to the volume servers with: seaweed-up cluster balance")
+	}
+	info(fmt.Sprintf("Updated %d hosts", len(hosts)))
+	return nil
+}
+
+// updateHost applies the updates and reboots the host if needed and allowed.
+// It returns whether the host still needs a reboot.
+func (m *Manager) updateHost(h *clusterHost, options OsUpdateOptions) (rebootRequired bool, err error) {
+	var bootId string
+	err = m.executeOnHost(h, func(op operator.CommandOperator) error {
+		dir := "/tmp/seaweed-up." + randstr.String(6)
+		defer op.Execute("rm -rf " + dir)
+		if err := op.Execute("mkdir -p " + dir); err != nil {
+			return err
+		}
+		script, err := scripts.RenderScript("os_update.sh", map[string]interface{}{
+			"TmpDir": dir,
+		})
+		if err != nil {
+			return err
+		}
+		if err := op.Upload(script, dir+"/os_update.sh", "0755"); err != nil {
+			return fmt.Errorf("upload os_update.sh: %w", err)
+		}
+		if err := op.Execute(m.scriptCommand(op, fmt.Sprintf("%s/os_update.sh", dir))); err != nil {
+			return fmt.Errorf("update packages: %w", err)
+		}
+		if _, err := op.Output(fmt.Sprintf("test -f %s/reboot_required", dir)); err != nil {
+			return nil
+		}
+		rebootRequired = true
+		if !options.RebootIfNeeded {
+			return nil
+		}
+		out, err := op.Output("cat /proc/sys/kernel/random/boot_id")
+		if err != nil {
+			return fmt.Errorf("read boot id: %w", err)
+		}
+		bootId = strings.TrimSpace(string(out))
+		info("Rebooting " + h.ip)
+		// the connection drops with the reboot, its error tells nothing
+		op.Execute(m.sudoCommand(op, "nohup sh -c 'sleep 2; systemctl reboot' >/dev/null 2>&1 &"))
+		return nil
+	})
+	if err != nil || bootId == "" {
+		return rebootRequired, err
+	}
+	return false, m.waitRebooted(h, bootId, options.Timeout)
+}
+
+// waitRebooted waits for the host to answer over SSH with another boot id.
+func (m *Manager) waitRebooted(h *clusterHost, bootId string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		time.Sleep(osUpdateCheckInterval)
+		var current string
+		err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+			out, err := op.Output("cat /proc/sys/kernel/random/boot_id")
+			current = strings.TrimSpace(string(out))
+			return err
+		})
+		if err == nil && current != bootId {
+			info(h.ip + " is back")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return exitcode.WithCode(exitcode.Unreachable, fmt.Errorf("not back after a reboot within %s", timeout))
+		}
+	}
+}
+
+// waitHealthy waits for the services of the host to be active and answer on
+// their http port, and for its volume servers to be registered on the master.
+func (m *Manager) waitHealthy(specification *spec.Specification, h *clusterHost, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		problem := m.hostProblem(specification, h)
+		if problem == "" {
+			info(h.ip + " is healthy")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not healthy within %s: %s", timeout, problem)
+		}
+		logging.Debug("waiting", "host", h.ip, "problem", problem)
+		time.Sleep(osUpdateCheckInterval)
+	}
+}
+
+// hostProblem is the first reason why a component of the host is not
+// healthy, empty if all are.
+func (m *Manager) hostProblem(specification *spec.Specification, h *clusterHost) (problem string) {
+	err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+		for _, instance := range h.instances {
+			if _, err := op.Output(fmt.Sprintf("systemctl is-active --quiet seaweed_%s", instance.name)); err != nil {
+				problem = fmt.Sprintf("seaweed_%s is not active", instance.name)
+				return nil
+			}
+			curl := "curl -sf"
+			switch instance.component {
+			case "envoy":
+				continue
+			case "mount":
+				if _, err := op.Output("mountpoint -q " + shellQuote(instance.dir)); err != nil {
+					problem = fmt.Sprintf("%s is not mounted", instance.dir)
+					return nil
+				}
+				continue
+			case "broker":
+				if _, err := op.Output(tcpCheck(h.instanceAddress(instance), 5)); err != nil {
+					problem = fmt.Sprintf("broker %s does not answer", h.instanceAddress(instance))
+					return nil
+				}
+				continue
+			case "filer", "s3", "webdav":
+				curl = "curl -s" // any answer will do
+			}
+			address := h.instanceAddress(instance)
+			if _, err := op.Output(fmt.Sprintf("%s -o /dev/null --max-time 5 http://%s%s", curl, address, healthPath(instance.component))); err != nil {
+				problem = fmt.Sprintf("%s %s does not answer", instance.component, address)
+				return nil
+			}
+			if port := instance.grpcPort(); port != 0 {
+				if _, err := op.Output(tcpCheck(utils.JoinHostPort(h.ip, port), 5)); err != nil {
+					problem = fmt.Sprintf("%s %s does not accept gRPC connections on port %d", instance.component, address, port)
+					return nil
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err.Error()
+	}
+	if problem != "" || !h.hasComponent("volume") {
+		return problem
+	}
+
+	err = m.onMasterHost(specification, func(op operator.CommandOperator, address string) error {
+		topo, err := masterTopology(op, masterAddresses(specification))
+		if err != nil {
+			return err
+		}
+		for _, node := range unregisteredVolumeServers(specification, topo) {
+			if nodeHost(node) == h.ip {
+				problem = fmt.Sprintf("volume server %s is not registered on the master", node)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return problem
+}
+
+// tcpCheck is a command succeeding once the host:port address accepts
+// connections, for the components serving gRPC only.
+func tcpCheck(address string, seconds int) string {
+	host, port, _ := net.SplitHostPort(address)
+	return fmt.Sprintf("timeout %d bash -c '</dev/tcp/%s/%s'", seconds, host, port)
+}
+
+// healthPath is the http path answering when a component is up.
+func healthPath(component string) string {
+	switch component {
+	case "master":
+		return "/cluster/status"
+	case "volume":
+		return "/status"
+	}
+	return "/"
+}
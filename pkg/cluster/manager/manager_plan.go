@@ -0,0 +1,328 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// Plan is what a mutating command would change on the hosts, printed by --dry-run.
+type Plan struct {
+	Operation string      `json:"operation" yaml:"operation"`
+	Hooks     []string    `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	Nodes     []*NodePlan `json:"nodes" yaml:"nodes"`
+}
+
+// NodePlan lists the changes on one host, or on one component instance of it.
+type NodePlan struct {
+	Host     string        `json:"host" yaml:"host"`
+	Instance string        `json:"instance,omitempty" yaml:"instance,omitempty"`
+	Hooks    []string      `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	Files    []*FileChange `json:"files,omitempty" yaml:"files,omitempty"`
+	Commands []string      `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Restart  []string      `json:"restart,omitempty" yaml:"restart,omitempty"` // systemd services to restart
+}
+
+// FileChange is a file written on the host, with the diff against its current content.
+type FileChange struct {
+	Path   string `json:"path" yaml:"path"`
+	Status string `json:"status" yaml:"status"` // create, update or unchanged
+	Diff   string `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
+func (n *NodePlan) changed() bool {
+	for _, f := range n.Files {
+		if f.Status != "unchanged" {
+			return true
+		}
+	}
+	return len(n.Commands) > 0 || len(n.Restart) > 0
+}
+
+// planOperator records the commands and uploads of a mutating operation in the
+// node plan instead of running them. Output runs, so the operation can still
+// inspect the host; it must only be used for reading.
+type planOperator struct {
+	operator.CommandOperator
+	node *NodePlan
+	m    *Manager
+}
+
+func (p *planOperator) Execute(command string) error {
+	p.command(command)
+	return nil
+}
+
+func (p *planOperator) Interactive(command string) error {
+	p.command(command)
+	return nil
+}
+
+func (p *planOperator) Stream(command string, stdout io.Writer) error {
+	p.command(command)
+	return nil
+}
+
+func (p *planOperator) Upload(src io.Reader, remotePath string, mode string) error {
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	_, err = p.file(remotePath, string(content))
+	return err
+}
+
+func (p *planOperator) UploadFile(path string, remotePath string, mode string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = p.file(remotePath, string(content))
+	return err
+}
+
+func (p *planOperator) command(command string) {
+	command = redact.String(strings.TrimSpace(command))
+	p.node.Commands = append(p.node.Commands, command)
+}
+
+// file adds the file to the plan with the diff against the content on the host.
+func (p *planOperator) file(remotePath string, content string) (*FileChange, error) {
+	out, err := p.CommandOperator.Output(fmt.Sprintf("if [ -e %s ]; then echo yes; fi", shellQuote(remotePath)))
+	if err != nil {
+		return nil, fmt.Errorf("check %s: %w", remotePath, err)
+	}
+	change := &FileChange{Path: remotePath, Status: "create"}
+	var current string
+	if strings.TrimSpace(string(out)) == "yes" {
+		installed, err := p.m.sudoOutput(p.CommandOperator, "cat "+shellQuote(remotePath))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", remotePath, err)
+		}
+		current = string(installed)
+		change.Status = "update"
+		if current == content {
+			change.Status = "unchanged"
+		}
+	}
+	change.Diff = redact.String(unifiedDiff(remotePath+" (installed)", remotePath+" (planned)", current, content))
+	p.node.Files = append(p.node.Files, change)
+	return change, nil
+}
+
+// plannedFile is a file the install script of a component instance writes.
+type plannedFile struct {
+	path    string
+	content string
+}
+
+// planInstance adds what the install script would change for the component
+// instance: its files, the binary when versionCommand prints another version,
+// and the restart of the service when anything changed.
+func (m *Manager) planInstance(p *planOperator, componentInstance, binary, version, versionCommand string, files ...plannedFile) error {
+	var changed bool
+	for _, f := range files {
+		change, err := p.file(f.path, f.content)
+		if err != nil {
+			return err
+		}
+		changed = changed || change.Status != "unchanged"
+	}
+
+	out, err := p.Output(versionCommand)
+	if err != nil {
+		return fmt.Errorf("read installed %s version: %w", binary, err)
+	}
+	if installed := strings.TrimSpace(string(out)); installed != version {
+		p.command(fmt.Sprintf("install %s %s into /usr/local/bin, replacing %s", binary, version, utils.Nvl(installed, "none")))
+		changed = true
+	}
+
+	if m.skipEnable {
+		return nil
+	}
+	if changed {
+		p.command(fmt.Sprintf("systemctl enable /etc/systemd/system/seaweed_%s.service", componentInstance))
+		p.command("systemctl daemon-reload")
+	}
+	if !m.skipStart && (changed || m.ForceRestart) {
+		p.node.Restart = append(p.node.Restart, "seaweed_"+componentInstance)
+	}
+	return nil
+}
+
+// hookDescriptions describes the hooks of an event for the plan.
+func hookDescriptions(event string, hooks []*spec.HookSpec) (descriptions []string) {
+	for _, hook := range hooks {
+		description := event + ": " + hook.Command
+		if hook.Script != "" {
+			description = event + ": script " + hook.Script
+		}
+		if hook.Remote {
+			description += " (remote)"
+		}
+		descriptions = append(descriptions, description)
+	}
+	return
+}
+
+func printPlan(plan *Plan) error {
+	return output.Print(plan, func(w io.Writer) {
+		for _, hook := range plan.Hooks {
+			fmt.Fprintf(w, "hook %s\n", hook)
+		}
+		var changed int
+		for _, node := range plan.Nodes {
+			title := node.Host
+			if node.Instance != "" {
+				title = node.Instance + " on " + node.Host
+			}
+			if !node.changed() {
+				fmt.Fprintf(w, "%s: no changes\n", title)
+				continue
+			}
+			changed++
+			fmt.Fprintf(w, "%s:\n", title)
+			for _, hook := range node.Hooks {
+				fmt.Fprintf(w, "  hook %s\n", hook)
+			}
+			for _, f := range node.Files {
+				fmt.Fprintf(w, "  %s %s\n", f.Status, f.Path)
+				if f.Status == "unchanged" {
+					continue
+				}
+				for _, line := range strings.SplitAfter(strings.TrimSuffix(f.Diff, "\n"), "\n") {
+					fmt.Fprintf(w, "      %s", line)
+				}
+				fmt.Fprintln(w)
+			}
+			for _, command := range node.Commands {
+				fmt.Fprintf(w, "  $ %s\n", command)
+			}
+			for _, service := range node.Restart {
+				fmt.Fprintf(w, "  restart %s\n", service)
+			}
+		}
+		fmt.Fprintf(w, "%s would change %d of %d nodes, run again without --dry-run to apply the plan\n", plan.Operation, changed, len(plan.Nodes))
+	})
+}
+
+// unifiedDiff returns the changes from a, labeled from, to b, labeled to, in
+// the unified diff format, empty if they are equal.
+func unifiedDiff(from, to, a, b string) string {
+	if a == b {
+		return ""
+	}
+	x, y := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = maxInt(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type edit struct {
+		kind byte
+		line string
+		// lines of a and b before this edit
+		ai, bi int
+	}
+	var edits []edit
+	for i, j := 0, 0; i < len(x) || j < len(y); {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			edits = append(edits, edit{' ', x[i], i, j})
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', x[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', y[j], i, j})
+			j++
+		}
+	}
+
+	const context = 3
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", from, to)
+	for k := 0; k < len(edits); k++ {
+		if edits[k].kind == ' ' {
+			continue
+		}
+		// extend the hunk over the changes separated by a few unchanged lines
+		end := k
+		for {
+			next := end + 1
+			for next < len(edits) && edits[next].kind == ' ' {
+				next++
+			}
+			if next == len(edits) || next-end-1 > 2*context {
+				break
+			}
+			end = next
+		}
+		start, stop := maxInt(k-context, 0), minInt(end+context+1, len(edits))
+		var aCount, bCount int
+		for _, e := range edits[start:stop] {
+			if e.kind != '+' {
+				aCount++
+			}
+			if e.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", hunkStart(edits[start].ai, aCount), aCount, hunkStart(edits[start].bi, bCount), bCount)
+		for _, e := range edits[start:stop] {
+			sb.WriteByte(e.kind)
+			sb.WriteString(e.line)
+			sb.WriteByte('\n')
+		}
+		k = stop - 1
+	}
+	return sb.String()
+}
+
+// hunkStart is the first line of a hunk, which is the line before it for empty hunks.
+func hunkStart(before, count int) int {
+	if count == 0 {
+		return before
+	}
+	return before + 1
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/preflight"
+)
+
+// PreflightReport is the outcome of all checks on one host.
+type PreflightReport struct {
+	Host    string             `json:"host" yaml:"host"`
+	Results []preflight.Result `json:"results" yaml:"results"`
+}
+
+// Preflight verifies that every host of the specification is ready for deployment.
+func (m *Manager) Preflight(specification *spec.Specification) error {
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+	reports, err := m.preflight(specification)
+	if output.Structured() {
+		if printErr := output.Print(reports, nil); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+// preflight checks all hosts, printing the results as they come in.
+func (m *Manager) preflight(specification *spec.Specification) ([]PreflightReport, error) {
+	var reports []PreflightReport
+	var failedHosts []string
+	offsets := make(map[string]time.Duration)
+	for _, h := range m.clusterHosts(specification) {
+		var ports []int
+		for _, instance := range h.instances {
+			if m.shouldInstall(instance.component) {
+				ports = append(ports, instance.ports...)
+			}
+		}
+		options := preflight.Options{
+			Ports:         ports,
+			DataDir:       m.dataDir,
+			MinDiskFreeMB: 10240,
+			MinMemoryMB:   2048,
+			MinOpenFiles:  1024,
+		}
+
+		var results []preflight.Result
+		user := m.remoteOptions(h.address()).User
+		err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+			results = append(results, preflight.Result{Check: "ssh", Status: preflight.Pass, Message: "connected as " + user})
+			results = append(results, m.checkSudo(op, user))
+			results = append(results, preflight.Run(op, options)...)
+			if skew, err := clockSkew(op); err == nil {
+				offsets[h.ip] = skew
+			}
+			return nil
+		})
+		if err != nil {
+			results = append(results, preflight.Result{
+				Check:      "ssh",
+				Status:     preflight.Fail,
+				Message:    err.Error(),
+				Suggestion: "check that the host is reachable and the SSH user and key are valid",
+			})
+		}
+
+		info("Preflight " + h.address())
+		printPreflightResults(output.Log(), results)
+		reports = append(reports, PreflightReport{Host: h.address(), Results: results})
+		if preflight.HasFailure(results) {
+			failedHosts = append(failedHosts, h.ip)
+		}
+	}
+	if earliest, latest, spread := clockSpread(offsets); earliest != latest {
+		r := preflight.Result{Check: "clock skew", Status: preflight.Pass, Message: fmt.Sprintf("the clocks of the hosts are within %s", spread.Round(time.Millisecond))}
+		if spread > m.MaxClockSkew {
+			r.Status = preflight.Fail
+			r.Message = fmt.Sprintf("the clocks of %s and %s are %s apart, more than %s", earliest, latest, spread.Round(time.Millisecond), m.MaxClockSkew)
+			r.Suggestion = "set global.time_sync to install chrony, or fix the NTP client of the hosts"
+			failedHosts = append(failedHosts, "clock skew")
+		}
+		info("Preflight all hosts")
+		printPreflightResults(output.Log(), []preflight.Result{r})
+		reports = append(reports, PreflightReport{Host: "all hosts", Results: []preflight.Result{r}})
+	}
+	if len(failedHosts) > 0 {
+		err := fmt.Errorf("preflight checks failed on %s", strings.Join(failedHosts, ", "))
+		if len(failedHosts) < len(reports) {
+			err = exitcode.WithCode(exitcode.Partial, err)
+		}
+		return reports, err
+	}
+	return reports, nil
+}
+
+func (m *Manager) checkSudo(op operator.CommandOperator, user string) preflight.Result {
+	r := preflight.Result{Check: "sudo", Status: preflight.Pass}
+	if user == "root" {
+		r.Message = "logged in as root"
+		return r
+	}
+	method := m.elevationOf(op).method
+	if method == "none" {
+		r.Message = user + " runs privileged commands without elevation"
+		return r
+	}
+	if _, err := op.Output(m.sudoCommand(op, "true")); err != nil {
+		r.Status = preflight.Fail
+		r.Message = fmt.Sprintf("%s can not run %s", user, method)
+		r.Suggestion = "grant the user sudo privilege or login as root"
+		if method == "doas" {
+			r.Suggestion = fmt.Sprintf("allow the user in /etc/doas.conf with: permit nopass %s", user)
+		}
+		return r
+	}
+	r.Message = fmt.Sprintf("%s can run %s", user, method)
+	return r
+}
+
+func printPreflightResults(w io.Writer, results []preflight.Result) {
+	for _, r := range results {
+		fmt.Fprintf(w, "  [%s] %-18s %s\n", strings.ToUpper(string(r.Status)), r.Check, r.Message)
+		if r.Status != preflight.Pass && r.Suggestion != "" {
+			fmt.Fprintf(w, "  %25s %s\n", "->", r.Suggestion)
+		}
+	}
+}
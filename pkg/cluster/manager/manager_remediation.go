@@ -0,0 +1,165 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/thanhpk/randstr"
+)
+
+// drift categories of InstanceStatus.Drift, see spec.RemediationSpec
+const (
+	driftService = "service"
+	driftConfig  = "config"
+)
+
+func remediationPolicy(specification *spec.Specification, category string) string {
+	remediation := specification.GlobalOptions.Remediation
+	if remediation == nil {
+		return "off"
+	}
+	policy := remediation.Service
+	if category == driftConfig {
+		policy = remediation.Config
+	}
+	if policy == "" {
+		return "off"
+	}
+	return policy
+}
+
+// desiredUnits returns the systemd unit each instance should have, by
+// instance name, or nil if config drift is not looked for.
+func (m *Manager) desiredUnits(specification *spec.Specification) map[string]string {
+	if remediationPolicy(specification, driftConfig) == "off" {
+		return nil
+	}
+	units := make(map[string]string)
+	for _, hu := range m.systemdUnits(specification) {
+		content, err := m.renderSystemdUnit(hu.unit)
+		if err != nil {
+			logging.Warn(fmt.Sprintf("can not check the systemd unit of %s: %v", hu.unit.componentInstance, err))
+			continue
+		}
+		units[hu.unit.componentInstance] = content.String()
+	}
+	return units
+}
+
+// checkDrift adds the drift of the instance to its status.
+func checkDrift(op operator.CommandOperator, specification *spec.Specification, instance *InstanceStatus, units map[string]string) {
+	stopped := instance.Service == "inactive" || instance.Service == "failed"
+	if stopped && remediationPolicy(specification, driftService) != "off" {
+		instance.Drift = append(instance.Drift, driftService)
+	}
+	unit, found := units[instance.Instance]
+	if !found {
+		return
+	}
+	out, err := op.Output(fmt.Sprintf("sha256sum /etc/systemd/system/seaweed_%s.service 2>/dev/null | cut -d' ' -f1", instance.Instance))
+	if err != nil {
+		logging.Debug("check unit", "instance", instance.Instance, "error", err)
+		return
+	}
+	if strings.TrimSpace(string(out)) != fmt.Sprintf("%x", sha256.Sum256([]byte(unit))) {
+		instance.Drift = append(instance.Drift, driftConfig)
+	}
+}
+
+// remediate applies the remediation policies to the drift of the status. The
+// on_drift hooks are run for the drift restored, or the one not found by the
+// previous status already, so a lasting drift is notified once.
+func (m *Manager) remediate(specification *spec.Specification, hosts []*clusterHost, status, previous *ClusterStatus, units map[string]string) {
+	known := make(map[string]bool)
+	if previous != nil {
+		for _, instance := range previous.Instances {
+			for _, category := range instance.Drift {
+				known[instance.Instance+"/"+category] = true
+			}
+		}
+	}
+	hostOf := make(map[string]*clusterHost)
+	for _, h := range hosts {
+		for _, instance := range h.instances {
+			hostOf[instance.name] = h
+		}
+	}
+
+	// auto remediation locks the cluster like deploy, once and only if needed
+	var unlock func()
+	tried := false
+	locked := func() bool {
+		if tried {
+			return unlock != nil
+		}
+		tried = true
+		if err := m.prepare(specification); err != nil {
+			logging.Warn(fmt.Sprintf("can not remediate the drift: %v", err))
+			return false
+		}
+		var err error
+		if unlock, err = m.lock(specification, "remediate"); err != nil {
+			logging.Warn(fmt.Sprintf("can not remediate the drift: %v", err))
+			return false
+		}
+		return true
+	}
+	defer func() {
+		if unlock != nil {
+			unlock()
+		}
+	}()
+
+	for _, instance := range status.Instances {
+		h := hostOf[instance.Instance]
+		var results []string
+		for _, category := range instance.Drift {
+			result := "notified"
+			if remediationPolicy(specification, category) == "auto" && locked() {
+				if err := m.restore(h, instance.Instance, category, units[instance.Instance]); err != nil {
+					logging.Warn(fmt.Sprintf("can not restore the %s of %s: %v", category, instance.Instance, err))
+					result = "failed"
+				} else {
+					info(fmt.Sprintf("restored the %s of %s", category, instance.Instance))
+					result = "restored"
+				}
+			}
+			results = append(results, category+" "+result)
+			if result == "notified" && known[instance.Instance+"/"+category] {
+				continue
+			}
+			target := hookTarget{instance: instance.Instance, ip: h.ip, portSsh: h.portSsh, env: map[string]string{
+				"SEAWEED_UP_DRIFT":       category,
+				"SEAWEED_UP_REMEDIATION": result,
+			}}
+			if err := m.runHooks("on_drift", specification.Hooks.OnDrift, target); err != nil {
+				logging.Warn(err.Error())
+			}
+		}
+		instance.Remediation = strings.Join(results, ", ")
+	}
+}
+
+// restore restarts the service of the instance, after writing its systemd
+// unit again for config drift.
+func (m *Manager) restore(h *clusterHost, instance, category, unit string) error {
+	return m.executeOnHost(h, func(op operator.CommandOperator) error {
+		service := "seaweed_" + instance
+		if category == driftConfig {
+			target := fmt.Sprintf("/tmp/seaweed-up.%s.service", randstr.String(6))
+			defer op.Execute("rm -f " + target)
+			if err := op.Upload(strings.NewReader(unit), target, "0644"); err != nil {
+				return fmt.Errorf("upload unit: %w", err)
+			}
+			if _, err := m.sudoOutput(op, fmt.Sprintf("install -m 0644 %s /etc/systemd/system/%s.service && systemctl daemon-reload", target, service)); err != nil {
+				return err
+			}
+		}
+		_, err := m.sudoOutput(op, "systemctl restart "+service)
+		return err
+	})
+}
@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+)
+
+// InstanceVersion is the binary a component instance runs, and the host it runs on.
+type InstanceVersion struct {
+	Instance      string `json:"instance" yaml:"instance"`
+	Component     string `json:"component" yaml:"component"`
+	Host          string `json:"host" yaml:"host"`
+	Version       string `json:"version" yaml:"version"`                                 // of the installed binary, empty if none
+	Build         string `json:"build,omitempty" yaml:"build,omitempty"`                 // commit hash of the binary
+	OS            string `json:"os" yaml:"os"`                                           // PRETTY_NAME of /etc/os-release
+	Kernel        string `json:"kernel" yaml:"kernel"`                                   // uname -r
+	Arch          string `json:"arch" yaml:"arch"`                                       // uname -m
+	HostUptime    string `json:"hostUptime" yaml:"hostUptime"`                           // since the host booted
+	ServiceUptime string `json:"serviceUptime,omitempty" yaml:"serviceUptime,omitempty"` // since the service started, empty if it is not running
+}
+
+// VersionReport lists the versions of the binaries deployed in a cluster.
+type VersionReport struct {
+	CollectedAt time.Time          `json:"collectedAt" yaml:"collectedAt"`
+	Instances   []*InstanceVersion `json:"instances" yaml:"instances"`
+	Unreachable []string           `json:"unreachable,omitempty" yaml:"unreachable,omitempty"` // hosts not reported
+}
+
+// CollectVersions inventories the binaries of all component instances, with
+// the OS and uptime of their hosts, querying parallel hosts at the same time.
+func (m *Manager) CollectVersions(specification *spec.Specification, parallel int) *VersionReport {
+	m.prepareSpecification(specification)
+	if parallel <= 0 {
+		parallel = 1
+	}
+	hosts := m.clusterHosts(specification)
+	byHost := make([][]*InstanceVersion, len(hosts))
+	failed := make([]bool, len(hosts))
+	limit := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h *clusterHost) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			versions, err := m.collectHostVersions(h)
+			if err != nil {
+				logging.Warn(fmt.Sprintf("versions of %s: %v", h.ip, err))
+				failed[i] = true
+			}
+			byHost[i] = versions
+		}(i, h)
+	}
+	wg.Wait()
+
+	report := &VersionReport{CollectedAt: time.Now().UTC()}
+	for i, h := range hosts {
+		report.Instances = append(report.Instances, byHost[i]...)
+		if failed[i] {
+			report.Unreachable = append(report.Unreachable, h.address())
+		}
+	}
+	return report
+}
+
+func (m *Manager) collectHostVersions(h *clusterHost) (versions []*InstanceVersion, err error) {
+	err = m.executeOnHost(h, func(op operator.CommandOperator) error {
+		out, err := op.Output(`. /etc/os-release 2>/dev/null; echo "$PRETTY_NAME"; uname -r; uname -m; cut -d' ' -f1 /proc/uptime`)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if len(lines) < 4 {
+			return fmt.Errorf("unexpected host facts %q", out)
+		}
+		osName, kernel, arch := lines[0], lines[1], lines[2]
+		uptime := parseSeconds(lines[3])
+
+		weedVersion, weedBuild := parseWeedVersion(commandOutput(op, "if [ -x /usr/local/bin/weed ]; then /usr/local/bin/weed version; fi"))
+		envoyVersion, envoyBuild := parseEnvoyVersion(commandOutput(op, `if [ -x /usr/local/bin/envoy ]; then /usr/local/bin/envoy --version | grep "\S"; fi`))
+		for _, instance := range h.instances {
+			v := &InstanceVersion{
+				Instance:   instance.name,
+				Component:  instance.component,
+				Host:       h.ip,
+				Version:    weedVersion,
+				Build:      weedBuild,
+				OS:         osName,
+				Kernel:     kernel,
+				Arch:       arch,
+				HostUptime: formatUptime(uptime),
+			}
+			if instance.component == "envoy" {
+				v.Version, v.Build = envoyVersion, envoyBuild
+			}
+			// microseconds since boot when the service started, 0 if it is not running
+			started := commandOutput(op, fmt.Sprintf("systemctl show -p ActiveEnterTimestampMonotonic --value seaweed_%s", instance.name))
+			if micros, err := strconv.ParseInt(started, 10, 64); err == nil && micros > 0 && commandOutput(op, fmt.Sprintf("systemctl is-active seaweed_%s || true", instance.name)) == "active" {
+				v.ServiceUptime = formatUptime(uptime - time.Duration(micros)*time.Microsecond)
+			}
+			versions = append(versions, v)
+		}
+		return nil
+	})
+	return versions, err
+}
+
+// commandOutput is the trimmed output of command, empty if it fails.
+func commandOutput(op operator.CommandOperator, command string) string {
+	out, err := op.Output(command)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// parseWeedVersion reads "version 30GB 3.71 0b1ae3a7 linux amd64".
+func parseWeedVersion(out string) (version, build string) {
+	fields := strings.Fields(out)
+	for i, field := range fields {
+		if field == "version" && i+3 < len(fields) {
+			return fields[i+2], fields[i+3]
+		}
+	}
+	return "", ""
+}
+
+// parseEnvoyVersion reads "envoy  version: <hash>/1.30.1/Clean/RELEASE/BoringSSL".
+func parseEnvoyVersion(out string) (version, build string) {
+	_, after, found := strings.Cut(out, "version: ")
+	if !found {
+		return "", ""
+	}
+	parts := strings.Split(strings.TrimSpace(after), "/")
+	if len(parts) < 2 {
+		return "", ""
+	}
+	return parts[1], parts[0]
+}
+
+func parseSeconds(s string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func formatUptime(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.Round(time.Minute).String()
+}
+
+// PrintVersions prints the report as a table.
+func PrintVersions(w io.Writer, report *VersionReport) {
+	t := output.NewTable(w)
+	fmt.Fprintln(t, "INSTANCE\tHOST\tVERSION\tBUILD\tOS\tKERNEL\tHOST UPTIME\tSERVICE UPTIME")
+	for _, v := range report.Instances {
+		fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", v.Instance, v.Host, dash(v.Version), dash(v.Build), v.OS, v.Kernel, v.HostUptime, dash(v.ServiceUptime))
+	}
+	t.Flush()
+	if len(report.Unreachable) > 0 {
+		fmt.Fprintf(w, "\nnot reported, unreachable: %s\n", strings.Join(report.Unreachable, ", "))
+	}
+}
@@ -0,0 +1,48 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// prepareRepository resolves where the weed archives are downloaded from.
+// The --repo-url and --repo-header flags take precedence over the configuration file.
+func (m *Manager) prepareRepository(repository *spec.RepositorySpec) {
+	if repository == nil {
+		repository = &spec.RepositorySpec{}
+	}
+	m.repoUrl = utils.Nvl(m.RepoUrl, repository.URL, spec.DefaultRepositoryURL)
+
+	headers := make(map[string]string)
+	for name, value := range repository.Headers {
+		headers[name] = value
+	}
+	for _, header := range m.RepoHeaders {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) == 2 {
+			headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var args []string
+	for _, name := range names {
+		// the headers authenticate to the mirror, and install.sh shows in plans
+		redact.Secret(headers[name])
+		args = append(args, "-H "+shellQuote(fmt.Sprintf("%s: %s", name, headers[name])))
+	}
+	m.curlArgs = strings.Join(args, " ")
+}
+
+// UsesCustomRepository tells if the weed archives come from somewhere else than the GitHub releases.
+func (m *Manager) UsesCustomRepository(specification *spec.Specification) bool {
+	return m.RepoUrl != "" || (specification.GlobalOptions.Repository != nil && specification.GlobalOptions.Repository.URL != "")
+}
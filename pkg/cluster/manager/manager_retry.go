@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"golang.org/x/crypto/ssh"
+)
+
+// retryPolicy is the spec.RetrySpec of a task type with the defaults filled in.
+type retryPolicy struct {
+	attempts int
+	initial  time.Duration
+	max      time.Duration
+}
+
+func (m *Manager) retryPolicy(task string) retryPolicy {
+	policy := m.retrySpec.Policy(task)
+	p := retryPolicy{attempts: 3, initial: time.Second, max: 30 * time.Second}
+	if policy.MaxAttempts > 0 {
+		p.attempts = policy.MaxAttempts
+	}
+	if initial := policy.Initial(); initial > 0 {
+		p.initial = initial
+	}
+	if max := policy.Max(); max > 0 {
+		p.max = max
+	}
+	return p
+}
+
+// backoff is the wait after the failed attempt, doubled for each attempt up
+// to the max, half of it random so hosts retried together spread out.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	wait := p.initial
+	for i := 1; i < attempt && wait < p.max; i++ {
+		wait *= 2
+	}
+	if wait > p.max {
+		wait = p.max
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// retry runs fn until it succeeds, fails with an error not worth retrying, or
+// the attempts of the task type are used up.
+func (m *Manager) retry(task, target string, fn func() error) error {
+	p := m.retryPolicy(task)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !retryable(err) || attempt >= p.attempts {
+			return err
+		}
+		// failed connections were retried as connect tasks already
+		var connectError *operator.TargetConnectError
+		if task != "connect" && errors.As(err, &connectError) {
+			return err
+		}
+		wait := p.backoff(attempt)
+		logging.Warn(fmt.Sprintf("%s %s failed, attempt %d of %d, retrying in %s: %v", task, target, attempt, p.attempts, wait.Round(time.Millisecond), err))
+		time.Sleep(wait)
+	}
+}
+
+// retryable tells if the error is transient, like a refused connection, a
+// timeout or a dropped session, rather than a failed command or a refused login.
+func retryable(err error) bool {
+	var skipped *HostSkippedError
+	var exitError *ssh.ExitError
+	if errors.As(err, &skipped) || errors.As(err, &exitError) {
+		return false
+	}
+	var dnsError *net.DNSError
+	if errors.As(err, &dnsError) {
+		return !dnsError.IsNotFound
+	}
+	// not any net.Error, syscall errors like a missing key file are ones too
+	var opError *net.OpError
+	var netError net.Error
+	var exitMissing *ssh.ExitMissingError
+	return errors.As(err, &opError) || errors.As(err, &netError) && netError.Timeout() || errors.As(err, &exitMissing) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE)
+}
+
+// HostSkippedError is returned for the operations on a host after it failed
+// too often in a row, so the rest of the operation proceeds without waiting
+// for it.
+type HostSkippedError struct {
+	Address  string
+	Failures int
+}
+
+func (e *HostSkippedError) Error() string {
+	return fmt.Sprintf("%s is skipped after %d failures in a row", e.Address, e.Failures)
+}
+
+// hostFailures counts the transient failures of each host in a row.
+type hostFailures struct {
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+// check returns a HostSkippedError if the host failed threshold times in a
+// row, never if threshold is negative.
+func (b *hostFailures) check(address string, threshold int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if failures := b.failures[address]; threshold >= 0 && failures >= threshold {
+		return &HostSkippedError{Address: address, Failures: failures}
+	}
+	return nil
+}
+
+// record counts the transient failures of the host, and forgets them when an
+// operation succeeds.
+func (b *hostFailures) record(address string, threshold int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures == nil {
+		b.failures = make(map[string]int)
+	}
+	switch {
+	case err == nil:
+		delete(b.failures, address)
+	case retryable(err):
+		b.failures[address]++
+		if b.failures[address] == threshold {
+			logging.Warn(fmt.Sprintf("%s failed %d times in a row, skipping it for the rest of the run", address, b.failures[address]))
+		}
+	}
+}
+
+// circuitBreaker is the number of transient failures in a row after which a
+// host is skipped, negative to never skip it.
+func (m *Manager) circuitBreaker() int {
+	if m.retrySpec == nil || m.retrySpec.CircuitBreaker == 0 {
+		return 2
+	}
+	return m.retrySpec.CircuitBreaker
+}
@@ -0,0 +1,77 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
+)
+
+// weedComponents are the components running weed, which read security.toml.
+var weedComponents = map[string]bool{"master": true, "volume": true, "filer": true, "s3": true, "webdav": true, "mount": true, "broker": true}
+
+func (m *Manager) prepareSecurity(specification *spec.Specification) {
+	m.security = specification.GlobalOptions.Security
+	m.clientDirs = make(map[string]string)
+	if m.security == nil {
+		return
+	}
+	redact.Secret(m.security.JwtSigningKey)
+	for _, h := range m.clusterHosts(specification) {
+		for _, instance := range h.instances {
+			if weedComponents[instance.component] {
+				m.clientDirs[h.address()] = m.instanceConfigDir(instance.name)
+				break
+			}
+		}
+	}
+}
+
+func (m *Manager) instanceConfigDir(componentInstance string) string {
+	return fmt.Sprintf("%s/%s.d", m.confDir, componentInstance)
+}
+
+// securityFiles returns the security.toml of the component instance, with the
+// CA, certificate and key it names, none without global.security.
+func (m *Manager) securityFiles(componentInstance string) ([]configFile, error) {
+	if m.security == nil {
+		return nil, nil
+	}
+	files := []configFile{{
+		name:    "security.toml",
+		content: []byte(m.security.Toml(m.instanceConfigDir(componentInstance))),
+		secret:  m.security.JwtSigningKey != "",
+	}}
+	if !m.security.TLS() {
+		return files, nil
+	}
+	for _, f := range []struct {
+		flag, local, name string
+		secret            bool
+	}{
+		{"ca.file", m.security.CaFile, "ca.crt", false},
+		{"cert.file", m.security.CertFile, "tls.crt", false},
+		{"key.file", m.security.KeyFile, "tls.key", true},
+	} {
+		content, err := os.ReadFile(f.local)
+		if err != nil {
+			return nil, fmt.Errorf("read the security %s: %w", f.flag, err)
+		}
+		files = append(files, configFile{name: f.name, content: content, secret: f.secret})
+	}
+	return files, nil
+}
+
+// weedClient returns command, running weed as a client on the host of op, so
+// it reads the security.toml of a weed instance of the host when the gRPC
+// ports use TLS. The key of the certificate is only readable by the services,
+// so the command then runs elevated.
+func (m *Manager) weedClient(op operator.CommandOperator, command string) string {
+	dir, found := m.clientDirs[operatorAddress(op)]
+	if !m.security.TLS() || !found {
+		return command
+	}
+	return m.sudoCommand(op, fmt.Sprintf("cd %s && %s", dir, command))
+}
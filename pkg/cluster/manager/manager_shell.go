@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// masterAddresses returns the http addresses of the masters.
+func masterAddresses(specification *spec.Specification) (masters []string) {
+	for _, masterSpec := range specification.MasterServers {
+		masters = append(masters, utils.JoinHostPort(masterSpec.Ip, defaultPort(masterSpec.Port, 9333)))
+	}
+	return
+}
+
+// weedMasterAddresses returns the addresses of the masters as the weed flags
+// and weed shell take them, with their gRPC port when it is not the default.
+func weedMasterAddresses(specification *spec.Specification) (masters []string) {
+	for _, masterSpec := range specification.MasterServers {
+		masters = append(masters, masterSpec.ServerAddress(masterSpec.Ip))
+	}
+	return
+}
+
+// weedShell pipes the commands into weed shell on the host, wrapped in lock and
+// unlock so they do not run concurrently with other admin operations.
+func (m *Manager) weedShell(op operator.CommandOperator, masters []string, commands []string) error {
+	info("[weed shell] " + strings.Join(commands, "; "))
+	return op.Execute(m.weedClient(op, weedShellCommand(masters, commands)))
+}
+
+func weedShellCommand(masters []string, commands []string) string {
+	var quoted []string
+	for _, command := range append(append([]string{"lock"}, commands...), "unlock") {
+		quoted = append(quoted, shellQuote(command))
+	}
+	return fmt.Sprintf("printf '%%s\\n' %s | /usr/local/bin/weed shell -master=%s", strings.Join(quoted, " "), strings.Join(masters, ","))
+}
+
+// filerShellCommand pipes the commands into weed shell, with the filer to
+// use, without the admin lock the commands on files do not need.
+func filerShellCommand(masters []string, filer string, commands []string) string {
+	var quoted []string
+	for _, command := range commands {
+		quoted = append(quoted, shellQuote(command))
+	}
+	return fmt.Sprintf("printf '%%s\\n' %s | /usr/local/bin/weed shell -master=%s -filer=%s", strings.Join(quoted, " "), strings.Join(masters, ","), filer)
+}
+
+// Shell runs weed shell on the first reachable master host: interactively when
+// there are no commands, otherwise it runs the commands as one locked job and
+// appends them with their output to logFile, if set, holding the cluster lock.
+func (m *Manager) Shell(specification *spec.Specification, commands []string, logFile string) error {
+	if len(commands) == 0 {
+		m.prepareSpecification(specification)
+	} else {
+		if err := m.prepare(specification); err != nil {
+			return err
+		}
+		unlock, err := m.lock(specification, "shell")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	masters := weedMasterAddresses(specification)
+	return m.onMasterHost(specification, func(op operator.CommandOperator, address string) error {
+		if len(commands) == 0 {
+			return op.Interactive(m.weedClient(op, fmt.Sprintf("/usr/local/bin/weed shell -master=%s", strings.Join(masters, ","))))
+		}
+		return m.weedShellJob(op, address, masters, commands, logFile)
+	})
+}
+
+// onMasterHost runs the callback on the first master host accepting the SSH connection.
+func (m *Manager) onMasterHost(specification *spec.Specification, callback func(op operator.CommandOperator, address string) error) error {
+	if len(specification.MasterServers) == 0 {
+		return fmt.Errorf("no master servers in the configuration")
+	}
+	var errs []string
+	for _, masterSpec := range specification.MasterServers {
+		address := utils.JoinHostPort(masterSpec.Ip, masterSpec.PortSsh)
+		var connected bool
+		err := m.executeRemote(address, func(op operator.CommandOperator) error {
+			connected = true
+			return callback(op, address)
+		})
+		if connected {
+			return err
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", address, err))
+	}
+	return fmt.Errorf("no master host is reachable: %s", strings.Join(errs, "; "))
+}
+
+func (m *Manager) weedShellJob(op operator.CommandOperator, address string, masters []string, commands []string, logFile string) error {
+	started := time.Now()
+	info("[weed shell] " + strings.Join(commands, "; "))
+	out, err := op.Output(m.weedClient(op, weedShellCommand(masters, commands)))
+	os.Stdout.Write(out)
+	if logFile == "" {
+		return err
+	}
+
+	f, openErr := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if openErr != nil {
+		return fmt.Errorf("open log %s: %v", logFile, openErr)
+	}
+	defer f.Close()
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+	}
+	fmt.Fprintf(f, "# %s on %s, took %s, %s\n", started.Format(time.RFC3339), address, time.Since(started).Round(time.Second), status)
+	for _, command := range commands {
+		fmt.Fprintf(f, "> %s\n", command)
+	}
+	f.Write(out)
+	fmt.Fprintln(f)
+	return err
+}
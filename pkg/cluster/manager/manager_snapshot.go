@@ -0,0 +1,194 @@
+package manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/statecrypt"
+)
+
+// snapshotInfo describes a snapshot, as snapshot.json in its archive.
+type snapshotInfo struct {
+	Stage     string    `json:"stage"` // pre-deploy or post-deploy
+	TakenAt   time.Time `json:"takenAt"`
+	Version   string    `json:"version,omitempty"`   // deployed, latest if empty
+	Hosts     []string  `json:"hosts"`               // with SeaweedFS installed
+	FilerMeta string    `json:"filerMeta,omitempty"` // the file of the filer metadata, if saved
+}
+
+func (m *Manager) snapshotDir(specification *spec.Specification) string {
+	dir := filepath.Join(m.StateDir, "snapshots")
+	if snapshots := specification.GlobalOptions.Snapshots; snapshots != nil && snapshots.Dir != "" {
+		dir = snapshots.Dir
+	}
+	return filepath.Join(dir, clusterKey(specification))
+}
+
+// snapshot archives the systemd units, config dir and master raft state of
+// each host, and the filer metadata, as <time>-<stage>.tar.gz in the snapshot
+// dir. It returns the archive, or "" if SeaweedFS is not installed on any
+// host yet. The archive is encrypted like the applied configurations.
+func (m *Manager) snapshot(specification *spec.Specification, stage string) (string, error) {
+	var archive bytes.Buffer
+	gw := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gw)
+	about := &snapshotInfo{Stage: stage, TakenAt: time.Now().UTC(), Version: m.Version}
+
+	var filerHost *clusterHost
+	for _, h := range m.clusterHosts(specification) {
+		var dirs []string
+		for _, instance := range h.instances {
+			if instance.component == "master" {
+				dirs = append(dirs, path.Join(m.dataDir, instance.name))
+			}
+			if instance.component == "filer" && filerHost == nil {
+				filerHost = h
+			}
+		}
+		var installed bool
+		err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+			out, err := op.Output("ls /etc/systemd/system/seaweed_*.service 2>/dev/null || true")
+			if err != nil || strings.TrimSpace(string(out)) == "" {
+				return err
+			}
+			installed = true
+			return m.snapshotFiles(op, tw, h.ip, dirs)
+		})
+		if err != nil {
+			return "", fmt.Errorf("snapshot %s: %w", h.address(), err)
+		}
+		if installed {
+			about.Hosts = append(about.Hosts, h.ip)
+		}
+	}
+	if len(about.Hosts) == 0 {
+		return "", nil
+	}
+
+	if snapshots := specification.GlobalOptions.Snapshots; filerHost != nil && (snapshots == nil || !snapshots.SkipFilerMetadata) {
+		about.FilerMeta = "filer.meta"
+		err := m.executeOnHost(filerHost, func(op operator.CommandOperator) error {
+			return m.snapshotFilerMeta(op, tw, specification, about.FilerMeta)
+		})
+		if err != nil {
+			return "", fmt.Errorf("save the filer metadata on %s: %w, or set global.snapshots.skip_filer_metadata", filerHost.address(), err)
+		}
+	}
+
+	data, _ := json.MarshalIndent(about, "", "  ")
+	if err := addToArchive(tw, "snapshot.json", data); err != nil {
+		return "", err
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	if err := gw.Close(); err != nil {
+		return "", err
+	}
+
+	dir := m.snapshotDir(specification)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, about.TakenAt.Format("20060102T150405Z")+"-"+stage+".tar.gz")
+	if err := statecrypt.WriteFile(file, archive.Bytes(), 0600); err != nil {
+		return "", err
+	}
+	if snapshots := specification.GlobalOptions.Snapshots; snapshots != nil && snapshots.Keep > 0 {
+		pruneSnapshots(dir, snapshots.Keep)
+	}
+	return file, nil
+}
+
+// snapshotFiles adds the systemd units, config dir and dirs of the host to
+// the archive, under the ip of the host. The weed logs are left out.
+func (m *Manager) snapshotFiles(op operator.CommandOperator, tw *tar.Writer, ip string, dirs []string) error {
+	paths := []string{"etc/systemd/system/seaweed_*.service", shellQuote(strings.TrimPrefix(m.confDir, "/"))}
+	for _, dir := range dirs {
+		paths = append(paths, shellQuote(strings.TrimPrefix(dir, "/")))
+	}
+	// exit status 1 is files changed while read, like the raft log of a running master
+	command := fmt.Sprintf("cd / && tar -cf - --ignore-failed-read --exclude='weed.*' --exclude='*.log' %s 2>/dev/null || [ $? -eq 1 ]", strings.Join(paths, " "))
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(op.Stream(m.sudoCommand(op, command), writer))
+	}()
+	defer io.Copy(io.Discard, reader)
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(ip, header.Name)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// snapshotFilerMeta saves the metadata of all filer entries with weed shell
+// on the host, and adds it to the archive as name.
+func (m *Manager) snapshotFilerMeta(op operator.CommandOperator, tw *tar.Writer, specification *spec.Specification, name string) error {
+	remote := fmt.Sprintf("/tmp/seaweed-up-snapshot-%d.meta", time.Now().UnixNano())
+	defer op.Execute("rm -f " + remote)
+	command := m.weedClient(op, filerShellCommand(weedMasterAddresses(specification), filerAddresses(specification)[0], []string{"fs.meta.save -o " + remote + " /"}))
+	if _, err := op.Output(command); err != nil {
+		return err
+	}
+	out, err := op.Output("stat -c %s " + remote)
+	if err != nil {
+		return err
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("size of %s: %q", remote, out)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: size, ModTime: time.Now()}); err != nil {
+		return err
+	}
+	return op.Stream("cat "+remote, tw)
+}
+
+func addToArchive(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// pruneSnapshots removes the oldest archives of dir beyond keep.
+func pruneSnapshots(dir string, keep int) {
+	archives, err := filepath.Glob(filepath.Join(dir, "*.tar.gz"))
+	if err != nil || len(archives) <= keep {
+		return
+	}
+	// named by the time they were taken
+	sort.Strings(archives)
+	for _, archive := range archives[:len(archives)-keep] {
+		if err := os.Remove(archive); err != nil {
+			logging.Warn(fmt.Sprintf("can not remove the snapshot %s: %v", archive, err))
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exitcode"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// StatusOptions describe how Status collects and shows the status.
+type StatusOptions struct {
+	Parallel int           // how many hosts are queried at the same time
+	CacheTTL time.Duration // how long a collected status is reused, 0 to always collect
+	Watch    bool          // refresh the status until interrupted
+	Interval time.Duration // time between two refreshes of Watch
+}
+
+// InstanceStatus is the state of a component instance.
+type InstanceStatus struct {
+	Instance  string `json:"instance" yaml:"instance"`
+	Component string `json:"component" yaml:"component"`
+	Host      string `json:"host" yaml:"host"`
+	Address   string `json:"address" yaml:"address"`
+	Location  string `json:"location,omitempty" yaml:"location,omitempty"` // data center/rack of volume servers and filers
+	Service   string `json:"service" yaml:"service"`                       // active, inactive, failed, ..., unreachable or maintenance
+	Healthy   bool   `json:"healthy" yaml:"healthy"`
+	// whether the gRPC port accepts connections, checked when http answers
+	GrpcHealthy *bool `json:"grpcHealthy,omitempty" yaml:"grpcHealthy,omitempty"`
+	// how the instance differs from the configuration, and what was done about it
+	Drift       []string `json:"drift,omitempty" yaml:"drift,omitempty"`
+	Remediation string   `json:"remediation,omitempty" yaml:"remediation,omitempty"`
+}
+
+// ClusterStatus is the state of all component instances at a time.
+type ClusterStatus struct {
+	CollectedAt time.Time         `json:"collectedAt" yaml:"collectedAt"`
+	Instances   []*InstanceStatus `json:"instances" yaml:"instances"`
+	topology    string            // the instances and their addresses, to invalidate the cache
+	recorded    time.Time         // when a status was last added to the uptime history
+}
+
+// Status prints the service state and health of all component instances,
+// querying the hosts in parallel. A status collected less than
+// options.CacheTTL ago, by this or another run, is shown again instead.
+func (m *Manager) Status(specification *spec.Specification, options StatusOptions) error {
+	if !options.Watch {
+		status := m.CollectStatus(specification, options)
+		return output.Print(status, func(w io.Writer) {
+			PrintStatus(w, status)
+		})
+	}
+
+	if output.Structured() {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("--watch only prints tables"))
+	}
+	if options.Interval <= 0 {
+		return exitcode.WithCode(exitcode.Invalid, fmt.Errorf("--interval must be positive"))
+	}
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+	for {
+		status := m.CollectStatus(specification, options)
+		// clear the terminal and print at its top, like watch
+		fmt.Fprint(os.Stdout, "\033[H\033[2J")
+		fmt.Fprintf(os.Stdout, "Every %s, collected %s, Ctrl-C to quit\n\n", options.Interval, status.CollectedAt.Local().Format("15:04:05"))
+		PrintStatus(os.Stdout, status)
+		select {
+		case <-interrupted:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// CollectStatus returns the status Status prints.
+func (m *Manager) CollectStatus(specification *spec.Specification, options StatusOptions) *ClusterStatus {
+	m.prepareSpecification(specification)
+	if options.Parallel <= 0 {
+		options.Parallel = 1
+	}
+	return m.status(specification, options)
+}
+
+// status returns the cached status if recent enough, or else collects it
+// and remediates the drift found, see spec.RemediationSpec.
+func (m *Manager) status(specification *spec.Specification, options StatusOptions) *ClusterStatus {
+	hosts := m.clusterHosts(specification)
+	topology := statusTopology(hosts)
+	previous := m.loadStatus(specification)
+	if options.CacheTTL > 0 && previous != nil && previous.topology == topology && time.Since(previous.CollectedAt) < options.CacheTTL {
+		return previous
+	}
+	units := m.desiredUnits(specification)
+
+	status := &ClusterStatus{CollectedAt: time.Now().UTC(), topology: topology}
+	maintenance := m.hostsInMaintenance(specification)
+	byHost := make([][]*InstanceStatus, len(hosts))
+	limit := make(chan struct{}, options.Parallel)
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		if _, found := maintenance[h.ip]; found {
+			byHost[i] = hostStatus(h, "maintenance")
+			continue
+		}
+		wg.Add(1)
+		go func(i int, h *clusterHost) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			byHost[i] = m.collectHostStatus(specification, h, units)
+		}(i, h)
+	}
+	wg.Wait()
+	drift := false
+	for _, instances := range byHost {
+		status.Instances = append(status.Instances, instances...)
+		for _, instance := range instances {
+			drift = drift || len(instance.Drift) > 0
+		}
+	}
+	// grouped by data center and rack, the masters and envoys without one first
+	sort.SliceStable(status.Instances, func(i, j int) bool {
+		return status.Instances[i].Location < status.Instances[j].Location
+	})
+	if drift {
+		m.remediate(specification, hosts, status, previous, units)
+	}
+	if previous != nil {
+		status.recorded = previous.recorded
+	}
+	if status.CollectedAt.Sub(status.recorded) >= historyInterval {
+		if err := m.recordStatus(specification, status); err != nil {
+			logging.Debug("record status", "error", err)
+		} else {
+			status.recorded = status.CollectedAt
+		}
+	}
+
+	// saved even without cache, for remediate to know the drift notified already
+	if err := m.saveStatus(specification, status); err != nil {
+		logging.Debug("cache status", "error", err)
+	}
+	return status
+}
+
+// collectHostStatus queries the services of the host and their http port,
+// and looks for drift of the instances from the configuration.
+func (m *Manager) collectHostStatus(specification *spec.Specification, h *clusterHost, units map[string]string) []*InstanceStatus {
+	instances := hostStatus(h, "unknown")
+	err := m.executeOnHost(h, func(op operator.CommandOperator) error {
+		for i, instance := range h.instances {
+			out, _ := op.Output(fmt.Sprintf("systemctl is-active seaweed_%s || true", instance.name))
+			instances[i].Service = strings.TrimSpace(string(out))
+			checkDrift(op, specification, instances[i], units)
+			if instance.component == "envoy" {
+				instances[i].Healthy = instances[i].Service == "active"
+				continue
+			}
+			if instance.component == "mount" {
+				_, err := op.Output("mountpoint -q " + shellQuote(instance.dir))
+				instances[i].Healthy = err == nil
+				continue
+			}
+			if instance.component == "broker" {
+				_, err := op.Output(tcpCheck(instances[i].Address, 3))
+				instances[i].Healthy = err == nil
+				continue
+			}
+			curl := "curl -sf"
+			if instance.component == "filer" || instance.component == "s3" || instance.component == "webdav" {
+				curl = "curl -s" // any answer will do
+			}
+			_, err := op.Output(fmt.Sprintf("%s -o /dev/null --max-time 3 http://%s%s", curl, instances[i].Address, healthPath(instance.component)))
+			instances[i].Healthy = err == nil
+			if port := instance.grpcPort(); port != 0 && instances[i].Healthy {
+				_, err := op.Output(tcpCheck(utils.JoinHostPort(h.ip, port), 3))
+				grpcHealthy := err == nil
+				instances[i].GrpcHealthy = &grpcHealthy
+				instances[i].Healthy = grpcHealthy
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.Debug("collect status", "host", h.ip, "error", err)
+		return hostStatus(h, "unreachable")
+	}
+	return instances
+}
+
+func hostStatus(h *clusterHost, service string) (instances []*InstanceStatus) {
+	for _, instance := range h.instances {
+		instances = append(instances, &InstanceStatus{
+			Instance:  instance.name,
+			Component: instance.component,
+			Host:      h.ip,
+			Address:   h.instanceAddress(instance),
+			Location:  instance.location,
+			Service:   service,
+		})
+	}
+	return
+}
+
+// PrintStatus prints the status as a table.
+func PrintStatus(w io.Writer, status *ClusterStatus) {
+	drift, located := false, false
+	for _, instance := range status.Instances {
+		drift = drift || len(instance.Drift) > 0
+		located = located || instance.Location != "" && instance.Location != joinLocation(spec.DefaultDataCenter, spec.DefaultRack)
+	}
+	header := []string{"INSTANCE", "ADDRESS"}
+	if located {
+		header = append(header, "LOCATION")
+	}
+	header = append(header, "SERVICE", "HEALTH")
+	if drift {
+		header = append(header, "DRIFT")
+	}
+	t := output.NewTable(w)
+	fmt.Fprintln(t, strings.Join(header, "\t"))
+	healthy := 0
+	for _, instance := range status.Instances {
+		health := "down"
+		if instance.GrpcHealthy != nil && !*instance.GrpcHealthy {
+			health = "grpc down"
+		}
+		if instance.Healthy {
+			health = "up"
+			healthy++
+		}
+		row := []string{instance.Instance, instance.Address}
+		if located {
+			row = append(row, utils.Nvl(instance.Location, "-"))
+		}
+		row = append(row, instance.Service, health)
+		if drift {
+			row = append(row, instance.Remediation)
+		}
+		fmt.Fprintln(t, strings.Join(row, "\t"))
+	}
+	t.Flush()
+	fmt.Fprintf(w, "\n%d of %d instances healthy\n", healthy, len(status.Instances))
+}
+
+func statusTopology(hosts []*clusterHost) string {
+	var instances []string
+	for _, h := range hosts {
+		for _, instance := range h.instances {
+			instances = append(instances, fmt.Sprintf("%s@%s:%v %s", instance.name, h.address(), instance.ports, instance.location))
+		}
+	}
+	return hash(instances...)
+}
+
+func (m *Manager) statusFile(specification *spec.Specification) string {
+	return filepath.Join(m.StateDir, "status", clusterKey(specification)+".json")
+}
+
+type cachedStatus struct {
+	*ClusterStatus
+	Topology string    `json:"topology"`
+	Recorded time.Time `json:"recorded"`
+}
+
+func (m *Manager) loadStatus(specification *spec.Specification) *ClusterStatus {
+	data, err := os.ReadFile(m.statusFile(specification))
+	if err != nil {
+		return nil
+	}
+	cached := cachedStatus{ClusterStatus: &ClusterStatus{}}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+	cached.ClusterStatus.topology = cached.Topology
+	cached.ClusterStatus.recorded = cached.Recorded
+	return cached.ClusterStatus
+}
+
+func (m *Manager) saveStatus(specification *spec.Specification, status *ClusterStatus) error {
+	data, err := json.Marshal(cachedStatus{ClusterStatus: status, Topology: status.topology, Recorded: status.recorded})
+	if err != nil {
+		return err
+	}
+	file := m.statusFile(specification)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	temp := fmt.Sprintf("%s.%d.tmp", file, os.Getpid())
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, file)
+}
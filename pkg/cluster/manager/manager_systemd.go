@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"github.com/thanhpk/randstr"
+)
+
+//go:embed systemd.service.tpl
+var systemdServiceTemplate string
+
+// systemdUnit is the systemd service of a component instance.
+type systemdUnit struct {
+	component         string
+	componentInstance string
+	command           string // the weed command, the component if empty
+	systemd           *spec.SystemdSpec
+	// directories the service writes to, owned by the service user
+	writableDirs []string
+	unmount      string // the mount point of a weed mount
+}
+
+func (m *Manager) newSystemdUnit(component string, index int, systemd *spec.SystemdSpec, dirs []string) *systemdUnit {
+	componentInstance := fmt.Sprintf("%s%d", component, index)
+	u := &systemdUnit{
+		component:         component,
+		componentInstance: componentInstance,
+		systemd:           systemd,
+		writableDirs:      []string{path.Join(m.dataDir, componentInstance)},
+	}
+	for _, dir := range dirs {
+		if path.IsAbs(dir) {
+			u.writableDirs = append(u.writableDirs, dir)
+		}
+	}
+	return u
+}
+
+func (m *Manager) renderSystemdUnit(u *systemdUnit) (*bytes.Buffer, error) {
+	tmpl, err := template.New("systemd.service").Funcs(template.FuncMap{"join": strings.Join}).Parse(systemdServiceTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	var readWritePaths []string
+	if u.systemd.ProtectSystem == "strict" || len(u.systemd.ReadWritePaths) > 0 {
+		readWritePaths = append(append(readWritePaths, u.writableDirs...), u.systemd.ReadWritePaths...)
+	}
+	data := map[string]interface{}{
+		"Component":         u.component,
+		"Command":           utils.Nvl(u.command, u.component),
+		"ComponentInstance": u.componentInstance,
+		"ConfigDir":         fmt.Sprintf("%s/%s.d", m.confDir, u.componentInstance),
+		"DataDir":           path.Join(m.dataDir, u.componentInstance),
+		"Systemd":           u.systemd,
+		"ReadWritePaths":    readWritePaths,
+		"Unmount":           u.unmount,
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("generating template: %w", err)
+	}
+	return &buf, nil
+}
+
+// hostUnit is the systemd unit of a component instance and the address of its host.
+type hostUnit struct {
+	address string
+	unit    *systemdUnit
+}
+
+// systemdUnits returns the systemd units the specification generates.
+func (m *Manager) systemdUnits(specification *spec.Specification) []hostUnit {
+	var units []hostUnit
+	for index, masterSpec := range specification.MasterServers {
+		units = append(units, hostUnit{utils.JoinHostPort(masterSpec.Ip, masterSpec.PortSsh), m.newSystemdUnit("master", index, masterSpec.Systemd, nil)})
+	}
+	for index, volumeSpec := range specification.VolumeServers {
+		units = append(units, hostUnit{utils.JoinHostPort(volumeSpec.Ip, volumeSpec.PortSsh), m.newSystemdUnit("volume", index, volumeSpec.Systemd, volumeSpec.Dirs())})
+	}
+	for index, filerSpec := range specification.FilerServers {
+		units = append(units, hostUnit{utils.JoinHostPort(filerSpec.Ip, filerSpec.PortSsh), m.newSystemdUnit("filer", index, filerSpec.Systemd, nil)})
+	}
+	for index, s3Spec := range specification.S3Servers {
+		units = append(units, hostUnit{utils.JoinHostPort(s3Spec.Ip, s3Spec.PortSsh), m.newSystemdUnit("s3", index, s3Spec.Systemd, nil)})
+	}
+	for index, webdavSpec := range specification.WebDAVServers {
+		units = append(units, hostUnit{utils.JoinHostPort(webdavSpec.Ip, webdavSpec.PortSsh), m.newSystemdUnit("webdav", index, webdavSpec.Systemd, []string{webdavSpec.CacheDir})})
+	}
+	for index, mountSpec := range specification.Mounts {
+		units = append(units, hostUnit{utils.JoinHostPort(mountSpec.Ip, mountSpec.PortSsh), m.mountUnit(mountSpec, index)})
+	}
+	for index, brokerSpec := range specification.MQBrokers {
+		units = append(units, hostUnit{utils.JoinHostPort(brokerSpec.Ip, brokerSpec.PortSsh), m.mqBrokerUnit(brokerSpec, index)})
+	}
+	return units
+}
+
+// SystemdDiff prints the differences between the installed systemd units and
+// the ones the specification generates.
+func (m *Manager) SystemdDiff(specification *spec.Specification) error {
+	if err := m.prepare(specification); err != nil {
+		return err
+	}
+
+	var changed int
+	for _, hu := range m.systemdUnits(specification) {
+		unit, err := m.renderSystemdUnit(hu.unit)
+		if err != nil {
+			return err
+		}
+		err = m.executeRemote(hu.address, func(op operator.CommandOperator) error {
+			target := fmt.Sprintf("/tmp/seaweed-up.%s.service", randstr.String(6))
+			defer op.Execute("rm -f " + target)
+			if err := op.Upload(unit, target, "0644"); err != nil {
+				return fmt.Errorf("upload unit: %w", err)
+			}
+			installed := fmt.Sprintf("/etc/systemd/system/seaweed_%s.service", hu.unit.componentInstance)
+			out, err := op.Output(fmt.Sprintf("if [ -f %s ]; then diff -u %s %s; else echo 'not installed'; fi; true", installed, installed, target))
+			if err != nil {
+				return err
+			}
+			if len(bytes.TrimSpace(out)) == 0 {
+				info(fmt.Sprintf("%s %s is up to date", hu.address, hu.unit.componentInstance))
+				return nil
+			}
+			changed++
+			info(fmt.Sprintf("%s %s differs:", hu.address, hu.unit.componentInstance))
+			fmt.Print(string(out))
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("diff unit of %s on %s :%w", hu.unit.componentInstance, hu.address, err)
+		}
+	}
+	if changed > 0 {
+		info(fmt.Sprintf("%d units differ, run deploy to update them", changed))
+	}
+	return nil
+}
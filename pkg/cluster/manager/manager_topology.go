@@ -0,0 +1,86 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// topology is the part of the master /dir/status response used by seaweed-up.
+type topology struct {
+	Topology struct {
+		Max         int
+		Free        int
+		DataCenters []struct {
+			Id    string
+			Racks []struct {
+				Id        string
+				DataNodes []topologyDataNode
+			}
+		}
+	}
+}
+
+type topologyDataNode struct {
+	Url       string
+	PublicUrl string
+	Volumes   int
+	Max       int
+}
+
+func (t *topology) dataNodes() (nodes []topologyDataNode) {
+	for _, dc := range t.Topology.DataCenters {
+		for _, rack := range dc.Racks {
+			nodes = append(nodes, rack.DataNodes...)
+		}
+	}
+	return
+}
+
+// masterTopology reads the topology from the first master answering, queried from the host of op.
+func masterTopology(op operator.CommandOperator, masters []string) (*topology, error) {
+	var errs []string
+	for _, master := range masters {
+		out, err := op.Output(fmt.Sprintf("curl -sf --max-time 10 http://%s/dir/status", master))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", master, err))
+			continue
+		}
+		t := &topology{}
+		if err := json.Unmarshal(out, t); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", master, err))
+			continue
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("no master answered: %s", strings.Join(errs, "; "))
+}
+
+// nodeHost returns the host of a host:port node address.
+func nodeHost(node string) string {
+	host, _, err := net.SplitHostPort(node)
+	if err != nil {
+		return node
+	}
+	return host
+}
+
+// unregisteredVolumeServers lists the volume servers of the specification missing in the topology.
+func unregisteredVolumeServers(specification *spec.Specification, t *topology) (missing []string) {
+	registered := make(map[string]bool)
+	for _, node := range t.dataNodes() {
+		registered[node.Url] = true
+	}
+	for _, volumeSpec := range specification.VolumeServers {
+		node := utils.JoinHostPort(volumeSpec.Ip, defaultPort(volumeSpec.Port, 8080))
+		if !registered[node] {
+			missing = append(missing, node)
+		}
+	}
+	return
+}
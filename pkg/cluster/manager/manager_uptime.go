@@ -0,0 +1,294 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+)
+
+const (
+	// historyInterval is the least time between two statuses of the uptime history.
+	historyInterval = time.Minute
+	// historyRetention is how long the statuses of the uptime history are kept.
+	historyRetention = 400 * 24 * time.Hour
+)
+
+// statusSample is a status of the uptime history. Instances in maintenance are left out.
+type statusSample struct {
+	At    time.Time         `json:"at"`
+	Total map[string]int    `json:"total"`          // instances by component
+	Down  map[string]string `json:"down,omitempty"` // component of the unhealthy instances, by instance
+}
+
+func (m *Manager) historyFile(specification *spec.Specification) string {
+	return filepath.Join(m.StateDir, "status", clusterKey(specification)+".history.jsonl")
+}
+
+// recordStatus appends status to the uptime history, dropping the statuses
+// older than historyRetention once a day.
+func (m *Manager) recordStatus(specification *spec.Specification, status *ClusterStatus) error {
+	sample := statusSample{At: status.CollectedAt, Total: make(map[string]int)}
+	for _, instance := range status.Instances {
+		if instance.Service == "maintenance" {
+			continue
+		}
+		sample.Total[instance.Component]++
+		if !instance.Healthy {
+			if sample.Down == nil {
+				sample.Down = make(map[string]string)
+			}
+			sample.Down[instance.Instance] = instance.Component
+		}
+	}
+	data, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	file := m.historyFile(specification)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	if first, err := firstSample(file); err == nil && time.Since(first.At) > historyRetention+24*time.Hour {
+		if err := pruneHistory(file, time.Now().Add(-historyRetention)); err != nil {
+			return fmt.Errorf("prune %s: %w", file, err)
+		}
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func firstSample(file string) (*statusSample, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	sample := &statusSample{}
+	return sample, json.Unmarshal(line, sample)
+}
+
+// readHistory returns the statuses of the uptime history collected since from.
+func readHistory(file string, from time.Time) (samples []*statusSample, err error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		sample := &statusSample{}
+		// a line cut by a crash is skipped
+		if json.Unmarshal(scanner.Bytes(), sample) != nil || sample.At.Before(from) {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+func pruneHistory(file string, from time.Time) error {
+	samples, err := readHistory(file, from)
+	if err != nil {
+		return err
+	}
+	temp := fmt.Sprintf("%s.%d.tmp", file, os.Getpid())
+	f, err := os.OpenFile(temp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, sample := range samples {
+		data, _ := json.Marshal(sample)
+		w.Write(append(data, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp, file)
+}
+
+// ComponentUptime is the availability of the instances of a component over a period.
+type ComponentUptime struct {
+	Component    string  `json:"component" yaml:"component"`
+	Availability float64 `json:"availability" yaml:"availability"` // percent of the instance statuses healthy
+	Incidents    int     `json:"incidents" yaml:"incidents"`       // times an instance became unhealthy
+	// mean time from unhealthy to healthy again, of the incidents resolved
+	MTTR string `json:"mttr,omitempty" yaml:"mttr,omitempty"`
+}
+
+// MonthUptime is the availability of the components over a calendar month, in UTC.
+type MonthUptime struct {
+	Month      string             `json:"month" yaml:"month"` // like 2026-10
+	Components []*ComponentUptime `json:"components" yaml:"components"`
+}
+
+// UptimeReport is the availability of the components computed from the
+// statuses of the uptime history, over the whole window and by month.
+type UptimeReport struct {
+	From       time.Time          `json:"from" yaml:"from"`
+	To         time.Time          `json:"to" yaml:"to"`
+	Samples    int                `json:"samples" yaml:"samples"`
+	Components []*ComponentUptime `json:"components" yaml:"components"`
+	Months     []*MonthUptime     `json:"months" yaml:"months"`
+}
+
+// incident is an instance unhealthy from start until end, zero if it still is.
+type incident struct {
+	instance  string
+	component string
+	start     time.Time
+	end       time.Time
+}
+
+// Uptime computes the availability of the components over the window ending
+// now, from the statuses recorded by the status commands.
+func (m *Manager) Uptime(specification *spec.Specification, window time.Duration) (*UptimeReport, error) {
+	to := time.Now().UTC()
+	from := to.Add(-window)
+	samples, err := readHistory(m.historyFile(specification), from)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].At.Before(samples[j].At) })
+	incidents := findIncidents(samples)
+
+	report := &UptimeReport{From: from, To: to, Samples: len(samples), Components: componentUptime(samples, incidents)}
+	for start := 0; start < len(samples); {
+		month := samples[start].At.UTC().Format("2006-01")
+		end := start
+		for end < len(samples) && samples[end].At.UTC().Format("2006-01") == month {
+			end++
+		}
+		var monthIncidents []*incident
+		for _, i := range incidents {
+			if i.start.UTC().Format("2006-01") == month {
+				monthIncidents = append(monthIncidents, i)
+			}
+		}
+		report.Months = append(report.Months, &MonthUptime{Month: month, Components: componentUptime(samples[start:end], monthIncidents)})
+		start = end
+	}
+	return report, nil
+}
+
+// findIncidents returns the times an instance went from healthy to unhealthy,
+// and when it was healthy again.
+func findIncidents(samples []*statusSample) (incidents []*incident) {
+	open := make(map[string]*incident)
+	for _, sample := range samples {
+		for instance, component := range sample.Down {
+			if _, found := open[instance]; !found {
+				open[instance] = &incident{instance: instance, component: component, start: sample.At}
+				incidents = append(incidents, open[instance])
+			}
+		}
+		for instance, i := range open {
+			if _, down := sample.Down[instance]; !down && sample.Total[i.component] > 0 {
+				i.end = sample.At
+				delete(open, instance)
+			}
+		}
+	}
+	return
+}
+
+func componentUptime(samples []*statusSample, incidents []*incident) (uptimes []*ComponentUptime) {
+	total := make(map[string]int)
+	down := make(map[string]int)
+	for _, sample := range samples {
+		for component, count := range sample.Total {
+			total[component] += count
+		}
+		for _, component := range sample.Down {
+			down[component]++
+		}
+	}
+	var components []string
+	for component := range total {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		uptime := &ComponentUptime{
+			Component:    component,
+			Availability: 100 * float64(total[component]-down[component]) / float64(total[component]),
+		}
+		var repaired time.Duration
+		var resolved int
+		for _, i := range incidents {
+			if i.component != component {
+				continue
+			}
+			uptime.Incidents++
+			if !i.end.IsZero() {
+				repaired += i.end.Sub(i.start)
+				resolved++
+			}
+		}
+		if resolved > 0 {
+			uptime.MTTR = (repaired / time.Duration(resolved)).Round(time.Second).String()
+		}
+		uptimes = append(uptimes, uptime)
+	}
+	return
+}
+
+// uptimeRows are the rows of the report tables, the window first then each month.
+func uptimeRows(report *UptimeReport) (rows [][]string) {
+	add := func(period string, uptimes []*ComponentUptime) {
+		for _, u := range uptimes {
+			rows = append(rows, []string{period, u.Component, strconv.FormatFloat(u.Availability, 'f', 3, 64), strconv.Itoa(u.Incidents), u.MTTR})
+		}
+	}
+	add("window", report.Components)
+	for _, month := range report.Months {
+		add(month.Month, month.Components)
+	}
+	return
+}
+
+// PrintUptime prints the report as a table.
+func PrintUptime(w io.Writer, report *UptimeReport) {
+	fmt.Fprintf(w, "from %s to %s, %d statuses\n\n", report.From.Local().Format(time.RFC3339), report.To.Local().Format(time.RFC3339), report.Samples)
+	t := output.NewTable(w)
+	fmt.Fprintln(t, "PERIOD\tCOMPONENT\tAVAILABILITY %\tINCIDENTS\tMTTR")
+	for _, row := range uptimeRows(report) {
+		fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\n", row[0], row[1], row[2], row[3], dash(row[4]))
+	}
+	t.Flush()
+}
+
+// WriteUptimeCSV writes the report as CSV, the window first then each month.
+func WriteUptimeCSV(w io.Writer, report *UptimeReport) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"period", "component", "availability_percent", "incidents", "mttr"})
+	c.WriteAll(uptimeRows(report))
+	return c.Error()
+}
@@ -0,0 +1,57 @@
+[Unit]
+Description=Seaweed{{.ComponentInstance}}
+Documentation=https://github.com/seaweedfs/seaweedfs/wiki
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+{{- with .Systemd}}
+{{- if .User}}
+User={{.User}}
+Group={{.Group}}
+{{- end}}
+{{- end}}
+WorkingDirectory={{.DataDir}}
+{{- range $name, $value := .Systemd.Environment}}
+Environment="{{$name}}={{$value}}"
+{{- end}}
+ExecStart=/usr/local/bin/weed -logdir={{.DataDir}} -alsologtostderr=false -config_dir={{.ConfigDir}} {{.Command}} -options={{.ConfigDir}}/{{.Component}}.options
+ExecReload=/bin/kill -s HUP $MAINPID
+{{- if .Unmount}}
+ExecStopPost=-/bin/umount -l {{.Unmount}}
+{{- end}}
+KillMode=process
+KillSignal=SIGINT
+{{- with .Systemd}}
+LimitNOFILE={{.LimitNOFILE}}
+LimitNPROC=infinity
+{{- if .MemoryMax}}
+MemoryMax={{.MemoryMax}}
+{{- end}}
+{{- if .CPUQuota}}
+CPUQuota={{.CPUQuota}}
+{{- end}}
+Restart={{.Restart}}
+RestartSec={{.RestartSec}}
+StartLimitBurst=3
+StartLimitIntervalSec=10
+TasksMax=infinity
+{{- if .ProtectSystem}}
+ProtectSystem={{.ProtectSystem}}
+{{- end}}
+{{- if .ProtectHome}}
+ProtectHome={{.ProtectHome}}
+{{- end}}
+{{- if .PrivateTmp}}
+PrivateTmp=true
+{{- end}}
+{{- if .NoNewPrivileges}}
+NoNewPrivileges=true
+{{- end}}
+{{- end}}
+{{- if .ReadWritePaths}}
+ReadWritePaths={{join .ReadWritePaths " "}}
+{{- end}}
+
+[Install]
+WantedBy=multi-user.target
@@ -0,0 +1,56 @@
+package spec
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML reads the specification, taking the ip of the servers with or
+// without the brackets of IPv6 addresses in URLs.
+func (s *Specification) UnmarshalYAML(value *yaml.Node) error {
+	type plain Specification
+	if err := value.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	for _, ip := range s.ips() {
+		*ip = unbracket(*ip)
+	}
+	return nil
+}
+
+// ips returns the ip fields of all servers of the specification.
+func (s *Specification) ips() (ips []*string) {
+	for _, master := range s.MasterServers {
+		ips = append(ips, &master.Ip, &master.IpBind)
+	}
+	for _, volume := range s.VolumeServers {
+		ips = append(ips, &volume.Ip, &volume.IpBind, &volume.IpPublic)
+	}
+	for _, filer := range s.FilerServers {
+		ips = append(ips, &filer.Ip, &filer.IpBind, &filer.IpPublic)
+	}
+	for _, envoy := range s.EnvoyServers {
+		ips = append(ips, &envoy.Ip)
+	}
+	for _, s3 := range s.S3Servers {
+		ips = append(ips, &s3.Ip, &s3.IpBind)
+	}
+	for _, webdav := range s.WebDAVServers {
+		ips = append(ips, &webdav.Ip)
+	}
+	for _, mount := range s.Mounts {
+		ips = append(ips, &mount.Ip)
+	}
+	for _, broker := range s.MQBrokers {
+		ips = append(ips, &broker.Ip)
+	}
+	return ips
+}
+
+func unbracket(ip string) string {
+	if strings.HasPrefix(ip, "[") && strings.HasSuffix(ip, "]") {
+		return ip[1 : len(ip)-1]
+	}
+	return ip
+}
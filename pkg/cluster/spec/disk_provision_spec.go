@@ -0,0 +1,68 @@
+package spec
+
+// DiskProvisionSpec controls how unmounted disks of volume servers are formatted and mounted.
+type DiskProvisionSpec struct {
+	Disabled     bool              `yaml:"disabled,omitempty"` // leave unmounted disks alone, like deploy --mountDisks=false
+	FsType       string            `yaml:"fs_type,omitempty" default:"ext4"`
+	MkfsOptions  string            `yaml:"mkfs_options,omitempty"`
+	MountOptions string            `yaml:"mount_options,omitempty" default:"noatime"`
+	MountByUUID  bool              `yaml:"mount_by_uuid,omitempty"`
+	Devices      []*DiskDeviceSpec `yaml:"devices,omitempty"`
+}
+
+// DiskDeviceSpec overrides the provisioning options of a single disk.
+type DiskDeviceSpec struct {
+	Device       string `yaml:"device"` // device path, /dev/disk/by-id path or serial number
+	FsType       string `yaml:"fs_type,omitempty"`
+	MkfsOptions  string `yaml:"mkfs_options,omitempty"`
+	MountOptions string `yaml:"mount_options,omitempty"`
+}
+
+// Merge returns the options with empty fields taken from defaults.
+// Device overrides of both are kept, the ones of d first.
+func (d *DiskProvisionSpec) Merge(defaults *DiskProvisionSpec) *DiskProvisionSpec {
+	if d == nil {
+		d = &DiskProvisionSpec{}
+	}
+	if defaults == nil {
+		defaults = &DiskProvisionSpec{}
+	}
+	merged := &DiskProvisionSpec{
+		Disabled:     d.Disabled || defaults.Disabled,
+		FsType:       firstNonEmpty(d.FsType, defaults.FsType, "ext4"),
+		MkfsOptions:  firstNonEmpty(d.MkfsOptions, defaults.MkfsOptions),
+		MountOptions: firstNonEmpty(d.MountOptions, defaults.MountOptions, "noatime"),
+		MountByUUID:  d.MountByUUID || defaults.MountByUUID,
+	}
+	merged.Devices = append(merged.Devices, d.Devices...)
+	merged.Devices = append(merged.Devices, defaults.Devices...)
+	return merged
+}
+
+// ForDevice returns the file system type, mkfs options and mount options for a disk.
+func (d *DiskProvisionSpec) ForDevice(path, byId, serial string) (fsType, mkfsOptions, mountOptions string) {
+	fsType, mkfsOptions, mountOptions = d.FsType, d.MkfsOptions, d.MountOptions
+	for _, device := range d.Devices {
+		if device.Device == "" || (device.Device != path && device.Device != byId && device.Device != serial) {
+			continue
+		}
+		if device.FsType != "" {
+			fsType = device.FsType
+			// options of another file system type do not apply
+			mkfsOptions = ""
+		}
+		mkfsOptions = firstNonEmpty(device.MkfsOptions, mkfsOptions)
+		mountOptions = firstNonEmpty(device.MountOptions, mountOptions)
+		break
+	}
+	return
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, s := range values {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
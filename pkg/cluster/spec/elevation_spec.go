@@ -0,0 +1,27 @@
+package spec
+
+// ElevationSpec is how the SSH user, unless root, runs the commands needing
+// root privileges on the hosts.
+type ElevationSpec struct {
+	Method          string `yaml:"method,omitempty" default:"sudo"`                       // sudo, doas, or none if the user needs no elevation
+	PasswordEnv     string `yaml:"password_env,omitempty" default:"SSH_TARGET_SUDO_PASS"` // environment variable with the sudo password
+	PasswordCommand string `yaml:"password_command,omitempty"`                            // local command printing the sudo password, like pass show seaweed/sudo
+}
+
+// Merge returns the elevation with empty fields taken from defaults.
+func (e *ElevationSpec) Merge(defaults *ElevationSpec) *ElevationSpec {
+	if e == nil {
+		e = &ElevationSpec{}
+	}
+	if defaults == nil {
+		defaults = &ElevationSpec{}
+	}
+	return &ElevationSpec{
+		Method:          firstNonEmpty(e.Method, defaults.Method),
+		PasswordEnv:     firstNonEmpty(e.PasswordEnv, defaults.PasswordEnv),
+		PasswordCommand: firstNonEmpty(e.PasswordCommand, defaults.PasswordCommand),
+	}
+}
+
+// ElevationMethods are the supported values of ElevationSpec.Method.
+var ElevationMethods = []string{"sudo", "doas", "none"}
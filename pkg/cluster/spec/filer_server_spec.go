@@ -10,20 +10,23 @@ type FilerServerSpec struct {
 	PortSsh            int                    `yaml:"port.ssh" default:"22"`
 	IpBind             string                 `yaml:"ip.bind,omitempty"`
 	IpPublic           string                 `yaml:"ip.public,omitempty"`
-	Port               int                    `yaml:"port" default:"9333"`
-	PortGrpc           int                    `yaml:"port.grpc" default:"19333"`
+	Port               int                    `yaml:"port" default:"8888"`
+	PortGrpc           int                    `yaml:"port.grpc" default:"18888"`
 	PortPublic         int                    `yaml:"port.public,omitempty"`
 	DataCenter         string                 `yaml:"dataCenter,omitempty"`
 	Rack               string                 `yaml:"rack,omitempty"`
 	DefaultReplication int                    `yaml:"defaultReplication,omitempty"`
 	MetricsPort        int                    `yaml:"metrics_port,omitempty"`
 	Config             map[string]interface{} `yaml:"config,omitempty"`
+	Options            map[string]string      `yaml:"options,omitempty"` // weed flags seaweed-up does not model, by name
 	Arch               string                 `yaml:"arch,omitempty"`
 	OS                 string                 `yaml:"os,omitempty"`
 	S3                 bool                   `yaml:"s3" default:"false"`
 	S3Port             int                    `yaml:"s3.port" default:"8333"`
 	Webdav             bool                   `yaml:"webdav" default:"false"`
 	WebdavPort         int                    `yaml:"webdav.port" default:"7333"`
+	Systemd            *SystemdSpec           `yaml:"systemd,omitempty"`
+	Ssh                *SshSpec               `yaml:"ssh,omitempty"`
 }
 
 func (f *FilerServerSpec) WriteToBuffer(masters []string, buf *bytes.Buffer) {
@@ -36,4 +39,8 @@ func (f *FilerServerSpec) WriteToBuffer(masters []string, buf *bytes.Buffer) {
 	addToBufferInt(buf, "s3.port", f.S3Port, 8333)
 	addToBufferBool(buf, "webdav", f.Webdav, false)
 	addToBufferInt(buf, "webdav.port", f.WebdavPort, 7333)
+	addToBuffer(buf, "dataCenter", f.DataCenter)
+	addToBuffer(buf, "rack", f.Rack)
+	addToBufferInt(buf, "metricsPort", f.MetricsPort, 0)
+	addOptions(buf, "filer", f.Options)
 }
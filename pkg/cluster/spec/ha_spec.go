@@ -0,0 +1,40 @@
+package spec
+
+import (
+	"net"
+	"strings"
+)
+
+// HighAvailabilitySpec gives the filer or S3 endpoint a floating virtual IP,
+// held by keepalived on the healthy server listed first.
+type HighAvailabilitySpec struct {
+	VirtualIp       string `yaml:"virtual_ip"`                               // like 10.0.0.100/24
+	Interface       string `yaml:"interface,omitempty"`                      // from the route to the virtual IP if empty
+	VirtualRouterId int    `yaml:"virtual_router_id,omitempty" default:"51"` // unique among the VRRP routers of the network
+	AuthPass        string `yaml:"auth_pass,omitempty"`                      // up to 8 characters
+	Component       string `yaml:"component,omitempty"`                      // filer or envoy, envoy if there are envoy servers
+	CheckPort       int    `yaml:"check_port,omitempty"`                     // port that must answer, the S3 port or else the filer port
+}
+
+// Address returns the virtual IP without the prefix length.
+func (ha *HighAvailabilitySpec) Address() string {
+	return strings.SplitN(ha.VirtualIp, "/", 2)[0]
+}
+
+// HighAvailabilityComponent returns the component holding the virtual IP.
+func (s *Specification) HighAvailabilityComponent() string {
+	if s.HighAvailability.Component != "" {
+		return s.HighAvailability.Component
+	}
+	if len(s.EnvoyServers) > 0 {
+		return "envoy"
+	}
+	return "filer"
+}
+
+func validVirtualIp(virtualIp string) bool {
+	if _, _, err := net.ParseCIDR(virtualIp); err == nil {
+		return true
+	}
+	return net.ParseIP(virtualIp) != nil
+}
@@ -0,0 +1,8 @@
+package spec
+
+// HistorySpec is where the revisions of the specification applied to the
+// cluster are kept, besides the state dir of seaweed-up.
+type HistorySpec struct {
+	GitRepo string `yaml:"git_repo,omitempty"` // local git repository each applied revision is committed to
+	GitFile string `yaml:"git_file,omitempty"` // path of the revision in the repository, cluster.yaml by default
+}
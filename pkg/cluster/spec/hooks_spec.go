@@ -0,0 +1,19 @@
+package spec
+
+// HooksSpec lists commands run around deployments, e.g. to drain load
+// balancers or notify a chat. A failing hook stops the deployment.
+type HooksSpec struct {
+	PreDeploy       []*HookSpec `yaml:"pre_deploy,omitempty"`
+	PostDeploy      []*HookSpec `yaml:"post_deploy,omitempty"`
+	PreUpgradeNode  []*HookSpec `yaml:"pre_upgrade_node,omitempty"`  // before each server is deployed
+	PostUpgradeNode []*HookSpec `yaml:"post_upgrade_node,omitempty"` // after each server is deployed
+	OnDrift         []*HookSpec `yaml:"on_drift,omitempty"`          // cluster status found drift, see RemediationSpec
+}
+
+// HookSpec is a shell command, or a local script, run on this machine or on
+// the server being deployed.
+type HookSpec struct {
+	Command string `yaml:"command,omitempty"`
+	Script  string `yaml:"script,omitempty"`
+	Remote  bool   `yaml:"remote,omitempty"` // run on the server, only for node hooks
+}
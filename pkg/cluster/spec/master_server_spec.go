@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
 )
 
 type MasterServerSpec struct {
@@ -14,10 +16,17 @@ type MasterServerSpec struct {
 	PortGrpc           int                    `yaml:"port.grpc" default:"19333"`
 	VolumeSizeLimitMB  int                    `yaml:"volumeSizeLimitMB" default:"5000"`
 	DefaultReplication string                 `yaml:"defaultReplication,omitempty"`
+	RaftHashicorp      bool                   `yaml:"raftHashicorp,omitempty"`     // hashicorp raft instead of the goraft default, the same on all masters
+	RaftBootstrap      bool                   `yaml:"raftBootstrap,omitempty"`     // bootstrap a new hashicorp raft cluster
+	ElectionTimeout    string                 `yaml:"electionTimeout,omitempty"`   // like 10s
+	HeartbeatInterval  string                 `yaml:"heartbeatInterval,omitempty"` // like 300ms
 	MetricsPort        int                    `yaml:"metrics_port,omitempty"`
 	Config             map[string]interface{} `yaml:"config,omitempty"`
+	Options            map[string]string      `yaml:"options,omitempty"` // weed flags seaweed-up does not model, by name
 	Arch               string                 `yaml:"arch,omitempty"`
 	OS                 string                 `yaml:"os,omitempty"`
+	Systemd            *SystemdSpec           `yaml:"systemd,omitempty"`
+	Ssh                *SshSpec               `yaml:"ssh,omitempty"`
 }
 
 func (masterSpec *MasterServerSpec) WriteToBuffer(masters []string, buf *bytes.Buffer) {
@@ -26,10 +35,35 @@ func (masterSpec *MasterServerSpec) WriteToBuffer(masters []string, buf *bytes.B
 	addToBuffer(buf, "ip", masterSpec.Ip)
 	addToBuffer(buf, "ip.bind", masterSpec.IpBind)
 	addToBufferInt(buf, "port", masterSpec.Port, 9333)
-	addToBufferInt(buf, "port.grpc", masterSpec.PortGrpc, 10000+masterSpec.Port)
+	addToBufferInt(buf, "port.grpc", masterSpec.PortGrpc, 10000+defaultInt(masterSpec.Port, 9333))
 	addToBufferInt(buf, "volumeSizeLimitMB", masterSpec.VolumeSizeLimitMB, 30000)
 	addToBuffer(buf, "defaultReplication", masterSpec.DefaultReplication)
+	addToBufferBool(buf, "raftHashicorp", masterSpec.RaftHashicorp, false)
+	addToBufferBool(buf, "raftBootstrap", masterSpec.RaftBootstrap, false)
+	addToBuffer(buf, "electionTimeout", masterSpec.ElectionTimeout)
+	addToBuffer(buf, "heartbeatInterval", masterSpec.HeartbeatInterval)
+	addToBufferInt(buf, "metricsPort", masterSpec.MetricsPort, 0)
+	addOptions(buf, "master", masterSpec.Options)
+}
+
+// raftHashicorp tells whether the master runs hashicorp raft, by its field
+// or its options.
+func (masterSpec *MasterServerSpec) raftHashicorp() bool {
+	if value, found := masterSpec.Options["raftHashicorp"]; found {
+		return value == "true"
+	}
+	return masterSpec.RaftHashicorp
+}
 
+// ServerAddress returns the address of the master on host as the -peers and
+// -master flags of weed take it, with the gRPC port when it is not the http
+// port + 10000.
+func (masterSpec *MasterServerSpec) ServerAddress(host string) string {
+	port := defaultInt(masterSpec.Port, 9333)
+	if grpcPort := defaultInt(masterSpec.PortGrpc, port+10000); grpcPort != port+10000 {
+		return fmt.Sprintf("%s.%d", utils.JoinHostPort(host, port), grpcPort)
+	}
+	return utils.JoinHostPort(host, port)
 }
 
 func addToBuffer(buf *bytes.Buffer, name, value string) {
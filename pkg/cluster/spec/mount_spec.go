@@ -0,0 +1,48 @@
+package spec
+
+import (
+	"bytes"
+	"strings"
+)
+
+// MountSpec is a weed mount of a filer folder on a host, a FUSE file system.
+type MountSpec struct {
+	Ip              string            `yaml:"ip"`
+	PortSsh         int               `yaml:"port.ssh" default:"22"`
+	Dir             string            `yaml:"dir"`                  // the mount point, created if missing
+	Filer           string            `yaml:"filer,omitempty"`      // host:port, all filer servers if empty
+	FilerPath       string            `yaml:"filer.path,omitempty"` // the folder of the filer mounted, / if empty
+	Collection      string            `yaml:"collection,omitempty"`
+	Replication     string            `yaml:"replication,omitempty"`
+	CacheDir        string            `yaml:"cacheDir,omitempty"`
+	CacheCapacityMB int               `yaml:"cacheCapacityMB,omitempty"`
+	ReadOnly        bool              `yaml:"readOnly,omitempty"`
+	Options         map[string]string `yaml:"options,omitempty"` // weed flags seaweed-up does not model, by name
+	Arch            string            `yaml:"arch,omitempty"`
+	OS              string            `yaml:"os,omitempty"`
+	Systemd         *SystemdSpec      `yaml:"systemd,omitempty"`
+	Ssh             *SshSpec          `yaml:"ssh,omitempty"`
+}
+
+// Filers returns the filers the mount connects to, by default all of filers.
+func (mount *MountSpec) Filers(filers []string) []string {
+	if mount.Filer != "" {
+		return []string{mount.Filer}
+	}
+	return filers
+}
+
+func (mount *MountSpec) WriteToBuffer(filers []string, buf *bytes.Buffer) {
+	addToBuffer(buf, "dir", mount.Dir)
+	addToBuffer(buf, "dirAutoCreate", "true")
+	addToBuffer(buf, "filer", strings.Join(mount.Filers(filers), ","))
+	if mount.FilerPath != "/" {
+		addToBuffer(buf, "filer.path", mount.FilerPath)
+	}
+	addToBuffer(buf, "collection", mount.Collection)
+	addToBuffer(buf, "replication", mount.Replication)
+	addToBuffer(buf, "cacheDir", mount.CacheDir)
+	addToBufferInt(buf, "cacheCapacityMB", mount.CacheCapacityMB, 0)
+	addToBufferBool(buf, "readOnly", mount.ReadOnly, false)
+	addOptions(buf, "mount", mount.Options)
+}
@@ -0,0 +1,33 @@
+package spec
+
+import (
+	"bytes"
+	"strings"
+)
+
+// MQBrokerSpec is a weed mq.broker, serving the topics of the message queue
+// over gRPC. The brokers find the filers, where they keep the messages,
+// through the masters.
+type MQBrokerSpec struct {
+	Ip         string            `yaml:"ip"`
+	PortSsh    int               `yaml:"port.ssh" default:"22"`
+	Port       int               `yaml:"port" default:"17777"`
+	FilerGroup string            `yaml:"filerGroup,omitempty"`
+	DataCenter string            `yaml:"dataCenter,omitempty"`
+	Rack       string            `yaml:"rack,omitempty"`
+	Options    map[string]string `yaml:"options,omitempty"` // weed flags seaweed-up does not model, by name
+	Arch       string            `yaml:"arch,omitempty"`
+	OS         string            `yaml:"os,omitempty"`
+	Systemd    *SystemdSpec      `yaml:"systemd,omitempty"`
+	Ssh        *SshSpec          `yaml:"ssh,omitempty"`
+}
+
+func (b *MQBrokerSpec) WriteToBuffer(masters []string, buf *bytes.Buffer) {
+	addToBuffer(buf, "ip", b.Ip)
+	addToBufferInt(buf, "port", b.Port, 17777)
+	addToBuffer(buf, "master", strings.Join(masters, ","))
+	addToBuffer(buf, "filerGroup", b.FilerGroup)
+	addToBuffer(buf, "dataCenter", b.DataCenter)
+	addToBuffer(buf, "rack", b.Rack)
+	addOptions(buf, "mq.broker", b.Options)
+}
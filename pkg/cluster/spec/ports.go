@@ -0,0 +1,59 @@
+package spec
+
+// ListenPorts returns the ports the master listens on.
+func (masterSpec *MasterServerSpec) ListenPorts() []int {
+	port := defaultInt(masterSpec.Port, 9333)
+	return []int{port, defaultInt(masterSpec.PortGrpc, port+10000)}
+}
+
+// ListenPorts returns the ports the volume server listens on.
+func (vs *VolumeServerSpec) ListenPorts() []int {
+	port := defaultInt(vs.Port, 8080)
+	return []int{port, defaultInt(vs.PortGrpc, port+10000)}
+}
+
+// ListenPorts returns the ports the filer listens on, including the embedded S3 and WebDAV servers.
+func (f *FilerServerSpec) ListenPorts() []int {
+	port := defaultInt(f.Port, 8888)
+	ports := []int{port, defaultInt(f.PortGrpc, port+10000)}
+	if f.S3 || f.S3Port != 0 {
+		ports = append(ports, defaultInt(f.S3Port, 8333))
+	}
+	if f.Webdav || f.WebdavPort != 0 {
+		ports = append(ports, defaultInt(f.WebdavPort, 7333))
+	}
+	return ports
+}
+
+// ListenPorts returns the ports the s3 gateway listens on.
+func (s *S3ServerSpec) ListenPorts() []int {
+	port := defaultInt(s.Port, 8333)
+	return []int{port, defaultInt(s.PortGrpc, port+10000)}
+}
+
+// ListenPorts returns the port the webdav server listens on.
+func (w *WebDAVServerSpec) ListenPorts() []int {
+	return []int{defaultInt(w.Port, 7333)}
+}
+
+// ListenPorts returns the gRPC port the message queue broker listens on.
+func (b *MQBrokerSpec) ListenPorts() []int {
+	return []int{defaultInt(b.Port, 17777)}
+}
+
+// ListenPorts returns the ports the envoy proxy listens on.
+func (e *EnvoyServerSpec) ListenPorts() (ports []int) {
+	for _, port := range []int{e.FilerPort, e.FilerGrpcPort, e.S3Port, e.WebdavPort} {
+		if port != 0 {
+			ports = append(ports, port)
+		}
+	}
+	return
+}
+
+func defaultInt(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,12 @@
+package spec
+
+// RemediationSpec is what cluster status does about the drift it finds, by
+// category: off ignores it, notify runs the on_drift hooks, and auto restores
+// the desired state and then runs the on_drift hooks.
+type RemediationSpec struct {
+	Service string `yaml:"service,omitempty" default:"off"` // the service of an instance is not active
+	Config  string `yaml:"config,omitempty" default:"off"`  // the systemd unit of an instance differs from the configuration
+}
+
+// RemediationPolicies are the supported values of the RemediationSpec fields.
+var RemediationPolicies = []string{"off", "notify", "auto"}
@@ -0,0 +1,112 @@
+package spec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Render expands the template actions and environment variables of a specification file.
+//
+// Template actions use Go text/template syntax with these functions:
+//
+//	env "NAME"              value of an environment variable
+//	default "x" VALUE       VALUE, or "x" if VALUE is empty
+//	split "a,b" ","         split a string into a list, e.g. to range over hosts
+//	seq 3                   the list 0, 1, 2
+//	add 1 2                 sum of integers
+//
+// After that, ${NAME} is replaced with the environment variable NAME,
+// ${NAME:-x} falls back to x when NAME is unset or empty, and $${ is a literal ${.
+// Referencing an unset variable without a fallback is an error. Comment lines are
+// left as they are.
+func Render(data []byte) ([]byte, error) {
+	if bytes.Contains(data, []byte("{{")) {
+		t, err := template.New("spec").Funcs(templateFuncs).Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("parse template: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, map[string]interface{}{"Env": environ()}); err != nil {
+			return nil, fmt.Errorf("render template: %v", err)
+		}
+		data = buf.Bytes()
+	}
+	return interpolate(data)
+}
+
+// TemplateFuncs returns the functions available in specification templates.
+func TemplateFuncs() template.FuncMap {
+	return templateFuncs
+}
+
+var templateFuncs = template.FuncMap{
+	"env": os.Getenv,
+	"default": func(defaultValue string, value interface{}) interface{} {
+		if value == nil || fmt.Sprint(value) == "" {
+			return defaultValue
+		}
+		return value
+	},
+	"split": func(s, sep string) []string {
+		var items []string
+		for _, item := range strings.Split(s, sep) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	},
+	"seq": func(n int) []int {
+		items := make([]int, n)
+		for i := range items {
+			items[i] = i
+		}
+		return items
+	},
+	"add": func(a, b int) int { return a + b },
+}
+
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, found := strings.Cut(kv, "="); found {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+func interpolate(data []byte) ([]byte, error) {
+	var missing []string
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines {
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			continue
+		}
+		lines[i] = variablePattern.ReplaceAllFunc(line, func(match []byte) []byte {
+			if bytes.HasPrefix(match, []byte("$$")) {
+				return match[1:]
+			}
+			groups := variablePattern.FindSubmatch(match)
+			name, hasDefault, defaultValue := string(groups[1]), len(groups[2]) > 0, groups[3]
+			if value := os.Getenv(name); value != "" {
+				return []byte(value)
+			}
+			if hasDefault {
+				return defaultValue
+			}
+			missing = append(missing, name)
+			return match
+		})
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	return bytes.Join(lines, nil), nil
+}
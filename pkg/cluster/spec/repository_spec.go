@@ -0,0 +1,13 @@
+package spec
+
+// DefaultRepositoryURL is where the weed release archives are downloaded from.
+// {version} and {asset} are replaced with the release tag and the archive name,
+// e.g. 3.59 and linux_amd64_full_large_disk.tar.gz.
+const DefaultRepositoryURL = "https://github.com/seaweedfs/seaweedfs/releases/download/{version}/{asset}"
+
+// RepositorySpec is an alternative source of the weed release archives, like an
+// internal HTTP server, an S3 bucket or an Artifactory repository.
+type RepositorySpec struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"` // e.g. Authorization
+}
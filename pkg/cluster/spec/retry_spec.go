@@ -0,0 +1,64 @@
+package spec
+
+import "time"
+
+// RetrySpec is how remote operations are retried on transient errors, like
+// refused connections, timeouts and dropped sessions, with exponential backoff
+// and jitter between the attempts. Commands failing on a host are not retried.
+type RetrySpec struct {
+	MaxAttempts    int                         `yaml:"max_attempts,omitempty" default:"3"`     // attempts of an operation, 1 never retries
+	InitialBackoff string                      `yaml:"initial_backoff,omitempty" default:"1s"` // wait before the second attempt, doubled for each one after
+	MaxBackoff     string                      `yaml:"max_backoff,omitempty" default:"30s"`    // longest wait between two attempts
+	CircuitBreaker int                         `yaml:"circuit_breaker,omitempty" default:"2"`  // failures of a host in a row after which it is skipped for the rest of the run, -1 never
+	Tasks          map[string]*RetryPolicySpec `yaml:"tasks,omitempty"`                        // overrides by task type, see RetryTaskTypes
+}
+
+// RetryPolicySpec overrides the retries of RetrySpec for a task type.
+type RetryPolicySpec struct {
+	MaxAttempts    int    `yaml:"max_attempts,omitempty"`
+	InitialBackoff string `yaml:"initial_backoff,omitempty"`
+	MaxBackoff     string `yaml:"max_backoff,omitempty"`
+}
+
+// RetryTaskTypes are the task types of RetrySpec.Tasks: connecting to a host
+// with SSH, and deploying a server on it.
+var RetryTaskTypes = []string{"connect", "deploy"}
+
+// Policy returns the retries of the task type, the fields it does not
+// override taken from the spec.
+func (s *RetrySpec) Policy(task string) *RetryPolicySpec {
+	if s == nil {
+		return &RetryPolicySpec{}
+	}
+	policy := &RetryPolicySpec{MaxAttempts: s.MaxAttempts, InitialBackoff: s.InitialBackoff, MaxBackoff: s.MaxBackoff}
+	if override := s.Tasks[task]; override != nil {
+		if override.MaxAttempts != 0 {
+			policy.MaxAttempts = override.MaxAttempts
+		}
+		policy.InitialBackoff = firstNonEmpty(override.InitialBackoff, policy.InitialBackoff)
+		policy.MaxBackoff = firstNonEmpty(override.MaxBackoff, policy.MaxBackoff)
+	}
+	return policy
+}
+
+// parseBackoff is the parsed duration, 0 if empty or invalid.
+func parseBackoff(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Initial is the parsed InitialBackoff, 0 if empty or invalid.
+func (p *RetryPolicySpec) Initial() time.Duration {
+	return parseBackoff(p.InitialBackoff)
+}
+
+// Max is the parsed MaxBackoff, 0 if empty or invalid.
+func (p *RetryPolicySpec) Max() time.Duration {
+	return parseBackoff(p.MaxBackoff)
+}
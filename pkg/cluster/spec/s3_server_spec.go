@@ -0,0 +1,48 @@
+package spec
+
+import (
+	"bytes"
+)
+
+// S3ServerSpec is a standalone weed s3 gateway in front of the filers,
+// scaled apart from them, unlike the S3 server embedded in a filer.
+type S3ServerSpec struct {
+	Ip          string            `yaml:"ip"`
+	PortSsh     int               `yaml:"port.ssh" default:"22"`
+	IpBind      string            `yaml:"ip.bind,omitempty"`
+	Port        int               `yaml:"port" default:"8333"`
+	PortGrpc    int               `yaml:"port.grpc" default:"18333"`
+	Filer       string            `yaml:"filer,omitempty"` // host:port, the filer servers take turns if empty
+	DomainName  string            `yaml:"domainName,omitempty"`
+	Config      string            `yaml:"config,omitempty"`    // local file of the identities and their keys, uploaded
+	CertFile    string            `yaml:"cert.file,omitempty"` // local certificate file, uploaded, to serve https
+	KeyFile     string            `yaml:"key.file,omitempty"`  // local private key file of the certificate, uploaded
+	MetricsPort int               `yaml:"metrics_port,omitempty"`
+	Options     map[string]string `yaml:"options,omitempty"` // weed flags seaweed-up does not model, by name
+	Arch        string            `yaml:"arch,omitempty"`
+	OS          string            `yaml:"os,omitempty"`
+	Systemd     *SystemdSpec      `yaml:"systemd,omitempty"`
+	Ssh         *SshSpec          `yaml:"ssh,omitempty"`
+}
+
+// FilerOf returns the filer the index-th gateway connects to, its own or
+// else one of filers in turn, so the gateways spread over the filers.
+func (s *S3ServerSpec) FilerOf(filers []string, index int) string {
+	if s.Filer != "" || len(filers) == 0 {
+		return s.Filer
+	}
+	return filers[index%len(filers)]
+}
+
+// WriteToBuffer writes the options of the gateway connecting to filer. The
+// uploaded config and certificate files are added by the deployment, where
+// their paths are known.
+func (s *S3ServerSpec) WriteToBuffer(filer string, buf *bytes.Buffer) {
+	addToBuffer(buf, "ip.bind", s.IpBind)
+	addToBufferInt(buf, "port", s.Port, 8333)
+	addToBufferInt(buf, "port.grpc", s.PortGrpc, 10000+defaultInt(s.Port, 8333))
+	addToBuffer(buf, "filer", filer)
+	addToBuffer(buf, "domainName", s.DomainName)
+	addToBufferInt(buf, "metricsPort", s.MetricsPort, 0)
+	addOptions(buf, "s3", s.Options)
+}
@@ -0,0 +1,71 @@
+package spec
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONSchema describes the cluster specification file as a JSON Schema document.
+func JSONSchema() map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(Specification{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "seaweed-up cluster specification"
+	return schema
+}
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+		for name, field := range yamlFields(t) {
+			property := typeSchema(field.Type)
+			if value, found := field.Tag.Lookup("default"); found {
+				property["default"] = defaultValue(field.Type, value)
+			}
+			properties[name] = property
+			if name == "ip" || name == "folder" || name == "device" {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+func defaultValue(t reflect.Type, value string) interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		return value == "true"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return strings.TrimSpace(value)
+}
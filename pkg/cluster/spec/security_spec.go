@@ -0,0 +1,36 @@
+package spec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SecuritySpec is the security.toml of the weed servers and of the weed
+// shell commands seaweed-up runs on the hosts.
+type SecuritySpec struct {
+	JwtSigningKey string `yaml:"jwt.signing.key,omitempty"` // volume servers accept only writes signed with it
+	CaFile        string `yaml:"ca.file,omitempty"`         // local CA file, uploaded, enables mutual TLS on the gRPC ports
+	CertFile      string `yaml:"cert.file,omitempty"`       // local certificate of all servers and clients, signed by the CA, uploaded
+	KeyFile       string `yaml:"key.file,omitempty"`        // local private key of the certificate, uploaded
+}
+
+// TLS tells if the gRPC ports use mutual TLS.
+func (s *SecuritySpec) TLS() bool {
+	return s != nil && s.CaFile != ""
+}
+
+// Toml returns the security.toml of the files installed in dir, the CA,
+// certificate and key as ca.crt, tls.crt and tls.key.
+func (s *SecuritySpec) Toml(dir string) string {
+	var b strings.Builder
+	if s.JwtSigningKey != "" {
+		fmt.Fprintf(&b, "[jwt.signing]\nkey = %q\n\n", s.JwtSigningKey)
+	}
+	if s.TLS() {
+		fmt.Fprintf(&b, "[grpc]\nca = %q\n", dir+"/ca.crt")
+		for _, section := range []string{"master", "volume", "filer", "s3", "msg_broker", "client"} {
+			fmt.Fprintf(&b, "\n[grpc.%s]\ncert = %q\nkey = %q\n", section, dir+"/tls.crt", dir+"/tls.key")
+		}
+	}
+	return b.String()
+}
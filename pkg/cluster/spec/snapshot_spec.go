@@ -0,0 +1,11 @@
+package spec
+
+// SnapshotSpec tunes the snapshots deploy takes before and after upgrading a
+// cluster: the remote configuration, systemd units, master raft state and
+// filer metadata, archived on the machine running seaweed-up.
+type SnapshotSpec struct {
+	Disabled          bool   `yaml:"disabled,omitempty"`
+	Dir               string `yaml:"dir,omitempty"`                 // local directory of the archives, snapshots in the state dir by default
+	Keep              int    `yaml:"keep,omitempty"`                // archives kept per cluster, the oldest removed first, 0 keeps all
+	SkipFilerMetadata bool   `yaml:"skip_filer_metadata,omitempty"` // leave out the filer metadata, when too large to save on each deploy
+}
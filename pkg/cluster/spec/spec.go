@@ -4,12 +4,25 @@ type (
 	// GlobalOptions represents the global options for all groups in topology
 	// specification in topology.yaml
 	GlobalOptions struct {
-		TLSEnabled        bool   `yaml:"enable_tls,omitempty"`
-		ConfigDir         string `yaml:"dir.conf,omitempty" default:"/etc/seaweed"`
-		DataDir           string `yaml:"dir.data,omitempty" default:"/opt/seaweed"`
-		OS                string `yaml:"os,omitempty" default:"linux"`
-		VolumeSizeLimitMB int    `yaml:"volumeSizeLimitMB" default:"5000"`
-		Replication       string `yaml:"replication" default:"000"`
+		TLSEnabled        bool               `yaml:"enable_tls,omitempty"`
+		ConfigDir         string             `yaml:"dir.conf,omitempty" default:"/etc/seaweed"`
+		DataDir           string             `yaml:"dir.data,omitempty" default:"/opt/seaweed"`
+		OS                string             `yaml:"os,omitempty" default:"linux"`
+		VolumeSizeLimitMB int                `yaml:"volumeSizeLimitMB" default:"5000"`
+		Replication       string             `yaml:"replication" default:"000"`
+		Disks             *DiskProvisionSpec `yaml:"disks,omitempty"`
+		Security          *SecuritySpec      `yaml:"security,omitempty"`
+		Systemd           *SystemdSpec       `yaml:"systemd,omitempty"`
+		Repository        *RepositorySpec    `yaml:"repository,omitempty"`
+		TimeSync          *TimeSyncSpec      `yaml:"time_sync,omitempty"`
+		Tuning            *TuningSpec        `yaml:"tuning,omitempty"`
+		Elevation         *ElevationSpec     `yaml:"elevation,omitempty"`
+		Ssh               *SshSpec           `yaml:"ssh,omitempty"`
+		History           *HistorySpec       `yaml:"history,omitempty"`
+		Snapshots         *SnapshotSpec      `yaml:"snapshots,omitempty"`
+		Remediation       *RemediationSpec   `yaml:"remediation,omitempty"`
+		Retry             *RetrySpec         `yaml:"retry,omitempty"`
+		DualStack         bool               `yaml:"dual_stack,omitempty"` // listen on IPv4 and IPv6, ip.bind :: unless a server sets its own
 	}
 
 	ServerConfigs struct {
@@ -25,5 +38,12 @@ type (
 		VolumeServers []*VolumeServerSpec `yaml:"volume_servers"`
 		FilerServers  []*FilerServerSpec  `yaml:"filer_servers"`
 		EnvoyServers  []*EnvoyServerSpec  `yaml:"envoy_servers"`
+		S3Servers     []*S3ServerSpec     `yaml:"s3_servers,omitempty"`
+		WebDAVServers []*WebDAVServerSpec `yaml:"webdav_servers,omitempty"`
+		Mounts        []*MountSpec        `yaml:"mounts,omitempty"`
+		MQBrokers     []*MQBrokerSpec     `yaml:"mq_brokers,omitempty"`
+		Hooks         HooksSpec           `yaml:"hooks,omitempty"`
+
+		HighAvailability *HighAvailabilitySpec `yaml:"ha,omitempty"`
 	}
 )
@@ -0,0 +1,45 @@
+package spec
+
+import "time"
+
+// SshSpec overrides how seaweed-up logs in to a host, the ssh port being
+// port.ssh of the server. Empty fields fall back to global.ssh, then to the
+// flags of the command.
+type SshSpec struct {
+	User           string         `yaml:"user,omitempty"`
+	IdentityFile   string         `yaml:"identity_file,omitempty"`
+	PasswordEnv    string         `yaml:"password_env,omitempty"`    // environment variable with the password, to login with a password
+	ProxyJump      string         `yaml:"proxy_jump,omitempty"`      // [user@]host[:port] of a bastion to connect through
+	ConnectTimeout string         `yaml:"connect_timeout,omitempty"` // like 10s
+	Elevation      *ElevationSpec `yaml:"elevation,omitempty"`       // of the host, global.elevation if empty
+}
+
+// Merge returns the options with empty fields taken from defaults.
+func (s *SshSpec) Merge(defaults *SshSpec) *SshSpec {
+	if s == nil {
+		s = &SshSpec{}
+	}
+	if defaults == nil {
+		defaults = &SshSpec{}
+	}
+	return &SshSpec{
+		User:           firstNonEmpty(s.User, defaults.User),
+		IdentityFile:   firstNonEmpty(s.IdentityFile, defaults.IdentityFile),
+		PasswordEnv:    firstNonEmpty(s.PasswordEnv, defaults.PasswordEnv),
+		ProxyJump:      firstNonEmpty(s.ProxyJump, defaults.ProxyJump),
+		ConnectTimeout: firstNonEmpty(s.ConnectTimeout, defaults.ConnectTimeout),
+		Elevation:      s.Elevation.Merge(defaults.Elevation),
+	}
+}
+
+// Timeout is the parsed ConnectTimeout, 0 if empty or invalid.
+func (s *SshSpec) Timeout() time.Duration {
+	if s == nil || s.ConnectTimeout == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(s.ConnectTimeout)
+	if err != nil {
+		return 0
+	}
+	return timeout
+}
@@ -0,0 +1,72 @@
+package spec
+
+// SystemdSpec customizes the systemd unit of a component.
+type SystemdSpec struct {
+	User            string            `yaml:"user,omitempty"` // created if missing, the service runs as root if empty
+	Group           string            `yaml:"group,omitempty"`
+	UID             int               `yaml:"uid,omitempty"` // of the created user, the next system uid if 0
+	GID             int               `yaml:"gid,omitempty"` // of the created group, the next system gid if 0
+	Home            string            `yaml:"home,omitempty"`
+	Shell           string            `yaml:"shell,omitempty" default:"/usr/sbin/nologin"`
+	LimitNOFILE     string            `yaml:"limit_nofile,omitempty" default:"infinity"`
+	MemoryMax       string            `yaml:"memory_max,omitempty"`
+	CPUQuota        string            `yaml:"cpu_quota,omitempty"`
+	Environment     map[string]string `yaml:"environment,omitempty"`
+	Restart         string            `yaml:"restart,omitempty" default:"on-failure"`
+	RestartSec      string            `yaml:"restart_sec,omitempty" default:"2"`
+	ProtectSystem   string            `yaml:"protect_system,omitempty"` // true, full or strict
+	ProtectHome     string            `yaml:"protect_home,omitempty"`   // true, read-only or tmpfs
+	PrivateTmp      bool              `yaml:"private_tmp,omitempty"`
+	NoNewPrivileges bool              `yaml:"no_new_privileges,omitempty"`
+	ReadWritePaths  []string          `yaml:"read_write_paths,omitempty"`
+}
+
+// Merge returns the options with empty fields taken from defaults.
+// Environment variables and writable paths of both are kept, the ones of s win.
+func (s *SystemdSpec) Merge(defaults *SystemdSpec) *SystemdSpec {
+	if s == nil {
+		s = &SystemdSpec{}
+	}
+	if defaults == nil {
+		defaults = &SystemdSpec{}
+	}
+	merged := &SystemdSpec{
+		User:            firstNonEmpty(s.User, defaults.User),
+		Group:           firstNonEmpty(s.Group, defaults.Group),
+		UID:             firstNonZero(s.UID, defaults.UID),
+		GID:             firstNonZero(s.GID, defaults.GID),
+		Home:            firstNonEmpty(s.Home, defaults.Home),
+		Shell:           firstNonEmpty(s.Shell, defaults.Shell, "/usr/sbin/nologin"),
+		LimitNOFILE:     firstNonEmpty(s.LimitNOFILE, defaults.LimitNOFILE, "infinity"),
+		MemoryMax:       firstNonEmpty(s.MemoryMax, defaults.MemoryMax),
+		CPUQuota:        firstNonEmpty(s.CPUQuota, defaults.CPUQuota),
+		Restart:         firstNonEmpty(s.Restart, defaults.Restart, "on-failure"),
+		RestartSec:      firstNonEmpty(s.RestartSec, defaults.RestartSec, "2"),
+		ProtectSystem:   firstNonEmpty(s.ProtectSystem, defaults.ProtectSystem),
+		ProtectHome:     firstNonEmpty(s.ProtectHome, defaults.ProtectHome),
+		PrivateTmp:      s.PrivateTmp || defaults.PrivateTmp,
+		NoNewPrivileges: s.NoNewPrivileges || defaults.NoNewPrivileges,
+		Environment:     make(map[string]string),
+	}
+	if merged.Group == "" {
+		merged.Group = merged.User
+	}
+	for name, value := range defaults.Environment {
+		merged.Environment[name] = value
+	}
+	for name, value := range s.Environment {
+		merged.Environment[name] = value
+	}
+	merged.ReadWritePaths = append(merged.ReadWritePaths, defaults.ReadWritePaths...)
+	merged.ReadWritePaths = append(merged.ReadWritePaths, s.ReadWritePaths...)
+	return merged
+}
+
+func firstNonZero(values ...int) int {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
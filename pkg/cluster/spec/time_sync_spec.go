@@ -0,0 +1,7 @@
+package spec
+
+// TimeSyncSpec makes deploy install and enable chrony on all hosts. Raft and
+// S3 signatures break when the clocks of the hosts drift apart.
+type TimeSyncSpec struct {
+	Servers []string `yaml:"servers,omitempty"` // NTP servers, the configuration of the distribution is kept if empty
+}
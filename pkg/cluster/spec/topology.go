@@ -0,0 +1,93 @@
+package spec
+
+import (
+	"fmt"
+)
+
+// the data center and rack weed puts servers without one in
+const (
+	DefaultDataCenter = "DefaultDataCenter"
+	DefaultRack       = "DefaultRack"
+)
+
+// Location returns the data center and rack of the volume server, as weed
+// registers it.
+func (vs *VolumeServerSpec) Location() (dataCenter, rack string) {
+	return location(vs.DataCenter, vs.Rack)
+}
+
+// Location returns the data center and rack of the filer, as weed registers it.
+func (f *FilerServerSpec) Location() (dataCenter, rack string) {
+	return location(f.DataCenter, f.Rack)
+}
+
+// Location returns the data center and rack of the message queue broker.
+func (b *MQBrokerSpec) Location() (dataCenter, rack string) {
+	return location(b.DataCenter, b.Rack)
+}
+
+func location(dataCenter, rack string) (string, string) {
+	if dataCenter == "" {
+		dataCenter = DefaultDataCenter
+	}
+	if rack == "" {
+		rack = DefaultRack
+	}
+	return dataCenter, rack
+}
+
+// Warnings returns the problems of the specification that do not stop a
+// deployment, like replications the volume servers can not place.
+func (s *Specification) Warnings() (warnings []FieldError) {
+	if n := len(s.MasterServers); n > 1 && n%2 == 0 {
+		warnings = append(warnings, FieldError{Path: "master_servers", Message: fmt.Sprintf("raft needs a majority of the masters, %d masters survive as many failures as %d", n, n-1)})
+	}
+	if len(s.VolumeServers) == 0 {
+		return warnings
+	}
+	racks := make(map[string]map[string]int) // volume servers by rack by data center
+	for _, volume := range s.VolumeServers {
+		dataCenter, rack := volume.Location()
+		if racks[dataCenter] == nil {
+			racks[dataCenter] = make(map[string]int)
+		}
+		racks[dataCenter][rack]++
+	}
+	mostRacks, mostServers := 0, 0
+	for _, byRack := range racks {
+		if len(byRack) > mostRacks {
+			mostRacks = len(byRack)
+		}
+		for _, servers := range byRack {
+			if servers > mostServers {
+				mostServers = servers
+			}
+		}
+	}
+
+	checkReplication := func(path, replication string) {
+		if !replicationPattern.MatchString(replication) {
+			return
+		}
+		// the digits are the copies in other data centers, other racks and other servers of the rack
+		dataCenters, rackCopies, serverCopies := int(replication[0]-'0'), int(replication[1]-'0'), int(replication[2]-'0')
+		if dataCenters+1 > len(racks) {
+			warnings = append(warnings, FieldError{Path: path, Message: fmt.Sprintf("replication %s needs volume servers in %d data centers, they are in %d", replication, dataCenters+1, len(racks))})
+		}
+		if rackCopies+1 > mostRacks {
+			warnings = append(warnings, FieldError{Path: path, Message: fmt.Sprintf("replication %s needs %d racks in a data center, the data centers have at most %d", replication, rackCopies+1, mostRacks)})
+		}
+		if serverCopies+1 > mostServers {
+			warnings = append(warnings, FieldError{Path: path, Message: fmt.Sprintf("replication %s needs %d volume servers in a rack, the racks have at most %d", replication, serverCopies+1, mostServers)})
+		}
+	}
+	checkReplication("global.replication", s.GlobalOptions.Replication)
+	for i, master := range s.MasterServers {
+		if replication, found := master.Options["defaultReplication"]; found {
+			checkReplication(fmt.Sprintf("master_servers[%d].options.defaultReplication", i), replication)
+		} else {
+			checkReplication(fmt.Sprintf("master_servers[%d].defaultReplication", i), master.DefaultReplication)
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,77 @@
+package spec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TuningSpec is the kernel tuning deploy applies to all hosts: sysctl
+// settings, transparent hugepages and the open files limit of the systemd
+// units. Settings given here override the ones of the profile.
+type TuningSpec struct {
+	Profile              string            `yaml:"profile,omitempty"`               // a built-in profile, like high-throughput
+	Sysctl               map[string]string `yaml:"sysctl,omitempty"`                // like vm.swappiness: 1
+	TransparentHugepages string            `yaml:"transparent_hugepages,omitempty"` // always, madvise or never
+	LimitNOFILE          string            `yaml:"limit_nofile,omitempty"`          // default of systemd.limit_nofile
+}
+
+// TuningProfiles are the built-in tuning profiles.
+var TuningProfiles = map[string]*TuningSpec{
+	"high-throughput": {
+		Sysctl: map[string]string{
+			"fs.file-max":                  "2097152",
+			"net.core.somaxconn":           "65535",
+			"net.core.netdev_max_backlog":  "16384",
+			"net.core.rmem_max":            "16777216",
+			"net.core.wmem_max":            "16777216",
+			"net.ipv4.tcp_max_syn_backlog": "8192",
+			"net.ipv4.ip_local_port_range": "1024 65535",
+			"vm.swappiness":                "1",
+		},
+		TransparentHugepages: "never",
+		LimitNOFILE:          "1048576",
+	},
+}
+
+// Resolve returns the settings of the profile with the ones of t applied.
+func (t *TuningSpec) Resolve() (*TuningSpec, error) {
+	resolved := &TuningSpec{Profile: t.Profile, Sysctl: make(map[string]string)}
+	if t.Profile != "" {
+		profile, found := TuningProfiles[t.Profile]
+		if !found {
+			return nil, fmt.Errorf("unknown tuning profile %q, known are %s", t.Profile, strings.Join(TuningProfileNames(), ", "))
+		}
+		for key, value := range profile.Sysctl {
+			resolved.Sysctl[key] = value
+		}
+		resolved.TransparentHugepages = profile.TransparentHugepages
+		resolved.LimitNOFILE = profile.LimitNOFILE
+	}
+	for key, value := range t.Sysctl {
+		resolved.Sysctl[key] = value
+	}
+	resolved.TransparentHugepages = firstNonEmpty(t.TransparentHugepages, resolved.TransparentHugepages)
+	resolved.LimitNOFILE = firstNonEmpty(t.LimitNOFILE, resolved.LimitNOFILE)
+	return resolved, nil
+}
+
+// SysctlKeys returns the sysctl settings in order.
+func (t *TuningSpec) SysctlKeys() []string {
+	var keys []string
+	for key := range t.Sysctl {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TuningProfileNames lists the built-in tuning profiles.
+func TuningProfileNames() []string {
+	var names []string
+	for name := range TuningProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,510 @@
+package spec
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a problem found at a position of the specification file.
+type FieldError struct {
+	Line    int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Column  int    `json:"column,omitempty" yaml:"column,omitempty"`
+	Path    string `json:"path" yaml:"path"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+	return fmt.Sprintf("line %d:%d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// UnknownFields reports keys of the yaml document that do not map to any
+// field of the Specification, with a suggestion for likely typos.
+func UnknownFields(data []byte) ([]FieldError, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	var errs []FieldError
+	checkNode(root.Content[0], reflect.TypeOf(Specification{}), "", &errs)
+	return errs, nil
+}
+
+func checkNode(node *yaml.Node, t reflect.Type, path string, errs *[]FieldError) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldPath := joinPath(path, key.Value)
+			field, found := fields[key.Value]
+			if !found {
+				message := fmt.Sprintf("unknown field %q", key.Value)
+				if suggestion := closest(key.Value, fields); suggestion != "" {
+					message += fmt.Sprintf(", did you mean %q?", suggestion)
+				}
+				*errs = append(*errs, FieldError{Line: key.Line, Column: key.Column, Path: fieldPath, Message: message})
+				continue
+			}
+			checkNode(value, field.Type, fieldPath, errs)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// yamlFields maps the yaml keys of a struct type to its fields.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// closest returns the known key with the smallest edit distance, if it is close enough.
+func closest(key string, fields map[string]reflect.StructField) string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best, bestDistance := "", len(key)/2+2
+	for _, name := range names {
+		if d := levenshtein(strings.ToLower(key), strings.ToLower(name)); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+var replicationPattern = regexp.MustCompile(`^[0-9]{3}$`)
+
+// diskTypePattern matches the disk types of volume folders, like hdd, ssd or a custom tag.
+var diskTypePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+var validSysctlKey = regexp.MustCompile(`^[a-z0-9_]+(\.[a-zA-Z0-9_-]+)+$`)
+
+// Validate checks the values of the specification that yaml decoding can not.
+func (s *Specification) Validate() (errs []FieldError) {
+	if len(s.MasterServers) == 0 {
+		errs = append(errs, FieldError{Path: "master_servers", Message: "at least one master server is required"})
+	}
+	if r := s.GlobalOptions.Replication; r != "" && !replicationPattern.MatchString(r) {
+		errs = append(errs, FieldError{Path: "global.replication", Message: fmt.Sprintf("replication %q must be 3 digits like 001", r)})
+	}
+	if security := s.GlobalOptions.Security; security != nil {
+		if (security.CaFile == "") != (security.CertFile == "") || (security.CertFile == "") != (security.KeyFile == "") {
+			errs = append(errs, FieldError{Path: "global.security", Message: "ca.file, cert.file and key.file are required together"})
+		}
+	}
+
+	type listener struct {
+		path string
+		port int
+	}
+	listeners := make(map[string][]listener)
+	checkServer := func(path, ip string, portSsh int, ports []int) {
+		if ip == "" {
+			errs = append(errs, FieldError{Path: path + ".ip", Message: "ip is required"})
+		}
+		if strings.Contains(ip, ":") && net.ParseIP(ip) == nil {
+			errs = append(errs, FieldError{Path: path + ".ip", Message: fmt.Sprintf("ip %q is not a valid IPv6 address", ip)})
+		}
+		for _, port := range append([]int{portSsh}, ports...) {
+			if port < 0 || port > 65535 {
+				errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("port %d is out of range", port)})
+			}
+		}
+		for _, port := range ports {
+			for _, other := range listeners[ip] {
+				if other.port == port {
+					errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("port %d on %s is also used by %s", port, ip, other.path)})
+				}
+			}
+			listeners[ip] = append(listeners[ip], listener{path, port})
+		}
+	}
+
+	checkArch := func(path, arch string) {
+		switch arch {
+		case "", "amd64", "x86_64", "arm64", "aarch64", "arm":
+		default:
+			errs = append(errs, FieldError{Path: path + ".arch", Message: fmt.Sprintf("unsupported architecture %q, use amd64, arm64 or arm", arch)})
+		}
+	}
+
+	checkWeedOptions := func(path, component string, options map[string]string) {
+		problems := checkOptions(component, options)
+		var names []string
+		for name := range options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if message, found := problems[name]; found {
+				errs = append(errs, FieldError{Path: path + ".options." + name, Message: fmt.Sprintf("%s %s", name, message)})
+			} else if strings.ContainsAny(options[name], "\r\n") {
+				errs = append(errs, FieldError{Path: path + ".options." + name, Message: "the value can not span lines"})
+			}
+		}
+	}
+
+	for i, master := range s.MasterServers {
+		path := fmt.Sprintf("master_servers[%d]", i)
+		checkServer(path, master.Ip, master.PortSsh, master.ListenPorts())
+		checkArch(path, master.Arch)
+		checkWeedOptions(path, "master", master.Options)
+		if r := master.DefaultReplication; r != "" && !replicationPattern.MatchString(r) {
+			errs = append(errs, FieldError{Path: path + ".defaultReplication", Message: fmt.Sprintf("replication %q must be 3 digits like 001", r)})
+		}
+		for name, value := range map[string]string{"electionTimeout": master.ElectionTimeout, "heartbeatInterval": master.HeartbeatInterval} {
+			if _, err := time.ParseDuration(value); value != "" && err != nil {
+				errs = append(errs, FieldError{Path: path + "." + name, Message: fmt.Sprintf("%s %q must be a duration like 10s", name, value)})
+			}
+		}
+		if master.RaftBootstrap && !master.raftHashicorp() {
+			errs = append(errs, FieldError{Path: path + ".raftBootstrap", Message: "raftBootstrap needs raftHashicorp"})
+		}
+		if i > 0 && master.raftHashicorp() != s.MasterServers[0].raftHashicorp() {
+			errs = append(errs, FieldError{Path: path + ".raftHashicorp", Message: "the masters must all use the same raft, unlike master_servers[0]"})
+		}
+	}
+	for i, volume := range s.VolumeServers {
+		path := fmt.Sprintf("volume_servers[%d]", i)
+		checkServer(path, volume.Ip, volume.PortSsh, volume.ListenPorts())
+		checkArch(path, volume.Arch)
+		checkWeedOptions(path, "volume", volume.Options)
+		if len(volume.Folders) == 0 {
+			errs = append(errs, FieldError{Path: path + ".folders", Message: "at least one folder is required"})
+		}
+		seen := make(map[string]bool)
+		for j, folder := range volume.Folders {
+			folderPath := fmt.Sprintf("%s.folders[%d]", path, j)
+			switch {
+			case folder.Folder == "":
+				errs = append(errs, FieldError{Path: folderPath + ".folder", Message: "folder is required"})
+			case strings.ContainsAny(folder.Folder, ", "):
+				errs = append(errs, FieldError{Path: folderPath + ".folder", Message: fmt.Sprintf("folder %q can not contain commas or spaces", folder.Folder)})
+			case seen[folder.Folder]:
+				errs = append(errs, FieldError{Path: folderPath + ".folder", Message: fmt.Sprintf("folder %s is given twice", folder.Folder)})
+			}
+			seen[folder.Folder] = true
+			if folder.DiskType != "" && !diskTypePattern.MatchString(folder.DiskType) {
+				errs = append(errs, FieldError{Path: folderPath + ".disk", Message: fmt.Sprintf("disk %q must be hdd, ssd or a tag of letters, digits and _", folder.DiskType)})
+			}
+			if folder.Max < 0 {
+				errs = append(errs, FieldError{Path: folderPath + ".max", Message: fmt.Sprintf("max %d can not be negative, 0 holds as many volumes as fit", folder.Max)})
+			}
+		}
+		if t := volume.IndexType; t != "" {
+			known := false
+			for _, indexType := range IndexTypes {
+				known = known || t == indexType
+			}
+			if !known {
+				errs = append(errs, FieldError{Path: path + ".index", Message: fmt.Sprintf("index %q must be one of %s", t, strings.Join(IndexTypes, ", "))})
+			}
+		}
+		if strings.ContainsAny(volume.IndexDir, ", ") {
+			errs = append(errs, FieldError{Path: path + ".dir.idx", Message: fmt.Sprintf("dir.idx %q can not contain commas or spaces", volume.IndexDir)})
+		}
+	}
+	for i, filer := range s.FilerServers {
+		path := fmt.Sprintf("filer_servers[%d]", i)
+		checkServer(path, filer.Ip, filer.PortSsh, filer.ListenPorts())
+		checkArch(path, filer.Arch)
+		checkWeedOptions(path, "filer", filer.Options)
+	}
+	for i, envoy := range s.EnvoyServers {
+		checkServer(fmt.Sprintf("envoy_servers[%d]", i), envoy.Ip, envoy.PortSsh, envoy.ListenPorts())
+	}
+	for i, s3 := range s.S3Servers {
+		path := fmt.Sprintf("s3_servers[%d]", i)
+		checkServer(path, s3.Ip, s3.PortSsh, s3.ListenPorts())
+		checkArch(path, s3.Arch)
+		checkWeedOptions(path, "s3", s3.Options)
+		if s3.Filer == "" && len(s.FilerServers) == 0 {
+			errs = append(errs, FieldError{Path: path + ".filer", Message: "filer is required without filer servers"})
+		}
+		if (s3.CertFile == "") != (s3.KeyFile == "") {
+			errs = append(errs, FieldError{Path: path, Message: "cert.file and key.file are required together"})
+		}
+	}
+	for i, webdav := range s.WebDAVServers {
+		path := fmt.Sprintf("webdav_servers[%d]", i)
+		checkServer(path, webdav.Ip, webdav.PortSsh, webdav.ListenPorts())
+		checkArch(path, webdav.Arch)
+		checkWeedOptions(path, "webdav", webdav.Options)
+		if webdav.Filer == "" && len(s.FilerServers) == 0 {
+			errs = append(errs, FieldError{Path: path + ".filer", Message: "filer is required without filer servers"})
+		}
+	}
+	mountPoints := make(map[string]string)
+	for i, mount := range s.Mounts {
+		path := fmt.Sprintf("mounts[%d]", i)
+		checkServer(path, mount.Ip, mount.PortSsh, nil)
+		checkArch(path, mount.Arch)
+		checkWeedOptions(path, "mount", mount.Options)
+		switch {
+		case !strings.HasPrefix(mount.Dir, "/"):
+			errs = append(errs, FieldError{Path: path + ".dir", Message: fmt.Sprintf("dir %q must be an absolute path", mount.Dir)})
+		case strings.ContainsAny(mount.Dir, " \t"):
+			errs = append(errs, FieldError{Path: path + ".dir", Message: fmt.Sprintf("dir %q can not contain spaces", mount.Dir)})
+		case mountPoints[mount.Ip+":"+mount.Dir] != "":
+			errs = append(errs, FieldError{Path: path + ".dir", Message: fmt.Sprintf("%s on %s is also mounted by %s", mount.Dir, mount.Ip, mountPoints[mount.Ip+":"+mount.Dir])})
+		}
+		mountPoints[mount.Ip+":"+mount.Dir] = path
+		if mount.Filer == "" && len(s.FilerServers) == 0 {
+			errs = append(errs, FieldError{Path: path + ".filer", Message: "filer is required without filer servers"})
+		}
+	}
+	for i, broker := range s.MQBrokers {
+		path := fmt.Sprintf("mq_brokers[%d]", i)
+		checkServer(path, broker.Ip, broker.PortSsh, broker.ListenPorts())
+		checkArch(path, broker.Arch)
+		checkWeedOptions(path, "mq.broker", broker.Options)
+	}
+	if len(s.MQBrokers) > 0 && len(s.FilerServers) == 0 {
+		errs = append(errs, FieldError{Path: "mq_brokers", Message: "the brokers keep the messages in the filers, filer servers are required"})
+	}
+
+	checkHooks := func(path string, hooks []*HookSpec, nodeHook bool) {
+		for i, hook := range hooks {
+			hookPath := fmt.Sprintf("hooks.%s[%d]", path, i)
+			if (hook.Command == "") == (hook.Script == "") {
+				errs = append(errs, FieldError{Path: hookPath, Message: "either command or script is required"})
+			}
+			if hook.Remote && !nodeHook {
+				errs = append(errs, FieldError{Path: hookPath + ".remote", Message: "only node hooks can run remote"})
+			}
+		}
+	}
+	checkHooks("pre_deploy", s.Hooks.PreDeploy, false)
+	checkHooks("post_deploy", s.Hooks.PostDeploy, false)
+	checkHooks("pre_upgrade_node", s.Hooks.PreUpgradeNode, true)
+	checkHooks("post_upgrade_node", s.Hooks.PostUpgradeNode, true)
+	checkHooks("on_drift", s.Hooks.OnDrift, true)
+
+	if timeSync := s.GlobalOptions.TimeSync; timeSync != nil {
+		for i, server := range timeSync.Servers {
+			if server == "" || strings.ContainsAny(server, " \t\n") {
+				errs = append(errs, FieldError{Path: fmt.Sprintf("global.time_sync.servers[%d]", i), Message: fmt.Sprintf("server %q must be a host name or address", server)})
+			}
+		}
+	}
+
+	checkElevation := func(path string, elevation *ElevationSpec) {
+		if elevation == nil {
+			return
+		}
+		switch elevation.Method {
+		case "", "sudo", "none":
+		case "doas":
+			if elevation.PasswordEnv != "" || elevation.PasswordCommand != "" {
+				errs = append(errs, FieldError{Path: path + ".method", Message: "doas can not be given a password, allow the user with a nopass rule"})
+			}
+		default:
+			errs = append(errs, FieldError{Path: path + ".method", Message: fmt.Sprintf("method %q must be one of %s", elevation.Method, strings.Join(ElevationMethods, ", "))})
+		}
+	}
+	checkElevation("global.elevation", s.GlobalOptions.Elevation)
+
+	if history := s.GlobalOptions.History; history != nil && history.GitFile != "" {
+		if history.GitRepo == "" {
+			errs = append(errs, FieldError{Path: "global.history.git_file", Message: "git_file needs a git_repo"})
+		} else if clean := path.Clean(history.GitFile); path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			errs = append(errs, FieldError{Path: "global.history.git_file", Message: fmt.Sprintf("git_file %q must be a path inside the git repository", history.GitFile)})
+		}
+	}
+
+	if snapshots := s.GlobalOptions.Snapshots; snapshots != nil && snapshots.Keep < 0 {
+		errs = append(errs, FieldError{Path: "global.snapshots.keep", Message: fmt.Sprintf("keep %d must not be negative", snapshots.Keep)})
+	}
+
+	if remediation := s.GlobalOptions.Remediation; remediation != nil {
+		for field, policy := range map[string]string{"service": remediation.Service, "config": remediation.Config} {
+			switch policy {
+			case "", "off", "notify", "auto":
+			default:
+				errs = append(errs, FieldError{Path: "global.remediation." + field, Message: fmt.Sprintf("%s %q must be one of %s", field, policy, strings.Join(RemediationPolicies, ", "))})
+			}
+		}
+	}
+
+	if retry := s.GlobalOptions.Retry; retry != nil {
+		checkPolicy := func(path string, attempts int, initial, max string) {
+			if attempts < 0 {
+				errs = append(errs, FieldError{Path: path + ".max_attempts", Message: fmt.Sprintf("max_attempts %d can not be negative", attempts)})
+			}
+			for field, value := range map[string]string{"initial_backoff": initial, "max_backoff": max} {
+				if value != "" && parseBackoff(value) <= 0 {
+					errs = append(errs, FieldError{Path: path + "." + field, Message: fmt.Sprintf("%s %q must be a duration like 2s", field, value)})
+				}
+			}
+		}
+		checkPolicy("global.retry", retry.MaxAttempts, retry.InitialBackoff, retry.MaxBackoff)
+		if retry.CircuitBreaker < -1 {
+			errs = append(errs, FieldError{Path: "global.retry.circuit_breaker", Message: fmt.Sprintf("circuit_breaker %d must be -1 to never skip a host, or a number of failures", retry.CircuitBreaker)})
+		}
+		for task, policy := range retry.Tasks {
+			path := "global.retry.tasks." + task
+			known := false
+			for _, taskType := range RetryTaskTypes {
+				known = known || task == taskType
+			}
+			if !known {
+				errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("task type %q must be one of %s", task, strings.Join(RetryTaskTypes, ", "))})
+			} else if policy != nil {
+				checkPolicy(path, policy.MaxAttempts, policy.InitialBackoff, policy.MaxBackoff)
+			}
+		}
+	}
+
+	checkSsh := func(path string, ssh *SshSpec) {
+		if ssh == nil {
+			return
+		}
+		if ssh.ConnectTimeout != "" && ssh.Timeout() <= 0 {
+			errs = append(errs, FieldError{Path: path + ".connect_timeout", Message: fmt.Sprintf("connect_timeout %q must be a duration like 10s", ssh.ConnectTimeout)})
+		}
+		if ssh.PasswordEnv != "" && ssh.IdentityFile != "" {
+			errs = append(errs, FieldError{Path: path + ".password_env", Message: "use either password_env or identity_file"})
+		}
+		checkElevation(path+".elevation", ssh.Elevation)
+	}
+	checkSsh("global.ssh", s.GlobalOptions.Ssh)
+	for i, masterSpec := range s.MasterServers {
+		checkSsh(fmt.Sprintf("master_servers[%d].ssh", i), masterSpec.Ssh)
+	}
+	for i, volumeSpec := range s.VolumeServers {
+		checkSsh(fmt.Sprintf("volume_servers[%d].ssh", i), volumeSpec.Ssh)
+	}
+	for i, filerSpec := range s.FilerServers {
+		checkSsh(fmt.Sprintf("filer_servers[%d].ssh", i), filerSpec.Ssh)
+	}
+	for i, envoySpec := range s.EnvoyServers {
+		checkSsh(fmt.Sprintf("envoy_servers[%d].ssh", i), envoySpec.Ssh)
+	}
+	for i, s3Spec := range s.S3Servers {
+		checkSsh(fmt.Sprintf("s3_servers[%d].ssh", i), s3Spec.Ssh)
+	}
+	for i, webdavSpec := range s.WebDAVServers {
+		checkSsh(fmt.Sprintf("webdav_servers[%d].ssh", i), webdavSpec.Ssh)
+	}
+	for i, mountSpec := range s.Mounts {
+		checkSsh(fmt.Sprintf("mounts[%d].ssh", i), mountSpec.Ssh)
+	}
+	for i, brokerSpec := range s.MQBrokers {
+		checkSsh(fmt.Sprintf("mq_brokers[%d].ssh", i), brokerSpec.Ssh)
+	}
+
+	if tuning := s.GlobalOptions.Tuning; tuning != nil {
+		if _, err := tuning.Resolve(); err != nil {
+			errs = append(errs, FieldError{Path: "global.tuning.profile", Message: err.Error()})
+		}
+		for _, key := range tuning.SysctlKeys() {
+			if value := tuning.Sysctl[key]; !validSysctlKey.MatchString(key) || strings.ContainsAny(value, "\n=") {
+				errs = append(errs, FieldError{Path: "global.tuning.sysctl." + key, Message: fmt.Sprintf("%s: %q is not a valid sysctl setting", key, value)})
+			}
+		}
+		switch tuning.TransparentHugepages {
+		case "", "always", "madvise", "never":
+		default:
+			errs = append(errs, FieldError{Path: "global.tuning.transparent_hugepages", Message: fmt.Sprintf("transparent_hugepages %q must be always, madvise or never", tuning.TransparentHugepages)})
+		}
+	}
+
+	if ha := s.HighAvailability; ha != nil {
+		if !validVirtualIp(ha.VirtualIp) {
+			errs = append(errs, FieldError{Path: "ha.virtual_ip", Message: fmt.Sprintf("virtual_ip %q must be an address like 10.0.0.100/24", ha.VirtualIp)})
+		}
+		if ha.VirtualRouterId < 0 || ha.VirtualRouterId > 255 {
+			errs = append(errs, FieldError{Path: "ha.virtual_router_id", Message: fmt.Sprintf("virtual_router_id %d must be between 1 and 255", ha.VirtualRouterId)})
+		}
+		if len(ha.AuthPass) > 8 {
+			errs = append(errs, FieldError{Path: "ha.auth_pass", Message: "auth_pass is limited to 8 characters by VRRP"})
+		}
+		switch component := s.HighAvailabilityComponent(); component {
+		case "filer", "envoy":
+			if (component == "filer" && len(s.FilerServers) < 2) || (component == "envoy" && len(s.EnvoyServers) < 2) {
+				errs = append(errs, FieldError{Path: "ha.component", Message: fmt.Sprintf("a virtual IP needs at least 2 %s servers", component)})
+			}
+		default:
+			errs = append(errs, FieldError{Path: "ha.component", Message: fmt.Sprintf("component %q must be filer or envoy", component)})
+		}
+	}
+	return
+}
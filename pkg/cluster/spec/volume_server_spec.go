@@ -12,23 +12,48 @@ type VolumeServerSpec struct {
 	IpBind             string                 `yaml:"ip.bind,omitempty"`
 	IpPublic           string                 `yaml:"ip.public,omitempty"`
 	Port               int                    `yaml:"port" default:"8080"`
-	PortGrpc           int                    `yaml:"port.grpc" default:"19333"`
+	PortGrpc           int                    `yaml:"port.grpc" default:"18080"`
 	PortPublic         int                    `yaml:"port.public,omitempty"`
 	Folders            []*FolderSpec          `yaml:"folders"`
+	IndexType          string                 `yaml:"index,omitempty" default:"memory"` // memory, leveldb, leveldbMedium or leveldbLarge
+	IndexDir           string                 `yaml:"dir.idx,omitempty"`                // keeps the .idx files of all folders, e.g. on an ssd
 	DataCenter         string                 `yaml:"dataCenter,omitempty"`
 	Rack               string                 `yaml:"rack,omitempty"`
 	DefaultReplication int                    `yaml:"defaultReplication,omitempty"`
 	MetricsPort        int                    `yaml:"metrics_port,omitempty"`
 	Config             map[string]interface{} `yaml:"config,omitempty"`
+	Options            map[string]string      `yaml:"options,omitempty"` // weed flags seaweed-up does not model, by name
 	Arch               string                 `yaml:"arch,omitempty"`
 	OS                 string                 `yaml:"os,omitempty"`
+	Disks              *DiskProvisionSpec     `yaml:"disks,omitempty"`
+	Systemd            *SystemdSpec           `yaml:"systemd,omitempty"`
+	Ssh                *SshSpec               `yaml:"ssh,omitempty"`
 }
+
+// FolderSpec is a folder the volume server stores volumes in, with the type of
+// its disk, like hdd, ssd or a custom tag, and the most volumes it holds, 0
+// for as many as fit.
 type FolderSpec struct {
 	Folder   string `yaml:"folder"`
 	DiskType string `yaml:"disk" default:"hdd"`
 	Max      int    `yaml:"max,omitempty"`
 }
 
+// IndexTypes are the supported values of VolumeServerSpec.IndexType.
+var IndexTypes = []string{"memory", "leveldb", "leveldbMedium", "leveldbLarge"}
+
+// Dirs returns the folders and the index dir of the volume server.
+func (vs *VolumeServerSpec) Dirs() []string {
+	var dirs []string
+	for _, folder := range vs.Folders {
+		dirs = append(dirs, folder.Folder)
+	}
+	if vs.IndexDir != "" {
+		dirs = append(dirs, vs.IndexDir)
+	}
+	return dirs
+}
+
 func (vs *VolumeServerSpec) WriteToBuffer(masters []string, buf *bytes.Buffer) {
 	addToBuffer(buf, "ip", vs.Ip)
 	addToBuffer(buf, "ip.bind", vs.IpBind)
@@ -47,5 +72,13 @@ func (vs *VolumeServerSpec) WriteToBuffer(masters []string, buf *bytes.Buffer) {
 	}
 	addToBuffer(buf, "dir", strings.Join(dirs, ","))
 	addToBuffer(buf, "max", strings.Join(maxes, ","))
-	addToBuffer(buf, "disks", strings.Join(disks, ","))
+	addToBuffer(buf, "disk", strings.Join(disks, ","))
+	addToBuffer(buf, "dir.idx", vs.IndexDir)
+	if vs.IndexType != "memory" {
+		addToBuffer(buf, "index", vs.IndexType)
+	}
+	addToBuffer(buf, "dataCenter", vs.DataCenter)
+	addToBuffer(buf, "rack", vs.Rack)
+	addToBufferInt(buf, "metricsPort", vs.MetricsPort, 0)
+	addOptions(buf, "volume", vs.Options)
 }
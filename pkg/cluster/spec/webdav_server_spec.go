@@ -0,0 +1,49 @@
+package spec
+
+import (
+	"bytes"
+)
+
+// WebDAVServerSpec is a standalone weed webdav server, serving the files of a filer.
+type WebDAVServerSpec struct {
+	Ip              string            `yaml:"ip"`
+	PortSsh         int               `yaml:"port.ssh" default:"22"`
+	Port            int               `yaml:"port" default:"7333"`
+	Filer           string            `yaml:"filer,omitempty"`      // host:port, the first filer server if empty
+	FilerPath       string            `yaml:"filer.path,omitempty"` // the folder of the filer served, / if empty
+	Collection      string            `yaml:"collection,omitempty"`
+	Replication     string            `yaml:"replication,omitempty"`
+	CacheDir        string            `yaml:"cacheDir,omitempty"`
+	CacheCapacityMB int               `yaml:"cacheCapacityMB,omitempty"`
+	Options         map[string]string `yaml:"options,omitempty"` // weed flags seaweed-up does not model, by name
+	Arch            string            `yaml:"arch,omitempty"`
+	OS              string            `yaml:"os,omitempty"`
+	Systemd         *SystemdSpec      `yaml:"systemd,omitempty"`
+	Ssh             *SshSpec          `yaml:"ssh,omitempty"`
+}
+
+// Filers returns the filer the server connects to, by default the first of filers.
+func (w *WebDAVServerSpec) Filers(filers []string) []string {
+	if w.Filer != "" {
+		return []string{w.Filer}
+	}
+	if len(filers) > 0 {
+		return filers[:1]
+	}
+	return nil
+}
+
+func (w *WebDAVServerSpec) WriteToBuffer(filers []string, buf *bytes.Buffer) {
+	addToBufferInt(buf, "port", w.Port, 7333)
+	if filers := w.Filers(filers); len(filers) > 0 {
+		addToBuffer(buf, "filer", filers[0])
+	}
+	if w.FilerPath != "/" {
+		addToBuffer(buf, "filer.path", w.FilerPath)
+	}
+	addToBuffer(buf, "collection", w.Collection)
+	addToBuffer(buf, "replication", w.Replication)
+	addToBuffer(buf, "cacheDir", w.CacheDir)
+	addToBufferInt(buf, "cacheCapacityMB", w.CacheCapacityMB, 0)
+	addOptions(buf, "webdav", w.Options)
+}
@@ -0,0 +1,141 @@
+package spec
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// weedFlags are the flags of the weed 3.x commands a component runs, the
+// names its options can use. Flags removed by a later weed version are told
+// by the removed_options of the compatibility rules.
+var weedFlags = map[string][]string{
+	"master": {
+		"cpuprofile", "defaultReplication", "disableHttp", "electionTimeout", "garbageThreshold",
+		"heartbeatInterval", "ip", "ip.bind", "maxParallelVacuumPerServer", "mdir", "memprofile",
+		"metrics.address", "metrics.intervalSeconds", "metricsIp", "metricsPort", "peers", "port",
+		"port.grpc", "raftBootstrap", "raftHashicorp", "resumeState", "volumePreallocate",
+		"volumeSizeLimitMB", "whiteList",
+	},
+	"volume": {
+		"compactionMBps", "concurrentDownloadLimitMB", "concurrentUploadLimitMB", "cpuprofile",
+		"dataCenter", "dir", "dir.idx", "disk", "fileSizeLimitMB", "hasSlowRead", "idleTimeout",
+		"images.fix.orientation", "index", "inflightUploadDataTimeout", "ip", "ip.bind", "max",
+		"memprofile", "metricsIp", "metricsPort", "minFreeSpace", "minFreeSpacePercent", "mserver",
+		"port", "port.grpc", "port.public", "pprof", "preStopSeconds", "publicUrl", "rack",
+		"readBufferSizeMB", "readMode", "whiteList",
+	},
+	"filer": {
+		"collection", "concurrentUploadLimitMB", "cpuprofile", "dataCenter", "defaultReplicaPlacement",
+		"defaultStoreDir", "dirListLimit", "disableDirListing", "disableHttp", "disk", "downloadMaxMBps",
+		"encryptVolumeData", "exposeDirectoryData", "filerGroup", "ip", "ip.bind", "localSocket",
+		"master", "maxMB", "memprofile", "metricsIp", "metricsPort", "port", "port.grpc",
+		"port.readonly", "rack", "s3", "s3.allowEmptyFolder", "s3.auditLogConfig", "s3.cert.file",
+		"s3.config", "s3.domainName", "s3.key.file", "s3.port", "s3.port.grpc", "saveToFilerLimit",
+		"ui.deleteDir", "webdav", "webdav.cacheCapacityMB", "webdav.cacheDir", "webdav.cert.file",
+		"webdav.collection", "webdav.disk", "webdav.key.file", "webdav.port", "webdav.replication",
+		"whiteList",
+	},
+	"s3": {
+		"allowDeleteBucketNotEmpty", "allowEmptyFolder", "auditLogConfig", "cert.file", "config",
+		"domainName", "filer", "ip.bind", "key.file", "localFilerSocket", "metricsPort", "port",
+		"port.grpc", "port.https", "tlsVerifyClientCert",
+	},
+	"mq.broker": {
+		"cpuprofile", "dataCenter", "filerGroup", "ip", "logFlushInterval", "master", "memprofile",
+		"port", "rack",
+	},
+	"webdav": {
+		"cacheCapacityMB", "cacheDir", "cert.file", "collection", "disk", "filer", "filer.path",
+		"key.file", "maxMB", "port", "replication",
+	},
+	"mount": {
+		"allowOthers", "cacheCapacityMB", "cacheDir", "chunkSizeLimitMB", "collection",
+		"collectionQuotaMB", "concurrentWriters", "cpuprofile", "dataCenter", "dir", "dirAutoCreate",
+		"disableXAttr", "disk", "filer", "filer.path", "localSocket", "map.gid", "map.uid", "memprofile",
+		"nonempty", "readOnly", "readRetryTime", "replication", "ttl", "umask", "volumeServerAccess",
+	},
+}
+
+// managedFlags are set by seaweed-up from the topology, options can not
+// change them.
+var managedFlags = map[string][]string{
+	"master":    {"mdir", "peers", "ip", "port", "port.grpc"},
+	"volume":    {"mserver", "dir", "dir.idx", "ip", "port", "port.grpc"},
+	"filer":     {"master", "ip", "port", "port.grpc"},
+	"s3":        {"port", "filer", "config", "cert.file", "key.file"},
+	"webdav":    {"port", "filer"},
+	"mount":     {"dir", "filer"},
+	"mq.broker": {"master", "ip", "port"},
+}
+
+// checkOptions returns a message for each option the weed command of the
+// component does not take, or that seaweed-up manages, by option name.
+func checkOptions(component string, options map[string]string) map[string]string {
+	problems := make(map[string]string)
+	for name := range options {
+		switch {
+		case contains(managedFlags[component], name):
+			problems[name] = "is set by seaweed-up from the topology"
+		case !contains(weedFlags[component], name):
+			message := "is not a flag of weed " + component
+			if suggestion := closestName(name, weedFlags[component]); suggestion != "" {
+				message += ", did you mean " + suggestion + "?"
+			}
+			problems[name] = message
+		}
+	}
+	return problems
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// closestName returns the name of names closest to name, or "" if none is
+// close enough to be a likely typo.
+func closestName(name string, names []string) string {
+	best, bestDistance := "", len(name)/2+2
+	for _, n := range names {
+		if d := levenshtein(strings.ToLower(name), strings.ToLower(n)); d < bestDistance {
+			best, bestDistance = n, d
+		}
+	}
+	return best
+}
+
+// addOptions writes the options of the weed command of the component to the
+// name=value lines of buf, in place of the lines of the same flags. The flags
+// seaweed-up sets from the topology are kept, config validate and deploy refuse
+// options changing them.
+func addOptions(buf *bytes.Buffer, component string, options map[string]string) {
+	var names []string
+	for name := range options {
+		if !contains(managedFlags[component], name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		name, _, _ := strings.Cut(line, "=")
+		if line != "" && !contains(names, name) {
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(names)
+	buf.Reset()
+	for _, line := range lines {
+		buf.WriteString(line + "\n")
+	}
+	for _, name := range names {
+		buf.WriteString(name + "=" + options[name] + "\n")
+	}
+}
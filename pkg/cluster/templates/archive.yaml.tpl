@@ -0,0 +1,35 @@
+# Archive cluster for erasure coding: many large disks and no replication, since
+# sealed volumes get their redundancy from EC shards. Encode full volumes with
+#   seaweed-up shell -f cluster.yaml -c "ec.encode -fullPercent=95 -quietFor=1h"
+global:
+  dir.conf: "/etc/seaweed"
+  dir.data: "/opt/seaweed"
+  volumeSizeLimitMB: {{.volume_size_limit_mb}}
+  replication: "000"
+  disks:
+    fs_type: xfs
+    mount_options: noatime,nodiratime
+    mount_by_uuid: true
+
+master_servers:
+{{- range split .masters ","}}
+  - ip: {{.}}
+    port: 9333
+{{- end}}
+
+volume_servers:
+{{- range split .volumes ","}}
+  - ip: {{.}}
+    port: 8080
+    folders:
+{{- range split $.folders ","}}
+      - folder: {{.}}
+        disk: "hdd"
+{{- end}}
+{{- end}}
+
+filer_servers:
+{{- range split .filers ","}}
+  - ip: {{.}}
+    port: 8888
+{{- end}}
@@ -0,0 +1,23 @@
+# Single node cluster for development and testing.
+global:
+  dir.conf: "/etc/seaweed"
+  dir.data: "{{.data_dir}}"
+  volumeSizeLimitMB: 1000
+  replication: "000"
+
+master_servers:
+  - ip: {{.host}}
+    port: 9333
+
+volume_servers:
+  - ip: {{.host}}
+    port: 8080
+    folders:
+      - folder: .
+        disk: "hdd"
+
+filer_servers:
+  - ip: {{.host}}
+    port: 8888
+    s3: true
+    s3.port: 8333
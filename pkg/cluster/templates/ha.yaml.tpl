@@ -0,0 +1,29 @@
+# Highly available cluster with a raft quorum of masters and replicated volumes.
+global:
+  dir.conf: "/etc/seaweed"
+  dir.data: "/opt/seaweed"
+  volumeSizeLimitMB: 5000
+  replication: "{{.replication}}"
+
+master_servers:
+{{- range split .masters ","}}
+  - ip: {{.}}
+    port: 9333
+{{- end}}
+
+volume_servers:
+{{- range split .volumes ","}}
+  - ip: {{.}}
+    port: 8080
+    folders:
+{{- range split $.folders ","}}
+      - folder: {{.}}
+        disk: "hdd"
+{{- end}}
+{{- end}}
+
+filer_servers:
+{{- range split .filers ","}}
+  - ip: {{.}}
+    port: 8888
+{{- end}}
@@ -0,0 +1,38 @@
+# S3 gateway cluster: filers with the embedded S3 server behind an envoy proxy.
+global:
+  dir.conf: "/etc/seaweed"
+  dir.data: "/opt/seaweed"
+  volumeSizeLimitMB: 5000
+  replication: "{{.replication}}"
+
+master_servers:
+{{- range split .masters ","}}
+  - ip: {{.}}
+    port: 9333
+{{- end}}
+
+volume_servers:
+{{- range split .volumes ","}}
+  - ip: {{.}}
+    port: 8080
+    folders:
+{{- range split $.folders ","}}
+      - folder: {{.}}
+        disk: "hdd"
+{{- end}}
+{{- end}}
+
+filer_servers:
+{{- range split .filers ","}}
+  - ip: {{.}}
+    port: 8888
+    s3: true
+    s3.port: 8333
+{{- end}}
+
+envoy_servers:
+{{- range split .proxies ","}}
+  - ip: {{.}}
+    filer.port: 8000
+    s3.port: {{$.s3_port}}
+{{- end}}
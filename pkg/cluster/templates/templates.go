@@ -0,0 +1,134 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+)
+
+//go:embed *.yaml.tpl
+var content embed.FS
+
+// Parameter is a value a template can be customized with.
+type Parameter struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Default     string `json:"default" yaml:"default"`
+}
+
+// Template renders a complete cluster specification from a few parameters.
+type Template struct {
+	Name        string      `json:"name" yaml:"name"`
+	Description string      `json:"description" yaml:"description"`
+	Parameters  []Parameter `json:"parameters" yaml:"parameters"`
+	file        string
+}
+
+var builtin = []*Template{
+	{
+		Name:        "dev",
+		Description: "single node cluster for development and testing",
+		file:        "dev.yaml.tpl",
+		Parameters: []Parameter{
+			{"host", "ip of the node", "127.0.0.1"},
+			{"data_dir", "data directory", "/opt/seaweed"},
+		},
+	},
+	{
+		Name:        "ha",
+		Description: "3 masters with replicated volumes across volume servers",
+		file:        "ha.yaml.tpl",
+		Parameters: []Parameter{
+			{"masters", "comma separated master ips", "192.168.2.1,192.168.2.2,192.168.2.3"},
+			{"volumes", "comma separated volume server ips", "192.168.2.1,192.168.2.2,192.168.2.3"},
+			{"filers", "comma separated filer ips", "192.168.2.1,192.168.2.2"},
+			{"folders", "comma separated data folders on each volume server", "/data1"},
+			{"replication", "default replication", "001"},
+		},
+	},
+	{
+		Name:        "s3",
+		Description: "filers serving S3 behind envoy proxies",
+		file:        "s3.yaml.tpl",
+		Parameters: []Parameter{
+			{"masters", "comma separated master ips", "192.168.2.1,192.168.2.2,192.168.2.3"},
+			{"volumes", "comma separated volume server ips", "192.168.2.1,192.168.2.2,192.168.2.3"},
+			{"filers", "comma separated filer ips", "192.168.2.1,192.168.2.2"},
+			{"proxies", "comma separated envoy proxy ips", "192.168.2.10"},
+			{"folders", "comma separated data folders on each volume server", "/data1"},
+			{"replication", "default replication", "001"},
+			{"s3_port", "S3 port of the proxies", "8333"},
+		},
+	},
+	{
+		Name:        "archive",
+		Description: "erasure coding heavy archive cluster with many large disks",
+		file:        "archive.yaml.tpl",
+		Parameters: []Parameter{
+			{"masters", "comma separated master ips", "192.168.2.1,192.168.2.2,192.168.2.3"},
+			{"volumes", "comma separated volume server ips", "192.168.2.11,192.168.2.12,192.168.2.13,192.168.2.14"},
+			{"filers", "comma separated filer ips", "192.168.2.1"},
+			{"folders", "comma separated data folders on each volume server", "/data1,/data2,/data3,/data4"},
+			{"volume_size_limit_mb", "volume size limit in MB", "30000"},
+		},
+	},
+}
+
+// List returns the built-in templates sorted by name.
+func List() []*Template {
+	list := append([]*Template{}, builtin...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns the built-in template with the given name.
+func Get(name string) (*Template, error) {
+	for _, t := range builtin {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	var names []string
+	for _, t := range List() {
+		names = append(names, t.Name)
+	}
+	return nil, fmt.Errorf("unknown template %q, available: %s", name, strings.Join(names, ", "))
+}
+
+// Source returns the unrendered template.
+func (t *Template) Source() (string, error) {
+	data, err := content.ReadFile(t.file)
+	return string(data), err
+}
+
+// Render generates the specification, using the defaults for parameters missing in values.
+func (t *Template) Render(values map[string]string) ([]byte, error) {
+	data := make(map[string]string)
+	for _, p := range t.Parameters {
+		data[p.Name] = p.Default
+	}
+	for name, value := range values {
+		if _, found := data[name]; !found {
+			return nil, fmt.Errorf("template %s has no parameter %q", t.Name, name)
+		}
+		data[name] = value
+	}
+	source, err := t.Source()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(t.Name).Funcs(spec.TemplateFuncs()).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %v", t.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render template %s: %v", t.Name, err)
+	}
+	return buf.Bytes(), nil
+}
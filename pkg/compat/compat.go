@@ -0,0 +1,184 @@
+package compat
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/httpclient"
+	"golang.org/x/net/context/ctxhttp"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed compatibility.yaml
+var bundled []byte
+
+const (
+	Error   = "error"
+	Warning = "warning"
+)
+
+// Rule is a constraint of upgrades crossing Version.
+type Rule struct {
+	Version        string              `yaml:"version"`
+	Severity       string              `yaml:"severity"`
+	Message        string              `yaml:"message"`
+	RemovedOptions map[string][]string `yaml:"removed_options,omitempty"` // by component
+}
+
+// Problem is a rule, or a built-in check, that an upgrade of a server breaks.
+type Problem struct {
+	Severity string `json:"severity" yaml:"severity"`
+	Instance string `json:"instance" yaml:"instance"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Load returns the bundled rules, and those of the file, a path or an http(s)
+// URL, if set.
+func Load(ctx context.Context, file string) ([]Rule, error) {
+	rules, err := parse(bundled)
+	if err != nil {
+		return nil, fmt.Errorf("bundled rules: %w", err)
+	}
+	if file == "" {
+		return rules, nil
+	}
+	data, err := read(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return append(rules, extra...), nil
+}
+
+func parse(data []byte) ([]Rule, error) {
+	var f rulesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	for i, rule := range f.Rules {
+		if _, err := parseVersion(rule.Version); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if rule.Severity != Error && rule.Severity != Warning {
+			return nil, fmt.Errorf("rule %d: severity %q must be error or warning", i+1, rule.Severity)
+		}
+	}
+	return f.Rules, nil
+}
+
+// downloadTimeout bounds the download of a rules file.
+const downloadTimeout = 30 * time.Second
+
+func read(ctx context.Context, file string) ([]byte, error) {
+	if !strings.HasPrefix(file, "http://") && !strings.HasPrefix(file, "https://") {
+		return os.ReadFile(file)
+	}
+	req, err := http.NewRequest(http.MethodGet, file, nil)
+	if err != nil {
+		return nil, err
+	}
+	client, err := httpclient.New(downloadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ctxhttp.Do(ctx, client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: unexpected status %v", file, res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// Check returns the problems of upgrading the component instance from the
+// installed version to the target one, with the options it is started with.
+func Check(rules []Rule, component, instance, installed, target string, options []string) []Problem {
+	from, err := parseVersion(installed)
+	if err != nil {
+		return []Problem{{Warning, instance, fmt.Sprintf("can not compare the installed version %q: %v", installed, err)}}
+	}
+	to, err := parseVersion(target)
+	if err != nil {
+		return []Problem{{Warning, instance, fmt.Sprintf("can not compare the version %q: %v", target, err)}}
+	}
+	var problems []Problem
+	if compare(to, from) < 0 {
+		problems = append(problems, Problem{Error, instance, fmt.Sprintf("%s is a downgrade from %s, newer versions may have changed the data on disk", target, installed)})
+	} else if to[0]-from[0] > 1 {
+		problems = append(problems, Problem{Error, instance, fmt.Sprintf("%s to %s skips major versions, upgrade to the latest %d.x release first", installed, target, from[0]+1)})
+	}
+	for _, rule := range rules {
+		v, _ := parseVersion(rule.Version)
+		if compare(from, v) >= 0 || compare(to, v) < 0 {
+			continue
+		}
+		if removed, found := rule.RemovedOptions[component]; found {
+			var used []string
+			for _, option := range removed {
+				for _, o := range options {
+					if o == option {
+						used = append(used, option)
+					}
+				}
+			}
+			if len(used) == 0 {
+				continue
+			}
+			problems = append(problems, Problem{rule.Severity, instance, fmt.Sprintf("%s (uses %s)", rule.Message, strings.Join(used, ", "))})
+			continue
+		}
+		if len(rule.RemovedOptions) == 0 {
+			problems = append(problems, Problem{rule.Severity, instance, rule.Message})
+		}
+	}
+	return problems
+}
+
+// parseVersion splits a version like 3.59 or 3.59.1 into its numbers.
+func parseVersion(version string) ([]int, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("version %q is not like 3.59", version)
+		}
+		numbers[i] = n
+	}
+	return numbers, nil
+}
+
+func compare(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
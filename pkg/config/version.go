@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cheggaaa/pb/v3"
+	"github.com/seaweedfs/seaweed-up/pkg/httpclient"
 	"golang.org/x/net/context/ctxhttp"
 	"io"
 	"io/ioutil"
@@ -43,29 +44,34 @@ type Asset struct {
 
 const githubAPITimeout = 30 * time.Second
 
+// downloadTimeout bounds the download of a release asset.
+const downloadTimeout = 30 * time.Minute
+
 // githubError is returned by the GitHub API, e.g. for rate-limiting.
 type githubError struct {
 	Message string
 }
 
-// GitHubLatestRelease uses the GitHub API to get information about the specific
-// release of a repository.
-func GitHubLatestRelease(ctx context.Context, ver string, owner, repo string) (Release, error) {
-	ctx, cancel := context.WithTimeout(ctx, githubAPITimeout)
-	defer cancel()
+// GitHubReleases uses the GitHub API to list the releases of a repository,
+// the latest first.
+func GitHubReleases(ctx context.Context, owner, repo string) ([]Release, error) {
+	client, err := httpclient.New(githubAPITimeout)
+	if err != nil {
+		return nil, err
+	}
 
 	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases", owner, repo)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return Release{}, err
+		return nil, err
 	}
 
 	// pin API version 3
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
-	res, err := ctxhttp.Do(ctx, http.DefaultClient, req)
+	res, err := ctxhttp.Do(ctx, client, req)
 	if err != nil {
-		return Release{}, err
+		return nil, err
 	}
 	defer res.Body.Close()
 
@@ -76,24 +82,38 @@ func GitHubLatestRelease(ctx context.Context, ver string, owner, repo string) (R
 			var msg githubError
 			jerr := json.NewDecoder(res.Body).Decode(&msg)
 			if jerr == nil {
-				return Release{}, fmt.Errorf("unexpected status %v (%v) returned, message:\n  %v", res.StatusCode, res.Status, msg.Message)
+				return nil, fmt.Errorf("unexpected status %v (%v) returned, message:\n  %v", res.StatusCode, res.Status, msg.Message)
 			}
 		}
 
-		return Release{}, fmt.Errorf("unexpected status %v (%v) returned", res.StatusCode, res.Status)
+		return nil, fmt.Errorf("unexpected status %v (%v) returned", res.StatusCode, res.Status)
 	}
 
 	buf, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return Release{}, err
+		return nil, err
 	}
 
-	var release Release
 	var releaseList []Release
 	err = json.Unmarshal(buf, &releaseList)
+	if err != nil {
+		return nil, err
+	}
+	return releaseList, nil
+}
+
+// GitHubLatestRelease uses the GitHub API to get information about the specific
+// release of a repository.
+func GitHubLatestRelease(ctx context.Context, ver string, owner, repo string) (Release, error) {
+	releaseList, err := GitHubReleases(ctx, owner, repo)
 	if err != nil {
 		return Release{}, err
 	}
+	if len(releaseList) == 0 {
+		return Release{}, fmt.Errorf("%s/%s has no release", owner, repo)
+	}
+
+	var release Release
 	if ver == "0" {
 		release = releaseList[0]
 		log.Printf("latest version is %v / %v", release.TagName, release.PublishedAt.Local())
@@ -166,6 +186,11 @@ func DownloadRelease(ctx context.Context, os, arch string, isLargeDisk, isFull b
 }
 
 func getGithubData(ctx context.Context, url string) ([]byte, error) {
+	client, err := httpclient.New(downloadTimeout)
+	if err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
@@ -174,7 +199,7 @@ func getGithubData(ctx context.Context, url string) ([]byte, error) {
 	// request binary data
 	req.Header.Set("Accept", "application/octet-stream")
 
-	res, err := ctxhttp.Do(ctx, http.DefaultClient, req)
+	res, err := ctxhttp.Do(ctx, client, req)
 	if err != nil {
 		return nil, err
 	}
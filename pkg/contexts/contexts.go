@@ -0,0 +1,123 @@
+// Package contexts keeps named clusters, so commands can operate one of them
+// without repeating its configuration file and SSH settings.
+package contexts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Context is a named cluster: its configuration file and how to login to its
+// hosts. Its state, like locks and trusted host keys, is kept in StateDir.
+type Context struct {
+	Name         string `yaml:"name" json:"name"`
+	File         string `yaml:"file" json:"file"`
+	User         string `yaml:"user,omitempty" json:"user,omitempty"`
+	IdentityFile string `yaml:"identity_file,omitempty" json:"identityFile,omitempty"`
+}
+
+// Config is the contexts and the one in use.
+type Config struct {
+	Current  string     `yaml:"current,omitempty" json:"current,omitempty"`
+	Contexts []*Context `yaml:"contexts" json:"contexts"`
+}
+
+// Dir is the data dir of seaweed-up the contexts are kept in.
+var Dir = filepath.Join(utils.UserHome(), ".seaweed-up")
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+func configFile() string {
+	return filepath.Join(Dir, "contexts.yaml")
+}
+
+// StateDir is where the state of operations on the cluster of the context is kept.
+func StateDir(name string) string {
+	return filepath.Join(Dir, "contexts", name)
+}
+
+// Load reads the contexts, none if there is no context yet.
+func Load() (*Config, error) {
+	c := &Config{}
+	data, err := os.ReadFile(configFile())
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read contexts: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", configFile(), err)
+	}
+	return c, nil
+}
+
+// Save writes the contexts.
+func (c *Config) Save() error {
+	sort.Slice(c.Contexts, func(i, j int) bool { return c.Contexts[i].Name < c.Contexts[j].Name })
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir, 0700); err != nil {
+		return fmt.Errorf("create %s: %w", Dir, err)
+	}
+	temp := configFile() + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return fmt.Errorf("write contexts: %w", err)
+	}
+	return os.Rename(temp, configFile())
+}
+
+// Find returns the context with the name, nil if there is none.
+func (c *Config) Find(name string) *Context {
+	for _, context := range c.Contexts {
+		if context.Name == name {
+			return context
+		}
+	}
+	return nil
+}
+
+// Set adds the context, or replaces the one with its name.
+func (c *Config) Set(context *Context) error {
+	if !validName.MatchString(context.Name) {
+		return fmt.Errorf("context name %q must be letters, digits, '.', '_' or '-'", context.Name)
+	}
+	file, err := filepath.Abs(context.File)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(file); err != nil {
+		return fmt.Errorf("configuration file: %w", err)
+	}
+	context.File = file
+	for i, existing := range c.Contexts {
+		if existing.Name == context.Name {
+			c.Contexts[i] = context
+			return nil
+		}
+	}
+	c.Contexts = append(c.Contexts, context)
+	return nil
+}
+
+// Remove removes the context and its state. It returns whether there was one.
+func (c *Config) Remove(name string) (bool, error) {
+	for i, context := range c.Contexts {
+		if context.Name == name {
+			c.Contexts = append(c.Contexts[:i], c.Contexts[i+1:]...)
+			if c.Current == name {
+				c.Current = ""
+			}
+			return true, os.RemoveAll(StateDir(name))
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,314 @@
+// Package dashboards generates dashboards of the metrics SeaweedFS components
+// expose on their metrics_port, for the monitoring tools scraping them.
+package dashboards
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Options tune the generated dashboards.
+type Options struct {
+	// Datasource is the uid of the Prometheus datasource the panels query.
+	// If empty, Grafana asks for it on import.
+	Datasource string
+}
+
+// Formats lists the supported dashboard formats.
+func Formats() []string {
+	return []string{"grafana"}
+}
+
+// Export returns the generated dashboards by file name.
+func Export(format string, options Options) (map[string][]byte, error) {
+	if format != "grafana" {
+		return nil, fmt.Errorf("unknown dashboard format %q, supported: %s", format, strings.Join(Formats(), ", "))
+	}
+	files := make(map[string][]byte)
+	for _, d := range grafanaDashboards(options) {
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		files[d.UID+".json"] = append(data, '\n')
+	}
+	return files, nil
+}
+
+// Bundle returns the dashboards of files as one JSON array, in file name order.
+func Bundle(files map[string][]byte) []byte {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var dashboards []json.RawMessage
+	for _, name := range names {
+		dashboards = append(dashboards, files[name])
+	}
+	data, _ := json.MarshalIndent(dashboards, "", "  ")
+	return append(data, '\n')
+}
+
+// importInput is the datasource variable Grafana asks for when importing a
+// dashboard, referenced by the panels as ${DS_PROMETHEUS}.
+const importInput = "DS_PROMETHEUS"
+
+type grafanaDashboard struct {
+	Inputs        []grafanaInput    `json:"__inputs,omitempty"`
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
+	Timezone      string            `json:"timezone"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Refresh       string            `json:"refresh"`
+	Time          grafanaTime       `json:"time"`
+	Templating    grafanaTemplating `json:"templating"`
+	Panels        []*grafanaPanel   `json:"panels"`
+}
+
+type grafanaInput struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	PluginID string `json:"pluginId"`
+}
+
+type grafanaTime struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaTemplating struct {
+	List []grafanaVariable `json:"list"`
+}
+
+type grafanaVariable struct {
+	Name       string             `json:"name"`
+	Label      string             `json:"label"`
+	Type       string             `json:"type"`
+	Datasource *grafanaDatasource `json:"datasource"`
+	Query      string             `json:"query"`
+	Refresh    int                `json:"refresh"`
+	Multi      bool               `json:"multi"`
+	IncludeAll bool               `json:"includeAll"`
+	AllValue   string             `json:"allValue,omitempty"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaPanel struct {
+	ID          int                `json:"id"`
+	Type        string             `json:"type"`
+	Title       string             `json:"title"`
+	Datasource  *grafanaDatasource `json:"datasource"`
+	GridPos     grafanaGridPos     `json:"gridPos"`
+	FieldConfig grafanaFieldConfig `json:"fieldConfig"`
+	Targets     []grafanaTarget    `json:"targets"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults struct {
+		Unit string `json:"unit,omitempty"`
+	} `json:"defaults"`
+	Overrides []interface{} `json:"overrides"`
+}
+
+type grafanaTarget struct {
+	RefID        string             `json:"refId"`
+	Datasource   *grafanaDatasource `json:"datasource"`
+	Expr         string             `json:"expr"`
+	LegendFormat string             `json:"legendFormat"`
+}
+
+// query is one Prometheus expression of a panel, with its series legend.
+type query struct {
+	expr   string
+	legend string
+}
+
+// panel is a graph, or a single stat, of one or more queries.
+type panel struct {
+	title   string
+	kind    string // timeseries or stat
+	unit    string
+	queries []query
+}
+
+// dashboard lays out its panels two per row.
+type dashboard struct {
+	uid      string
+	title    string
+	variable string // series the instances to filter on are read from, none if empty
+	panels   []panel
+}
+
+func grafanaDashboards(options Options) (dashboards []*grafanaDashboard) {
+	for _, d := range []dashboard{overviewDashboard, volumeDisksDashboard, filerS3Dashboard} {
+		dashboards = append(dashboards, d.grafana(options))
+	}
+	return
+}
+
+func (d dashboard) grafana(options Options) *grafanaDashboard {
+	datasource := &grafanaDatasource{Type: "prometheus", UID: options.Datasource}
+	g := &grafanaDashboard{
+		UID:           d.uid,
+		Title:         d.title,
+		Tags:          []string{"seaweedfs"},
+		Timezone:      "browser",
+		SchemaVersion: 39,
+		Refresh:       "30s",
+		Time:          grafanaTime{From: "now-6h", To: "now"},
+		Templating:    grafanaTemplating{List: []grafanaVariable{}},
+	}
+	if options.Datasource == "" {
+		datasource.UID = "${" + importInput + "}"
+		g.Inputs = []grafanaInput{{Name: importInput, Label: "Prometheus", Type: "datasource", PluginID: "prometheus"}}
+	}
+	if d.variable != "" {
+		g.Templating.List = append(g.Templating.List, grafanaVariable{
+			Name:       "instance",
+			Label:      "Instance",
+			Type:       "query",
+			Datasource: datasource,
+			Query:      fmt.Sprintf("label_values(%s, instance)", d.variable),
+			Refresh:    2,
+			Multi:      true,
+			IncludeAll: true,
+			AllValue:   ".*",
+		})
+	}
+	for i, p := range d.panels {
+		gp := &grafanaPanel{
+			ID:         i + 1,
+			Type:       p.kind,
+			Title:      p.title,
+			Datasource: datasource,
+			GridPos:    grafanaGridPos{H: 8, W: 12, X: (i % 2) * 12, Y: (i / 2) * 8},
+		}
+		gp.FieldConfig.Defaults.Unit = p.unit
+		gp.FieldConfig.Overrides = []interface{}{}
+		for j, q := range p.queries {
+			gp.Targets = append(gp.Targets, grafanaTarget{
+				RefID:        string(rune('A' + j)),
+				Datasource:   datasource,
+				Expr:         q.expr,
+				LegendFormat: q.legend,
+			})
+		}
+		g.Panels = append(g.Panels, gp)
+	}
+	return g
+}
+
+// quantiles are the p50, p95 and p99 of a request duration histogram, by type.
+func quantiles(histogram string) (queries []query) {
+	for _, q := range []struct{ quantile, name string }{{"0.5", "p50"}, {"0.95", "p95"}, {"0.99", "p99"}} {
+		queries = append(queries, query{
+			expr:   fmt.Sprintf(`histogram_quantile(%s, sum by (le, type) (rate(%s_bucket{instance=~"$instance"}[5m])))`, q.quantile, histogram),
+			legend: "{{type}} " + q.name,
+		})
+	}
+	return
+}
+
+// seaweedTargets keeps the series of the scraped targets that are SeaweedFS
+// components, when the process metrics of other targets share the Prometheus.
+const seaweedTargets = ` and on (instance) (SeaweedFS_master_is_leader or SeaweedFS_volumeServer_max_volumes or SeaweedFS_filer_request_total or SeaweedFS_s3_request_total)`
+
+var overviewDashboard = dashboard{
+	uid:   "seaweedfs-overview",
+	title: "SeaweedFS cluster overview",
+	panels: []panel{
+		{title: "Master leader", kind: "stat", queries: []query{
+			{`max by (instance) (SeaweedFS_master_is_leader)`, "{{instance}}"},
+		}},
+		{title: "Master heartbeats", kind: "timeseries", unit: "reqps", queries: []query{
+			{`sum by (instance) (rate(SeaweedFS_master_received_heartbeats[5m]))`, "{{instance}}"},
+		}},
+		{title: "Volumes", kind: "timeseries", queries: []query{
+			{`sum(SeaweedFS_volumeServer_volumes)`, "volumes"},
+			{`sum(SeaweedFS_volumeServer_max_volumes)`, "max volumes"},
+		}},
+		{title: "Disk usage", kind: "timeseries", unit: "bytes", queries: []query{
+			{`sum(SeaweedFS_volumeServer_resource{type="used"})`, "used"},
+			{`sum(SeaweedFS_volumeServer_resource{type="all"})`, "capacity"},
+		}},
+		{title: "Requests", kind: "timeseries", unit: "reqps", queries: []query{
+			{`sum(rate(SeaweedFS_volumeServer_request_total[5m]))`, "volume"},
+			{`sum(rate(SeaweedFS_filer_request_total[5m]))`, "filer"},
+			{`sum(rate(SeaweedFS_s3_request_total[5m]))`, "s3"},
+		}},
+		{title: "Server errors", kind: "timeseries", unit: "reqps", queries: []query{
+			{`sum(rate(SeaweedFS_volumeServer_request_total{code=~"5.."}[5m]))`, "volume"},
+			{`sum(rate(SeaweedFS_filer_request_total{code=~"5.."}[5m]))`, "filer"},
+			{`sum(rate(SeaweedFS_s3_request_total{code=~"5.."}[5m]))`, "s3"},
+		}},
+		{title: "Memory", kind: "timeseries", unit: "bytes", queries: []query{
+			{`process_resident_memory_bytes` + seaweedTargets, "{{instance}}"},
+		}},
+		{title: "CPU", kind: "timeseries", unit: "percentunit", queries: []query{
+			{`rate(process_cpu_seconds_total[5m])` + seaweedTargets, "{{instance}}"},
+		}},
+	},
+}
+
+var volumeDisksDashboard = dashboard{
+	uid:      "seaweedfs-volume-disks",
+	title:    "SeaweedFS volume server disks",
+	variable: "SeaweedFS_volumeServer_resource",
+	panels: []panel{
+		{title: "Disk usage", kind: "timeseries", unit: "percentunit", queries: []query{
+			{`SeaweedFS_volumeServer_resource{type="used", instance=~"$instance"} / ignoring (type) SeaweedFS_volumeServer_resource{type="all", instance=~"$instance"}`, "{{instance}} {{name}}"},
+		}},
+		{title: "Free space", kind: "timeseries", unit: "bytes", queries: []query{
+			{`SeaweedFS_volumeServer_resource{type="free", instance=~"$instance"}`, "{{instance}} {{name}}"},
+		}},
+		{title: "Volumes", kind: "timeseries", queries: []query{
+			{`sum by (instance) (SeaweedFS_volumeServer_volumes{instance=~"$instance"})`, "{{instance}}"},
+			{`sum by (instance) (SeaweedFS_volumeServer_max_volumes{instance=~"$instance"})`, "{{instance}} max"},
+		}},
+		{title: "Size by collection", kind: "timeseries", unit: "bytes", queries: []query{
+			{`sum by (collection) (SeaweedFS_volumeServer_total_disk_size{instance=~"$instance"})`, "{{collection}}"},
+		}},
+		{title: "Requests", kind: "timeseries", unit: "reqps", queries: []query{
+			{`sum by (type) (rate(SeaweedFS_volumeServer_request_total{instance=~"$instance"}[5m]))`, "{{type}}"},
+		}},
+		{title: "Request latency", kind: "timeseries", unit: "s", queries: quantiles("SeaweedFS_volumeServer_request_seconds")},
+	},
+}
+
+var filerS3Dashboard = dashboard{
+	uid:      "seaweedfs-filer-s3",
+	title:    "SeaweedFS filer and S3 latency",
+	variable: `{__name__=~"SeaweedFS_(filer|s3)_request_total"}`,
+	panels: []panel{
+		{title: "Filer requests", kind: "timeseries", unit: "reqps", queries: []query{
+			{`sum by (type) (rate(SeaweedFS_filer_request_total{instance=~"$instance"}[5m]))`, "{{type}}"},
+		}},
+		{title: "Filer latency", kind: "timeseries", unit: "s", queries: quantiles("SeaweedFS_filer_request_seconds")},
+		{title: "S3 requests", kind: "timeseries", unit: "reqps", queries: []query{
+			{`sum by (type) (rate(SeaweedFS_s3_request_total{instance=~"$instance"}[5m]))`, "{{type}}"},
+		}},
+		{title: "S3 latency", kind: "timeseries", unit: "s", queries: quantiles("SeaweedFS_s3_request_seconds")},
+		{title: "Filer errors", kind: "timeseries", unit: "reqps", queries: []query{
+			{`sum by (type, code) (rate(SeaweedFS_filer_request_total{code=~"[45]..", instance=~"$instance"}[5m]))`, "{{type}} {{code}}"},
+		}},
+		{title: "S3 errors", kind: "timeseries", unit: "reqps", queries: []query{
+			{`sum by (type, code) (rate(SeaweedFS_s3_request_total{code=~"[45]..", instance=~"$instance"}[5m]))`, "{{type}} {{code}}"},
+		}},
+	},
+}
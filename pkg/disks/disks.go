@@ -3,6 +3,7 @@ package disks
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"github.com/seaweedfs/seaweed-up/pkg/operator"
 	"regexp"
 	"strconv"
@@ -20,10 +21,94 @@ type BlockDevice struct {
 	MountPoint     string
 	SerialId       string
 	Type           string
+	Model          string
+	ReadOnly       bool
+	Removable      bool
+	Rotational     bool
+	ById           string // stable /dev/disk/by-id path, if any
+	Health         string // SMART overall health, empty if unknown
+}
+
+// Discoverer lists the block devices of a host.
+type Discoverer interface {
+	Name() string
+	Discover(op operator.CommandOperator, prefixes []string) ([]*BlockDevice, error)
+}
+
+var discoverers = []Discoverer{
+	&lsblkJsonDiscoverer{},
+	&lsblkDiscoverer{},
+	&sysfsDiscoverer{},
+}
+
+// NewDiscoverer returns the discovery backend with the given name.
+func NewDiscoverer(name string) (Discoverer, error) {
+	for _, d := range discoverers {
+		if d.Name() == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown disk discovery backend %q, supported: lsblk-json, lsblk, sysfs", name)
+}
+
+// Discover lists block devices with the named backend, or with the first
+// backend working on the host if name is empty or "auto". The devices are
+// enriched with their /dev/disk/by-id paths.
+func Discover(op operator.CommandOperator, name string, prefixes []string) (devices []*BlockDevice, err error) {
+	if name != "" && name != "auto" {
+		d, err := NewDiscoverer(name)
+		if err != nil {
+			return nil, err
+		}
+		devices, err = d.Discover(op, prefixes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", d.Name(), err)
+		}
+	} else {
+		var errs []string
+		for _, d := range discoverers {
+			devices, err = d.Discover(op, prefixes)
+			if err == nil {
+				break
+			}
+			errs = append(errs, fmt.Sprintf("%s: %v", d.Name(), err))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("no disk discovery backend works: %s", strings.Join(errs, "; "))
+		}
+	}
+	if err := enrichById(op, devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// MountPoints returns all mount points of the host.
+func MountPoints(op operator.CommandOperator) (map[string]struct{}, error) {
+	out, err := op.Output("awk '{print $2}' /proc/mounts")
+	if err != nil {
+		return nil, err
+	}
+	mountpoints := make(map[string]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			mountpoints[line] = struct{}{}
+		}
+	}
+	return mountpoints, nil
+}
+
+type lsblkDiscoverer struct{}
+
+func (d *lsblkDiscoverer) Name() string { return "lsblk" }
+
+func (d *lsblkDiscoverer) Discover(op operator.CommandOperator, prefixes []string) ([]*BlockDevice, error) {
+	devices, _, err := ListBlockDevices(op, prefixes)
+	return devices, err
 }
 
 func ListBlockDevices(op operator.CommandOperator, prefixes []string) (output []*BlockDevice, mountpoints map[string]struct{}, err error) {
-	mountpoints = make(map[string]struct{})
 	out, err := op.Output(
 		strings.Join([]string{
 			"lsblk",
@@ -40,18 +125,28 @@ func ListBlockDevices(op operator.CommandOperator, prefixes []string) (output []
 				"MOUNTPOINT", // mount point
 				"MAJ:MIN",    // major/minor device numbers
 				"FSUSED",
+				"MODEL",  // device model
+				"SERIAL", // disk serial number
+				"RO",     // read only device
+				"RM",     // removable device
+				"ROTA",   // rotational device
 			}, ","),
 		}, " "))
 	if err != nil {
 		return
 	}
+	return parseKeyValuePairs(out, prefixes)
+}
+
+// parseKeyValuePairs parses lines of KEY="value" pairs as printed by lsblk -P.
+func parseKeyValuePairs(out []byte, prefixes []string) (output []*BlockDevice, mountpoints map[string]struct{}, err error) {
+	mountpoints = make(map[string]struct{})
 	scanner := bufio.NewScanner(bytes.NewReader(out))
 	nvPairPattern := regexp.MustCompile(`([A-Z:]+)=(?:"(.*?)")`)
 	for scanner.Scan() {
 		pairs := nvPairPattern.FindAllStringSubmatch(scanner.Text(), -1)
 		dev := &BlockDevice{}
 		var majorMinor string
-		var hasValidPrefix bool
 		for _, pair := range pairs {
 			if len(pair) != 3 {
 				continue
@@ -62,13 +157,6 @@ func ListBlockDevices(op operator.CommandOperator, prefixes []string) (output []
 				dev.DeviceName = value
 			case "PATH":
 				dev.Path = value
-				for _, prefix := range prefixes {
-					if strings.HasPrefix(dev.Path, prefix) {
-						hasValidPrefix = true
-						println("valid path", dev.Path)
-						break
-					}
-				}
 			case "SIZE":
 				var size uint64
 				size, err = strconv.ParseUint(value, 10, 64)
@@ -87,21 +175,77 @@ func ListBlockDevices(op operator.CommandOperator, prefixes []string) (output []
 				dev.Type = value
 			case "MOUNTPOINT":
 				dev.MountPoint = value
-				mountpoints[value] = struct{}{}
+				if value != "" {
+					mountpoints[value] = struct{}{}
+				}
 			case "MAJ:MIN":
-				majorMinor = pair[2]
+				majorMinor = value
+			case "MODEL":
+				dev.Model = value
+			case "SERIAL":
+				dev.SerialId = value
+			case "RO":
+				dev.ReadOnly = value == "1"
+			case "RM":
+				dev.Removable = value == "1"
+			case "ROTA":
+				dev.Rotational = value == "1"
 			}
 		}
-		if !hasValidPrefix {
+		if keepDevice(dev, majorMinor, prefixes) {
+			output = append(output, dev)
+		}
+	}
+	return
+}
+
+func keepDevice(dev *BlockDevice, majorMinor string, prefixes []string) bool {
+	var hasValidPrefix bool
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(dev.Path, prefix) {
+			hasValidPrefix = true
+			break
+		}
+	}
+	if !hasValidPrefix {
+		return false
+	}
+	if dev.Type == "disk" {
+		// Floppy disks, which have major device number 2
+		if strings.HasPrefix(majorMinor, "2:") {
+			return false
+		}
+	}
+	return true
+}
+
+// enrichById records the stable /dev/disk/by-id path of each device.
+func enrichById(op operator.CommandOperator, devices []*BlockDevice) error {
+	out, err := op.Output(`for f in /dev/disk/by-id/*; do [ -e "$f" ] && echo "$f $(readlink -f "$f")"; done; true`)
+	if err != nil {
+		return fmt.Errorf("list /dev/disk/by-id: %v", err)
+	}
+	byPath := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
 			continue
 		}
-		if dev.Type == "disk" {
-			// Floppy disks, which have major device number 2
-			if strings.HasPrefix(majorMinor, "2:") {
-				continue
-			}
+		link, target := fields[0], fields[1]
+		// prefer the vendor specific names over the generic wwn- and nvme-eui. ones
+		if existing, found := byPath[target]; found && !isGenericId(existing) {
+			continue
 		}
-		output = append(output, dev)
+		byPath[target] = link
 	}
-	return
+	for _, dev := range devices {
+		dev.ById = byPath[dev.Path]
+	}
+	return nil
+}
+
+func isGenericId(link string) bool {
+	name := link[strings.LastIndex(link, "/")+1:]
+	return strings.HasPrefix(name, "wwn-") || strings.HasPrefix(name, "nvme-eui.")
 }
@@ -0,0 +1,98 @@
+package disks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+// lsblkJsonDiscoverer uses lsblk --json, which nests partitions under their disks.
+type lsblkJsonDiscoverer struct{}
+
+func (d *lsblkJsonDiscoverer) Name() string { return "lsblk-json" }
+
+type lsblkJsonOutput struct {
+	BlockDevices []map[string]interface{} `json:"blockdevices"`
+}
+
+func (d *lsblkJsonDiscoverer) Discover(op operator.CommandOperator, prefixes []string) ([]*BlockDevice, error) {
+	out, err := op.Output("lsblk -J -b -o KNAME,PATH,SIZE,LABEL,UUID,FSTYPE,TYPE,MOUNTPOINT,MAJ:MIN,MODEL,SERIAL,RO,RM,ROTA")
+	if err != nil {
+		return nil, err
+	}
+	return parseLsblkJson(out, prefixes)
+}
+
+func parseLsblkJson(out []byte, prefixes []string) (output []*BlockDevice, err error) {
+	var parsed lsblkJsonOutput
+	if err = json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("parse lsblk json: %v", err)
+	}
+	var walk func(entries []map[string]interface{}) error
+	walk = func(entries []map[string]interface{}) error {
+		for _, entry := range entries {
+			size, err := strconv.ParseUint(jsonString(entry["size"]), 10, 64)
+			if err != nil {
+				return fmt.Errorf("parse size of %s: %v", jsonString(entry["kname"]), err)
+			}
+			dev := &BlockDevice{
+				DeviceName:     jsonString(entry["kname"]),
+				Path:           jsonString(entry["path"]),
+				Size:           size,
+				Label:          jsonString(entry["label"]),
+				UUID:           jsonString(entry["uuid"]),
+				FilesystemType: jsonString(entry["fstype"]),
+				Type:           jsonString(entry["type"]),
+				MountPoint:     jsonString(entry["mountpoint"]),
+				Model:          jsonString(entry["model"]),
+				SerialId:       jsonString(entry["serial"]),
+				ReadOnly:       jsonBool(entry["ro"]),
+				Removable:      jsonBool(entry["rm"]),
+				Rotational:     jsonBool(entry["rota"]),
+			}
+			if keepDevice(dev, jsonString(entry["maj:min"]), prefixes) {
+				output = append(output, dev)
+			}
+			if children, ok := entry["children"].([]interface{}); ok {
+				var childEntries []map[string]interface{}
+				for _, child := range children {
+					if c, ok := child.(map[string]interface{}); ok {
+						childEntries = append(childEntries, c)
+					}
+				}
+				if err := walk(childEntries); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err = walk(parsed.BlockDevices)
+	return
+}
+
+// jsonString converts the values of lsblk json output, which are strings in
+// older util-linux versions and numbers, booleans or null in newer ones.
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatUint(uint64(v), 10)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func jsonBool(value interface{}) bool {
+	return jsonString(value) == "1"
+}
@@ -0,0 +1,71 @@
+package disks
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+const (
+	HealthPassed = "PASSED"
+	HealthFailed = "FAILED"
+)
+
+// EnrichWithSmart fills in model, serial and SMART health of disks with smartctl.
+// smartctl usually needs root, so op should run commands with elevated privileges.
+// Devices are left unchanged if smartctl is not installed.
+func EnrichWithSmart(op operator.CommandOperator, devices []*BlockDevice) error {
+	out, err := op.Output("command -v smartctl || true")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(out)) == "" {
+		return nil
+	}
+	for _, dev := range devices {
+		if dev.Type != "disk" {
+			continue
+		}
+		// smartctl uses bit masks in its exit status, so the output is parsed regardless
+		out, _ := op.Output(fmt.Sprintf("smartctl -i -H %s", dev.Path))
+		parseSmartctl(out, dev)
+	}
+	return nil
+}
+
+func parseSmartctl(out []byte, dev *BlockDevice) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Device Model", "Model Number", "Product":
+			if dev.Model == "" {
+				dev.Model = value
+			}
+		case "Serial Number", "Serial number":
+			if dev.SerialId == "" {
+				dev.SerialId = value
+			}
+		case "SMART overall-health self-assessment test result":
+			dev.Health = value
+		case "SMART Health Status":
+			// SCSI devices report OK instead of PASSED
+			if value == "OK" {
+				dev.Health = HealthPassed
+			} else {
+				dev.Health = HealthFailed
+			}
+		}
+	}
+	if strings.HasPrefix(dev.Health, "FAILED") {
+		dev.Health = HealthFailed
+	}
+}
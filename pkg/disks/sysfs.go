@@ -0,0 +1,36 @@
+package disks
+
+import (
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+// sysfsDiscoverer scans /sys/block directly, for hosts without a usable lsblk.
+type sysfsDiscoverer struct{}
+
+func (d *sysfsDiscoverer) Name() string { return "sysfs" }
+
+// sysfsScript prints one line of lsblk -P style key="value" pairs per disk and partition.
+const sysfsScript = `for s in /sys/block/*; do
+  k=$(basename $s)
+  for p in $s $s/$k*; do
+    [ -e $p/dev ] || continue
+    n=$(basename $p)
+    t=disk; [ -e $p/partition ] && t=part
+    fs=$(blkid -s TYPE -o value /dev/$n 2>/dev/null)
+    uuid=$(blkid -s UUID -o value /dev/$n 2>/dev/null)
+    label=$(blkid -s LABEL -o value /dev/$n 2>/dev/null)
+    mp=$(awk -v d=/dev/$n '$1==d {print $2; exit}' /proc/mounts)
+    model=$(cat $s/device/model 2>/dev/null | xargs)
+    serial=$(cat $s/device/serial 2>/dev/null | xargs)
+    echo "KNAME=\"$n\" PATH=\"/dev/$n\" SIZE=\"$(( $(cat $p/size) * 512 ))\" LABEL=\"$label\" UUID=\"$uuid\" FSTYPE=\"$fs\" TYPE=\"$t\" MOUNTPOINT=\"$mp\" MAJ:MIN=\"$(cat $p/dev)\" MODEL=\"$model\" SERIAL=\"$serial\" RO=\"$(cat $p/ro)\" RM=\"$(cat $s/removable)\" ROTA=\"$(cat $s/queue/rotational)\""
+  done
+done`
+
+func (d *sysfsDiscoverer) Discover(op operator.CommandOperator, prefixes []string) ([]*BlockDevice, error) {
+	out, err := op.Output(sysfsScript)
+	if err != nil {
+		return nil, err
+	}
+	devices, _, err := parseKeyValuePairs(out, prefixes)
+	return devices, err
+}
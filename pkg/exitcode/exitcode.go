@@ -0,0 +1,60 @@
+// Package exitcode defines the exit codes of seaweed-up, a stable contract for scripts and CI pipelines.
+package exitcode
+
+import (
+	"errors"
+
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+const (
+	OK          = 0
+	Failure     = 1 // any error not covered below
+	Invalid     = 2 // invalid flags or configuration file, or required input missing
+	Partial     = 3 // the operation failed on some hosts after succeeding on others
+	Unreachable = 4 // a host could not be reached or authenticated over SSH
+)
+
+type codeError struct {
+	code int
+	err  error
+}
+
+func (e *codeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codeError) Unwrap() error {
+	return e.err
+}
+
+// WithCode marks the error to exit with the code.
+func WithCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codeError{code: code, err: err}
+}
+
+// Of returns the exit code for the error returned by a command. Explicit codes
+// win over input refused in non-interactive mode, and over SSH connection
+// errors found in the chain of wrapped errors.
+func Of(err error) int {
+	if err == nil {
+		return OK
+	}
+	var coded *codeError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	if errors.Is(err, utils.ErrNonInteractive) {
+		return Invalid
+	}
+	var connectError *operator.TargetConnectError
+	var agentError *operator.SshAgentError
+	if errors.As(err, &connectError) || errors.As(err, &agentError) {
+		return Unreachable
+	}
+	return Failure
+}
@@ -0,0 +1,131 @@
+package exporters
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	Register(&ComposeExporter{})
+}
+
+// ComposeExporter generates a Docker Compose file running every component in its own container.
+type ComposeExporter struct{}
+
+type composeFile struct {
+	Services map[string]*composeService `yaml:"services"`
+	Volumes  map[string]struct{}        `yaml:"volumes,omitempty"`
+}
+
+type composeService struct {
+	Image       string              `yaml:"image"`
+	Hostname    string              `yaml:"hostname"`
+	Entrypoint  []string            `yaml:"entrypoint"`
+	Command     []string            `yaml:"command"`
+	Ports       []string            `yaml:"ports,omitempty"`
+	Volumes     []string            `yaml:"volumes,omitempty"`
+	DependsOn   []string            `yaml:"depends_on,omitempty"`
+	Profiles    []string            `yaml:"profiles,omitempty"`
+	Healthcheck *composeHealthcheck `yaml:"healthcheck,omitempty"`
+	Deploy      *composeDeploy      `yaml:"deploy,omitempty"`
+	Restart     string              `yaml:"restart"`
+}
+
+type composeHealthcheck struct {
+	Test     []string `yaml:"test"`
+	Interval string   `yaml:"interval"`
+	Timeout  string   `yaml:"timeout"`
+	Retries  int      `yaml:"retries"`
+}
+
+type composeDeploy struct {
+	Resources struct {
+		Limits map[string]string `yaml:"limits"`
+	} `yaml:"resources"`
+}
+
+func (e *ComposeExporter) Name() string { return "compose" }
+
+func (e *ComposeExporter) Export(specification *spec.Specification, options Options) (map[string][]byte, error) {
+	file := &composeFile{
+		Services: make(map[string]*composeService),
+		Volumes:  make(map[string]struct{}),
+	}
+	publishedPorts := make(map[int]string)
+	var masters []string
+	serviceName := func(component, name, ip string) string { return name }
+	for _, i := range instances(specification, serviceName) {
+		test := []string{"CMD", "wget", "-q", "-O", "/dev/null", fmt.Sprintf("http://%s:%d%s", i.name, i.ports[0], healthPath(i.component))}
+		if healthPath(i.component) == "" {
+			test = []string{"CMD", "nc", "-z", i.name, fmt.Sprint(i.ports[0])}
+		}
+		service := &composeService{
+			Image:      image(options),
+			Hostname:   i.name,
+			Entrypoint: []string{"/usr/bin/weed"},
+			Command:    i.args,
+			Restart:    "unless-stopped",
+			Healthcheck: &composeHealthcheck{
+				Test:     test,
+				Interval: "10s",
+				Timeout:  "5s",
+				Retries:  6,
+			},
+		}
+		// containers have their own network, but ports published on the docker host must be unique
+		for _, port := range i.ports {
+			if _, used := publishedPorts[port]; used {
+				continue
+			}
+			publishedPorts[port] = i.name
+			service.Ports = append(service.Ports, fmt.Sprintf("%d:%d", port, port))
+		}
+		for _, v := range i.volumes {
+			file.Volumes[v.name] = struct{}{}
+			service.Volumes = append(service.Volumes, v.name+":"+v.path)
+		}
+		if i.component == "master" {
+			masters = append(masters, i.name)
+		} else if len(masters) > 0 {
+			service.DependsOn = masters
+		}
+		if options.Profiles {
+			service.Profiles = []string{i.component}
+		}
+		if options.CPUs != "" || options.Memory != "" {
+			service.Deploy = &composeDeploy{}
+			service.Deploy.Resources.Limits = make(map[string]string)
+			if options.CPUs != "" {
+				service.Deploy.Resources.Limits["cpus"] = options.CPUs
+			}
+			if options.Memory != "" {
+				service.Deploy.Resources.Limits["memory"] = options.Memory
+			}
+		}
+		file.Services[i.name] = service
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# generated by seaweed-up export compose\n")
+	if len(specification.EnvoyServers) > 0 {
+		buf.WriteString("# envoy_servers are not exported, the filers publish their ports directly\n")
+	}
+	for _, s3Spec := range specification.S3Servers {
+		if s3Spec.Config != "" || s3Spec.CertFile != "" {
+			buf.WriteString("# the config and certificates of s3_servers are not exported, mount them and add -config, -cert.file and -key.file\n")
+			break
+		}
+	}
+	if len(specification.Mounts) > 0 {
+		buf.WriteString("# mounts are not exported, they are FUSE file systems of the hosts\n")
+	}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file); err != nil {
+		return nil, err
+	}
+	return map[string][]byte{"docker-compose.yml": buf.Bytes()}, nil
+}
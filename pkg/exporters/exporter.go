@@ -0,0 +1,277 @@
+package exporters
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+)
+
+// Options tune how a specification is exported.
+type Options struct {
+	Version  string `yaml:"version,omitempty"`  // SeaweedFS version, latest if empty
+	Profiles bool   `yaml:"profiles,omitempty"` // group the services by component
+	CPUs     string `yaml:"cpus,omitempty"`     // cpu limit of each service, e.g. "2"
+	Memory   string `yaml:"memory,omitempty"`   // memory limit of each service, e.g. "4G"
+}
+
+// Exporter converts a cluster specification into the deployment files of another tool.
+type Exporter interface {
+	Name() string
+	// Export returns the generated files by file name.
+	Export(specification *spec.Specification, options Options) (map[string][]byte, error)
+}
+
+var exporters = map[string]Exporter{}
+
+// Register adds an export format, replacing any of the same name. The
+// built-in formats register themselves, plugins add theirs at run time.
+func Register(e Exporter) {
+	exporters[e.Name()] = e
+}
+
+// Get returns the exporter with the given name.
+func Get(name string) (Exporter, error) {
+	if e, found := exporters[name]; found {
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q, supported: %s", name, strings.Join(Names(), ", "))
+}
+
+// Names lists the supported export formats.
+func Names() (names []string) {
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func image(options Options) string {
+	if options.Version == "" {
+		return "chrislusf/seaweedfs:latest"
+	}
+	return "chrislusf/seaweedfs:" + strings.TrimPrefix(options.Version, "v")
+}
+
+// indexPath is where the index dir of a volume server is mounted in containers.
+const indexPath = "/index"
+
+// optionsToArgs turns the name=value lines of a component options file into command line flags.
+func optionsToArgs(buf *bytes.Buffer) (args []string) {
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			args = append(args, "-"+line)
+		}
+	}
+	return
+}
+
+// instance is a component of the specification as started by the export.
+type instance struct {
+	component string
+	name      string // component and index, e.g. volume0
+	ip        string // the spec ip of the host
+	address   string // the address the instance advertises to the cluster
+	args      []string
+	ports     []int
+	portNames []string
+	volumes   []instanceVolume
+}
+
+type instanceVolume struct {
+	name string
+	path string
+}
+
+// addressFunc returns the address an instance advertises, given its name and spec ip.
+type addressFunc func(component, name, ip string) string
+
+// instances lists the components of the specification with the flags to start
+// them, where the servers find each other by the addresses returned by address.
+func instances(specification *spec.Specification, address addressFunc) (list []*instance) {
+	var masters []string
+	for index, masterSpec := range specification.MasterServers {
+		host := address("master", fmt.Sprintf("master%d", index), masterSpec.Ip)
+		masters = append(masters, masterSpec.ServerAddress(host))
+	}
+
+	for index, masterSpec := range specification.MasterServers {
+		s := *masterSpec
+		name := fmt.Sprintf("master%d", index)
+		s.Ip = address("master", name, masterSpec.Ip)
+		s.IpBind = "0.0.0.0"
+		s.VolumeSizeLimitMB = firstInt(s.VolumeSizeLimitMB, specification.GlobalOptions.VolumeSizeLimitMB, 5000)
+		if s.DefaultReplication == "" {
+			s.DefaultReplication = specification.GlobalOptions.Replication
+		}
+		var buf bytes.Buffer
+		s.WriteToBuffer(masters, &buf)
+		args := append([]string{"master"}, optionsToArgs(&buf)...)
+		list = append(list, &instance{
+			component: "master",
+			name:      name,
+			ip:        masterSpec.Ip,
+			address:   s.Ip,
+			args:      replaceArg(args, "-mdir=", "/data"),
+			ports:     s.ListenPorts(),
+			portNames: []string{"http", "grpc"},
+			volumes:   []instanceVolume{{name: name, path: "/data"}},
+		})
+	}
+
+	for index, volumeSpec := range specification.VolumeServers {
+		s := *volumeSpec
+		name := fmt.Sprintf("volume%d", index)
+		s.Ip = address("volume", name, volumeSpec.Ip)
+		s.IpBind = "0.0.0.0"
+		s.Folders = nil
+		var volumes []instanceVolume
+		for i, folder := range volumeSpec.Folders {
+			f := *folder
+			f.Folder = fmt.Sprintf("/data/%d", i)
+			s.Folders = append(s.Folders, &f)
+			volumes = append(volumes, instanceVolume{name: fmt.Sprintf("%s-%d", name, i), path: f.Folder})
+		}
+		if s.IndexDir != "" {
+			s.IndexDir = indexPath
+			volumes = append(volumes, instanceVolume{name: name + "-idx", path: indexPath})
+		}
+		var buf bytes.Buffer
+		s.WriteToBuffer(masters, &buf)
+		list = append(list, &instance{
+			component: "volume",
+			name:      name,
+			ip:        volumeSpec.Ip,
+			address:   s.Ip,
+			args:      append([]string{"volume"}, optionsToArgs(&buf)...),
+			ports:     s.ListenPorts(),
+			portNames: []string{"http", "grpc"},
+			volumes:   volumes,
+		})
+	}
+
+	var filers []string
+	for index, filerSpec := range specification.FilerServers {
+		s := *filerSpec
+		name := fmt.Sprintf("filer%d", index)
+		s.Ip = address("filer", name, filerSpec.Ip)
+		s.IpBind = "0.0.0.0"
+		filers = append(filers, utils.JoinHostPort(s.Ip, defaultInt(s.Port, 8888)))
+		var buf bytes.Buffer
+		s.WriteToBuffer(masters, &buf)
+		args := append([]string{"filer"}, optionsToArgs(&buf)...)
+		portNames := []string{"http", "grpc"}
+		if s.S3 || s.S3Port != 0 {
+			portNames = append(portNames, "s3")
+		}
+		if s.Webdav || s.WebdavPort != 0 {
+			portNames = append(portNames, "webdav")
+		}
+		list = append(list, &instance{
+			component: "filer",
+			name:      name,
+			ip:        filerSpec.Ip,
+			address:   s.Ip,
+			args:      append(args, "-defaultStoreDir=/data"),
+			ports:     s.ListenPorts(),
+			portNames: portNames,
+			volumes:   []instanceVolume{{name: name, path: "/data"}},
+		})
+	}
+
+	for index, s3Spec := range specification.S3Servers {
+		s := *s3Spec
+		name := fmt.Sprintf("s3%d", index)
+		s.IpBind = "0.0.0.0"
+		var buf bytes.Buffer
+		s.WriteToBuffer(s.FilerOf(filers, index), &buf)
+		list = append(list, &instance{
+			component: "s3",
+			name:      name,
+			ip:        s3Spec.Ip,
+			address:   address("s3", name, s3Spec.Ip),
+			args:      append([]string{"s3"}, optionsToArgs(&buf)...),
+			ports:     s.ListenPorts(),
+			portNames: []string{"http", "grpc"},
+		})
+	}
+
+	// mounts are FUSE file systems of the hosts, they are not exported
+	for index, webdavSpec := range specification.WebDAVServers {
+		name := fmt.Sprintf("webdav%d", index)
+		var buf bytes.Buffer
+		webdavSpec.WriteToBuffer(filers, &buf)
+		list = append(list, &instance{
+			component: "webdav",
+			name:      name,
+			ip:        webdavSpec.Ip,
+			address:   address("webdav", name, webdavSpec.Ip),
+			args:      append([]string{"webdav"}, optionsToArgs(&buf)...),
+			ports:     webdavSpec.ListenPorts(),
+			portNames: []string{"http"},
+		})
+	}
+
+	for index, brokerSpec := range specification.MQBrokers {
+		b := *brokerSpec
+		name := fmt.Sprintf("broker%d", index)
+		b.Ip = address("broker", name, brokerSpec.Ip)
+		var buf bytes.Buffer
+		b.WriteToBuffer(masters, &buf)
+		list = append(list, &instance{
+			component: "broker",
+			name:      name,
+			ip:        brokerSpec.Ip,
+			address:   b.Ip,
+			args:      append([]string{"mq.broker"}, optionsToArgs(&buf)...),
+			ports:     b.ListenPorts(),
+			portNames: []string{"grpc"},
+		})
+	}
+	return
+}
+
+// healthPath is the http path answering when a component is up, empty if
+// only its port can be checked.
+func healthPath(component string) string {
+	switch component {
+	case "master":
+		return "/cluster/status"
+	case "volume":
+		return "/status"
+	case "s3", "webdav", "broker":
+		return ""
+	}
+	return "/"
+}
+
+func replaceArg(args []string, prefix, value string) []string {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, prefix) {
+			args[i] = prefix + value
+		}
+	}
+	return args
+}
+
+func defaultInt(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func firstInt(values ...int) int {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
@@ -0,0 +1,260 @@
+package exporters
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+)
+
+func init() {
+	Register(&NomadExporter{})
+}
+
+// NomadExporter generates Nomad job files: service jobs pinned to the hosts of
+// the masters and filers, and a system job running a volume server on every
+// volume server host. The services are registered in Consul.
+//
+// The Nomad clients need host volumes named seaweedfs-master, seaweedfs-filer
+// and seaweedfs-volume-<n> for the n-th folder of the volume servers, and
+// seaweedfs-volume-index for their dir.idx.
+type NomadExporter struct{}
+
+const nodeAddress = "${attr.unique.network.ip-address}"
+
+type nomadGroup struct {
+	Name       string
+	Hosts      []string // addresses of the nodes the group runs on
+	Task       string
+	Args       []string
+	Ports      []nomadPort
+	Volumes    []nomadVolume
+	HealthPath string
+}
+
+// Constraint returns a regular expression matching the node addresses of the group.
+func (g *nomadGroup) Constraint() string {
+	var hosts []string
+	for _, host := range g.Hosts {
+		hosts = append(hosts, regexp.QuoteMeta(host))
+	}
+	if len(hosts) == 1 {
+		return "^" + hosts[0] + "$"
+	}
+	return "^(" + strings.Join(hosts, "|") + ")$"
+}
+
+type nomadPort struct {
+	Label string
+	Port  int
+}
+
+type nomadVolume struct {
+	Name        string
+	Source      string
+	Destination string
+}
+
+type nomadJob struct {
+	Name    string
+	Type    string
+	Image   string
+	Service string
+	Groups  []*nomadGroup
+	Cores   int
+	Memory  int
+}
+
+var nomadTemplate = template.Must(template.New("nomad").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+	"list": func(values []string) string {
+		var quoted []string
+		for _, v := range values {
+			quoted = append(quoted, strconv.Quote(v))
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	},
+}).Parse(`# generated by seaweed-up export nomad
+job {{quote .Name}} {
+  datacenters = ["*"]
+  type        = {{quote .Type}}
+{{range .Groups}}
+  group {{quote .Name}} {
+    constraint {
+      attribute = {{quote "${attr.unique.network.ip-address}"}}
+      operator  = "regexp"
+      value     = {{quote .Constraint}}
+    }
+
+    network {
+      mode = "host"
+{{- range .Ports}}
+      port {{quote .Label}} {
+        static = {{.Port}}
+      }
+{{- end}}
+    }
+{{range .Volumes}}
+    volume {{quote .Name}} {
+      type   = "host"
+      source = {{quote .Source}}
+    }
+{{end}}
+    task {{quote .Task}} {
+      driver = "docker"
+
+      config {
+        image        = {{quote $.Image}}
+        entrypoint   = ["/usr/bin/weed"]
+        args         = {{list .Args}}
+        network_mode = "host"
+      }
+{{range .Volumes}}
+      volume_mount {
+        volume      = {{quote .Name}}
+        destination = {{quote .Destination}}
+      }
+{{end}}
+      service {
+        name     = {{quote $.Service}}
+        port     = {{quote (index .Ports 0).Label}}
+        tags     = [{{quote .Name}}]
+        provider = "consul"
+
+        check {
+{{- if .HealthPath}}
+          type     = "http"
+          path     = {{quote .HealthPath}}
+{{- else}}
+          type     = "tcp"
+{{- end}}
+          interval = "10s"
+          timeout  = "5s"
+        }
+      }
+{{- if or $.Cores $.Memory}}
+
+      resources {
+{{- if $.Cores}}
+        cores  = {{$.Cores}}
+{{- end}}
+{{- if $.Memory}}
+        memory = {{$.Memory}}
+{{- end}}
+      }
+{{- end}}
+    }
+  }
+{{end -}}
+}
+`))
+
+func (e *NomadExporter) Name() string { return "nomad" }
+
+func (e *NomadExporter) Export(specification *spec.Specification, options Options) (map[string][]byte, error) {
+	var cores, memory int
+	var err error
+	if options.CPUs != "" {
+		if cores, err = strconv.Atoi(options.CPUs); err != nil {
+			return nil, fmt.Errorf("nomad reserves whole cores, invalid cpus %q", options.CPUs)
+		}
+	}
+	if options.Memory != "" {
+		if memory, err = memoryMB(options.Memory); err != nil {
+			return nil, err
+		}
+	}
+
+	// volume servers advertise the address of the node the system job runs on
+	address := func(component, name, ip string) string {
+		if component == "volume" {
+			return nodeAddress
+		}
+		return ip
+	}
+	jobs := make(map[string]*nomadJob)
+	var order []string
+	for _, i := range instances(specification, address) {
+		job, found := jobs[i.component]
+		if !found {
+			job = &nomadJob{
+				Name:    "seaweedfs-" + i.component,
+				Type:    "service",
+				Image:   image(options),
+				Service: "seaweedfs-" + i.component,
+				Cores:   cores,
+				Memory:  memory,
+			}
+			jobs[i.component] = job
+			order = append(order, i.component)
+		}
+		group := &nomadGroup{
+			Name:       i.name,
+			Hosts:      []string{i.ip},
+			Task:       i.component,
+			Args:       i.args,
+			HealthPath: healthPath(i.component),
+		}
+		for n, port := range i.ports {
+			group.Ports = append(group.Ports, nomadPort{Label: i.portNames[n], Port: port})
+		}
+		for n, v := range i.volumes {
+			source := "seaweedfs-" + i.component
+			if i.component == "volume" {
+				source = fmt.Sprintf("seaweedfs-volume-%d", n)
+				if v.path == indexPath {
+					source = "seaweedfs-volume-index"
+				}
+			}
+			group.Volumes = append(group.Volumes, nomadVolume{Name: fmt.Sprintf("data%d", n), Source: source, Destination: v.path})
+		}
+
+		if i.component != "volume" {
+			job.Groups = append(job.Groups, group)
+			continue
+		}
+		// all volume servers share one group of the system job
+		if len(job.Groups) == 0 {
+			job.Type = "system"
+			group.Name = "volume"
+			job.Groups = append(job.Groups, group)
+			continue
+		}
+		first := job.Groups[0]
+		if strings.Join(first.Args, " ") != strings.Join(group.Args, " ") {
+			return nil, fmt.Errorf("volume server %s: a nomad system job needs the same port, folders, data center and rack on all volume servers", i.ip)
+		}
+		first.Hosts = append(first.Hosts, i.ip)
+	}
+
+	files := make(map[string][]byte)
+	for _, component := range order {
+		var buf bytes.Buffer
+		if err := nomadTemplate.Execute(&buf, jobs[component]); err != nil {
+			return nil, err
+		}
+		files[jobs[component].Name+".nomad.hcl"] = buf.Bytes()
+	}
+	return files, nil
+}
+
+// memoryMB converts a size like 512M or 4G to megabytes.
+func memoryMB(size string) (int, error) {
+	s := strings.TrimSuffix(strings.ToUpper(size), "B")
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(s, "G"):
+		multiplier, s = 1024, strings.TrimSuffix(s, "G")
+	case strings.HasSuffix(s, "M"):
+		s = strings.TrimSuffix(s, "M")
+	}
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory %q, expected e.g. 512M or 4G", size)
+	}
+	return value * multiplier, nil
+}
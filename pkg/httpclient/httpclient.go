@@ -0,0 +1,63 @@
+// Package httpclient makes the clients of the http requests seaweed-up sends
+// itself, like to the GitHub API, plugin downloads and health checks, so
+// they all honor the proxy settings and trust the same CAs.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// CABundle is a PEM file of CAs trusted besides the ones of the system, like
+// the CA of a corporate proxy.
+var CABundle string
+
+var (
+	mu        sync.Mutex
+	transport *http.Transport
+	bundle    string // the CABundle of transport
+)
+
+// New returns a client sending the requests through the proxy of HTTPS_PROXY
+// or HTTP_PROXY, except to the hosts of NO_PROXY, trusting the CAs of
+// CABundle and giving up a request after timeout, never if 0.
+func New(timeout time.Duration) (*http.Client, error) {
+	t, err := sharedTransport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: t, Timeout: timeout}, nil
+}
+
+// sharedTransport returns the transport of all clients, so they reuse their
+// connections.
+func sharedTransport() (*http.Transport, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if transport != nil && bundle == CABundle {
+		return transport, nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	if CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		data, err := os.ReadFile(CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s has no PEM certificate", CABundle)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	transport, bundle = t, CABundle
+	return transport, nil
+}
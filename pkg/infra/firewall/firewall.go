@@ -0,0 +1,214 @@
+package firewall
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+// Rule allows tcp traffic to Port only from the given Sources.
+type Rule struct {
+	Port    int
+	Sources []string
+	Comment string
+}
+
+// Backend turns firewall rules into the commands of one firewall implementation.
+type Backend interface {
+	Name() string
+	// Commands adds the rules, and with prune removes the rules added before
+	// that are not in rules anymore. confDir keeps the rules added on the host
+	// for the firewalls whose rules have no comment.
+	Commands(rules []Rule, confDir string, prune bool) []string
+}
+
+var backends = map[string]Backend{
+	"firewalld": &firewalld{},
+	"ufw":       &ufw{},
+	"nftables":  &nftables{},
+}
+
+// backendBinaries lists the detection order and the binary each backend needs.
+var backendBinaries = []struct {
+	name   string
+	binary string
+}{
+	{"firewalld", "firewall-cmd"},
+	{"ufw", "ufw"},
+	{"nftables", "nft"},
+}
+
+// NewBackend returns the backend with the given name.
+func NewBackend(name string) (Backend, error) {
+	b, found := backends[name]
+	if !found {
+		return nil, fmt.Errorf("unknown firewall backend %q, supported: firewalld, ufw, nftables", name)
+	}
+	return b, nil
+}
+
+// Detect picks the first firewall backend installed on the host.
+func Detect(op operator.CommandOperator) (Backend, error) {
+	for _, b := range backendBinaries {
+		out, err := op.Output(fmt.Sprintf("command -v %s || true", b.binary))
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(string(out)) != "" {
+			return backends[b.name], nil
+		}
+	}
+	return nil, fmt.Errorf("no supported firewall found, install firewalld, ufw or nftables")
+}
+
+// firewalld rich rules have no comment, the rules added are listed in the
+// firewalld.rules file of the configuration directory.
+type firewalld struct{}
+
+func (f *firewalld) Name() string { return "firewalld" }
+
+func (f *firewalld) Commands(rules []Rule, confDir string, prune bool) []string {
+	file := path.Join(confDir, "firewalld.rules")
+	commands := []string{"mkdir -p " + confDir}
+	var richRules []string
+	for _, rule := range rules {
+		for _, source := range rule.Sources {
+			richRule := fmt.Sprintf(`'rule family="%s" source address="%s" port port="%d" protocol="tcp" accept'`, family(source), source, rule.Port)
+			richRules = append(richRules, richRule)
+			commands = append(commands, "firewall-cmd --permanent --add-rich-rule="+richRule)
+		}
+	}
+	if prune {
+		commands = append(commands,
+			fmt.Sprintf(`touch %s && while IFS= read -r rule; do case "$rule" in %s) ;; *) firewall-cmd --permanent --remove-rich-rule="$rule" ;; esac; done < %s`,
+				file, strings.Join(append([]string{"''"}, richRules...), "|"), file),
+			fmt.Sprintf("printf '%%s\\n' %s > %s", strings.Join(richRules, " "), file))
+	} else {
+		commands = append(commands,
+			fmt.Sprintf("printf '%%s\\n' %s >> %s", strings.Join(richRules, " "), file),
+			fmt.Sprintf("sort -u -o %s %s", file, file))
+	}
+	return append(commands, "firewall-cmd --reload")
+}
+
+// ufw keeps the comment of the rules, the rules added are those commented
+// "seaweed ...". An inactive ufw is not enabled, as it could lock out SSH.
+type ufw struct{}
+
+func (u *ufw) Name() string { return "ufw" }
+
+func (u *ufw) Commands(rules []Rule, confDir string, prune bool) []string {
+	commands := []string{"ufw status | grep -q '^Status: active' || { echo 'ufw is inactive, allow SSH and run ufw enable first' >&2; exit 1; }"}
+	keep := []string{"''"}
+	for _, rule := range rules {
+		for _, source := range rule.Sources {
+			allow := fmt.Sprintf("allow from %s to any port %d proto tcp", source, rule.Port)
+			keep = append(keep, "'"+allow+"'")
+			commands = append(commands, fmt.Sprintf("ufw %s comment '%s'", allow, rule.Comment))
+		}
+	}
+	if prune {
+		commands = append(commands, fmt.Sprintf(
+			`ufw show added | sed -n "s/^ufw \(allow .*\) comment 'seaweed .*'\$/\1/p" | while read -r rule; do case "$rule" in %s) ;; *) ufw --force delete $rule ;; esac; done`,
+			strings.Join(keep, "|")))
+	}
+	return commands
+}
+
+// nftables keeps all rules in a dedicated "inet seaweed" table, so applying
+// again replaces the previous rules and other tables are left untouched. The
+// table is saved to an include of the nftables service, loaded at boot.
+type nftables struct{}
+
+// nftablesInclude is the file the "inet seaweed" table is saved to.
+const nftablesInclude = "/etc/nftables.d/seaweed.nft"
+
+func (n *nftables) Name() string { return "nftables" }
+
+func (n *nftables) Commands(rules []Rule, confDir string, prune bool) []string {
+	commands := []string{
+		"nft add table inet seaweed",
+		"nft add chain inet seaweed input '{ type filter hook input priority 0 ; policy accept ; }'",
+		"nft flush chain inet seaweed input",
+		"nft add rule inet seaweed input iif lo accept",
+		"nft add rule inet seaweed input ct state established,related accept",
+	}
+	var ports []string
+	for _, rule := range rules {
+		port := strconv.Itoa(rule.Port)
+		ports = append(ports, port)
+		var v4, v6 []string
+		for _, source := range rule.Sources {
+			if family(source) == "ipv6" {
+				v6 = append(v6, source)
+			} else {
+				v4 = append(v4, source)
+			}
+		}
+		if len(v4) > 0 {
+			commands = append(commands, fmt.Sprintf("nft add rule inet seaweed input ip saddr '{ %s }' tcp dport %s accept", strings.Join(v4, ", "), port))
+		}
+		if len(v6) > 0 {
+			commands = append(commands, fmt.Sprintf("nft add rule inet seaweed input ip6 saddr '{ %s }' tcp dport %s accept", strings.Join(v6, ", "), port))
+		}
+	}
+	if len(ports) > 0 {
+		commands = append(commands, fmt.Sprintf("nft add rule inet seaweed input tcp dport '{ %s }' drop", strings.Join(ports, ", ")))
+	}
+	// the table is declared and flushed first, so loading the include again replaces it
+	return append(commands,
+		fmt.Sprintf("mkdir -p %s && { echo 'table inet seaweed'; echo 'flush table inet seaweed'; nft list table inet seaweed; } > %s", path.Dir(nftablesInclude), nftablesInclude),
+		fmt.Sprintf(`conf=/etc/nftables.conf; if [ -f /etc/sysconfig/nftables.conf ]; then conf=/etc/sysconfig/nftables.conf; fi; grep -qxF 'include "%[1]s"' $conf 2>/dev/null || echo 'include "%[1]s"' >> $conf`, nftablesInclude),
+		"systemctl enable nftables")
+}
+
+// MergeRules combines rules for the same port and removes duplicated sources.
+func MergeRules(rules []Rule) []Rule {
+	byPort := make(map[int]*Rule)
+	var order []int
+	for _, rule := range rules {
+		r, found := byPort[rule.Port]
+		if !found {
+			r = &Rule{Port: rule.Port, Comment: rule.Comment}
+			byPort[rule.Port] = r
+			order = append(order, rule.Port)
+		}
+		r.Sources = append(r.Sources, rule.Sources...)
+	}
+	sort.Ints(order)
+	var merged []Rule
+	for _, port := range order {
+		r := byPort[port]
+		r.Sources = dedup(r.Sources)
+		merged = append(merged, *r)
+	}
+	return merged
+}
+
+func dedup(values []string) (result []string) {
+	seen := make(map[string]struct{})
+	for _, v := range values {
+		if _, found := seen[v]; found {
+			continue
+		}
+		seen[v] = struct{}{}
+		result = append(result, v)
+	}
+	return
+}
+
+func family(source string) string {
+	ip := net.ParseIP(source)
+	if ip == nil {
+		ip, _, _ = net.ParseCIDR(source)
+	}
+	if ip != nil && ip.To4() == nil {
+		return "ipv6"
+	}
+	return "ipv4"
+}
@@ -0,0 +1,157 @@
+package inventory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseAnsible reads the hosts of an Ansible inventory in INI format. Groups
+// named after a component, like [masters] or [seaweed_volume_servers], give
+// their hosts that role, also through [group:children]. ansible_host,
+// ansible_user and ansible_port are read from the host lines, from
+// [group:vars] and from [all:vars]. With only, just the hosts of that group
+// are returned.
+func ParseAnsible(r io.Reader, only string) ([]*Host, error) {
+	var hosts []*Host
+	byName := make(map[string]*Host)
+	members := make(map[string][]string)  // group -> hosts
+	children := make(map[string][]string) // group -> child groups
+	vars := make(map[string]map[string]string)
+
+	group, section := "ungrouped", "hosts"
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group, section = strings.Trim(line, "[]"), "hosts"
+			if name, kind, found := strings.Cut(group, ":"); found {
+				group, section = name, kind
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		switch section {
+		case "hosts":
+			name := fields[0]
+			if strings.Contains(name, "[") {
+				return nil, fmt.Errorf("line %d: host ranges like %s are not supported", lineNumber, name)
+			}
+			h, found := byName[name]
+			if !found {
+				h = &Host{Name: name}
+				byName[name] = h
+				hosts = append(hosts, h)
+			}
+			if err := applyVars(h, parseVars(fields[1:]), false); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			members[group] = append(members[group], name)
+		case "children":
+			children[group] = append(children[group], fields[0])
+		case "vars":
+			if vars[group] == nil {
+				vars[group] = make(map[string]string)
+			}
+			for k, v := range parseVars([]string{strings.Join(fields, "")}) {
+				vars[group][k] = v
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// hostsOf lists the hosts of a group and its child groups
+	var hostsOf func(group string, seen map[string]bool) []string
+	hostsOf = func(group string, seen map[string]bool) []string {
+		if seen[group] {
+			return nil
+		}
+		seen[group] = true
+		names := append([]string{}, members[group]...)
+		for _, child := range children[group] {
+			names = append(names, hostsOf(child, seen)...)
+		}
+		return names
+	}
+
+	groups := make(map[string]bool)
+	for g := range members {
+		groups[g] = true
+	}
+	for g := range children {
+		groups[g] = true
+	}
+	for g := range groups {
+		for _, name := range hostsOf(g, map[string]bool{}) {
+			h := byName[name]
+			if role := Role(g); role != "" {
+				h.addRole(role)
+			}
+			if err := applyVars(h, vars[g], true); err != nil {
+				return nil, fmt.Errorf("[%s:vars]: %w", g, err)
+			}
+		}
+	}
+	for _, h := range hosts {
+		if err := applyVars(h, vars["all"], true); err != nil {
+			return nil, fmt.Errorf("[all:vars]: %w", err)
+		}
+		if h.Address == "" {
+			h.Address = h.Name
+		}
+	}
+
+	if only == "" {
+		return hosts, nil
+	}
+	selected := make(map[string]bool)
+	for _, name := range hostsOf(only, map[string]bool{}) {
+		selected[name] = true
+	}
+	var filtered []*Host
+	for _, h := range hosts {
+		if selected[h.Name] {
+			filtered = append(filtered, h)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("no hosts in group %s", only)
+	}
+	return filtered, nil
+}
+
+func parseVars(fields []string) map[string]string {
+	vars := make(map[string]string)
+	for _, field := range fields {
+		if k, v, found := strings.Cut(field, "="); found {
+			vars[k] = strings.Trim(v, `"'`)
+		}
+	}
+	return vars
+}
+
+// applyVars sets the connection variables of the host, keeping values already
+// set if onlyMissing, since host variables win over group variables.
+func applyVars(h *Host, vars map[string]string, onlyMissing bool) error {
+	if v, found := vars["ansible_host"]; found && (h.Address == "" || !onlyMissing) {
+		h.Address = v
+	}
+	if v, found := vars["ansible_user"]; found && (h.User == "" || !onlyMissing) {
+		h.User = v
+	}
+	if v, found := vars["ansible_port"]; found && (h.Port == 0 || !onlyMissing) {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("ansible_port %q of %s is not a number", v, h.Name)
+		}
+		h.Port = port
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package inventory
+
+import (
+	"strings"
+)
+
+// Host is a machine found in an inventory.
+type Host struct {
+	Name    string
+	Address string
+	User    string // ssh user, empty for the default
+	Port    int    // ssh port, 0 for the default
+	Roles   []string
+}
+
+// Role maps a group or tag value like "masters", "volume_servers" or
+// "seaweed_filer" to a component role, or returns "" if it is none.
+func Role(name string) string {
+	name = strings.ToLower(name)
+	for _, prefix := range []string{"seaweedfs_", "seaweedfs-", "seaweed_", "seaweed-"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	for _, suffix := range []string{"_servers", "-servers", "_server", "-server", "s"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	switch name {
+	case "master", "volume", "filer", "envoy":
+		return name
+	}
+	return ""
+}
+
+// AssignRoles gives hosts without any role a default layout: every host is a
+// volume server, the first three (or the first one, for less than three hosts)
+// are masters and the first is a filer. It does nothing if any host has a role.
+func AssignRoles(hosts []*Host) {
+	for _, h := range hosts {
+		if len(h.Roles) > 0 {
+			return
+		}
+	}
+	masters := 1
+	if len(hosts) >= 3 {
+		masters = 3
+	}
+	for i, h := range hosts {
+		if i < masters {
+			h.Roles = append(h.Roles, "master")
+		}
+		h.Roles = append(h.Roles, "volume")
+		if i == 0 {
+			h.Roles = append(h.Roles, "filer")
+		}
+	}
+}
+
+func (h *Host) addRole(role string) {
+	for _, r := range h.Roles {
+		if r == role {
+			return
+		}
+	}
+	h.Roles = append(h.Roles, role)
+}
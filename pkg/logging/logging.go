@@ -0,0 +1,186 @@
+// Package logging writes progress messages to the console and an optional
+// execution log file, as text or one json object per line.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
+)
+
+type Level int
+
+const (
+	LevelTrace Level = iota // output of remote commands
+	LevelDebug              // remote commands with duration and exit status
+	LevelInfo
+	LevelWarn
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelWarn:
+		return "WARN"
+	}
+	return "INFO"
+}
+
+var (
+	mu        sync.Mutex
+	threshold = LevelInfo
+	jsonLines bool
+	file      *os.File
+	capture   io.Writer // also gets the messages from captureAt, see Capture
+	captureAt Level
+)
+
+// SetVerbosity shows debug messages on the console from 1 and trace messages from 2.
+func SetVerbosity(verbosity int) {
+	mu.Lock()
+	defer mu.Unlock()
+	threshold = LevelInfo - Level(verbosity)
+	if threshold < LevelTrace {
+		threshold = LevelTrace
+	}
+}
+
+// SetFormat selects text or json lines.
+func SetFormat(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	switch name {
+	case "text":
+		jsonLines = false
+	case "json":
+		jsonLines = true
+	default:
+		return fmt.Errorf("unknown log format %q, use text or json", name)
+	}
+	return nil
+}
+
+// SetFile appends all messages of all levels to the file, whatever the verbosity.
+func SetFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open log file: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	file = f
+	return nil
+}
+
+// File returns the path of the log file, empty without one.
+func File() string {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return ""
+	}
+	return file.Name()
+}
+
+// Capture also writes the messages from level on to w, as in the log file,
+// until stop is called.
+func Capture(w io.Writer, level Level) (stop func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	capture, captureAt = w, level
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		capture = nil
+	}
+}
+
+// Close closes the log file.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// Trace logs the message with key value pairs of fields.
+func Trace(message string, fields ...interface{}) { write(LevelTrace, message, fields) }
+
+// Debug logs the message with key value pairs of fields.
+func Debug(message string, fields ...interface{}) { write(LevelDebug, message, fields) }
+
+// Info logs the message with key value pairs of fields.
+func Info(message string, fields ...interface{}) { write(LevelInfo, message, fields) }
+
+// Warn logs the message with key value pairs of fields.
+func Warn(message string, fields ...interface{}) { write(LevelWarn, message, fields) }
+
+func write(level Level, message string, fields []interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	if level >= threshold {
+		fmt.Fprintln(output.Log(), redact.String(format(now, level, message, fields, false)))
+	}
+	if file != nil {
+		fmt.Fprintln(file, redact.String(format(now, level, message, fields, true)))
+	}
+	if capture != nil && level >= captureAt {
+		fmt.Fprintln(capture, redact.String(format(now, level, message, fields, true)))
+	}
+}
+
+// format renders a message, with a time stamp for the log file.
+func format(now time.Time, level Level, message string, fields []interface{}, timestamp bool) string {
+	if jsonLines {
+		entry := map[string]interface{}{
+			"time":  now.Format(time.RFC3339Nano),
+			"level": strings.ToLower(level.String()),
+			"msg":   message,
+		}
+		for i := 0; i+1 < len(fields); i += 2 {
+			entry[fmt.Sprint(fields[i])] = value(fields[i+1])
+		}
+		line, _ := json.Marshal(entry)
+		return string(line)
+	}
+	var b strings.Builder
+	if timestamp {
+		b.WriteString(now.Format("2006-01-02T15:04:05.000Z07:00 "))
+	}
+	fmt.Fprintf(&b, "[%s] %s", level, message)
+	for i := 0; i+1 < len(fields); i += 2 {
+		v := fmt.Sprint(value(fields[i+1]))
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(&b, " %v=%s", fields[i], v)
+	}
+	return b.String()
+}
+
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Duration:
+		return v.String()
+	case error:
+		return v.Error()
+	}
+	return v
+}
@@ -0,0 +1,220 @@
+package operator
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/seaweedfs/seaweed-up/pkg/httpclient"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"golang.org/x/crypto/ssh"
+)
+
+// CertificateConfig has SSH logins use short-lived user certificates, signed
+// for each run of seaweed-up with a CA key or by the SSH secrets engine of
+// Vault.
+type CertificateConfig struct {
+	CAKey     string        // private key of the user CA signing the certificates
+	VaultSign string        // sign endpoint of the vault ssh secrets engine, like ssh-client-signer/sign/deployer
+	Validity  time.Duration // how long a certificate is valid
+}
+
+// Certificates configures the certificates of the SSH logins, none by default.
+var Certificates CertificateConfig
+
+func (c *CertificateConfig) enabled() bool {
+	return c.CAKey != "" || c.VaultSign != ""
+}
+
+type signedCertificate struct {
+	signer      ssh.Signer
+	validBefore time.Time
+}
+
+var (
+	certificatesMu sync.Mutex
+	certificates   = make(map[string]*signedCertificate)
+)
+
+// certificateSigner returns a signer with a certificate for the user. The
+// certificate of an ephemeral key is signed on first use and again when it
+// is about to expire.
+func certificateSigner(user string) (ssh.Signer, error) {
+	certificatesMu.Lock()
+	defer certificatesMu.Unlock()
+
+	validity := Certificates.Validity
+	if validity <= 0 {
+		validity = 5 * time.Minute
+	}
+	if c, found := certificates[user]; found && time.Until(c.validBefore) > validity/5 {
+		return c.signer, nil
+	}
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		return nil, err
+	}
+	var cert *ssh.Certificate
+	if Certificates.CAKey != "" {
+		cert, err = signWithCA(signer.PublicKey(), user, validity)
+	} else {
+		cert, err = signWithVault(signer.PublicKey(), user, validity)
+	}
+	if err != nil {
+		return nil, NewSshAgentError(err)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, NewSshAgentError(fmt.Errorf("the signed certificate does not match the key: %w", err))
+	}
+	validBefore := time.Unix(int64(cert.ValidBefore), 0)
+	certificates[user] = &signedCertificate{signer: certSigner, validBefore: validBefore}
+	logging.Debug("certificate", "user", user, "serial", cert.Serial, "valid_before", validBefore.Format(time.RFC3339))
+	return certSigner, nil
+}
+
+func signWithCA(key ssh.PublicKey, user string, validity time.Duration) (*ssh.Certificate, error) {
+	buffer, err := ioutil.ReadFile(expandPath(Certificates.CAKey))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read CA key: %s", Certificates.CAKey)
+	}
+	ca, err := ssh.ParsePrivateKey(buffer)
+	if _, protected := err.(*ssh.PassphraseMissingError); protected {
+		passphrase, promptErr := utils.PromptForPassword("Enter passphrase for '%s': ", Certificates.CAKey)
+		if promptErr != nil {
+			return nil, promptErr
+		}
+		ca, err = ssh.ParsePrivateKeyWithPassphrase(buffer, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse CA key: %s", Certificates.CAKey)
+	}
+
+	var serial [8]byte
+	if _, err := rand.Read(serial[:]); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	cert := &ssh.Certificate{
+		Key:             key,
+		Serial:          binary.BigEndian.Uint64(serial[:]),
+		CertType:        ssh.UserCert,
+		KeyId:           "seaweed-up:" + utils.CurrentUser(),
+		ValidPrincipals: []string{user},
+		ValidAfter:      uint64(now.Add(-time.Minute).Unix()), // allow for clock skew of the hosts
+		ValidBefore:     uint64(now.Add(validity).Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: map[string]string{"permit-pty": ""},
+		},
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		return nil, fmt.Errorf("sign certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// signWithVault has the key signed by Vault, found with the environment
+// variables of the vault cli: VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE.
+func signWithVault(key ssh.PublicKey, user string, validity time.Duration) (*ssh.Certificate, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		data, err := ioutil.ReadFile(filepath.Join(utils.UserHome(), ".vault-token"))
+		if err != nil {
+			return nil, fmt.Errorf("VAULT_TOKEN is not set and no vault login was found")
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"public_key":       string(ssh.MarshalAuthorizedKey(key)),
+		"valid_principals": user,
+		"cert_type":        "user",
+		"ttl":              validity.String(),
+	})
+	url := strings.TrimSuffix(address, "/") + "/v1/" + strings.Trim(Certificates.VaultSign, "/")
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		request.Header.Set("X-Vault-Namespace", namespace)
+	}
+	client, err := httpclient.New(30 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("sign certificate with vault: %w", err)
+	}
+	defer response.Body.Close()
+
+	var result struct {
+		Errors []string `json:"errors"`
+		Data   struct {
+			SignedKey string `json:"signed_key"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("sign certificate with vault: %s: %w", response.Status, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sign certificate with vault: %s: %s", response.Status, strings.Join(result.Errors, ", "))
+	}
+	signed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(result.Data.SignedKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate signed by vault: %w", err)
+	}
+	cert, ok := signed.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("vault did not return a certificate")
+	}
+	return cert, nil
+}
+
+// withCertificate returns the signer with the certificate of the identity
+// file next to it, as ssh does with <identity>-cert.pub, or signer if there
+// is none.
+func withCertificate(signer ssh.Signer, privateKey string) (ssh.Signer, error) {
+	data, err := ioutil.ReadFile(expandPath(privateKey) + "-cert.pub")
+	if os.IsNotExist(err) {
+		return signer, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse certificate: %s-cert.pub", privateKey)
+	}
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s-cert.pub is not a certificate", privateKey)
+	}
+	if validBefore := time.Unix(int64(cert.ValidBefore), 0); cert.ValidBefore != ssh.CertTimeInfinity && time.Now().After(validBefore) {
+		return nil, NewSshAgentError(fmt.Errorf("the certificate %s-cert.pub expired at %s", privateKey, validBefore.Format(time.RFC3339)))
+	}
+	return ssh.NewCertSigner(cert, signer)
+}
@@ -15,6 +15,10 @@ func (e *TargetConnectError) Error() string {
 	return fmt.Sprintf("%s", e.reason)
 }
 
+func (e *TargetConnectError) Unwrap() error {
+	return e.reason
+}
+
 type SshAgentError struct {
 	reason error
 }
@@ -0,0 +1,239 @@
+package operator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// KnownHosts is the file with the host keys seaweed-up trusts, in the format
+// of OpenSSH known_hosts.
+var KnownHosts = filepath.Join(utils.UserHome(), ".seaweed-up", "known_hosts")
+
+// StrictHostKeys refuses hosts without a key in KnownHosts instead of
+// trusting their key on first use.
+var StrictHostKeys bool
+
+// knownHostsMu serializes the writes of KnownHosts by parallel connections.
+var knownHostsMu sync.Mutex
+
+// HostKeyChangedError is a host answering with another key than the one in
+// KnownHosts.
+type HostKeyChangedError struct {
+	Host        string
+	Fingerprint string
+}
+
+func (e *HostKeyChangedError) Error() string {
+	return fmt.Sprintf("the host key of %s changed to %s, which may be an attack or a reinstalled host; "+
+		"if expected, trust the new key with: seaweed-up ssh trust %s", e.Host, e.Fingerprint, e.Host)
+}
+
+// hostKeyCallback checks host keys against KnownHosts, adding the keys of
+// unknown hosts unless StrictHostKeys.
+func hostKeyCallback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	if err := ensureKnownHosts(); err != nil {
+		return err
+	}
+	check, err := knownhosts.New(KnownHosts)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", KnownHosts, err)
+	}
+	err = check(hostname, remote, key)
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return err
+	}
+	fingerprint := ssh.FingerprintSHA256(key)
+	if len(keyErr.Want) > 0 {
+		return &HostKeyChangedError{Host: hostname, Fingerprint: fingerprint}
+	}
+	if StrictHostKeys {
+		return fmt.Errorf("%s is not a known host, its key is %s; trust it with: seaweed-up ssh trust %s", hostname, fingerprint, hostname)
+	}
+	if err := appendKnownHost(hostname, key); err != nil {
+		return err
+	}
+	logging.Warn(fmt.Sprintf("permanently added %s with key %s to %s", hostname, fingerprint, KnownHosts))
+	return nil
+}
+
+func ensureKnownHosts() error {
+	if err := os.MkdirAll(filepath.Dir(KnownHosts), 0700); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(KnownHosts), err)
+	}
+	f, err := os.OpenFile(KnownHosts, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", KnownHosts, err)
+	}
+	return f.Close()
+}
+
+func appendKnownHost(hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(KnownHosts, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", KnownHosts, err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)); err != nil {
+		return fmt.Errorf("write %s: %w", KnownHosts, err)
+	}
+	return nil
+}
+
+// KnownHost is a host key in KnownHosts.
+type KnownHost struct {
+	Host        string `json:"host" yaml:"host"`
+	Type        string `json:"type" yaml:"type"`
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+}
+
+// ListKnownHosts returns the host keys in KnownHosts, sorted by host.
+func ListKnownHosts() ([]*KnownHost, error) {
+	data, err := os.ReadFile(KnownHosts)
+	if os.IsNotExist(err) {
+		return []*KnownHost{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", KnownHosts, err)
+	}
+	hosts := []*KnownHost{}
+	for len(data) > 0 {
+		var names []string
+		var key ssh.PublicKey
+		_, names, key, _, data, err = ssh.ParseKnownHosts(data)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", KnownHosts, err)
+		}
+		for _, name := range names {
+			hosts = append(hosts, &KnownHost{Host: name, Type: key.Type(), Fingerprint: ssh.FingerprintSHA256(key)})
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts, nil
+}
+
+// ForgetHost removes the keys of the host, an address with an optional ssh
+// port, from KnownHosts. It returns whether there were any.
+func ForgetHost(address string) (bool, error) {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	return forgetHost(address)
+}
+
+func forgetHost(address string) (bool, error) {
+	data, err := os.ReadFile(KnownHosts)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read %s: %w", KnownHosts, err)
+	}
+	host := knownhosts.Normalize(withSshPort(address))
+	var kept bytes.Buffer
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if _, names, _, _, _, err := ssh.ParseKnownHosts([]byte(line)); err == nil && containsHost(names, host) {
+			found = true
+			continue
+		}
+		kept.WriteString(line + "\n")
+	}
+	if !found {
+		return false, nil
+	}
+	temp := KnownHosts + ".tmp"
+	if err := os.WriteFile(temp, kept.Bytes(), 0600); err != nil {
+		return false, fmt.Errorf("write %s: %w", KnownHosts, err)
+	}
+	return true, os.Rename(temp, KnownHosts)
+}
+
+// TrustHost connects to the host, an address with an optional ssh port, and
+// replaces its keys in KnownHosts with the key it answers with. Unless
+// fingerprint is empty, the key must have this SHA256 fingerprint.
+func TrustHost(address, fingerprint string) (*KnownHost, error) {
+	address = withSshPort(address)
+	key, err := fetchHostKey(address)
+	if err != nil {
+		return nil, NewTargetConnectError(err)
+	}
+	actual := ssh.FingerprintSHA256(key)
+	if fingerprint != "" && fingerprint != actual {
+		return nil, fmt.Errorf("the key of %s is %s, not %s", address, actual, fingerprint)
+	}
+
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	if err := ensureKnownHosts(); err != nil {
+		return nil, err
+	}
+	if _, err := forgetHost(address); err != nil {
+		return nil, err
+	}
+	if err := appendKnownHost(address, key); err != nil {
+		return nil, err
+	}
+	return &KnownHost{Host: knownhosts.Normalize(address), Type: key.Type(), Fingerprint: actual}, nil
+}
+
+// errHostKeyFetched ends the handshake once the host key is known.
+var errHostKeyFetched = errors.New("host key fetched")
+
+func fetchHostKey(address string) (ssh.PublicKey, error) {
+	var key ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: "seaweed-up",
+		HostKeyCallback: func(hostname string, remote net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errHostKeyFetched
+		},
+		Timeout: 10 * time.Second,
+	}
+	conn, err := ssh.Dial("tcp", address, config)
+	if conn != nil {
+		conn.Close()
+	}
+	if key == nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func withSshPort(address string) string {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(strings.Trim(address, "[]"), "22")
+	}
+	return address
+}
+
+func containsHost(names []string, host string) bool {
+	for _, name := range names {
+		if name == host {
+			return true
+		}
+	}
+	return false
+}
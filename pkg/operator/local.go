@@ -3,6 +3,7 @@ package operator
 import (
 	"io"
 	"os"
+	"os/exec"
 	"strconv"
 
 	goexecute "github.com/alexellis/go-execute/pkg/v1"
@@ -35,6 +36,21 @@ func (e LocalOperator) Execute(command string) error {
 	return nil
 }
 
+func (e LocalOperator) Stream(command string, stdout io.Writer) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (e LocalOperator) Interactive(command string) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 func (e LocalOperator) UploadFile(path string, remotePath string, mode string) error {
 	source, err := os.Open(expandPath(path))
 	if err != nil {
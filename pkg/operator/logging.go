@@ -0,0 +1,91 @@
+package operator
+
+import (
+	"io"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+)
+
+// loggingOperator records every command run through the wrapped operator
+// with its duration and exit status.
+type loggingOperator struct {
+	CommandOperator
+	host string
+}
+
+func withLogging(host string, op CommandOperator) CommandOperator {
+	return loggingOperator{CommandOperator: op, host: host}
+}
+
+func (o loggingOperator) Execute(command string) error {
+	start := time.Now()
+	err := o.CommandOperator.Execute(command)
+	o.logCommand(command, start, err)
+	return err
+}
+
+func (o loggingOperator) Output(command string) ([]byte, error) {
+	start := time.Now()
+	out, err := o.CommandOperator.Output(command)
+	o.logCommand(command, start, err)
+	logging.Trace("command output", "host", o.host, "output", truncate(string(out)))
+	return out, err
+}
+
+func (o loggingOperator) Interactive(command string) error {
+	start := time.Now()
+	err := o.CommandOperator.Interactive(command)
+	o.logCommand(command, start, err)
+	return err
+}
+
+func (o loggingOperator) Stream(command string, stdout io.Writer) error {
+	start := time.Now()
+	err := o.CommandOperator.Stream(command, stdout)
+	o.logCommand(command, start, err)
+	return err
+}
+
+func (o loggingOperator) Upload(src io.Reader, remotePath string, mode string) error {
+	start := time.Now()
+	err := o.CommandOperator.Upload(src, remotePath, mode)
+	logging.Debug("upload", "host", o.host, "target", remotePath, "mode", mode, "duration", time.Since(start), "error", errorText(err))
+	return err
+}
+
+func (o loggingOperator) UploadFile(path string, remotePath string, mode string) error {
+	start := time.Now()
+	err := o.CommandOperator.UploadFile(path, remotePath, mode)
+	logging.Debug("upload", "host", o.host, "source", path, "target", remotePath, "mode", mode, "duration", time.Since(start), "error", errorText(err))
+	return err
+}
+
+func (o loggingOperator) logCommand(command string, start time.Time, err error) {
+	exitStatus := 0
+	if exitErr, ok := err.(interface{ ExitStatus() int }); ok {
+		exitStatus = exitErr.ExitStatus()
+	} else if exitErr, ok := err.(interface{ ExitCode() int }); ok {
+		exitStatus = exitErr.ExitCode()
+	} else if err != nil {
+		exitStatus = -1
+	}
+	logging.Debug("command", "host", o.host, "command", command, "duration", time.Since(start).Round(time.Millisecond), "exit", exitStatus)
+}
+
+// maxLoggedOutput keeps large outputs, like archives of the support bundle, out of the log.
+const maxLoggedOutput = 4096
+
+func truncate(s string) string {
+	if len(s) <= maxLoggedOutput {
+		return s
+	}
+	return s[:maxLoggedOutput] + "...(truncated)"
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
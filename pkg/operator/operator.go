@@ -8,12 +8,14 @@ import (
 	"net"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 type CommandRes struct {
@@ -24,6 +26,10 @@ type CommandRes struct {
 type CommandOperator interface {
 	Execute(command string) error
 	Output(command string) ([]byte, error)
+	// Interactive runs the command attached to the terminal of the user.
+	Interactive(command string) error
+	// Stream runs the command, copying its output to stdout while it runs.
+	Stream(command string, stdout io.Writer) error
 	Upload(src io.Reader, remotePath string, mode string) error
 	UploadFile(path string, remotePath string, mode string) error
 }
@@ -31,13 +37,34 @@ type CommandOperator interface {
 type Callback func(CommandOperator) error
 
 func ExecuteLocal(callback Callback) error {
-	return callback(NewLocalOperator())
+	return callback(withLogging("localhost", NewLocalOperator()))
+}
+
+// RemoteOptions are the settings to login to a host.
+type RemoteOptions struct {
+	User           string
+	PrivateKey     string // path of the identity file, empty to use the ssh agent
+	Password       string // login with this password instead of a key
+	ProxyJump      string // [user@]host[:port] of a bastion to connect through
+	ConnectTimeout time.Duration
 }
 
 func ExecuteRemote(host string, user string, privateKey string, password string, callback Callback) error {
+	return ExecuteRemoteWith(host, RemoteOptions{User: user, PrivateKey: privateKey, Password: password}, callback)
+}
+
+// ExecuteRemoteWith logs in to the host with the options and runs the callback on it.
+func ExecuteRemoteWith(host string, options RemoteOptions, callback Callback) error {
+	user, privateKey, password := options.User, options.PrivateKey, options.Password
 	var method ssh.AuthMethod
 
-	if password != "" {
+	if Certificates.enabled() {
+		signer, err := certificateSigner(user)
+		if err != nil {
+			return err
+		}
+		method = ssh.PublicKeys(signer)
+	} else if password != "" {
 		method = ssh.Password(password)
 	} else if privateKey == "" {
 		sshAgentConn, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
@@ -75,23 +102,33 @@ func ExecuteRemote(host string, user string, privateKey string, password string,
 			if sshAgent != nil {
 				method = sshAgent
 			} else {
-				fmt.Printf("Enter passphrase for '%s': ", privateKey)
-				STDIN := int(os.Stdin.Fd())
-				bytePassword, _ := terminal.ReadPassword(STDIN)
-				fmt.Println()
+				passphrase, err := utils.PromptForPassword("Enter passphrase for '%s': ", privateKey)
+				if errors.Is(err, utils.ErrNonInteractive) {
+					// missing input rather than an agent problem, see exitcode.Of
+					return fmt.Errorf("%w, load the key into the ssh agent", err)
+				}
+				if err != nil {
+					return NewSshAgentError(err)
+				}
 
-				key, err = ssh.ParsePrivateKeyWithPassphrase(buffer, bytePassword)
+				key, err = ssh.ParsePrivateKeyWithPassphrase(buffer, []byte(passphrase))
 				if err != nil {
 					return errors.Wrapf(err, "parse private key with passphrase failed: %s", privateKey)
 				}
+				if key, err = withCertificate(key, privateKey); err != nil {
+					return err
+				}
 				method = ssh.PublicKeys(key)
 			}
 		} else {
+			if key, err = withCertificate(key, privateKey); err != nil {
+				return err
+			}
 			method = ssh.PublicKeys(key)
 		}
 	}
 
-	return executeRemote(host, user, method, callback)
+	return executeRemote(host, options, method, callback)
 }
 
 func privateKeyUsingSSHAgent(publicKeyPath string) (ssh.AuthMethod, func() error) {
@@ -123,26 +160,31 @@ func privateKeyUsingSSHAgent(publicKeyPath string) (ssh.AuthMethod, func() error
 	return nil, func() error { return nil }
 }
 
-func executeRemote(address string, user string, authMethod ssh.AuthMethod, callback Callback) error {
-
-	host, port, err := net.SplitHostPort(address)
-	if err != nil {
-		if strings.Contains(err.Error(), "missing port") {
-			host = address
-			port = "22"
-		} else {
-			return fmt.Errorf("error splitting host/port: %w", err)
-		}
-	}
+func executeRemote(address string, options RemoteOptions, authMethod ssh.AuthMethod, callback Callback) error {
 
 	config := &ssh.ClientConfig{
-		User: user,
+		User: options.User,
 		Auth: []ssh.AuthMethod{
 			authMethod,
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         options.ConnectTimeout,
+	}
+	start := time.Now()
+	var operator *SSHOperator
+	var err error
+	if options.ProxyJump == "" {
+		operator, err = NewSSHOperator(withSshPort(address), config)
+	} else {
+		jumpUser, jumpAddress := options.User, options.ProxyJump
+		if at := strings.LastIndex(jumpAddress, "@"); at >= 0 {
+			jumpUser, jumpAddress = jumpAddress[:at], jumpAddress[at+1:]
+		}
+		jumpConfig := *config
+		jumpConfig.User = jumpUser
+		operator, err = NewSSHOperatorVia(withSshPort(jumpAddress), &jumpConfig, withSshPort(address), config)
 	}
-	operator, err := NewSSHOperator(net.JoinHostPort(host, port), config)
+	logging.Debug("connect", "host", address, "user", options.User, "proxy_jump", options.ProxyJump, "duration", time.Since(start).Round(time.Millisecond), "error", errorText(err))
 
 	if err != nil {
 		return NewTargetConnectError(err)
@@ -150,7 +192,7 @@ func executeRemote(address string, user string, authMethod ssh.AuthMethod, callb
 
 	defer operator.Close()
 
-	return callback(operator)
+	return callback(withLogging(address, operator))
 }
 
 func expandPath(path string) string {
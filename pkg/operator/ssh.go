@@ -2,15 +2,19 @@ package operator
 
 import (
 	"context"
+	"fmt"
 	"github.com/bramvdbogaerde/go-scp"
 	"io"
 	"os"
 
+	"github.com/seaweedfs/seaweed-up/pkg/output"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
 )
 
 type SSHOperator struct {
 	conn *ssh.Client
+	jump *ssh.Client // the bastion conn is tunneled through, if any
 }
 
 func NewSSHOperator(address string, config *ssh.ClientConfig) (*SSHOperator, error) {
@@ -26,11 +30,42 @@ func NewSSHOperator(address string, config *ssh.ClientConfig) (*SSHOperator, err
 	return &operator, nil
 }
 
+// NewSSHOperatorVia connects to address through an SSH tunnel of the
+// bastion at jumpAddress, like ssh -J.
+func NewSSHOperatorVia(jumpAddress string, jumpConfig *ssh.ClientConfig, address string, config *ssh.ClientConfig) (*SSHOperator, error) {
+	jump, err := ssh.Dial("tcp", jumpAddress, jumpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("connect to proxy jump %s: %w", jumpAddress, err)
+	}
+	tunnel, err := jump.Dial("tcp", address)
+	if err != nil {
+		jump.Close()
+		return nil, fmt.Errorf("tunnel to %s through %s: %w", address, jumpAddress, err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(tunnel, address, config)
+	if err != nil {
+		tunnel.Close()
+		jump.Close()
+		return nil, err
+	}
+
+	operator := SSHOperator{
+		conn: ssh.NewClient(c, chans, reqs),
+		jump: jump,
+	}
+
+	return &operator, nil
+}
+
 func (s SSHOperator) Close() error {
-	return s.conn.Close()
+	err := s.conn.Close()
+	if s.jump != nil {
+		s.jump.Close()
+	}
+	return err
 }
 
-func (s SSHOperator) Output(command string) (output []byte, err error) {
+func (s SSHOperator) Output(command string) (out []byte, err error) {
 	sess, err := s.conn.NewSession()
 	if err != nil {
 		return nil, err
@@ -38,10 +73,10 @@ func (s SSHOperator) Output(command string) (output []byte, err error) {
 
 	defer sess.Close()
 
-	sess.Stderr = os.Stderr
-	output, err = sess.Output(command)
+	sess.Stderr = output.Errors()
+	out, err = sess.Output(command)
 
-	return output, err
+	return out, err
 }
 
 func (s SSHOperator) Execute(command string) error {
@@ -52,13 +87,57 @@ func (s SSHOperator) Execute(command string) error {
 
 	defer sess.Close()
 
-	sess.Stdout = os.Stdout
-	sess.Stderr = os.Stderr
+	sess.Stdout = output.Log()
+	sess.Stderr = output.Errors()
 	err = sess.Run(command)
 
 	return err
 }
 
+func (s SSHOperator) Stream(command string, stdout io.Writer) error {
+	sess, err := s.conn.NewSession()
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	sess.Stdout = stdout
+	sess.Stderr = os.Stderr
+	return sess.Run(command)
+}
+
+func (s SSHOperator) Interactive(command string) error {
+	sess, err := s.conn.NewSession()
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	sess.Stdin = os.Stdin
+	sess.Stdout = os.Stdout
+	sess.Stderr = os.Stderr
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		if err := sess.RequestPty(os.Getenv("TERM"), height, width, ssh.TerminalModes{ssh.ECHO: 1}); err != nil {
+			return err
+		}
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+	}
+
+	return sess.Run(command)
+}
+
 func (s SSHOperator) Upload(source io.Reader, remotePath string, mode string) error {
 	client, _ := scp.NewClientBySSH(s.conn)
 	return client.CopyFile(context.Background(), source, remotePath, mode)
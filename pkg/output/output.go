@@ -0,0 +1,110 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/seaweedfs/seaweed-up/pkg/redact"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is how commands print their results.
+type Format string
+
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+)
+
+var (
+	current = Table
+	console io.Writer // replaces stdout and stderr for messages, see Redirect
+)
+
+// SetFormat selects the format of the results of all commands.
+func SetFormat(name string) error {
+	switch f := Format(name); f {
+	case Table, JSON, YAML:
+		current = f
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, use table, json or yaml", name)
+}
+
+// Current returns the selected format.
+func Current() Format {
+	return current
+}
+
+// Structured tells if results are printed as json or yaml for other programs.
+func Structured() bool {
+	return current != Table
+}
+
+// Log is where progress messages are written: stdout for tables, and stderr
+// for json and yaml so they do not mix with the results.
+func Log() io.Writer {
+	if console != nil {
+		return console
+	}
+	if current == Table {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// Errors is where error output of remote commands is written.
+func Errors() io.Writer {
+	if console != nil {
+		return console
+	}
+	return os.Stderr
+}
+
+// Redirect sends the progress messages and the output of remote commands to w,
+// e.g. a live progress display, until restore is called.
+func Redirect(w io.Writer) (restore func()) {
+	previous := console
+	console = w
+	return func() {
+		console = previous
+	}
+}
+
+// Print writes the result v as json or yaml, or calls table to print it for
+// humans, with the secrets redacted.
+func Print(v interface{}, table func(w io.Writer)) error {
+	var buf bytes.Buffer
+	switch current {
+	case JSON:
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+	case YAML:
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+	default:
+		table(&buf)
+	}
+	_, err := os.Stdout.Write(redact.Bytes(buf.Bytes()))
+	return err
+}
+
+// NewTable returns a writer aligning tab separated columns, flushed by the caller.
+func NewTable(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
@@ -0,0 +1,206 @@
+package plugins
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/httpclient"
+	"golang.org/x/net/context/ctxhttp"
+	"gopkg.in/yaml.v3"
+)
+
+// Plugin is an installed plugin executable.
+type Plugin struct {
+	Name        string    `yaml:"name" json:"name"`
+	Version     string    `yaml:"version,omitempty" json:"version,omitempty"`
+	Description string    `yaml:"description,omitempty" json:"description,omitempty"`
+	Exporters   []string  `yaml:"exporters,omitempty" json:"exporters,omitempty"` // export formats it provides
+	Commands    bool      `yaml:"commands,omitempty" json:"commands,omitempty"`   // runs with seaweed-up plugin run
+	Source      string    `yaml:"source" json:"source"`
+	Sha256      string    `yaml:"sha256" json:"sha256"`
+	Installed   time.Time `yaml:"installed" json:"installed"`
+}
+
+// Registry is the list of installed plugins, kept with their executables in
+// the plugins directory of the state dir.
+type Registry struct {
+	dir     string
+	Plugins []*Plugin `yaml:"plugins"`
+}
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Open reads the registry of the state dir. It is empty if no plugin was
+// ever installed.
+func Open(stateDir string) (*Registry, error) {
+	r := &Registry{dir: filepath.Join(stateDir, "plugins")}
+	data, err := os.ReadFile(r.file())
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", r.file(), err)
+	}
+	return r, nil
+}
+
+func (r *Registry) file() string { return filepath.Join(r.dir, "registry.yaml") }
+
+// Path is the installed executable of the plugin.
+func (r *Registry) Path(p *Plugin) string { return filepath.Join(r.dir, p.Name) }
+
+// Get returns the installed plugin with the name.
+func (r *Registry) Get(name string) (*Plugin, error) {
+	for _, p := range r.Plugins {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("plugin %s is not installed", name)
+}
+
+// Install copies the executable at source, a local path or an http(s) URL,
+// into the plugins directory and records what it describes about itself. If
+// sha256 is set, the executable must have this checksum. A plugin of the same
+// name is replaced.
+func (r *Registry) Install(ctx context.Context, source, sha256sum string) (*Plugin, error) {
+	data, err := read(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if sha256sum != "" && !strings.EqualFold(sha256sum, checksum) {
+		return nil, fmt.Errorf("sha256 of %s is %s, not %s", source, checksum, sha256sum)
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// describe the plugin from a temporary copy, to learn its name
+	tmp, err := os.CreateTemp(r.dir, ".install-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return nil, err
+	}
+	p, err := describe(ctx, tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a seaweed-up plugin: %w", source, err)
+	}
+	if !validName.MatchString(p.Name) {
+		return nil, fmt.Errorf("plugin name %q must be lower case letters, digits, - and _", p.Name)
+	}
+	if !strings.Contains(source, "://") {
+		if abs, err := filepath.Abs(source); err == nil {
+			source = abs
+		}
+	}
+	p.Source, p.Sha256, p.Installed = source, checksum, time.Now().UTC()
+	if err := os.Rename(tmp.Name(), r.Path(p)); err != nil {
+		return nil, err
+	}
+
+	var plugins []*Plugin
+	for _, existing := range r.Plugins {
+		if existing.Name != p.Name {
+			plugins = append(plugins, existing)
+		}
+	}
+	r.Plugins = append(plugins, p)
+	sort.Slice(r.Plugins, func(i, j int) bool { return r.Plugins[i].Name < r.Plugins[j].Name })
+	return p, r.save()
+}
+
+// Remove deletes the plugin and its executable.
+func (r *Registry) Remove(name string) error {
+	p, err := r.Get(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(r.Path(p)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var plugins []*Plugin
+	for _, existing := range r.Plugins {
+		if existing != p {
+			plugins = append(plugins, existing)
+		}
+	}
+	r.Plugins = plugins
+	return r.save()
+}
+
+// Verify checks that the executable of the plugin was not changed since it
+// was installed.
+func (r *Registry) Verify(p *Plugin) error {
+	data, err := os.ReadFile(r.Path(p))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != p.Sha256 {
+		return fmt.Errorf("%s was modified after it was installed, install plugin %s again", r.Path(p), p.Name)
+	}
+	return nil
+}
+
+func (r *Registry) save() error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	tmp := r.file() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.file())
+}
+
+// downloadTimeout bounds the download of a plugin executable.
+const downloadTimeout = 5 * time.Minute
+
+func read(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	client, err := httpclient.New(downloadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ctxhttp.Do(ctx, client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: unexpected status %v", source, res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
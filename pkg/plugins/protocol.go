@@ -0,0 +1,145 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/cluster/spec"
+	"github.com/seaweedfs/seaweed-up/pkg/exporters"
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// ProtocolVersion is the version of the plugin protocol. A plugin is an
+// executable answering these subcommands:
+//
+//	describe           print a YAML or JSON document with name, version,
+//	                   description, protocol, exporters (the export formats
+//	                   it provides) and commands (true to be run with
+//	                   seaweed-up plugin run)
+//	export <format>    read an ExportRequest from stdin, print a YAML or
+//	                   JSON document with the generated files under "files",
+//	                   like exporters.Exporter
+//	run [args...]      run a command with the terminal of seaweed-up
+//
+// A non-zero exit status is a failure, with the message on stderr.
+const ProtocolVersion = 1
+
+const describeTimeout = 10 * time.Second
+
+// ExportRequest is sent to an exporter plugin.
+type ExportRequest struct {
+	Protocol      int                 `yaml:"protocol"`
+	Format        string              `yaml:"format"`
+	Options       exporters.Options   `yaml:"options"`
+	Specification *spec.Specification `yaml:"specification"`
+}
+
+type exportResponse struct {
+	Files map[string]string `yaml:"files"`
+}
+
+func describe(ctx context.Context, executable string) (*Plugin, error) {
+	ctx, cancel := context.WithTimeout(ctx, describeTimeout)
+	defer cancel()
+	out, err := call(ctx, executable, nil, "describe")
+	if err != nil {
+		return nil, err
+	}
+	var description struct {
+		Plugin   `yaml:",inline"`
+		Protocol int `yaml:"protocol"`
+	}
+	if err := yaml.Unmarshal(out, &description); err != nil {
+		return nil, fmt.Errorf("parse describe output: %w", err)
+	}
+	if description.Protocol > ProtocolVersion {
+		return nil, fmt.Errorf("it needs plugin protocol %d, this seaweed-up supports %d", description.Protocol, ProtocolVersion)
+	}
+	p := description.Plugin
+	return &p, nil
+}
+
+// call runs the executable with stdin, returning its stdout, or its stderr as
+// the error.
+func call(ctx context.Context, executable string, stdin []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, executable, args...)
+	c.Stdin, c.Stdout, c.Stderr = bytes.NewReader(stdin), &stdout, &stderr
+	logging.Debug("run plugin", "command", strings.Join(append([]string{executable}, args...), " "))
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s %s: %s", executable, args[0], msg)
+		}
+		return nil, fmt.Errorf("%s %s: %w", executable, args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Exporter runs the export of a plugin.
+type Exporter struct {
+	format   string
+	registry *Registry
+	plugin   *Plugin
+}
+
+func (e *Exporter) Name() string { return e.format }
+
+func (e *Exporter) Export(specification *spec.Specification, options exporters.Options) (map[string][]byte, error) {
+	if err := e.registry.Verify(e.plugin); err != nil {
+		return nil, err
+	}
+	request, err := yaml.Marshal(&ExportRequest{Protocol: ProtocolVersion, Format: e.format, Options: options, Specification: specification})
+	if err != nil {
+		return nil, err
+	}
+	out, err := call(context.Background(), e.registry.Path(e.plugin), request, "export", e.format)
+	if err != nil {
+		return nil, err
+	}
+	var response exportResponse
+	if err := yaml.Unmarshal(out, &response); err != nil {
+		return nil, fmt.Errorf("parse export output of plugin %s: %w", e.plugin.Name, err)
+	}
+	files := make(map[string][]byte)
+	for name, content := range response.Files {
+		files[name] = []byte(content)
+	}
+	return files, nil
+}
+
+// RegisterExporters makes the export formats of the installed plugins
+// available to exporters.Get. Built-in formats are not replaced.
+func (r *Registry) RegisterExporters() {
+	for _, p := range r.Plugins {
+		for _, format := range p.Exporters {
+			if existing, err := exporters.Get(format); err == nil {
+				if _, fromPlugin := existing.(*Exporter); !fromPlugin {
+					logging.Warn(fmt.Sprintf("plugin %s: export format %s is built in, ignoring it", p.Name, format))
+					continue
+				}
+			}
+			exporters.Register(&Exporter{format: format, registry: r, plugin: p})
+		}
+	}
+}
+
+// Run runs a command of the plugin with the terminal of seaweed-up. The
+// plugin finds the state dir in SEAWEED_UP_STATE_DIR.
+func (r *Registry) Run(p *Plugin, stateDir string, args []string) error {
+	if !p.Commands {
+		return fmt.Errorf("plugin %s has no commands", p.Name)
+	}
+	if err := r.Verify(p); err != nil {
+		return err
+	}
+	c := exec.Command(r.Path(p), append([]string{"run"}, args...)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.Env = append(os.Environ(), "SEAWEED_UP_STATE_DIR="+stateDir, fmt.Sprintf("SEAWEED_UP_PLUGIN_PROTOCOL=%d", ProtocolVersion))
+	return c.Run()
+}
@@ -0,0 +1,297 @@
+package preflight
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/seaweedfs/seaweed-up/pkg/operator"
+)
+
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Result is the outcome of one check on one host.
+type Result struct {
+	Check      string `json:"check" yaml:"check"`
+	Status     Status `json:"status" yaml:"status"`
+	Message    string `json:"message" yaml:"message"`
+	Suggestion string `json:"suggestion,omitempty" yaml:"suggestion,omitempty"`
+}
+
+// Options describes what the host is expected to provide.
+type Options struct {
+	Ports         []int  // ports the component instances will listen on
+	DataDir       string // directory holding the component data
+	MinDiskFreeMB int
+	MinMemoryMB   int
+	MinOpenFiles  int
+}
+
+type check func(op operator.CommandOperator, options Options) Result
+
+var checks = []check{
+	checkOS,
+	checkUtilities,
+	checkLsblk,
+	checkPorts,
+	checkDiskSpace,
+	checkMemory,
+	checkOpenFiles,
+	checkTimeSync,
+	checkSecurityModules,
+}
+
+// Run executes all checks on the host behind the operator.
+func Run(op operator.CommandOperator, options Options) (results []Result) {
+	for _, c := range checks {
+		results = append(results, c(op, options))
+	}
+	return
+}
+
+// HasFailure reports whether any of the results failed.
+func HasFailure(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+func output(op operator.CommandOperator, command string) (string, error) {
+	out, err := op.Output(command)
+	return strings.TrimSpace(string(out)), err
+}
+
+func checkOS(op operator.CommandOperator, options Options) Result {
+	r := Result{Check: "os"}
+	kernel, err := output(op, "uname -sr")
+	if err != nil {
+		r.Status, r.Message = Fail, fmt.Sprintf("uname: %v", err)
+		return r
+	}
+	release, _ := output(op, `. /etc/os-release 2>/dev/null && echo "$PRETTY_NAME"`)
+	r.Message = strings.TrimSpace(release + " " + kernel)
+	fields := strings.Fields(kernel)
+	if len(fields) < 2 || fields[0] != "Linux" {
+		r.Status = Fail
+		r.Suggestion = "SeaweedFS components are deployed with systemd, use a Linux host"
+		return r
+	}
+	if major, minor := kernelVersion(fields[1]); major < 3 || (major == 3 && minor < 10) {
+		r.Status = Warn
+		r.Suggestion = "kernel older than 3.10 is not tested, consider upgrading the OS"
+		return r
+	}
+	r.Status = Pass
+	return r
+}
+
+func kernelVersion(release string) (major, minor int) {
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) >= 1 {
+		major, _ = strconv.Atoi(parts[0])
+	}
+	if len(parts) >= 2 {
+		minor, _ = strconv.Atoi(strings.TrimFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	}
+	return
+}
+
+func checkUtilities(op operator.CommandOperator, options Options) Result {
+	r := Result{Check: "utilities", Status: Pass}
+	var missing, optional []string
+	for _, util := range []string{"systemctl", "sha256sum", "md5sum"} {
+		if out, _ := output(op, "command -v "+util+" || true"); out == "" {
+			missing = append(missing, util)
+		}
+	}
+	for _, util := range []string{"curl", "tar", "ss"} {
+		if out, _ := output(op, "command -v "+util+" || true"); out == "" {
+			optional = append(optional, util)
+		}
+	}
+	switch {
+	case len(missing) > 0:
+		r.Status = Fail
+		r.Message = "missing " + strings.Join(append(missing, optional...), ", ")
+		r.Suggestion = "install systemd and coreutils on the host"
+	case len(optional) > 0:
+		r.Status = Warn
+		r.Message = "missing " + strings.Join(optional, ", ")
+		r.Suggestion = "curl and tar are installed with apt-get or yum during deploy, install them beforehand on other systems"
+	default:
+		r.Message = "systemctl, sha256sum, md5sum, curl, tar, ss found"
+	}
+	return r
+}
+
+func checkLsblk(op operator.CommandOperator, options Options) Result {
+	r := Result{Check: "lsblk"}
+	if _, err := op.Output("lsblk -b -P -o KNAME,PATH,SIZE,LABEL,UUID,FSTYPE,TYPE,MOUNTPOINT,MAJ:MIN,FSUSED >/dev/null 2>&1"); err != nil {
+		r.Status = Warn
+		r.Message = "lsblk does not support the PATH or FSUSED columns"
+		r.Suggestion = "upgrade util-linux to 2.33 or later, or deploy with --mountDisks=false"
+		return r
+	}
+	r.Status, r.Message = Pass, "all needed columns supported"
+	return r
+}
+
+func checkPorts(op operator.CommandOperator, options Options) Result {
+	r := Result{Check: "ports"}
+	out, err := op.Output("ss -ltnH 2>/dev/null || netstat -ltn 2>/dev/null")
+	if err != nil {
+		r.Status, r.Message = Warn, "can not list listening ports"
+		r.Suggestion = "install iproute2 (ss) or net-tools (netstat)"
+		return r
+	}
+	listening := listeningPorts(out)
+	var used []string
+	for _, port := range options.Ports {
+		if _, found := listening[port]; found {
+			used = append(used, strconv.Itoa(port))
+		}
+	}
+	if len(used) == 0 {
+		r.Status, r.Message = Pass, "no conflicts"
+		return r
+	}
+	r.Message = "already in use: " + strings.Join(used, ", ")
+	if weed, _ := output(op, "pgrep -x weed || true"); weed != "" {
+		r.Status = Warn
+		r.Suggestion = "a weed process is running, this is expected when redeploying an existing cluster"
+		return r
+	}
+	r.Status = Fail
+	r.Suggestion = "stop the processes using these ports or change the ports in the configuration file"
+	return r
+}
+
+// listeningPorts extracts the local ports from ss or netstat output.
+func listeningPorts(out []byte) map[int]struct{} {
+	ports := make(map[int]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			_, portString, err := net.SplitHostPort(field)
+			if err != nil {
+				continue
+			}
+			if port, err := strconv.Atoi(portString); err == nil {
+				ports[port] = struct{}{}
+			}
+			break
+		}
+	}
+	return ports
+}
+
+func checkDiskSpace(op operator.CommandOperator, options Options) Result {
+	r := Result{Check: "disk space"}
+	dir := options.DataDir
+	out, err := output(op, fmt.Sprintf(`d=%s; while [ ! -d "$d" ]; do d=$(dirname "$d"); done; df -Pm "$d" | tail -1`, dir))
+	fields := strings.Fields(out)
+	if err != nil || len(fields) < 4 {
+		r.Status, r.Message = Warn, "can not read free space of "+dir
+		return r
+	}
+	free, _ := strconv.Atoi(fields[3])
+	r.Message = fmt.Sprintf("%d MB free for %s", free, dir)
+	switch {
+	case free < options.MinDiskFreeMB/10:
+		r.Status = Fail
+		r.Suggestion = "free up space or point dir.data to a larger disk"
+	case free < options.MinDiskFreeMB:
+		r.Status = Warn
+		r.Suggestion = fmt.Sprintf("at least %d MB free space is recommended", options.MinDiskFreeMB)
+	default:
+		r.Status = Pass
+	}
+	return r
+}
+
+func checkMemory(op operator.CommandOperator, options Options) Result {
+	r := Result{Check: "memory"}
+	out, err := output(op, "awk '/^MemTotal:/ {print $2}' /proc/meminfo")
+	totalKB, convErr := strconv.Atoi(out)
+	if err != nil || convErr != nil {
+		r.Status, r.Message = Warn, "can not read /proc/meminfo"
+		return r
+	}
+	totalMB := totalKB / 1024
+	r.Message = fmt.Sprintf("%d MB total", totalMB)
+	if totalMB < options.MinMemoryMB {
+		r.Status = Warn
+		r.Suggestion = fmt.Sprintf("at least %d MB memory is recommended", options.MinMemoryMB)
+		return r
+	}
+	r.Status = Pass
+	return r
+}
+
+func checkOpenFiles(op operator.CommandOperator, options Options) Result {
+	r := Result{Check: "ulimit"}
+	out, err := output(op, "ulimit -n")
+	if err != nil {
+		r.Status, r.Message = Warn, "can not read ulimit -n"
+		return r
+	}
+	r.Message = "open files " + out
+	if limit, convErr := strconv.Atoi(out); convErr == nil && limit < options.MinOpenFiles {
+		r.Status = Warn
+		r.Suggestion = "the systemd units raise LimitNOFILE, raise nofile in /etc/security/limits.conf for manual runs"
+		return r
+	}
+	r.Status = Pass
+	return r
+}
+
+func checkTimeSync(op operator.CommandOperator, options Options) Result {
+	r := Result{Check: "time sync"}
+	out, _ := output(op, "timedatectl show -p NTPSynchronized --value 2>/dev/null || true")
+	switch out {
+	case "yes":
+		r.Status, r.Message = Pass, "clock synchronized"
+	case "":
+		r.Status, r.Message = Warn, "can not determine clock synchronization"
+		r.Suggestion = "make sure chrony or another NTP client is running, or set global.time_sync to install chrony"
+	default:
+		r.Status, r.Message = Warn, "clock not synchronized"
+		r.Suggestion = "enable chrony or systemd-timesyncd, or set global.time_sync to install chrony, clock skew breaks raft and S3 signatures"
+	}
+	return r
+}
+
+func checkSecurityModules(op operator.CommandOperator, options Options) Result {
+	r := Result{Check: "selinux/apparmor", Status: Pass}
+	selinux, _ := output(op, "getenforce 2>/dev/null || true")
+	apparmor, _ := output(op, "cat /sys/module/apparmor/parameters/enabled 2>/dev/null || true")
+	var modules []string
+	if selinux != "" {
+		modules = append(modules, "SELinux "+strings.ToLower(selinux))
+	}
+	if apparmor == "Y" {
+		modules = append(modules, "AppArmor enabled")
+	}
+	if len(modules) == 0 {
+		r.Message = "none active"
+		return r
+	}
+	r.Message = strings.Join(modules, ", ")
+	if selinux == "Enforcing" {
+		r.Status = Warn
+		r.Suggestion = "label dir.data and dir.conf for the seaweed services or set SELinux to permissive"
+	}
+	return r
+}
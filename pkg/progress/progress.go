@@ -0,0 +1,277 @@
+// Package progress shows the state of the tasks of long operations, one line
+// per task redrawn in place on terminals, or as plain log lines otherwise.
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seaweedfs/seaweed-up/pkg/logging"
+	"github.com/seaweedfs/seaweed-up/pkg/output"
+	"golang.org/x/term"
+)
+
+type state int
+
+const (
+	pending state = iota
+	running
+	succeeded
+	failed
+)
+
+// EventType is a change of the state of a task.
+type EventType string
+
+const (
+	TaskStarted   EventType = "started"
+	TaskStep      EventType = "step"
+	TaskSucceeded EventType = "succeeded"
+	TaskFailed    EventType = "failed"
+	TaskSkipped   EventType = "skipped"
+)
+
+// Event is sent to the listeners of a tracker when a task changes.
+type Event struct {
+	Type  EventType
+	Task  string
+	Step  string
+	Error error
+	Time  time.Time
+}
+
+// Listener receives the events of a tracker, from the goroutine changing the task.
+type Listener func(Event)
+
+var spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Tracker follows a group of tasks, e.g. one per node of a deployment.
+type Tracker struct {
+	mu        sync.Mutex
+	title     string
+	tasks     []*Task
+	live      bool
+	width     int // of the terminal, longer lines are cut to keep one line per task
+	out       io.Writer
+	lines     int          // lines of the last drawing, to move back over it
+	partial   bytes.Buffer // output not yet ending with a new line
+	frame     int
+	stop      chan struct{}
+	stopped   chan struct{}
+	restore   func()
+	listeners []Listener
+}
+
+// Task is one unit of work of a tracker.
+type Task struct {
+	tracker  *Tracker
+	name     string
+	step     string
+	state    state
+	started  time.Time
+	finished time.Time
+	err      error
+}
+
+// New starts a tracker. The tasks are drawn live when stdout is a terminal and
+// the results are printed as tables, in which case all other output of the
+// process goes above the drawing until Stop is called.
+func New(title string) *Tracker {
+	t := &Tracker{
+		title:   title,
+		live:    output.Current() == output.Table && term.IsTerminal(int(os.Stdout.Fd())),
+		out:     os.Stdout,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if !t.live {
+		close(t.stopped)
+		return t
+	}
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		t.width = width
+	} else {
+		t.width = 80
+	}
+	t.restore = output.Redirect(t)
+	go t.run()
+	return t
+}
+
+// Subscribe adds a listener for the events of all tasks.
+func (t *Tracker) Subscribe(listener Listener) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.listeners = append(t.listeners, listener)
+}
+
+func (t *Tracker) publish(event Event) {
+	t.mu.Lock()
+	listeners := t.listeners
+	t.mu.Unlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// Add registers a pending task.
+func (t *Tracker) Add(name string) *Task {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task := &Task{tracker: t, name: name, step: "waiting"}
+	t.tasks = append(t.tasks, task)
+	return task
+}
+
+// Step marks the task as running the named step.
+func (task *Task) Step(step string) {
+	t := task.tracker
+	t.mu.Lock()
+	event := Event{Type: TaskStep, Task: task.name, Step: step, Time: time.Now()}
+	if task.state == pending {
+		task.started = event.Time
+		event.Type = TaskStarted
+	}
+	task.state, task.step = running, step
+	t.mu.Unlock()
+	if !t.live {
+		logging.Info(fmt.Sprintf("%s: %s", task.name, step))
+	}
+	t.publish(event)
+}
+
+// Done marks the task as finished, failed if err is not nil.
+func (task *Task) Done(err error) {
+	t := task.tracker
+	t.mu.Lock()
+	task.finished = time.Now()
+	if task.started.IsZero() {
+		task.started = task.finished
+	}
+	task.err = err
+	task.state, task.step = succeeded, "done"
+	if err != nil {
+		task.state, task.step = failed, "failed: "+err.Error()
+	}
+	elapsed := task.finished.Sub(task.started).Round(time.Second)
+	event := Event{Type: TaskSucceeded, Task: task.name, Step: task.step, Error: err, Time: task.finished}
+	if err != nil {
+		event.Type = TaskFailed
+	}
+	t.mu.Unlock()
+	if !t.live {
+		logging.Info(fmt.Sprintf("%s: %s (%s)", task.name, task.step, elapsed))
+	}
+	t.publish(event)
+}
+
+// Skip marks the task as finished without running it.
+func (task *Task) Skip(reason string) {
+	t := task.tracker
+	t.mu.Lock()
+	task.started = time.Now()
+	task.finished = task.started
+	task.state, task.step = succeeded, "skipped, "+reason
+	event := Event{Type: TaskSkipped, Task: task.name, Step: task.step, Time: task.finished}
+	t.mu.Unlock()
+	if !t.live {
+		logging.Info(fmt.Sprintf("%s: %s", task.name, task.step))
+	}
+	t.publish(event)
+}
+
+// Stop draws the final state of the tasks and gives the output back.
+func (t *Tracker) Stop() {
+	if !t.live {
+		return
+	}
+	close(t.stop)
+	<-t.stopped
+	t.restore()
+}
+
+func (t *Tracker) run() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	defer close(t.stopped)
+	for {
+		select {
+		case <-ticker.C:
+			t.mu.Lock()
+			t.frame++
+			t.redraw(nil)
+			t.mu.Unlock()
+		case <-t.stop:
+			t.mu.Lock()
+			if t.partial.Len() > 0 {
+				t.partial.WriteByte('\n')
+			}
+			t.redraw(t.partial.Bytes())
+			t.partial.Reset()
+			t.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Write prints complete lines of other output above the drawing.
+func (t *Tracker) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.partial.Write(p)
+	if i := bytes.LastIndexByte(t.partial.Bytes(), '\n'); i >= 0 {
+		lines := append([]byte{}, t.partial.Next(i+1)...)
+		t.redraw(lines)
+	}
+	return len(p), nil
+}
+
+// redraw clears the previous drawing, prints the text and draws the tasks again.
+func (t *Tracker) redraw(text []byte) {
+	var b bytes.Buffer
+	if t.lines > 0 {
+		fmt.Fprintf(&b, "\033[%dA\r\033[J", t.lines)
+	}
+	b.Write(text)
+	fmt.Fprintf(&b, "%s\n", t.fit(t.title))
+	for _, task := range t.tasks {
+		fmt.Fprintf(&b, "%s\n", t.fit(fmt.Sprintf("  %s %-32s %-8s %s", task.symbol(t.frame), task.name, task.elapsed(), task.step)))
+	}
+	t.lines = len(t.tasks) + 1
+	t.out.Write(b.Bytes())
+}
+
+func (t *Tracker) fit(line string) string {
+	runes := []rune(strings.ReplaceAll(line, "\n", " "))
+	if len(runes) >= t.width {
+		runes = runes[:t.width-1]
+	}
+	return string(runes)
+}
+
+func (task *Task) symbol(frame int) string {
+	switch task.state {
+	case running:
+		return spinner[frame%len(spinner)]
+	case succeeded:
+		return "✔"
+	case failed:
+		return "✘"
+	}
+	return "·"
+}
+
+func (task *Task) elapsed() string {
+	switch task.state {
+	case pending:
+		return ""
+	case running:
+		return time.Since(task.started).Round(time.Second).String()
+	}
+	return task.finished.Sub(task.started).Round(time.Second).String()
+}
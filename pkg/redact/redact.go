@@ -0,0 +1,139 @@
+// Package redact masks secrets, like passwords and keys, in everything
+// seaweed-up prints or writes: log messages, plans, command results and
+// support bundles.
+package redact
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mask replaces the secrets.
+const Mask = "REDACTED"
+
+// ShowSecrets turns redaction off, for admins who need to see the secrets.
+var ShowSecrets bool
+
+var (
+	mu     sync.RWMutex
+	values []string // secrets known at runtime, the longest first
+)
+
+// secretKey matches the keys whose values are secrets.
+var secretKey = regexp.MustCompile(`(?i)pass|secret|token|credential|auth|key$`)
+
+// secretPatterns match secrets in commands, configuration files and api
+// responses. The text before the secret is the first group, after it the second.
+var secretPatterns = []*regexp.Regexp{
+	// sudo passwords passed along with the commands, quoted for the shell
+	regexp.MustCompile(`(printf '%s\\n' )'(?:[^']|'"'"')*'( \| sudo)`),
+	regexp.MustCompile(`(SUDO_PASS=)'(?:[^']|'"'"')*'()`),
+	// the signing key of security.toml, in its [jwt.signing] section
+	regexp.MustCompile(`(?m)(^\s*key\s*=\s*")[^"]*(")`),
+	// the vrrp password of keepalived.conf
+	regexp.MustCompile(`(auth_pass\s+)\S+()`),
+	// the credentials of http authorization headers, like -H 'Authorization: Bearer ...' to a mirror
+	regexp.MustCompile(`(?i)((?:(?:proxy-)?authorization|x-jfrog-art-api):\s*(?:(?:basic|bearer|token)\s+)?)[^'"\s]+()`),
+}
+
+// secretAssignment matches key = "value", "key": "value" and key=value of
+// secret looking keys, the end of the key being the second group.
+var secretAssignment = regexp.MustCompile(`(?i)([\w.-]*(?:password|passwd|secret|token|api_?key|signing\.key|private_?key)([\w.-]*)"?\s*[:=]\s*"?)[^"'\s,&}]+`)
+
+// Secret registers a value read at runtime, like a password, to mask wherever it appears.
+func Secret(value string) {
+	// too short values would mask unrelated text
+	if len(value) < 4 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range values {
+		if v == value {
+			return
+		}
+	}
+	values = append(values, value)
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+}
+
+// String returns s with the registered secrets and the values of secret
+// looking keys masked.
+func String(s string) string {
+	if ShowSecrets {
+		return s
+	}
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+Mask+"${2}")
+	}
+	s = secretAssignment.ReplaceAllStringFunc(s, func(assignment string) string {
+		match := secretAssignment.FindStringSubmatch(assignment)
+		// keys telling where the secret is, like password_env, are no secrets
+		for _, suffix := range []string{"_env", "_command", "_file"} {
+			if strings.HasSuffix(strings.ToLower(match[2]), suffix) {
+				return assignment
+			}
+		}
+		return match[1] + Mask
+	})
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, value := range values {
+		s = strings.ReplaceAll(s, value, Mask)
+	}
+	return s
+}
+
+// Bytes is String for byte slices.
+func Bytes(data []byte) []byte {
+	if ShowSecrets {
+		return data
+	}
+	return []byte(String(string(data)))
+}
+
+// YAML returns the yaml document with the values of secret looking keys and
+// of all http headers masked, and the registered secrets masked anywhere.
+func YAML(data []byte) ([]byte, error) {
+	if ShowSecrets {
+		return data, nil
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	redactNode(&root, false)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, err
+	}
+	return Bytes(buf.Bytes()), nil
+}
+
+func redactNode(node *yaml.Node, secret bool) {
+	if node.Kind == yaml.ScalarNode {
+		if secret {
+			node.Value = Mask
+			node.Tag = "!!str"
+			node.Style = 0
+		}
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		for _, child := range node.Content {
+			redactNode(child, secret)
+		}
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		redactNode(node.Content[i+1], secret || key == "headers" || secretKey.MatchString(key))
+	}
+}
@@ -0,0 +1,63 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	for _, test := range []struct {
+		name, in, want string
+	}{
+		{"sudo password", `printf '%s\n' 'pa'"'"'ss' | sudo -S -p '' sh -c 'id'`, `printf '%s\n' REDACTED | sudo -S -p '' sh -c 'id'`},
+		{"script sudo password", `SUDO_PASS='pa'"'"'ss' bash install.sh`, `SUDO_PASS=REDACTED bash install.sh`},
+		{"jwt signing key", "[jwt.signing]\nkey = \"abcdef\"\n", "[jwt.signing]\nkey = \"REDACTED\"\n"},
+		{"vrrp password", "  auth_pass s3cr3t\n", "  auth_pass REDACTED\n"},
+		{"bearer header", `curl -H 'Authorization: Bearer abc123xyz' url`, `curl -H 'Authorization: Bearer REDACTED' url`},
+		{"basic header", `-H "Proxy-Authorization: Basic dXNlcjpwYXNz"`, `-H "Proxy-Authorization: Basic REDACTED"`},
+		{"artifactory header", `-H 'X-JFrog-Art-Api: AKCp8abc'`, `-H 'X-JFrog-Art-Api: REDACTED'`},
+		{"gitlab header", `-H 'PRIVATE-TOKEN: glpat-abc' -o file`, `-H 'PRIVATE-TOKEN: REDACTED' -o file`},
+		{"password assignment", `password=hunter22&user=admin`, `password=REDACTED&user=admin`},
+		{"json secret", `{"secret_key": "abcd1234"}`, `{"secret_key": "REDACTED"}`},
+		{"secret location", `password_env: SUDO_PASSWORD`, `password_env: SUDO_PASSWORD`},
+		{"no secret", `weed master -port=9333`, `weed master -port=9333`},
+	} {
+		if got := String(test.in); got != test.want {
+			t.Errorf("%s: String(%q) = %q, want %q", test.name, test.in, got, test.want)
+		}
+	}
+}
+
+func TestSecret(t *testing.T) {
+	Secret("abc")
+	Secret("mirror-credential-1")
+	if got := String("-H 'X-Custom: mirror-credential-1' abc"); got != "-H 'X-Custom: REDACTED' abc" {
+		t.Errorf("registered secret not masked: %q", got)
+	}
+}
+
+func TestShowSecrets(t *testing.T) {
+	ShowSecrets = true
+	defer func() { ShowSecrets = false }()
+	if got := String("password=hunter22"); got != "password=hunter22" {
+		t.Errorf("secret masked with ShowSecrets: %q", got)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	out, err := YAML([]byte("repository:\n  url: https://mirror.local\n  headers:\n    X-Custom: value1\nuser: admin\nauth_pass: vrrp123\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	for _, secret := range []string{"value1", "vrrp123"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("%s not masked in %s", secret, got)
+		}
+	}
+	for _, kept := range []string{"https://mirror.local", "admin"} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("%s masked in %s", kept, got)
+		}
+	}
+}
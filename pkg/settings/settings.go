@@ -0,0 +1,100 @@
+// Package settings reads the defaults of the user, from ~/.seaweed-up/config.yaml
+// and SEAWEED_UP_* environment variables, so common flags need not be
+// repeated on every command.
+package settings
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the environment variable with the path of the config file.
+const FileEnv = "SEAWEED_UP_CONFIG"
+
+// Settings are the defaults of the user. Empty values are not set.
+type Settings struct {
+	DataDir      string `yaml:"data_dir,omitempty" json:"dataDir,omitempty"`           // instead of ~/.seaweed-up
+	IdentityFile string `yaml:"identity_file,omitempty" json:"identityFile,omitempty"` // -i
+	Proxy        string `yaml:"proxy,omitempty" json:"proxy,omitempty"`                // -x
+	Format       string `yaml:"format,omitempty" json:"format,omitempty"`              // --format
+	Context      string `yaml:"context,omitempty" json:"context,omitempty"`            // context used when none is selected
+	RepoUrl      string `yaml:"repo_url,omitempty" json:"repoUrl,omitempty"`           // --repo-url
+	CABundle     string `yaml:"ca_bundle,omitempty" json:"caBundle,omitempty"`         // --ca-bundle
+}
+
+// envs maps the environment variables to the settings they set.
+var envs = map[string]func(*Settings) *string{
+	"SEAWEED_UP_DATA_DIR":      func(s *Settings) *string { return &s.DataDir },
+	"SEAWEED_UP_IDENTITY_FILE": func(s *Settings) *string { return &s.IdentityFile },
+	"SEAWEED_UP_PROXY":         func(s *Settings) *string { return &s.Proxy },
+	"SEAWEED_UP_FORMAT":        func(s *Settings) *string { return &s.Format },
+	"SEAWEED_UP_CONTEXT":       func(s *Settings) *string { return &s.Context },
+	"SEAWEED_UP_REPO_URL":      func(s *Settings) *string { return &s.RepoUrl },
+	"SEAWEED_UP_CA_BUNDLE":     func(s *Settings) *string { return &s.CABundle },
+}
+
+// File is the config file: the one of FileEnv, or else ~/.seaweed-up/config.yaml.
+func File() string {
+	if file := os.Getenv(FileEnv); file != "" {
+		return file
+	}
+	return filepath.Join(utils.UserHome(), ".seaweed-up", "config.yaml")
+}
+
+// Load reads the config file, no settings if there is none.
+func Load() (*Settings, error) {
+	s := &Settings{}
+	data, err := os.ReadFile(File())
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", File(), err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(s); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", File(), err)
+	}
+	return s, nil
+}
+
+// FromEnv returns the settings of the SEAWEED_UP_* environment variables.
+func FromEnv() *Settings {
+	s := &Settings{}
+	for env, setting := range envs {
+		*setting(s) = os.Getenv(env)
+	}
+	return s
+}
+
+// Merge returns the settings with the empty ones taken from other.
+func (s *Settings) Merge(other *Settings) *Settings {
+	return &Settings{
+		DataDir:      utils.Nvl(s.DataDir, other.DataDir),
+		IdentityFile: utils.Nvl(s.IdentityFile, other.IdentityFile),
+		Proxy:        utils.Nvl(s.Proxy, other.Proxy),
+		Format:       utils.Nvl(s.Format, other.Format),
+		Context:      utils.Nvl(s.Context, other.Context),
+		RepoUrl:      utils.Nvl(s.RepoUrl, other.RepoUrl),
+		CABundle:     utils.Nvl(s.CABundle, other.CABundle),
+	}
+}
+
+// Flags returns the values of the settings by the name of the flags they are
+// the default of.
+func (s *Settings) Flags() map[string]string {
+	return map[string]string{
+		"identity_file": s.IdentityFile,
+		"proxy":         s.Proxy,
+		"format":        s.Format,
+		"repo-url":      s.RepoUrl,
+	}
+}
@@ -0,0 +1,271 @@
+// Package statecrypt encrypts the files of the data dir of seaweed-up that may
+// hold secrets, like the applied configurations, with AES-GCM and a key derived
+// from a passphrase or key file.
+package statecrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/seaweedfs/seaweed-up/pkg/utils"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// PassphraseEnv is the environment variable with the passphrase.
+	PassphraseEnv = "SEAWEED_UP_STATE_PASSPHRASE"
+	// KeyFileEnv is the environment variable with the path of a file holding the passphrase.
+	KeyFileEnv = "SEAWEED_UP_STATE_KEY_FILE"
+)
+
+// Dir is the data dir whose encryption file holds the salt of the key, and
+// tells whether the sensitive files are written encrypted.
+var Dir = filepath.Join(utils.UserHome(), ".seaweed-up")
+
+// magic starts the encrypted files.
+var magic = []byte("seaweed-up encrypted v1\n")
+
+const saltSize = 16
+
+// verifier is encrypted in the encryption file to detect a wrong passphrase.
+var verifier = []byte("seaweed-up")
+
+var (
+	mu  sync.Mutex
+	key []byte // derived once per run
+)
+
+func encryptionFile() string {
+	return filepath.Join(Dir, "encryption")
+}
+
+// Enabled tells if the sensitive files are written encrypted.
+func Enabled() bool {
+	_, err := os.Stat(encryptionFile())
+	return err == nil
+}
+
+// IsEncrypted tells if the content was written by WriteFile with encryption enabled.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Passphrase returns the passphrase of the key file of KeyFileEnv, or of
+// PassphraseEnv, or else asks it.
+func Passphrase(prompt string) (string, error) {
+	if keyFile := os.Getenv(KeyFileEnv); keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", KeyFileEnv, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if passphrase, found := os.LookupEnv(PassphraseEnv); found {
+		return passphrase, nil
+	}
+	return utils.PromptForPassword(prompt)
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := append(append([]byte{}, magic...), nonce...)
+	return gcm.Seal(sealed, nonce, plaintext, magic), nil
+}
+
+func open(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, magic)
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("truncated encrypted content")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], magic)
+}
+
+// Enable writes the sensitive files encrypted from now on, with a key derived
+// from the passphrase. The files written before are left as they are, see
+// Rewrite.
+func Enable(passphrase string) error {
+	if Enabled() {
+		return fmt.Errorf("the state is encrypted already")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	derived, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(derived, verifier)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(encryptionFile(), append(salt, sealed...), 0600); err != nil {
+		return fmt.Errorf("write %s: %w", encryptionFile(), err)
+	}
+	key = derived
+	return nil
+}
+
+// Disable writes the sensitive files in plain text from now on. The files
+// still encrypted can not be read anymore, see Rewrite.
+func Disable() error {
+	mu.Lock()
+	defer mu.Unlock()
+	key = nil
+	return os.Remove(encryptionFile())
+}
+
+// currentKey derives the key from the passphrase and the salt of the
+// encryption file, and checks it against the verifier.
+func currentKey() ([]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+	data, err := os.ReadFile(encryptionFile())
+	if err != nil {
+		return nil, fmt.Errorf("the state is encrypted but %s can not be read: %w", encryptionFile(), err)
+	}
+	if len(data) < saltSize {
+		return nil, fmt.Errorf("%s is truncated", encryptionFile())
+	}
+	passphrase, err := Passphrase("Passphrase of the seaweed-up state: ")
+	if err != nil {
+		return nil, err
+	}
+	derived, err := deriveKey(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := open(derived, data[saltSize:]); err != nil {
+		return nil, fmt.Errorf("wrong passphrase of the seaweed-up state")
+	}
+	key = derived
+	return key, nil
+}
+
+// ReadFile reads the file, decrypting it if it is encrypted.
+func ReadFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if err != nil || !IsEncrypted(data) {
+		return data, err
+	}
+	k, err := currentKey()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(k, data)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", name, err)
+	}
+	return plaintext, nil
+}
+
+// WriteFile writes the file, encrypted if encryption is enabled.
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	return writeFile(name, data, perm, Enabled())
+}
+
+func writeFile(name string, data []byte, perm os.FileMode, encrypt bool) error {
+	if encrypt {
+		k, err := currentKey()
+		if err != nil {
+			return err
+		}
+		if data, err = seal(k, data); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(name, data, perm)
+}
+
+// SensitiveFiles returns the files under dir that may hold secrets: the
+// configurations applied to the clusters, and the snapshots of the clusters.
+func SensitiveFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		revision := filepath.Base(filepath.Dir(path)) == "revisions" && strings.HasSuffix(path, ".yaml")
+		snapshot := filepath.Base(filepath.Dir(filepath.Dir(path))) == "snapshots" && strings.HasSuffix(path, ".tar.gz")
+		if !entry.IsDir() && (revision || snapshot) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// Rewrite writes each file again, encrypted or in plain text. It returns how
+// many files were changed.
+func Rewrite(files []string, encrypt bool) (int, error) {
+	changed := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return changed, err
+		}
+		if IsEncrypted(data) == encrypt {
+			continue
+		}
+		plaintext, err := ReadFile(file)
+		if err != nil {
+			return changed, err
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return changed, err
+		}
+		// write and rename, so an interruption never leaves a truncated file
+		temp := file + ".tmp"
+		if err := writeFile(temp, plaintext, info.Mode().Perm(), encrypt); err != nil {
+			return changed, err
+		}
+		if err := os.Rename(temp, file); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
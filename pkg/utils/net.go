@@ -0,0 +1,11 @@
+package utils
+
+import (
+	"net"
+	"strconv"
+)
+
+// JoinHostPort returns host:port, the host in brackets if it is an IPv6 address.
+func JoinHostPort(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Pick asks to choose one of the options, with fzf if it is installed, or
+// else from a numbered list. It fails in non-interactive mode and when the
+// input is no terminal, so scripts missing an argument fail instead of hanging.
+func Pick(prompt string, options []string) (string, error) {
+	if NonInteractive || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("%s is not given and can not be asked in non-interactive mode", prompt)
+	}
+	if len(options) == 0 {
+		return "", fmt.Errorf("there is no %s to choose from", prompt)
+	}
+	if _, err := exec.LookPath("fzf"); err == nil {
+		return pickWithFzf(prompt, options)
+	}
+
+	for i, option := range options {
+		fmt.Fprintf(os.Stderr, "%3d) %s\n", i+1, option)
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprintf(os.Stderr, "%s [1-%d]: ", prompt, len(options))
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("read %s: %v", prompt, err)
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(line))
+		if err == nil && choice >= 1 && choice <= len(options) {
+			return options[choice-1], nil
+		}
+	}
+}
+
+func pickWithFzf(prompt string, options []string) (string, error) {
+	cmd := exec.Command("fzf", "--height=40%", "--reverse", "--prompt", prompt+"> ")
+	cmd.Stdin = strings.NewReader(strings.Join(options, "\n"))
+	cmd.Stderr = os.Stderr
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		// fzf exits with 130 when the choice is aborted
+		return "", fmt.Errorf("no %s chosen", prompt)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"golang.org/x/term"
 	"log"
+	"os"
 	"os/user"
+	"strings"
 	"syscall"
 )
 
@@ -48,16 +51,26 @@ func NvlInt(values ...int) int {
 	return 0
 }
 
+// NonInteractive forbids prompting for input, for CI pipelines.
+var NonInteractive bool
+
+// ErrNonInteractive is returned for input that would be prompted for with NonInteractive set.
+var ErrNonInteractive = errors.New("can not be asked in non-interactive mode")
+
 // PromptForPassword reads a password input from console
-func PromptForPassword(format string, a ...interface{}) string {
-	defer fmt.Println("")
+func PromptForPassword(format string, a ...interface{}) (string, error) {
+	prompt := fmt.Sprintf(format, a...)
+	if NonInteractive {
+		return "", fmt.Errorf("%s %w", strings.TrimRight(prompt, ": "), ErrNonInteractive)
+	}
+	defer fmt.Fprintln(os.Stderr)
 
-	fmt.Printf(format, a...)
+	fmt.Fprint(os.Stderr, prompt)
 
 	input, err := term.ReadPassword(syscall.Stdin)
 
 	if err != nil {
-		return ""
+		return "", fmt.Errorf("read password: %v", err)
 	}
-	return string(input)
+	return string(input), nil
 }
@@ -0,0 +1,48 @@
+#!/bin/bash
+set -e
+
+info() {
+  echo '[INFO] ->' "$@"
+}
+
+setup_env() {
+  SUDO=sudo
+  if [ "$(id -u)" -eq 0 ] || [ "$ELEVATION" = none ]; then
+    SUDO=
+  elif [ "$ELEVATION" = doas ]; then
+    SUDO="doas -n"
+  else
+    if [ ! -z "$SUDO_PASS" ]; then
+      printf '%s\n' "$SUDO_PASS" | sudo -S true
+      echo ""
+    fi
+  fi
+
+  TMP_DIR={{.TmpDir}}
+  SYSCTL_FILE={{.SysctlFile}}
+  THP_UNIT={{.ThpUnit}}
+}
+
+apply_sysctl() {
+  if [ ! -f ${TMP_DIR}/sysctl.conf ]; then
+    return
+  fi
+  info "Applying sysctl settings"
+  $SUDO install -m 0644 ${TMP_DIR}/sysctl.conf ${SYSCTL_FILE}
+  $SUDO sysctl -q -p ${SYSCTL_FILE}
+}
+
+apply_transparent_hugepages() {
+  if [ ! -f ${TMP_DIR}/thp.service ]; then
+    return
+  fi
+  info "Applying transparent hugepages setting"
+  $SUDO install -m 0644 ${TMP_DIR}/thp.service /etc/systemd/system/${THP_UNIT}.service
+  $SUDO systemctl daemon-reload >/dev/null
+  $SUDO systemctl enable ${THP_UNIT} >/dev/null 2>&1
+  $SUDO systemctl restart ${THP_UNIT}
+}
+
+setup_env
+apply_sysctl
+apply_transparent_hugepages
@@ -18,11 +18,13 @@ verify_system() {
 
 setup_sudo() {
   SUDO=sudo
-  if [ "$(id -u)" -eq 0 ]; then
+  if [ "$(id -u)" -eq 0 ] || [ "$ELEVATION" = none ]; then
     SUDO=
+  elif [ "$ELEVATION" = doas ]; then
+    SUDO="doas -n"
   else
     if [ ! -z "$SUDO_PASS" ]; then
-      echo "$SUDO_PASS" | sudo -S true
+      printf '%s\n' "$SUDO_PASS" | sudo -S true
       echo ""
     fi
   fi
@@ -50,6 +52,15 @@ setup_env() {
   SKIP_START={{.SkipStart}}
   FORCE_RESTART={{.ForceRestart}}
   SEAWEED_VERSION={{.Version}}
+  SERVICE_USER={{.ServiceUser}}
+  SERVICE_GROUP={{.ServiceGroup}}
+  SERVICE_UID={{.ServiceUid}}
+  SERVICE_GID={{.ServiceGid}}
+  SERVICE_HOME={{.ServiceHome}}
+  SERVICE_SHELL={{.ServiceShell}}
+  WRITABLE_DIRS="{{.WritableDirs}}"
+  ARCH={{.Arch}}
+  REPO_URL={{.RepoUrl}}
 
   cd $TMP_DIR
 }
@@ -118,11 +129,12 @@ download_and_install() {
     FULL_SUFIX="_full"
     LARGE_SUFIX="_large_disk"
     assetFileName="${OS}_${SUFFIX}${FULL_SUFIX}${LARGE_SUFIX}.tar.gz"
+    assetUrl=$(echo "${REPO_URL}" | sed -e "s|{version}|${SEAWEED_VERSION}|g" -e "s|{asset}|${assetFileName}|g")
     info "Downloading ${SEAWEED_VERSION} ${assetFileName}"
-    curl {{.ProxyConfig}} -o "$TMP_DIR/seaweed_${SEAWEED_VERSION}_${assetFileName}" -sfL "https://github.com/seaweedfs/seaweedfs/releases/download/${SEAWEED_VERSION}/${assetFileName}"
+    curl {{.ProxyConfig}} {{.CurlArgs}} -o "$TMP_DIR/seaweed_${SEAWEED_VERSION}_${assetFileName}" -sfL "${assetUrl}"
 
     info "Downloading ${SEAWEED_VERSION} ${assetFileName} md5"
-    curl {{.ProxyConfig}} -o "$TMP_DIR/seaweed_${SEAWEED_VERSION}_${assetFileName}.md5" -sfL "https://github.com/seaweedfs/seaweedfs/releases/download/${SEAWEED_VERSION}/${assetFileName}.md5"
+    curl {{.ProxyConfig}} {{.CurlArgs}} -o "$TMP_DIR/seaweed_${SEAWEED_VERSION}_${assetFileName}.md5" -sfL "${assetUrl}.md5"
     info "Verifying downloaded ${SEAWEED_VERSION} ${assetFileName}"
     md5Value=`cat $TMP_DIR/seaweed_${SEAWEED_VERSION}_${assetFileName}.md5`
     echo "${md5Value}  seaweed_${SEAWEED_VERSION}_${assetFileName}" | md5sum -c
@@ -140,35 +152,61 @@ create_user_and_config() {
       info "Copying configuration files"
       $SUDO cp ${TMP_DIR}/config/* ${SEAWEED_COMPONENT_INSTANCE_CONFIG_DIR}
     fi
+
+  if [ -n "${SERVICE_USER}" ]; then
+    create_group
+    create_user
+    for dir in ${WRITABLE_DIRS}; do
+      $SUDO mkdir --parents ${dir}
+      $SUDO chown -R ${SERVICE_USER}:${SERVICE_GROUP} ${dir}
+    done
+    $SUDO chown -R ${SERVICE_USER}:${SERVICE_GROUP} ${SEAWEED_COMPONENT_INSTANCE_CONFIG_DIR}
+  fi
+}
+
+create_group() {
+  if getent group ${SERVICE_GROUP} >/dev/null; then
+    if [ "${SERVICE_GID}" != 0 ] && [ "$(getent group ${SERVICE_GROUP} | cut -d: -f3)" != "${SERVICE_GID}" ]; then
+      info "Group ${SERVICE_GROUP} exists with another gid than ${SERVICE_GID}, keeping it"
+    fi
+    return
+  fi
+  info "Creating group ${SERVICE_GROUP}"
+  GID_OPTION=
+  if [ "${SERVICE_GID}" != 0 ]; then
+    GID_OPTION="--gid ${SERVICE_GID}"
+  fi
+  $SUDO groupadd --system ${GID_OPTION} ${SERVICE_GROUP}
+}
+
+create_user() {
+  if id -u ${SERVICE_USER} >/dev/null 2>&1; then
+    if [ "${SERVICE_UID}" != 0 ] && [ "$(id -u ${SERVICE_USER})" != "${SERVICE_UID}" ]; then
+      info "User ${SERVICE_USER} exists with another uid than ${SERVICE_UID}, keeping it"
+    fi
+    return
+  fi
+  info "Creating user ${SERVICE_USER}"
+  # the nologin shell is in /sbin on older distributions
+  if [ ! -x "${SERVICE_SHELL}" ] && [ -x "/sbin/$(basename ${SERVICE_SHELL})" ]; then
+    SERVICE_SHELL=/sbin/$(basename ${SERVICE_SHELL})
+  fi
+  USER_OPTIONS="--shell ${SERVICE_SHELL} --gid ${SERVICE_GROUP}"
+  if [ "${SERVICE_UID}" != 0 ]; then
+    USER_OPTIONS="${USER_OPTIONS} --uid ${SERVICE_UID}"
+  fi
+  if [ -n "${SERVICE_HOME}" ]; then
+    USER_OPTIONS="${USER_OPTIONS} --home-dir ${SERVICE_HOME} --create-home"
+  else
+    USER_OPTIONS="${USER_OPTIONS} --no-create-home"
+  fi
+  $SUDO useradd --system ${USER_OPTIONS} ${SERVICE_USER}
 }
 
-# --- write systemd service file ---
+# --- install systemd service file ---
 create_systemd_service_file() {
   info "Adding systemd service file ${SEAWEED_COMPONENT_INSTANCE_SERVICE_FILE}"
-  $SUDO tee ${SEAWEED_COMPONENT_INSTANCE_SERVICE_FILE} >/dev/null <<EOF
-[Unit]
-Description=Seaweed${COMPONENT_INSTANCE}
-Documentation=https://github.com/seaweedfs/seaweedfs/wiki
-Wants=network-online.target
-After=network-online.target
-
-[Service]
-WorkingDirectory=${SEAWEED_COMPONENT_INSTANCE_DATA_DIR}
-ExecStart=${BIN_DIR}/${BINARY} -logdir=${SEAWEED_COMPONENT_INSTANCE_DATA_DIR} -alsologtostderr=false -config_dir=${SEAWEED_COMPONENT_INSTANCE_CONFIG_DIR} ${COMPONENT} -options=${SEAWEED_COMPONENT_INSTANCE_CONFIG_DIR}/${COMPONENT}.options
-ExecReload=/bin/kill -s HUP \$MAINPID
-KillMode=process
-KillSignal=SIGINT
-LimitNOFILE=infinity
-LimitNPROC=infinity
-Restart=on-failure
-RestartSec=2
-StartLimitBurst=3
-StartLimitIntervalSec=10
-TasksMax=infinity
-
-[Install]
-WantedBy=multi-user.target
-EOF
+  $SUDO cp ${TMP_DIR}/seaweed_${COMPONENT_INSTANCE}.service ${SEAWEED_COMPONENT_INSTANCE_SERVICE_FILE}
 }
 
 # --- startup systemd service ---
@@ -0,0 +1,81 @@
+#!/bin/bash
+set -e
+
+info() {
+  echo '[INFO] ->' "$@"
+}
+
+fatal() {
+  echo '[ERROR] ->' "$@"
+  exit 1
+}
+
+setup_env() {
+  SUDO=sudo
+  if [ "$(id -u)" -eq 0 ] || [ "$ELEVATION" = none ]; then
+    SUDO=
+  elif [ "$ELEVATION" = doas ]; then
+    SUDO="doas -n"
+  else
+    if [ ! -z "$SUDO_PASS" ]; then
+      printf '%s\n' "$SUDO_PASS" | sudo -S true
+      echo ""
+    fi
+  fi
+
+  TMP_DIR={{.TmpDir}}
+  CONF_FILE={{.ConfFile}}
+  SKIP_START={{.SkipStart}}
+  FORCE_RESTART={{.ForceRestart}}
+}
+
+install_chrony() {
+  if [ -x "$(command -v chronyd)" ]; then
+    return
+  fi
+  info "Installing chrony"
+  if [ -n "$(command -v apt-get)" ]; then
+    $SUDO apt-get install -y chrony
+  elif [ -n "$(command -v dnf)" ]; then
+    $SUDO dnf install -y chrony
+  elif [ -n "$(command -v yum)" ]; then
+    $SUDO yum install -y chrony
+  else
+    fatal "Could not find apt-get, dnf or yum. Cannot install chrony on this OS"
+  fi
+}
+
+install_config() {
+  if [ -z "${CONF_FILE}" ]; then
+    return
+  fi
+  PRE_INSTALL_HASHES=$($SUDO sha256sum ${CONF_FILE} 2>&1 || true)
+  $SUDO install -m 0644 ${TMP_DIR}/chrony.conf ${CONF_FILE}
+  POST_INSTALL_HASHES=$($SUDO sha256sum ${CONF_FILE} 2>&1 || true)
+}
+
+systemd_enable_and_start() {
+  # debian names the unit chrony, red hat chronyd
+  UNIT=chrony
+  if ! systemctl cat chrony.service >/dev/null 2>&1; then
+    UNIT=chronyd
+  fi
+  # systemd-timesyncd would fight chrony over the clock
+  if systemctl is-active --quiet systemd-timesyncd; then
+    info "Disabling systemd-timesyncd"
+    $SUDO systemctl disable --now systemd-timesyncd >/dev/null 2>&1 || true
+  fi
+  $SUDO systemctl enable ${UNIT} >/dev/null 2>&1 || true
+  [ "${SKIP_START}" = true ] && return
+  if [ "${FORCE_RESTART}" != true ] && [ "${PRE_INSTALL_HASHES}" = "${POST_INSTALL_HASHES}" ] && systemctl is-active --quiet ${UNIT}; then
+    info "No change detected so skipping ${UNIT} restart"
+    return
+  fi
+  info "Restarting ${UNIT}"
+  $SUDO systemctl restart ${UNIT}
+}
+
+setup_env
+install_chrony
+install_config
+systemd_enable_and_start
@@ -18,11 +18,13 @@ verify_system() {
 
 setup_sudo() {
   SUDO=sudo
-  if [ "$(id -u)" -eq 0 ]; then
+  if [ "$(id -u)" -eq 0 ] || [ "$ELEVATION" = none ]; then
     SUDO=
+  elif [ "$ELEVATION" = doas ]; then
+    SUDO="doas -n"
   else
     if [ ! -z "$SUDO_PASS" ]; then
-      echo "$SUDO_PASS" | sudo -S true
+      printf '%s\n' "$SUDO_PASS" | sudo -S true
       echo ""
     fi
   fi
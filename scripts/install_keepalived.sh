@@ -0,0 +1,70 @@
+#!/bin/bash
+set -e
+
+info() {
+  echo '[INFO] ->' "$@"
+}
+
+fatal() {
+  echo '[ERROR] ->' "$@"
+  exit 1
+}
+
+setup_env() {
+  SUDO=sudo
+  if [ "$(id -u)" -eq 0 ] || [ "$ELEVATION" = none ]; then
+    SUDO=
+  elif [ "$ELEVATION" = doas ]; then
+    SUDO="doas -n"
+  else
+    if [ ! -z "$SUDO_PASS" ]; then
+      printf '%s\n' "$SUDO_PASS" | sudo -S true
+      echo ""
+    fi
+  fi
+
+  TMP_DIR={{.TmpDir}}
+  CONFIG_DIR=/etc/keepalived
+  SKIP_START={{.SkipStart}}
+  FORCE_RESTART={{.ForceRestart}}
+}
+
+install_keepalived() {
+  if [ -x "$(command -v keepalived)" ]; then
+    return
+  fi
+  info "Installing keepalived"
+  if [ -n "$(command -v apt-get)" ]; then
+    $SUDO apt-get install -y keepalived curl
+  elif [ -n "$(command -v dnf)" ]; then
+    $SUDO dnf install -y keepalived curl
+  elif [ -n "$(command -v yum)" ]; then
+    $SUDO yum install -y keepalived curl
+  else
+    fatal "Could not find apt-get, dnf or yum. Cannot install keepalived on this OS"
+  fi
+}
+
+install_config() {
+  PRE_INSTALL_HASHES=$($SUDO sha256sum ${CONFIG_DIR}/keepalived.conf ${CONFIG_DIR}/check_seaweed.sh 2>&1 || true)
+  $SUDO mkdir -p ${CONFIG_DIR}
+  $SUDO install -m 0644 ${TMP_DIR}/keepalived.conf ${CONFIG_DIR}/keepalived.conf
+  $SUDO install -m 0755 ${TMP_DIR}/check_seaweed.sh ${CONFIG_DIR}/check_seaweed.sh
+  POST_INSTALL_HASHES=$($SUDO sha256sum ${CONFIG_DIR}/keepalived.conf ${CONFIG_DIR}/check_seaweed.sh 2>&1 || true)
+}
+
+systemd_enable_and_start() {
+  $SUDO systemctl enable keepalived >/dev/null
+  [ "${SKIP_START}" = true ] && return
+  if [ "${FORCE_RESTART}" != true ] && [ "${PRE_INSTALL_HASHES}" = "${POST_INSTALL_HASHES}" ] && systemctl is-active --quiet keepalived; then
+    info "No change detected so skipping keepalived restart"
+    return
+  fi
+  info "Restarting keepalived"
+  $SUDO systemctl restart keepalived
+}
+
+setup_env
+install_keepalived
+install_config
+systemd_enable_and_start
@@ -0,0 +1,65 @@
+#!/bin/bash
+set -e
+
+info() {
+  echo '[INFO] ->' "$@"
+}
+
+fatal() {
+  echo '[ERROR] ->' "$@"
+  exit 1
+}
+
+setup_env() {
+  SUDO=sudo
+  if [ "$(id -u)" -eq 0 ] || [ "$ELEVATION" = none ]; then
+    SUDO=
+  elif [ "$ELEVATION" = doas ]; then
+    SUDO="doas -n"
+  else
+    if [ ! -z "$SUDO_PASS" ]; then
+      printf '%s\n' "$SUDO_PASS" | sudo -S true
+      echo ""
+    fi
+  fi
+
+  TMP_DIR={{.TmpDir}}
+}
+
+update_packages() {
+  if [ -n "$(command -v apt-get)" ]; then
+    info "Updating packages with apt-get"
+    $SUDO apt-get update -q
+    $SUDO env DEBIAN_FRONTEND=noninteractive apt-get -q -y -o Dpkg::Options::=--force-confold dist-upgrade
+  elif [ -n "$(command -v dnf)" ]; then
+    info "Updating packages with dnf"
+    $SUDO dnf -y upgrade
+  elif [ -n "$(command -v yum)" ]; then
+    info "Updating packages with yum"
+    $SUDO yum -y update
+  else
+    fatal "Could not find apt-get, dnf or yum. Cannot update packages on this OS"
+  fi
+}
+
+# check_reboot leaves a reboot_required file in the temporary directory if
+# the updates need a reboot, like a new kernel.
+check_reboot() {
+  if [ -f /var/run/reboot-required ]; then
+    touch ${TMP_DIR}/reboot_required
+  elif [ -n "$(command -v needs-restarting)" ]; then
+    $SUDO needs-restarting -r >/dev/null 2>&1 || touch ${TMP_DIR}/reboot_required
+  else
+    LATEST_KERNEL=$(ls /boot/vmlinuz-* 2>/dev/null | sed 's|/boot/vmlinuz-||' | sort -V | tail -n 1)
+    if [ -n "${LATEST_KERNEL}" ] && [ "${LATEST_KERNEL}" != "$(uname -r)" ]; then
+      touch ${TMP_DIR}/reboot_required
+    fi
+  fi
+  if [ -f ${TMP_DIR}/reboot_required ]; then
+    info "A reboot is required"
+  fi
+}
+
+setup_env
+update_packages
+check_reboot
@@ -12,11 +12,13 @@ fatal() {
 
 setup_sudo() {
   SUDO=sudo
-  if [ "$(id -u)" -eq 0 ]; then
+  if [ "$(id -u)" -eq 0 ] || [ "$ELEVATION" = none ]; then
     SUDO=
+  elif [ "$ELEVATION" = doas ]; then
+    SUDO="doas -n"
   else
     if [ ! -z "$SUDO_PASS" ]; then
-      echo "$SUDO_PASS" | sudo -S true
+      printf '%s\n' "$SUDO_PASS" | sudo -S true
       echo ""
     fi
   fi
@@ -27,16 +29,19 @@ setup_env() {
 
   MOUNT_POINT={{.MountPoint}}
   DEVICE_PATH={{.DevicePath}}
+  FSTAB_DEVICE={{.FstabDevice}}
+  FS_TYPE={{.FsType}}
+  MOUNT_OPTIONS={{.MountOptions}}
 }
 
 setup_mount() {
 
   info "Setup Mount Point"
   $SUDO mkdir -p -m 755 ${MOUNT_POINT}
-  info "add ${DEVICE_PATH} ${MOUNT_POINT} to fstab"
-  echo "${DEVICE_PATH} ${MOUNT_POINT} ext4 noatime 0 2" | $SUDO tee -a /etc/fstab
+  info "add ${FSTAB_DEVICE} ${MOUNT_POINT} to fstab"
+  echo "${FSTAB_DEVICE} ${MOUNT_POINT} ${FS_TYPE} ${MOUNT_OPTIONS} 0 2" | $SUDO tee -a /etc/fstab
   info "mount ${DEVICE_PATH} ${MOUNT_POINT}"
-  $SUDO mount ${DEVICE_PATH} ${MOUNT_POINT}
+  $SUDO mount -t ${FS_TYPE} -o ${MOUNT_OPTIONS} ${DEVICE_PATH} ${MOUNT_POINT}
 
   return 0
 }